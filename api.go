@@ -4,96 +4,810 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
 )
 
+// addOptions describes the worktree addWorktree should create and what to
+// copy into it.
+type addOptions struct {
+	branch     string
+	fromBranch string
+	// track sets fromBranch, a remote-tracking branch, as the upstream of
+	// the branch created from it; noTrack keeps git from doing so.
+	track      bool
+	noTrack    bool
+	copyConfig bool
+	copyLibs   bool
+	noCheckout bool
+	quietGit   bool
+	linkEnv    bool
+	// copyUntracked copies the main worktree's untracked files too.
+	copyUntracked bool
+	// fetch decides whether a branch that only exists on the remote is
+	// fetched first: fetchAuto, fetchAlways or fetchNever.
+	fetch string
+	// allowProtected skips the worktree.protected check (--force).
+	allowProtected bool
+	cfg            wtConfig
+	// worktreeRoot, when set, is an absolute directory that holds the
+	// "<repo>-worktrees" directory instead of the repo's parent.
+	worktreeRoot string
+	// timings, when set, records how long each step took (--timings).
+	timings *phaseTimer
+	// copied, when set, records what the copies wrote.
+	copied *copySummary
+}
+
+// phaseTimer measures the phases of a command for --timings. Each mark
+// ends the phase running since the previous one. A nil *phaseTimer records
+// nothing, so callers don't need to check whether timings were asked for.
+type phaseTimer struct {
+	start  time.Time
+	last   time.Time
+	names  []string
+	totals map[string]time.Duration
+}
+
+func newPhaseTimer() *phaseTimer {
+	now := timeNow()
+	return &phaseTimer{start: now, last: now, totals: make(map[string]time.Duration)}
+}
+
+// mark ends the current phase and adds its time to name, which is listed in
+// the order first marked. Marking a name again, as jira new does for each
+// issue, adds up.
+func (t *phaseTimer) mark(name string) {
+	if t == nil {
+		return
+	}
+	now := timeNow()
+	if _, ok := t.totals[name]; !ok {
+		t.names = append(t.names, name)
+	}
+	t.totals[name] += now.Sub(t.last)
+	t.last = now
+}
+
+// print writes each phase and the total to stderr.
+func (t *phaseTimer) print() {
+	if t == nil {
+		return
+	}
+	width := len("total")
+	for _, name := range t.names {
+		width = max(width, len(name))
+	}
+	fmt.Fprintln(stderr, "timings:")
+	for _, name := range t.names {
+		fmt.Fprintf(stderr, "  %-*s  %s\n", width, name, roundDuration(t.totals[name]))
+	}
+	fmt.Fprintf(stderr, "  %-*s  %s\n", width, "total", roundDuration(t.last.Sub(t.start)))
+}
+
+// roundDuration keeps three significant digits or so: 1.23s, 45ms, 120µs.
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
 // addWorktree creates a new git worktree for the given branch.
 // repoRoot is the git repository root, mainWT is the main worktree path
 // (used as the base for the new worktree path and as the source for file copies).
-func addWorktree(repoRoot, mainWT, branch, fromBranch string, copyConfig, copyLibs bool) (string, error) {
+func addWorktree(repoRoot, mainWT string, opts addOptions) (string, error) {
+	branch := opts.branch
+	fromBranch := opts.fromBranch
 	if branch == "" {
 		return "", errors.New("branch required")
 	}
+	if !opts.allowProtected {
+		if err := checkProtectedBranch(opts.cfg, branch); err != nil {
+			return "", err
+		}
+	}
+	symlinks, err := copySymlinkMode(opts.cfg)
+	if err != nil {
+		return "", err
+	}
 
-	wtPath := worktreePath(mainWT, branch)
-	if err := osMkdirAll(filepath.Dir(wtPath), 0o755); err != nil {
+	wtPath := worktreePathUnder(opts.worktreeRoot, mainWT, branch)
+	if err := checkNotNested(mainWT, wtPath); err != nil {
+		return "", err
+	}
+	if err := checkWorktreeTarget(wtPath); err != nil {
+		return "", err
+	}
+	if err := checkCaseCollision(repoRoot, wtPath); err != nil {
 		return "", err
 	}
+	opts.timings.mark("checks")
 
+	addArgs := []string{"worktree", "add"}
+	if opts.noCheckout {
+		addArgs = append(addArgs, "--no-checkout")
+	}
+	if opts.quietGit {
+		addArgs = append(addArgs, "-q")
+	}
 	if fromBranch != "" {
-		if err := runGit(repoRoot, "worktree", "add", "-b", branch, wtPath, fromBranch); err != nil {
+		if err := validateBaseRef(repoRoot, fromBranch); err != nil {
 			return "", err
 		}
+		if opts.track {
+			addArgs = append(addArgs, "--track")
+		} else if opts.noTrack {
+			addArgs = append(addArgs, "--no-track")
+		}
+		addArgs = append(addArgs, "-b", branch, wtPath, fromBranch)
 	} else {
 		exists, err := gitBranchExists(repoRoot, branch)
 		if err != nil {
 			return "", err
 		}
-		if exists {
-			if err := runGit(repoRoot, "worktree", "add", wtPath, branch); err != nil {
+		var remoteRef string
+		if !exists {
+			remoteRef, err = remoteTrackingBranch(repoRoot, branch, opts.fetch)
+			if err != nil {
 				return "", err
 			}
-		} else {
-			if err := runGit(repoRoot, "worktree", "add", "-b", branch, wtPath); err != nil {
+		}
+		switch {
+		case exists:
+			addArgs = append(addArgs, wtPath, branch)
+		case remoteRef != "":
+			addArgs = append(addArgs, "--track", "-b", branch, wtPath, remoteRef)
+		default:
+			addArgs = append(addArgs, "-b", branch, wtPath)
+		}
+	}
+	// The parent directories are made only once the base and any remote
+	// branch check out, so a refused worktree leaves nothing behind.
+	if err := osMkdirAll(filepath.Dir(wtPath), 0o755); err != nil {
+		return "", err
+	}
+	if err := runGitProgress(repoRoot, opts.quietGit, addArgs...); err != nil {
+		return "", err
+	}
+	opts.timings.mark("git worktree add")
+
+	if len(opts.cfg.Worktree.GitConfig) > 0 {
+		applyGitConfig(repoRoot, wtPath, opts.cfg.Worktree.GitConfig)
+		opts.timings.mark("git config")
+	}
+
+	// Without a checkout there is no tree to mirror, so only the top-level
+	// config files are copied.
+	if opts.noCheckout {
+		if opts.copyConfig {
+			items, err := copyItems(mainWT, wtPath, defaultCopyConfigItems, symlinks)
+			if err != nil {
 				return "", err
 			}
+			opts.copied.addConfigItems(items)
+			opts.timings.mark("config copy")
 		}
+		writeTemplates(wtPath, opts)
+		opts.timings.mark("templates")
+		return wtPath, nil
 	}
 
-	if copyConfig {
-		if err := copyItems(mainWT, wtPath, defaultCopyConfigItems); err != nil {
+	if opts.copyConfig {
+		items, err := copyItems(mainWT, wtPath, defaultCopyConfigItems, symlinks)
+		if err != nil {
 			return "", err
 		}
-		if err := copyMatchingFiles(mainWT, wtPath, defaultCopyConfigRecursive); err != nil {
+		opts.copied.addConfigItems(items)
+		if opts.linkEnv && len(opts.cfg.Copy.EnvOverrides) > 0 {
+			fmt.Fprintln(stderr, "warning: copy.envOverrides is not applied to linked .env files")
+		}
+		stats, err := copyMatchingFiles(mainWT, wtPath, defaultCopyConfigRecursive, symlinks, opts.linkEnv, opts.cfg.Copy.EnvOverrides)
+		if err != nil {
+			return "", err
+		}
+		opts.copied.addConfig(stats)
+		items, err = copyPaths(mainWT, wtPath, opts.cfg.Copy.Paths, symlinks)
+		if err != nil {
 			return "", err
 		}
+		opts.copied.addConfigItems(items)
+		opts.timings.mark("config copy")
 	}
-	if copyLibs {
-		if err := copyItems(mainWT, wtPath, defaultCopyLibItems); err != nil {
+	if opts.copyLibs {
+		items, err := copyPaths(mainWT, wtPath, libItems(opts.cfg), symlinks)
+		if err != nil {
+			return "", err
+		}
+		opts.copied.addLibs(items)
+		opts.timings.mark("libs copy")
+	}
+
+	// Running after the other copies, this leaves linked and overridden
+	// .env files alone: copyUntracked skips files that already exist.
+	if opts.copyUntracked {
+		stats, err := copyUntracked(mainWT, wtPath, libItems(opts.cfg), opts.cfg.Copy.UntrackedSkip, symlinks)
+		if err != nil {
 			return "", err
 		}
+		opts.copied.addUntracked(stats)
+		opts.timings.mark("untracked copy")
+	}
+
+	writeTemplates(wtPath, opts)
+	opts.timings.mark("templates")
+
+	if init := opts.cfg.Worktree.InitSubmodules; init != nil && *init {
+		initSubmodules(wtPath, opts.quietGit)
+		opts.timings.mark("submodules")
 	}
 
 	return wtPath, nil
 }
 
-// findWorktree looks up a worktree by name, matching against branch name,
-// directory basename, or full path (in that priority order).
-func findWorktree(repoRoot, name string) (string, error) {
+// Values for addOptions.fetch.
+const (
+	fetchAuto   = ""
+	fetchAlways = "always"
+	fetchNever  = "never"
+)
+
+// remoteTrackingBranch returns the remote-tracking ref, such as
+// origin/feature, that a new local branch should track, or "" when the
+// remote has no such branch. With fetchAuto the remote is only fetched if
+// there is no remote-tracking ref yet, and a failed fetch (offline, say) only
+// warns, so the branch is created from the local base; fetchAlways always
+// fetches and fails with the fetch, and fetchNever never fetches.
+func remoteTrackingBranch(repoRoot, branch, mode string) (string, error) {
+	remote, err := gitDefaultRemote(repoRoot)
+	if err != nil || remote == "" {
+		return "", err
+	}
+	ref := remote + "/" + branch
+	tracked, err := gitRefExists(repoRoot, "refs/remotes/"+ref)
+	if err != nil {
+		return "", err
+	}
+	if mode == fetchNever || (mode == fetchAuto && tracked) {
+		if tracked {
+			return ref, nil
+		}
+		return "", nil
+	}
+	if err := gitFetchBranch(repoRoot, remote, branch); err != nil {
+		if errors.Is(err, errRemoteBranchNotFound) {
+			return "", nil
+		}
+		if mode == fetchAuto {
+			fmt.Fprintf(stderr, "warning: could not fetch %s from %s, creating it from the local base: %v\n", branch, remote, err)
+			return "", nil
+		}
+		return "", fmt.Errorf("could not fetch %s from %s: %w\ncheck your connection and access to %s, or pass --no-fetch to create the branch from what is known locally", branch, remote, err, remote)
+	}
+	tracked, err = gitRefExists(repoRoot, "refs/remotes/"+ref)
+	if err != nil || !tracked {
+		return "", err
+	}
+	return ref, nil
+}
+
+// remoteDefaultBase returns the remote-tracking ref of the default remote's
+// default branch, such as origin/main, for wt new --base-remote-default.
+// That is the branch the remote's HEAD points to, or main or master when it
+// has none recorded. Unless mode is fetchNever the branch is fetched first,
+// so the new branch starts from the remote's latest commit rather than a
+// stale copy; with fetchAuto a failed fetch only warns and the local copy
+// is used.
+func remoteDefaultBase(repoRoot, mode string) (string, error) {
+	remote, err := gitDefaultRemote(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	if remote == "" {
+		return "", errors.New("no remote to take the default branch from; add one or pass --from")
+	}
+	candidates := []string{"main", "master"}
+	if head := gitRemoteHead(repoRoot, remote); head != "" {
+		candidates = []string{head}
+	}
+	for _, name := range candidates {
+		if mode != fetchNever {
+			err := gitFetchBranch(repoRoot, remote, name)
+			if errors.Is(err, errRemoteBranchNotFound) {
+				continue
+			}
+			if err != nil && mode == fetchAuto {
+				fmt.Fprintf(stderr, "warning: could not fetch %s from %s, using the local copy: %v\n", name, remote, err)
+			} else if err != nil {
+				return "", fmt.Errorf("could not fetch %s from %s: %w\ncheck your connection and access to %s, or pass --no-fetch to use what is known locally", name, remote, err, remote)
+			}
+		}
+		ref := remote + "/" + name
+		exists, err := gitRefExists(repoRoot, "refs/remotes/"+ref)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return ref, nil
+		}
+	}
+	return "", fmt.Errorf("could not determine the default branch of %s (no %s/HEAD, main, or master)", remote, remote)
+}
+
+// newBranchBase picks the start point of a branch created without --from:
+// the remote's branch of the same name if there is one (see
+// remoteTrackingBranch), else whatever is checked out in the worktree at
+// repoRoot. tracking reports which of the two it is.
+func newBranchBase(repoRoot, branch, fetch string) (base string, tracking bool, err error) {
+	ref, err := remoteTrackingBranch(repoRoot, branch, fetch)
+	if err != nil || ref != "" {
+		return ref, ref != "", err
+	}
+	base, err = gitCurrentBase(repoRoot)
+	return base, false, err
+}
+
+// checkWorktreeTarget rejects a target path that already exists but is
+// neither a worktree nor an empty directory, which git worktree add would
+// otherwise report confusingly. git checks out into an empty directory.
+func checkWorktreeTarget(wtPath string) error {
+	if _, err := osStat(wtPath); err != nil {
+		return nil
+	}
+	if _, err := osLstat(filepath.Join(wtPath, ".git")); err == nil {
+		return nil
+	}
+	if entries, err := osReadDir(wtPath); err == nil && len(entries) == 0 {
+		return nil
+	}
+	return fmt.Errorf("target path %s already exists and is not a worktree; move it aside or pass --worktree-root", wtPath)
+}
+
+// checkCaseCollision rejects a target path that differs only in case from
+// an existing worktree's, as for branches Feature and feature. On a
+// case-insensitive filesystem, such as macOS's default, both are the same
+// directory, and the new worktree would open the existing one.
+func checkCaseCollision(repoRoot, wtPath string) error {
 	wts, err := gitWorktrees(repoRoot)
+	if err != nil {
+		return err
+	}
+	for _, wt := range wts {
+		if wt.Path != wtPath && strings.EqualFold(wt.Path, wtPath) {
+			return fmt.Errorf("worktree path %s differs only in case from existing worktree %s, which is the same directory on a case-insensitive filesystem; choose another branch name or pass --worktree-root", wtPath, wt.Path)
+		}
+	}
+	return nil
+}
+
+// checkNotNested rejects a worktree path inside the main worktree. git
+// would see the new worktree as untracked files of the main one, and
+// copying config files into it would walk into its own output.
+func checkNotNested(mainWT, wtPath string) error {
+	rel, err := filepath.Rel(mainWT, wtPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	return fmt.Errorf("worktree path %s is inside the main worktree %s; choose a --worktree-root outside the repository", wtPath, mainWT)
+}
+
+// resolveWorktreeRoot makes a --worktree-root argument absolute and checks
+// that it is a directory if it exists. A missing root is created by
+// addWorktree once the new worktree passes its checks, so a refused one
+// leaves nothing behind. An empty dir is returned as is.
+func resolveWorktreeRoot(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	abs, err := filepathAbs(expandHome(dir))
 	if err != nil {
 		return "", err
 	}
-	if len(wts) == 0 {
-		return "", errors.New("no worktrees found")
+	info, err := osStat(abs)
+	if errors.Is(err, os.ErrNotExist) {
+		return abs, nil
 	}
+	if err != nil {
+		return "", fmt.Errorf("invalid worktree root: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("worktree root %s is not a directory", abs)
+	}
+	return abs, nil
+}
+
+// initSubmodules initializes the submodules of a new worktree. The worktree
+// is usable without them, so a failure is only a warning.
+func initSubmodules(wtPath string, quiet bool) {
+	args := []string{"submodule", "update", "--init"}
+	if quiet {
+		args = append(args, "--quiet")
+	}
+	if err := runGitProgress(wtPath, quiet, args...); err != nil {
+		fmt.Fprintf(stderr, "warning: could not initialize submodules in %s: %v\n", wtPath, err)
+	}
+}
+
+// applyGitConfig sets worktree.gitConfig in the new worktree's own git
+// config, leaving the repository's other worktrees alone. The worktree is
+// usable without it, so failures are only warnings.
+func applyGitConfig(repoRoot, wtPath string, settings map[string]string) {
+	if err := gitEnableWorktreeConfig(repoRoot); err != nil {
+		fmt.Fprintf(stderr, "warning: worktree.gitConfig not applied: could not enable per-worktree git config: %v\n", err)
+		return
+	}
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := runGit(wtPath, "config", "--worktree", key, settings[key]); err != nil {
+			fmt.Fprintf(stderr, "warning: could not set git config %s in %s: %v\n", key, wtPath, err)
+		}
+	}
+}
+
+// writeTemplates renders worktree.templates into a new worktree. {base} is
+// the --from ref, empty when an existing branch was checked out. A file
+// that can't be written is only a warning.
+func writeTemplates(wtPath string, opts addOptions) {
+	templates := opts.cfg.Worktree.Templates
+	dests := make([]string, 0, len(templates))
+	for dest := range templates {
+		dests = append(dests, dest)
+	}
+	sort.Strings(dests)
+
+	r := strings.NewReplacer("{branch}", opts.branch, "{base}", opts.fromBranch, "{path}", wtPath)
+	for _, dest := range dests {
+		if !filepath.IsLocal(dest) {
+			fmt.Fprintf(stderr, "warning: skipping template %s: path must be relative to the worktree\n", dest)
+			continue
+		}
+		target := filepath.Join(wtPath, dest)
+		err := osMkdirAll(filepath.Dir(target), 0o755)
+		if err == nil {
+			err = osWriteFile(target, []byte(r.Replace(templates[dest])), 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: could not write template %s: %v\n", dest, err)
+		}
+	}
+}
 
+// validateBaseRef checks that base names something git can branch from:
+// a local branch or any other commit-ish (tag, SHA, remote ref, relative
+// ref such as main~3, reflog entry such as HEAD@{2}, ...). It is passed to
+// git worktree add as given.
+func validateBaseRef(repoRoot, base string) error {
+	isBranch, err := gitBranchExists(repoRoot, base)
+	if err != nil {
+		return err
+	}
+	if isBranch {
+		return nil
+	}
+	_, err = runGitOutput(repoRoot, "rev-parse", "--verify", base+"^{commit}")
+	if err == nil {
+		return nil
+	}
+	// git explains some failures, such as a reflog entry past the end of
+	// the log or a branch without an upstream; for the rest it only says
+	// "Needed a single revision".
+	lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+	reason := strings.TrimPrefix(lines[len(lines)-1], "fatal: ")
+	if len(lines) > 1 && reason != "" && reason != "Needed a single revision" {
+		return fmt.Errorf("base %q does not resolve to a commit: %s", base, reason)
+	}
+	return fmt.Errorf("base %q does not resolve to a branch, tag, or commit", base)
+}
+
+// worktreeForBranch returns the path of the worktree that has branch checked
+// out, if any.
+func worktreeForBranch(repoRoot, branch string) (string, bool, error) {
+	wts, err := gitWorktrees(repoRoot)
+	if err != nil {
+		return "", false, err
+	}
 	for _, wt := range wts {
-		if wt.Branch == name {
-			return wt.Path, nil
+		if wt.Branch == branch {
+			return wt.Path, true, nil
 		}
-		if filepath.Base(wt.Path) == name {
-			return wt.Path, nil
+	}
+	return "", false, nil
+}
+
+// findWorktree looks up a worktree by name (see lookupWorktree) and returns
+// its path.
+func findWorktree(repoRoot, name string) (string, error) {
+	wt, err := lookupWorktree(repoRoot, name)
+	if err != nil {
+		return "", err
+	}
+	return wt.Path, nil
+}
+
+// findWorktreeFuzzy is findWorktree that also accepts part of a name (see
+// lookupWorktreeFuzzy). Only wt go and wt t use it: a command that changes
+// or removes a worktree must be given an exact name.
+func findWorktreeFuzzy(repoRoot, name string) (string, error) {
+	wt, err := lookupWorktreeFuzzy(repoRoot, name)
+	if err != nil {
+		return "", err
+	}
+	return wt.Path, nil
+}
+
+// findWorktreeOrBookmark resolves name as a bookmark from the config if
+// there is one of that name, else as findWorktreeFuzzy does.
+func findWorktreeOrBookmark(repoRoot, name string) (string, error) {
+	return findBookmark(repoRoot, name, findWorktreeFuzzy)
+}
+
+// findBookmark resolves name as a bookmark from the config if there is one
+// of that name, else with find.
+func findBookmark(repoRoot, name string, find func(repoRoot, name string) (string, error)) (string, error) {
+	bookmarks, err := loadBookmarks(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	path, ok := bookmarks[name]
+	if !ok {
+		return find(repoRoot, name)
+	}
+	wts, err := gitWorktrees(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	for _, wt := range wts {
+		if wt.Path == path {
+			return path, nil
 		}
-		if wt.Path == name {
-			return wt.Path, nil
+	}
+	return "", fmt.Errorf("bookmark %s points to %s, which is not a worktree; update it with wt bookmark %s <branch>", name, path, name)
+}
+
+// worktreeBaseBranch resolves a worktree selector (as accepted by
+// findWorktree) to the branch checked out in that worktree.
+func worktreeBaseBranch(repoRoot, name string) (string, error) {
+	wt, err := lookupWorktree(repoRoot, name)
+	if err != nil {
+		return "", err
+	}
+	if wt.Branch == "" {
+		return "", fmt.Errorf("worktree %s has no branch checked out", wt.Path)
+	}
+	return wt.Branch, nil
+}
+
+// lookupWorktree resolves name to a worktree. An exact branch name wins over
+// an exact directory basename, which wins over an exact full path, so
+// "main" finds main even when "maintenance" exists. The keywords @main and
+// @root come before all of that and name the main worktree, whatever it has
+// checked out.
+func lookupWorktree(repoRoot, name string) (worktree, error) {
+	wts, err := gitWorktrees(repoRoot)
+	if err != nil {
+		return worktree{}, err
+	}
+	if wt, ok := matchWorktreeExact(wts, name); ok {
+		return wt, nil
+	}
+	return worktree{}, notFoundError(fmt.Errorf("%w: %s", errWorktreeNotFound, name))
+}
+
+// lookupWorktreeFuzzy is lookupWorktree, except that without any exact
+// match a substring of a branch or basename counts, as long as it matches a
+// single worktree.
+func lookupWorktreeFuzzy(repoRoot, name string) (worktree, error) {
+	wts, err := gitWorktrees(repoRoot)
+	if err != nil {
+		return worktree{}, err
+	}
+	if wt, ok := matchWorktreeExact(wts, name); ok {
+		return wt, nil
+	}
+
+	var found []worktree
+	for _, wt := range wts {
+		if name != "" && (strings.Contains(wt.Branch, name) || strings.Contains(filepath.Base(wt.Path), name)) {
+			found = append(found, wt)
 		}
 	}
-	return "", fmt.Errorf("worktree not found: %s", name)
+	switch len(found) {
+	case 0:
+		return worktree{}, notFoundError(fmt.Errorf("%w: %s", errWorktreeNotFound, name))
+	case 1:
+		return found[0], nil
+	}
+	labels := make([]string, len(found))
+	for i, wt := range found {
+		labels[i] = worktreeLabel(wt)
+	}
+	// Something did match, so this is a name to narrow down, not a miss.
+	return worktree{}, usageError(fmt.Errorf("%s matches several worktrees: %s", name, strings.Join(labels, ", ")))
+}
+
+// matchWorktreeExact returns the worktree of wts that name selects exactly,
+// in lookupWorktree's order of preference.
+func matchWorktreeExact(wts []worktree, name string) (worktree, bool) {
+	if isMainWorktreeKeyword(name) && len(wts) > 0 {
+		// The first worktree listed is the main one, as in gitMainWorktree.
+		return wts[0], true
+	}
+	exact := []func(wt worktree) bool{
+		func(wt worktree) bool { return wt.Branch == name },
+		func(wt worktree) bool { return filepath.Base(wt.Path) == name },
+		func(wt worktree) bool { return wt.Path == filepath.Clean(name) },
+	}
+	for _, match := range exact {
+		for _, wt := range wts {
+			if match(wt) {
+				return wt, true
+			}
+		}
+	}
+	return worktree{}, false
+}
+
+// errWorktreeNotFound is returned (as a notFoundError) when no worktree
+// matches at all, as opposed to several matching.
+var errWorktreeNotFound = errors.New("worktree not found")
+
+// isMainWorktreeKeyword reports whether name is @main or @root, which
+// select the main worktree.
+func isMainWorktreeKeyword(name string) bool {
+	return name == "@main" || name == "@root"
+}
+
+var errMainWorktree = blockedError(errors.New("cannot remove the main worktree"))
+
+// checkNotMainWorktree guards removal: git refuses to remove the main
+// worktree, but with an error that doesn't say why.
+func checkNotMainWorktree(mainWT, path string) error {
+	if filepath.Clean(mainWT) == filepath.Clean(path) {
+		return errMainWorktree
+	}
+	return nil
 }
 
 // removeWorktree removes a git worktree at the given path.
 func removeWorktree(repoRoot, path string) error {
+	if err := requireGitVersion(minGitVersion, "removing worktrees"); err != nil {
+		return err
+	}
 	return runGit(repoRoot, "worktree", "remove", path)
 }
 
-// openShell opens an interactive shell in the given directory.
+// renameWorktree renames the branch checked out at wtPath from oldBranch to
+// newBranch. When the worktree directory is named after the branch it is
+// moved to match, and the branch rename is undone if the move fails. It
+// returns the worktree's path afterwards.
+func renameWorktree(repoRoot, wtPath, oldBranch, newBranch string) (string, error) {
+	if err := requireGitVersion(minGitVersion, "moving worktrees"); err != nil {
+		return "", err
+	}
+	if newBranch == "" || newBranch == oldBranch {
+		return "", fmt.Errorf("choose a new name for %s", oldBranch)
+	}
+	exists, err := gitBranchExists(repoRoot, newBranch)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("branch %s already exists", newBranch)
+	}
+	newPath := wtPath
+	prefix, ok := strings.CutSuffix(filepath.Clean(wtPath), filepath.FromSlash(oldBranch))
+	if ok && strings.HasSuffix(prefix, string(filepath.Separator)) {
+		newPath = prefix + filepath.FromSlash(newBranch)
+		if _, err := osStat(newPath); err == nil {
+			return "", fmt.Errorf("%s already exists", newPath)
+		}
+	}
+	if err := runGit(repoRoot, "branch", "-m", oldBranch, newBranch); err != nil {
+		return "", err
+	}
+	if newPath == wtPath {
+		return wtPath, nil
+	}
+	err = osMkdirAll(filepath.Dir(newPath), 0o755)
+	if err == nil {
+		err = runGit(repoRoot, "worktree", "move", wtPath, newPath)
+	}
+	if err != nil {
+		_ = runGit(repoRoot, "branch", "-m", newBranch, oldBranch)
+		return "", err
+	}
+	return newPath, nil
+}
+
+var (
+	execLookPath = exec.LookPath
+	runtimeGOOS  = runtime.GOOS
+)
+
+var errTmuxUnsupported = errors.New("tmux is not supported on Windows")
+
+// defaultShell returns the shell used when $SHELL is unset or unusable:
+// /bin/sh, or on Windows %COMSPEC%, then pwsh, then powershell.
+func defaultShell() string {
+	if runtimeGOOS != "windows" {
+		return "/bin/sh"
+	}
+	if comspec := os.Getenv("COMSPEC"); comspec != "" {
+		return comspec
+	}
+	for _, name := range []string{"pwsh", "powershell"} {
+		if _, err := execLookPath(name); err == nil {
+			return name
+		}
+	}
+	return "cmd.exe"
+}
+
+// openShell opens an interactive shell in the given directory. $SHELL is used
+// when it names an executable; otherwise it falls back to defaultShell.
 func openShell(targetPath string) error {
-	shell := os.Getenv("SHELL")
+	return openShellWith(targetPath, "")
+}
+
+// openShellWith is openShell with an explicit shell (wt go --shell), which
+// takes precedence over $SHELL and must be executable.
+func openShellWith(targetPath, shell string) error {
+	if shell != "" {
+		if _, err := execLookPath(shell); err != nil {
+			return fmt.Errorf("--shell %s is not an executable: %w", shell, err)
+		}
+		return runShell(targetPath, shell)
+	}
+	shell = os.Getenv("SHELL")
 	if shell == "" {
-		shell = "/bin/sh"
+		shell = defaultShell()
+	} else if _, err := execLookPath(shell); err != nil {
+		fallback := defaultShell()
+		fmt.Fprintf(stderr, "warning: $SHELL %s is not executable; using %s\n", shell, fallback)
+		shell = fallback
+	}
+	return runShell(targetPath, shell)
+}
+
+// writeCdFile writes wtPath to file for a shell wrapper to cd into (the
+// --cd-file flag). The file is replaced atomically, so a wrapper never reads
+// a partial path. Failing to write it only warns: the worktree itself is
+// still usable.
+func writeCdFile(file, wtPath string) {
+	if file == "" {
+		return
+	}
+	f, err := osCreateTemp(filepath.Dir(file), ".wt-cd-*")
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: could not write %s: %v\n", file, err)
+		return
+	}
+	_, err = f.WriteString(wtPath + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = osRename(f.Name(), file)
 	}
+	if err != nil {
+		_ = osRemove(f.Name())
+		fmt.Fprintf(stderr, "warning: could not write %s: %v\n", file, err)
+	}
+}
 
+func runShell(targetPath, shell string) error {
 	cmd := execCommand(shell)
 	cmd.Dir = targetPath
 	cmd.Stdin = stdin
@@ -102,15 +816,328 @@ func openShell(targetPath string) error {
 	return cmd.Run()
 }
 
-// openTmux opens or attaches to a tmux session for the given directory.
+// openEditor opens path in $VISUAL or $EDITOR (falling back to vi) and waits
+// for the editor to exit. The editor setting may include arguments, such as
+// "code --wait".
+func openEditor(path string) error {
+	fields := strings.Fields(os.Getenv("VISUAL"))
+	if len(fields) == 0 {
+		fields = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(fields) == 0 {
+		fields = []string{"vi"}
+	}
+
+	cmd := execCommand(fields[0], append(fields[1:], path)...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", fields[0], err)
+	}
+	return nil
+}
+
+// tmuxSessionName returns the tmux session name for a worktree: its
+// directory name, or its branch when tmux.sessionNameFrom is "branch".
+// A worktree with a detached HEAD falls back to the directory name.
+func tmuxSessionName(cfg wtConfig, targetPath string) (string, error) {
+	from, err := tmuxSessionNameFrom(cfg)
+	if err != nil {
+		return "", err
+	}
+	return worktreeSessionName(from, targetPath), nil
+}
+
+// worktreeSessionName names the session for the worktree at targetPath
+// according to the sessionNameFrom mode.
+func worktreeSessionName(from, targetPath string) string {
+	branch := ""
+	if from == "branch" {
+		out, err := runGitOutput(targetPath, "symbolic-ref", "--quiet", "--short", "HEAD")
+		if err == nil {
+			branch = strings.TrimSpace(out)
+		}
+	}
+	return deriveSessionName(from, targetPath, branch)
+}
+
+// deriveSessionName names the session for a worktree at dir with the given
+// branch (empty when detached), according to the sessionNameFrom mode. A
+// directory name keeps its spaces and only loses the '.' and ':' tmux
+// rejects, so "v1.2" gives the session v1-2 that has-session and
+// switch-client find again, while existing sessions for other directories
+// keep their names.
+func deriveSessionName(from, dir, branch string) string {
+	if from == "branch" && branch != "" {
+		return sanitizeSessionName(branch)
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == ':' {
+			return '-'
+		}
+		return r
+	}, filepath.Base(dir))
+}
+
+// sanitizeSessionName makes name usable as a tmux session name, which may
+// not contain '.' or ':'. Slashes and whitespace become '-' as well, so
+// feature/login becomes feature-login.
+func sanitizeSessionName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '.' || r == ':' || unicode.IsSpace(r) {
+			return '-'
+		}
+		return r
+	}, name)
+}
+
+// fileManagerOpener returns the command that opens a directory in the
+// platform's file manager.
+func fileManagerOpener() (string, error) {
+	name := "xdg-open"
+	switch runtimeGOOS {
+	case "darwin":
+		name = "open"
+	case "windows":
+		name = "explorer"
+	}
+	if _, err := execLookPath(name); err != nil {
+		return "", fmt.Errorf("no file manager opener found: %s is not installed", name)
+	}
+	return name, nil
+}
+
+// revealWorktree opens targetPath in the OS file manager.
+func revealWorktree(targetPath string) error {
+	opener, err := fileManagerOpener()
+	if err != nil {
+		return err
+	}
+	// explorer exits non-zero even when it opened the window.
+	if err := execCommand(opener, targetPath).Run(); err != nil && opener != "explorer" {
+		return fmt.Errorf("%s failed: %w", opener, err)
+	}
+	return nil
+}
+
+// tmuxHasSession reports whether a tmux session with the given name exists.
+// Like every session wt targets, the name is prefixed with "=" so tmux
+// matches it exactly instead of falling back to a session whose name starts
+// with it.
+func tmuxHasSession(name string) bool {
+	return execCommand("tmux", "has-session", "-t", "="+name).Run() == nil
+}
+
+// renameTmuxSession renames the session oldName to newName. It does nothing
+// and reports false when no session named oldName exists.
+func renameTmuxSession(oldName, newName string) (bool, error) {
+	if runtimeGOOS == "windows" {
+		return false, errTmuxUnsupported
+	}
+	if oldName == newName || !tmuxHasSession(oldName) {
+		return false, nil
+	}
+	cmd := execCommand("tmux", "rename-session", "-t", "="+oldName, newName)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return false, fmt.Errorf("tmux rename-session failed: %w", err)
+	}
+	return true, nil
+}
+
+// tmuxSession is a running tmux session and the directory it was started
+// in.
+type tmuxSession struct {
+	Name string
+	Path string
+}
+
+// tmuxSessions lists the running tmux sessions. It returns none when no tmux
+// server is running, which list-sessions reports as an error.
+func tmuxSessions() []tmuxSession {
+	out, err := execCommand("tmux", "list-sessions", "-F", "#{session_name}\t#{session_path}").Output()
+	if err != nil {
+		return nil
+	}
+	var sessions []tmuxSession
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		name, path, ok := strings.Cut(line, "\t")
+		if !ok || name == "" {
+			continue
+		}
+		sessions = append(sessions, tmuxSession{Name: name, Path: path})
+	}
+	return sessions
+}
+
+// worktreeSessionBranch returns the branch wt would have created a worktree
+// at dir for, judged by its place under one of the "<repo>-worktrees"
+// directories in containers. It reports false for directories outside them.
+func worktreeSessionBranch(containers []string, dir string) (string, bool) {
+	for _, container := range containers {
+		rel, err := filepath.Rel(container, filepath.Clean(dir))
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return filepath.ToSlash(rel), true
+	}
+	return "", false
+}
+
+// pruneTmuxSessions kills the tmux sessions wt opened for worktrees of the
+// repository at repoRoot that no longer exist, and returns their names. A
+// session counts as wt's when it started in a directory under the repo's
+// "<repo>-worktrees" directory, next to the main worktree or in one of
+// roots, and its name is the one wt derives for that directory; sessions
+// for live worktrees and any others are left alone.
+func pruneTmuxSessions(repoRoot string, roots []string) ([]string, error) {
+	if runtimeGOOS == "windows" {
+		return nil, errTmuxUnsupported
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	from, err := tmuxSessionNameFrom(cfg)
+	if err != nil {
+		return nil, err
+	}
+	wts, err := gitWorktrees(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	mainWT, err := gitMainWorktree(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[string]bool, len(wts))
+	for _, wt := range wts {
+		live[filepath.Clean(wt.Path)] = true
+	}
+	containers := []string{worktreePathUnder("", mainWT, "")}
+	for _, root := range roots {
+		containers = append(containers, worktreePathUnder(root, mainWT, ""))
+	}
+
+	var killed []string
+	for _, s := range tmuxSessions() {
+		if s.Path == "" || live[filepath.Clean(s.Path)] {
+			continue
+		}
+		branch, ok := worktreeSessionBranch(containers, s.Path)
+		if !ok || deriveSessionName(from, s.Path, branch) != s.Name {
+			continue
+		}
+		// "=" makes tmux match the name exactly rather than as a prefix.
+		cmd := execCommand("tmux", "kill-session", "-t", "="+s.Name)
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			return killed, fmt.Errorf("tmux kill-session %s failed: %w", s.Name, err)
+		}
+		killed = append(killed, s.Name)
+	}
+	return killed, nil
+}
+
+// tmuxProjectFiles are the project files tmux.loader looks for in a
+// worktree, in order of preference.
+var tmuxProjectFiles = []struct{ loader, name string }{
+	{"tmuxp", ".tmuxp.yaml"},
+	{"tmuxp", ".tmuxp.yml"},
+	{"tmuxinator", ".tmuxinator.yml"},
+	{"tmuxinator", ".tmuxinator.yaml"},
+}
+
+// tmuxProjectFile finds a project file in dir that the configured loader
+// handles ("auto" accepts either tool's). It returns the loader to run and
+// the file, or empty strings when there is none.
+func tmuxProjectFile(loader, dir string) (string, string) {
+	if loader == "none" {
+		return "", ""
+	}
+	for _, pf := range tmuxProjectFiles {
+		if loader != "auto" && loader != pf.loader {
+			continue
+		}
+		path := filepath.Join(dir, pf.name)
+		if info, err := osStat(path); err == nil && !info.IsDir() {
+			return pf.loader, path
+		}
+	}
+	return "", ""
+}
+
+// openTmuxProject starts or attaches to the session described by a
+// tmuxp/tmuxinator project file, naming it sessionName so it matches the
+// plain sessions wt creates. Both tools switch clients themselves when run
+// inside tmux. It reports false when the loader is not installed.
+func openTmuxProject(loader, file, targetPath, sessionName string) (bool, error) {
+	if _, err := execLookPath(loader); err != nil {
+		fmt.Fprintf(stderr, "warning: %s not found; opening a plain tmux session\n", loader)
+		return false, nil
+	}
+	var cmd *exec.Cmd
+	if loader == "tmuxp" {
+		cmd = execCommand("tmuxp", "load", "-y", "-s", sessionName, file)
+	} else {
+		cmd = execCommand("tmuxinator", "start", "-p", file, "-n", sessionName)
+	}
+	cmd.Dir = targetPath
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return true, fmt.Errorf("%s failed: %w", loader, err)
+	}
+	return true, nil
+}
+
+// openTmux opens or attaches to a tmux session for the given directory. With
+// tmux.loader set and a matching project file in the directory, the session
+// is loaded from that file instead.
 func openTmux(targetPath string) error {
-	sessionName := filepath.Base(targetPath)
+	return openTmuxWith(targetPath, false)
+}
+
+// openTmuxWith is openTmux for wt t --no-switch: with noSwitch or
+// tmux.noSwitch set, running inside tmux with the session already there only
+// prints its name, leaving the current client where it is.
+func openTmuxWith(targetPath string, noSwitch bool) error {
+	if runtimeGOOS == "windows" {
+		return errTmuxUnsupported
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.Tmux.NoSwitch != nil && *cfg.Tmux.NoSwitch {
+		noSwitch = true
+	}
+	sessionName, err := tmuxSessionName(cfg, targetPath)
+	if err != nil {
+		return err
+	}
+	loader, err := tmuxLoader(cfg)
+	if err != nil {
+		return err
+	}
 
-	checkCmd := execCommand("tmux", "has-session", "-t", sessionName)
-	sessionExists := checkCmd.Run() == nil
+	sessionExists := tmuxHasSession(sessionName)
 
 	inTmux := os.Getenv("TMUX") != ""
 
+	if sessionExists && inTmux && noSwitch {
+		fmt.Fprintln(stdout, sessionName)
+		fmt.Fprintf(stderr, "session %s already exists; not switching to it\n", sessionName)
+		return nil
+	}
+	if loader, file := tmuxProjectFile(loader, targetPath); file != "" {
+		if loaded, err := openTmuxProject(loader, file, targetPath, sessionName); loaded || err != nil {
+			return err
+		}
+	}
+
 	if !sessionExists {
 		if inTmux {
 			cmd := execCommand("tmux", "new-session", "-d", "-s", sessionName, "-c", targetPath)
@@ -120,7 +1147,7 @@ func openTmux(targetPath string) error {
 			if err := cmd.Run(); err != nil {
 				return err
 			}
-			cmd = execCommand("tmux", "switch-client", "-t", sessionName)
+			cmd = execCommand("tmux", "switch-client", "-t", "="+sessionName)
 			cmd.Stdin = stdin
 			cmd.Stdout = stdout
 			cmd.Stderr = stderr
@@ -134,13 +1161,13 @@ func openTmux(targetPath string) error {
 	}
 
 	if inTmux {
-		cmd := execCommand("tmux", "switch-client", "-t", sessionName)
+		cmd := execCommand("tmux", "switch-client", "-t", "="+sessionName)
 		cmd.Stdin = stdin
 		cmd.Stdout = stdout
 		cmd.Stderr = stderr
 		return cmd.Run()
 	}
-	cmd := execCommand("tmux", "attach-session", "-t", sessionName)
+	cmd := execCommand("tmux", "attach-session", "-t", "="+sessionName)
 	cmd.Stdin = stdin
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
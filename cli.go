@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"path/filepath"
+	"strings"
 )
 
 func printUsage() {
@@ -17,6 +20,19 @@ func printUsage() {
 	fmt.Fprintln(stderr, "  list                list worktrees")
 	fmt.Fprintln(stderr, "  go <name>           enter a worktree shell")
 	fmt.Fprintln(stderr, "  t <name>            open worktree in tmux session")
+	fmt.Fprintln(stderr, "  reveal <name>       open worktree in the file manager")
+	fmt.Fprintln(stderr, "  base <name>         show where a worktree branched from")
+	fmt.Fprintln(stderr, "  rm <name>           remove a worktree")
+	fmt.Fprintln(stderr, "  undo                undo the last wt new or wt rm")
+	fmt.Fprintln(stderr, "  repair [<path>...]  fix worktrees moved by hand")
+	fmt.Fprintln(stderr, "  rename-session <old> <new>")
+	fmt.Fprintln(stderr, "                      rename a worktree's tmux session")
+	fmt.Fprintln(stderr, "  prune --merged      remove worktrees of merged branches")
+	fmt.Fprintln(stderr, "  bookmark <name> [<branch>]")
+	fmt.Fprintln(stderr, "                      name a worktree for wt go and wt t")
+	fmt.Fprintln(stderr, "  config get <key>    show a config setting")
+	fmt.Fprintln(stderr, "  config set <key> <value>")
+	fmt.Fprintln(stderr, "                      change a config setting")
 	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "  jira new <key>      create worktree from Jira issue")
 	fmt.Fprintln(stderr, "  jira status [key]   view/update Jira issue status")
@@ -35,30 +51,147 @@ func printNewUsage() {
 	fmt.Fprintln(stderr, "  -C, --no-copy-config   skip copying config files")
 	fmt.Fprintln(stderr, "  -l, --copy-libs        copy library directories")
 	fmt.Fprintln(stderr, "  -L, --no-copy-libs     skip copying libraries (default)")
-	fmt.Fprintln(stderr, "  -f, --from <branch>    base branch to create from")
+	fmt.Fprintln(stderr, "  -f, --from <ref>       base branch, tag, or commit to create from")
+	fmt.Fprintln(stderr, "  --base-remote-default  create from the remote's default branch")
+	fmt.Fprintln(stderr, "                         (origin/HEAD), fetching it first")
+	fmt.Fprintln(stderr, "  --into <worktree>      create from the branch checked out in an")
+	fmt.Fprintln(stderr, "                         existing worktree (alias: --from-worktree)")
+	fmt.Fprintln(stderr, "  --switch-existing      print the existing worktree if the branch")
+	fmt.Fprintln(stderr, "                         already has one instead of failing")
+	fmt.Fprintln(stderr, "  --no-checkout          register the worktree without checking out")
+	fmt.Fprintln(stderr, "                         files; only top-level config files are copied")
+	fmt.Fprintln(stderr, "  --quiet-git            silence git's progress output (default when")
+	fmt.Fprintln(stderr, "                         stderr is not a terminal)")
+	fmt.Fprintln(stderr, "  -q, --quiet            don't print a summary of the copied files")
+	fmt.Fprintln(stderr, "  --json                 print the result as a JSON object instead of")
+	fmt.Fprintln(stderr, "                         the worktree path")
+	fmt.Fprintln(stderr, "  --stash                move the current worktree's uncommitted")
+	fmt.Fprintln(stderr, "                         changes into the new worktree")
+	fmt.Fprintln(stderr, "  --worktree-root <dir>  put <repo>-worktrees/ under dir instead of")
+	fmt.Fprintln(stderr, "                         next to the repo, for this worktree only")
+	fmt.Fprintln(stderr, "  --link-env             symlink .env files to the main worktree's")
+	fmt.Fprintln(stderr, "                         instead of copying them")
+	fmt.Fprintln(stderr, "  --copy-untracked       copy every untracked file of the main")
+	fmt.Fprintln(stderr, "                         worktree, skipping large files")
+	fmt.Fprintln(stderr, "  --fetch                fetch a branch that isn't local from the")
+	fmt.Fprintln(stderr, "                         remote first, even if it was fetched before")
+	fmt.Fprintln(stderr, "  --no-fetch             never fetch; use remote branches known locally")
+	fmt.Fprintln(stderr, "  --force                create the worktree even if the branch is")
+	fmt.Fprintln(stderr, "                         listed in worktree.protected")
+	fmt.Fprintln(stderr, "  --timings              print how long each step took to stderr")
+	fmt.Fprintln(stderr, "  --cd-file <file>       write the worktree path to file, for a shell")
+	fmt.Fprintln(stderr, "                         function to cd into afterwards")
 }
 
 func printListUsage() {
-	fmt.Fprintln(stderr, "usage: wt list")
+	fmt.Fprintln(stderr, "usage: wt list [--all] [--format <fmt>] [--filter <glob>]")
+	fmt.Fprintln(stderr, "       wt list --current [--bare | --format <fmt>]")
 	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "List all worktrees with their branch names and paths.")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "options:")
+	fmt.Fprintln(stderr, "  -a, --all         list worktrees of every repo in the config's")
+	fmt.Fprintln(stderr, "                    \"repos\" registry, grouped by repo")
+	fmt.Fprintln(stderr, "  --current         list only the worktree you are in")
+	fmt.Fprintln(stderr, "  --bare            with --current, print only its path")
+	fmt.Fprintln(stderr, "  --format <fmt>    text (default), json, or porcelain; the json")
+	fmt.Fprintln(stderr, "                    and porcelain formats include clean status")
+	fmt.Fprintln(stderr, "  --filter <glob>   only list worktrees whose branch, path, or")
+	fmt.Fprintln(stderr, "                    directory name matches, e.g. 'PROJ-*'")
 }
 
 func printGoUsage() {
-	fmt.Fprintln(stderr, "usage: wt go <name>")
+	fmt.Fprintln(stderr, "usage: wt go [--create] [--tmux | --shell <path> | --cd-file <file>] <name>")
 	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "Open a shell in the named worktree. Matches against branch")
-	fmt.Fprintln(stderr, "names and directory basenames.")
+	fmt.Fprintln(stderr, "names and directory basenames; @main or @root is the main worktree.")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "options:")
+	fmt.Fprintln(stderr, "  --create          create a worktree for branch <name> unless one")
+	fmt.Fprintln(stderr, "                    matches exactly, as wt new <name> would")
+	fmt.Fprintln(stderr, "  -t, --tmux        open in a tmux session instead (same as wt t)")
+	fmt.Fprintln(stderr, "  --shell <path>    open this shell instead of $SHELL")
+	fmt.Fprintln(stderr, "  --cd-file <file>  write the worktree path to file instead of opening")
+	fmt.Fprintln(stderr, "                    a shell, for a shell function to cd into")
 }
 
 func printTmuxUsage() {
-	fmt.Fprintln(stderr, "usage: wt t <name>")
+	fmt.Fprintln(stderr, "usage: wt t [--no-switch] <name>")
+	fmt.Fprintln(stderr, "       wt t --prune [--worktree-root <dir>]")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Open the named worktree in a tmux session. With --prune, kill the")
+	fmt.Fprintln(stderr, "sessions wt opened for worktrees that have since been removed.")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "options:")
+	fmt.Fprintln(stderr, "  --no-switch       inside tmux, print the name of an existing session")
+	fmt.Fprintln(stderr, "                    instead of switching this client to it")
+	fmt.Fprintln(stderr, "  --prune           kill sessions of removed worktrees and list them")
+	fmt.Fprintln(stderr, "  --worktree-root   with --prune, also kill sessions of worktrees that")
+	fmt.Fprintln(stderr, "                    were created with this --worktree-root")
+}
+
+func printRevealUsage() {
+	fmt.Fprintln(stderr, "usage: wt reveal <name>")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Open the named worktree's directory in the file manager (open on")
+	fmt.Fprintln(stderr, "macOS, explorer on Windows, xdg-open elsewhere).")
+}
+
+func printBaseUsage() {
+	fmt.Fprintln(stderr, "usage: wt base <name>")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Show where the named worktree branched from: the short SHA of its")
+	fmt.Fprintln(stderr, "merge-base with the default branch (origin/HEAD, or main/master),")
+	fmt.Fprintln(stderr, "and how long ago that commit was made.")
+}
+
+func printRmUsage() {
+	fmt.Fprintln(stderr, "usage: wt rm [--force] <name>")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Remove the named worktree. Matches against branch names and")
+	fmt.Fprintln(stderr, "directory basenames. The main worktree cannot be removed, and")
+	fmt.Fprintln(stderr, "git refuses to remove a worktree with uncommitted changes.")
+	fmt.Fprintln(stderr, "Worktrees of branches listed in worktree.protected are only")
+	fmt.Fprintln(stderr, "removed with --force.")
+}
+
+func printRepairUsage() {
+	fmt.Fprintln(stderr, "usage: wt repair [--prune] [<path>...]")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Run git worktree repair to fix worktrees whose links to the")
+	fmt.Fprintln(stderr, "repository broke, for example after moving the repo. If you")
+	fmt.Fprintln(stderr, "moved a worktree directory by hand, pass its new path. Worktrees")
+	fmt.Fprintln(stderr, "that are still missing afterwards are reported.")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "options:")
+	fmt.Fprintln(stderr, "  --prune    forget worktrees whose directory is still missing")
+}
+
+func printUndoUsage() {
+	fmt.Fprintln(stderr, "usage: wt undo [options]")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Reverse the last wt new or wt rm. Undoing wt new removes the")
+	fmt.Fprintln(stderr, "worktree if it has no uncommitted changes. Undoing wt rm checks")
+	fmt.Fprintln(stderr, "the branch out again at the same path; uncommitted files that")
+	fmt.Fprintln(stderr, "were removed with it cannot be restored. Only the single most")
+	fmt.Fprintln(stderr, "recent operation can be undone.")
 	fmt.Fprintln(stderr, "")
-	fmt.Fprintln(stderr, "Open the named worktree in a tmux session.")
+	fmt.Fprintln(stderr, "options:")
+	fmt.Fprintln(stderr, "  -D, --delete-branch  when undoing wt new, also delete the branch")
+	fmt.Fprintln(stderr, "                       if wt new created it")
+}
+
+func printRenameSessionUsage() {
+	fmt.Fprintln(stderr, "usage: wt rename-session <old> <new>")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Rename the tmux session of a worktree whose directory or branch")
+	fmt.Fprintln(stderr, "was renamed. <old> is the previous directory or branch name and")
+	fmt.Fprintln(stderr, "<new> names the worktree as it is now. Does nothing if there is no")
+	fmt.Fprintln(stderr, "session under the old name.")
 }
 
 func printJiraUsage() {
-	fmt.Fprintln(stderr, "usage: wt jira <new|status|config> [options]")
+	fmt.Fprintln(stderr, "usage: wt jira [--site <name>] <new|status|config> [options]")
 	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "Jira integration for worktree management.")
 	fmt.Fprintln(stderr, "")
@@ -68,15 +201,68 @@ func printJiraUsage() {
 	fmt.Fprintln(stderr, "  status sync         sync Jira status from GitHub PR state")
 	fmt.Fprintln(stderr, "  config              show status mappings")
 	fmt.Fprintln(stderr, "  config --init       bootstrap a template config")
+	fmt.Fprintln(stderr, "  <key>               same as new <key>")
+	fmt.Fprintln(stderr, "  <issue URL>         same as new <issue URL>")
+	fmt.Fprintln(stderr, "  <number>            same as new <number>")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Wherever a key is expected, an issue URL copied from the browser")
+	fmt.Fprintln(stderr, "works too, as does a bare issue number when jira.defaultProject")
+	fmt.Fprintln(stderr, "is configured.")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "--site <name> uses that site from jira.sites. Without it, an issue")
+	fmt.Fprintln(stderr, "in a project a site lists goes to that site and any other to the")
+	fmt.Fprintln(stderr, "default site, set by the environment variables below.")
 	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "environment variables: JIRA_URL, JIRA_USER, JIRA_TOKEN")
 }
 
+func printPruneUsage() {
+	fmt.Fprintln(stderr, "usage: wt prune --merged [options]")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Remove worktrees whose branch is fully merged into the default")
+	fmt.Fprintln(stderr, "branch (origin/HEAD, or main/master). Worktrees with uncommitted")
+	fmt.Fprintln(stderr, "changes are skipped unless --fail-dirty is given.")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "options:")
+	fmt.Fprintln(stderr, "  --merged           remove worktrees of merged branches")
+	fmt.Fprintln(stderr, "  -D, --delete-branch also delete the merged branch")
+	fmt.Fprintln(stderr, "  -n, --dry-run      show what would be removed")
+	fmt.Fprintln(stderr, "  --skip-dirty       skip worktrees with uncommitted changes (default)")
+	fmt.Fprintln(stderr, "  --fail-dirty       stop before removing anything if a worktree has")
+	fmt.Fprintln(stderr, "                     uncommitted changes (same as --skip-dirty=false)")
+}
+
+func printBookmarkUsage() {
+	fmt.Fprintln(stderr, "usage: wt bookmark <name> [<branch>]")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Bookmark the current worktree, or the one for <branch>, as <name> in")
+	fmt.Fprintln(stderr, "the main worktree's .wt.json. wt go <name> and wt t <name> then open")
+	fmt.Fprintln(stderr, "it from any worktree, even after its branch is renamed. A bookmark is")
+	fmt.Fprintln(stderr, "checked before branch and directory names.")
+}
+
+func printConfigUsage() {
+	fmt.Fprintln(stderr, "usage: wt config get [--global | --repo] <key>")
+	fmt.Fprintln(stderr, "       wt config set [--global | --repo] <key> <value>")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "Show or change a config setting. Keys are dotted paths into the")
+	fmt.Fprintln(stderr, "config JSON, such as worktree.protected or tmux.noSwitch. get shows")
+	fmt.Fprintln(stderr, "the merged config; set writes the repo's .wt.json if it exists, else")
+	fmt.Fprintln(stderr, "the global config. A list can be given comma-separated, as in")
+	fmt.Fprintln(stderr, "wt config set copy.libs node_modules,.venv.")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "options:")
+	fmt.Fprintln(stderr, "  --global          use the global config (~/.config/wt/config.json)")
+	fmt.Fprintln(stderr, "  --repo            use the repo config (.wt.json)")
+}
+
 func printJiraNewUsage() {
-	fmt.Fprintln(stderr, "usage: wt jira new [options] <key>")
+	fmt.Fprintln(stderr, "usage: wt jira new [options] <key> [key...]")
 	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "Create a worktree from a Jira issue. The branch name is")
-	fmt.Fprintln(stderr, "generated from the issue key and summary.")
+	fmt.Fprintln(stderr, "generated from the issue key and summary. With several keys,")
+	fmt.Fprintln(stderr, "a worktree is created for each issue and a summary is printed.")
+	fmt.Fprintln(stderr, "A key may also be given as an issue URL.")
 	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "options:")
 	fmt.Fprintln(stderr, "  -t                     open worktree in tmux after creation")
@@ -85,18 +271,42 @@ func printJiraNewUsage() {
 	fmt.Fprintln(stderr, "  -C, --no-copy-config   skip copying config files")
 	fmt.Fprintln(stderr, "  -l, --copy-libs        copy library directories")
 	fmt.Fprintln(stderr, "  -L, --no-copy-libs     skip copying libraries (default)")
-	fmt.Fprintln(stderr, "  -f, --from <branch>    base branch to create from")
+	fmt.Fprintln(stderr, "  -f, --from <ref>       base branch, tag, or commit to create from")
 	fmt.Fprintln(stderr, "  -S, --no-status-update skip auto-transition to working")
+	fmt.Fprintln(stderr, "  --force                create a worktree even if one already exists")
+	fmt.Fprintln(stderr, "                         for the issue (a branch named <key> or <key>-*)")
+	fmt.Fprintln(stderr, "                         or the branch is in worktree.protected")
+	fmt.Fprintln(stderr, "  --worktree-root <dir>  put <repo>-worktrees/ under dir instead of")
+	fmt.Fprintln(stderr, "                         next to the repo")
+	fmt.Fprintln(stderr, "  --children             for an epic, list its child issues in the")
+	fmt.Fprintln(stderr, "                         generated markdown")
+	fmt.Fprintln(stderr, "  --all-comments         fetch every comment for the markdown, not")
+	fmt.Fprintln(stderr, "                         just the first page Jira returns inline")
+	fmt.Fprintln(stderr, "  --timings              print how long each step took to stderr")
+	fmt.Fprintln(stderr, "  --quiet-git            silence git's progress output (default when")
+	fmt.Fprintln(stderr, "                         stderr is not a terminal)")
+	fmt.Fprintln(stderr, "  --retries <n>          retry a request Jira rate-limits (429) up to n")
+	fmt.Fprintln(stderr, "                         times, waiting as Retry-After asks (default: 3)")
+	fmt.Fprintln(stderr, "  --sprint <sprint>      move the issue into a sprint: \"current\" for the")
+	fmt.Fprintln(stderr, "                         active sprint of its board, or a sprint ID")
+	fmt.Fprintln(stderr, "  --fix-version <v>      add v to the issue's fix versions")
 	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "environment variables: JIRA_URL, JIRA_USER, JIRA_TOKEN")
 }
 
 func printJiraStatusUsage() {
-	fmt.Fprintln(stderr, "usage: wt jira status [key] [status]")
+	fmt.Fprintln(stderr, "usage: wt jira status [--set <status> | --watch [--until <status>]] [key] [status]")
 	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "View or update a Jira issue's status. If no key is given,")
 	fmt.Fprintln(stderr, "the issue key is inferred from the current branch name.")
 	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "options:")
+	fmt.Fprintln(stderr, "  --set <status>      transition to the named status (case-insensitive)")
+	fmt.Fprintln(stderr, "  --watch             poll the issue and print status changes (Ctrl-C to stop)")
+	fmt.Fprintln(stderr, "  --until <status>    with --watch, exit once the issue reaches this status")
+	fmt.Fprintln(stderr, "  --interval <dur>    with --watch, time between polls (default: 30s)")
+	fmt.Fprintln(stderr, "  --retries <n>       with --watch, consecutive errors tolerated (default: 3)")
+	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "subcommands:")
 	fmt.Fprintln(stderr, "  sync                sync status from GitHub PR state")
 	fmt.Fprintln(stderr, "")
@@ -105,13 +315,110 @@ func printJiraStatusUsage() {
 }
 
 func printJiraConfigUsage() {
-	fmt.Fprintln(stderr, "usage: wt jira config [--init]")
+	fmt.Fprintln(stderr, "usage: wt jira config [--init | --edit] [--global | --repo] [key]")
+	fmt.Fprintln(stderr, "       wt jira config --validate <key>")
 	fmt.Fprintln(stderr, "")
 	fmt.Fprintln(stderr, "Show current Jira status mappings, or bootstrap a template")
 	fmt.Fprintln(stderr, "config file with --init.")
+	fmt.Fprintln(stderr, "")
+	fmt.Fprintln(stderr, "options:")
+	fmt.Fprintln(stderr, "  --init            write a template config file")
+	fmt.Fprintln(stderr, "  --edit            open the config file in $EDITOR, then validate it")
+	fmt.Fprintln(stderr, "  --global          use ~/.config/wt/config.json instead of asking")
+	fmt.Fprintln(stderr, "  --repo            use the repo's .wt.json instead of asking")
+	fmt.Fprintln(stderr, "  --validate <key>  check that each mapped status can be reached from")
+	fmt.Fprintln(stderr, "                    the issue's current status")
+}
+
+// commandNames lists the top-level subcommands, used to suggest a
+// correction for a mistyped command.
+var commandNames = []string{"new", "list", "go", "t", "reveal", "base", "rm", "undo", "repair", "rename-session", "prune", "bookmark", "config", "jira", "help"}
+
+// isCommand reports whether name is a built-in subcommand, including the
+// help flags. Aliases can't shadow these.
+func isCommand(name string) bool {
+	if name == "-h" || name == "--help" {
+		return true
+	}
+	for _, cmd := range commandNames {
+		if cmd == name {
+			return true
+		}
+	}
+	return false
+}
+
+// expandAliases replaces args[0] with the expansion of the alias of that
+// name, as often as it names another alias, and returns args unchanged when
+// it names none. Expansions are split on whitespace; there is no quoting.
+func expandAliases(args []string, aliases map[string]string) ([]string, error) {
+	var chain []string
+	for !isCommand(args[0]) {
+		name := args[0]
+		expansion, ok := aliases[name]
+		if !ok {
+			return args, nil
+		}
+		chain = append(chain, name)
+		for _, seen := range chain[:len(chain)-1] {
+			if seen == name {
+				return nil, usageError(fmt.Errorf("alias %s is recursive: %s", chain[0], strings.Join(chain, " → ")))
+			}
+		}
+		tokens := strings.Fields(expansion)
+		if len(tokens) == 0 {
+			return nil, usageError(fmt.Errorf("alias %s is empty", name))
+		}
+		args = append(tokens, args[1:]...)
+	}
+	return args, nil
+}
+
+// suggestCommand returns the subcommand closest to name, or "" when none is
+// close enough to be a likely typo.
+func suggestCommand(name string) string {
+	best, bestDist := "", 3
+	for _, cmd := range commandNames {
+		d := levenshtein(name, cmd)
+		if d < bestDist && d < len(cmd) {
+			best, bestDist = cmd, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// isHelpArg reports whether args starts with a help flag, so commands can
+// print their usage before parsing flags or touching git.
+func isHelpArg(args []string) bool {
+	return len(args) > 0 && (args[0] == "-h" || args[0] == "--help")
 }
 
 func newCmd(args []string) {
+	if isHelpArg(args) {
+		printNewUsage()
+		return
+	}
 	fs := flag.NewFlagSet("new", flag.ExitOnError)
 	fs.Usage = printNewUsage
 	copyConfig := fs.Bool("copy-config", true, "copy config files")
@@ -124,17 +431,36 @@ func newCmd(args []string) {
 	fs.BoolVar(noCopyLibs, "L", false, "skip copying libraries")
 	fromBranch := fs.String("from", "", "base branch to create from")
 	fs.StringVar(fromBranch, "f", "", "base branch to create from")
+	remoteDefault := fs.Bool("base-remote-default", false, "create from the remote's default branch, fetched first")
+	into := fs.String("into", "", "create from the branch checked out in this worktree")
+	fs.StringVar(into, "from-worktree", "", "create from the branch checked out in this worktree")
+	switchExisting := fs.Bool("switch-existing", false, "reuse an existing worktree for the branch")
+	noCheckout := fs.Bool("no-checkout", false, "register the worktree without checking out files")
+	quietGit := fs.Bool("quiet-git", !stderrIsTerminal(), "silence git's progress output")
+	quiet := fs.Bool("quiet", false, "don't print a summary of the copied files")
+	fs.BoolVar(quiet, "q", false, "don't print a summary of the copied files")
+	jsonOut := fs.Bool("json", false, "print the result as JSON")
+	stash := fs.Bool("stash", false, "move uncommitted changes into the new worktree")
+	worktreeRoot := fs.String("worktree-root", "", "create the worktree under this directory")
+	linkEnv := fs.Bool("link-env", false, "symlink .env files to the main worktree's instead of copying them")
+	copyUntracked := fs.Bool("copy-untracked", false, "copy the main worktree's untracked files")
+	fetch := fs.Bool("fetch", false, "fetch the branch from the remote first")
+	noFetch := fs.Bool("no-fetch", false, "never fetch the branch from the remote")
+	force := fs.Bool("force", false, "create the worktree even if the branch is protected")
+	timings := fs.Bool("timings", false, "print how long each step took")
+	cdFile := fs.String("cd-file", "", "write the worktree path to this file")
 	_ = fs.Parse(args)
+	var timer *phaseTimer
+	if *timings {
+		timer = newPhaseTimer()
+	}
 
 	branch := ""
 	if fs.NArg() > 0 {
 		branch = fs.Arg(0)
 	}
 	if branch == "" {
-		fmt.Fprintln(stderr, "error: branch required")
-		fmt.Fprintln(stderr, "")
-		printNewUsage()
-		exitFunc(1)
+		dieUsage("branch required", printNewUsage)
 		return
 	}
 
@@ -144,6 +470,31 @@ func newCmd(args []string) {
 	if *noCopyLibs {
 		*copyLibs = false
 	}
+	if *into != "" && *fromBranch != "" {
+		die(usageError(errors.New("--into and --from cannot be used together")))
+	}
+	if *remoteDefault && (*into != "" || *fromBranch != "") {
+		die(usageError(errors.New("--base-remote-default cannot be used with --from or --into")))
+	}
+	if *stash && *noCheckout {
+		die(usageError(errors.New("--stash and --no-checkout cannot be used together")))
+	}
+	if *copyUntracked && *noCheckout {
+		die(usageError(errors.New("--copy-untracked and --no-checkout cannot be used together")))
+	}
+	if *fetch && *noFetch {
+		die(usageError(errors.New("--fetch and --no-fetch cannot be used together")))
+	}
+	fetchMode := fetchAuto
+	if *fetch {
+		fetchMode = fetchAlways
+	} else if *noFetch {
+		fetchMode = fetchNever
+	}
+	root, err := resolveWorktreeRoot(*worktreeRoot)
+	if err != nil {
+		die(err)
+	}
 
 	repoRoot, err := gitRepoRoot()
 	if err != nil {
@@ -153,15 +504,189 @@ func newCmd(args []string) {
 	if err != nil {
 		die(err)
 	}
+	if *into != "" {
+		base, err := worktreeBaseBranch(repoRoot, *into)
+		if err != nil {
+			die(err)
+		}
+		*fromBranch = base
+	}
+	if *switchExisting {
+		existing, found, err := worktreeForBranch(repoRoot, branch)
+		if err != nil {
+			die(err)
+		}
+		if found {
+			fmt.Fprintf(stderr, "worktree for %s already exists\n", branch)
+			writeCdFile(*cdFile, existing)
+			if *jsonOut {
+				printNewResult(newResult{Branch: branch, Path: existing})
+				return
+			}
+			fmt.Fprintln(stdout, existing)
+			return
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		die(err)
+	}
+	if *remoteDefault {
+		*fromBranch, err = remoteDefaultBase(repoRoot, fetchMode)
+		if err != nil {
+			die(err)
+		}
+		fmt.Fprintf(stderr, "creating %s from %s\n", branch, *fromBranch)
+	}
+
+	start, err := branchStartFor(repoRoot, branch, *fromBranch, fetchMode)
+	if err != nil {
+		die(err)
+	}
 
-	wtPath, err := addWorktree(repoRoot, mainWT, branch, *fromBranch, *copyConfig, *copyLibs)
+	stashed := ""
+	if *stash {
+		stashed, err = stashForNew(repoRoot, branch)
+		if err != nil {
+			die(err)
+		}
+	}
+	timer.mark("setup")
+
+	copied := &copySummary{}
+	wtPath, err := addWorktree(repoRoot, mainWT, addOptions{
+		branch:         branch,
+		fromBranch:     start.from,
+		copyConfig:     *copyConfig,
+		copyLibs:       *copyLibs,
+		noCheckout:     *noCheckout,
+		quietGit:       *quietGit,
+		linkEnv:        *linkEnv,
+		copyUntracked:  *copyUntracked,
+		fetch:          start.fetch,
+		allowProtected: *force,
+		cfg:            cfg,
+		worktreeRoot:   root,
+		timings:        timer,
+		copied:         copied,
+		noTrack:        *remoteDefault,
+	})
 	if err != nil {
+		if stashed != "" {
+			if restoreErr := gitStashApply(repoRoot, stashed); restoreErr != nil {
+				err = fmt.Errorf("%w; your changes are still in the stash (%s): %v", err, stashed, restoreErr)
+			}
+		}
 		die(err)
 	}
+	if stashed != "" {
+		if err := gitStashApply(wtPath, stashed); err != nil {
+			if undoErr := gitStashUndo(wtPath, stashed); undoErr != nil {
+				die(fmt.Errorf("could not apply your changes in %s, and undoing the partial apply failed (%v); they are still in the stash (%s): %w", wtPath, undoErr, stashed, err))
+			}
+			die(fmt.Errorf("could not apply your changes in %s, so it was left clean; they are still in the stash (%s): %w", wtPath, stashed, err))
+		}
+		fmt.Fprintf(stderr, "moved uncommitted changes from %s\n", repoRoot)
+		timer.mark("stash apply")
+	}
+	recordJournal(repoRoot, journalEntry{Op: journalOpNew, Path: wtPath, Branch: branch, BranchCreated: start.created})
+	if line := copied.String(); line != "" && !*quiet {
+		fmt.Fprintln(stderr, line)
+	}
+	timer.print()
+	writeCdFile(*cdFile, wtPath)
 
+	if *jsonOut {
+		printNewResult(newResult{
+			Branch:       branch,
+			Path:         wtPath,
+			Created:      true,
+			CopiedConfig: copied.config.files > 0,
+			CopiedLibs:   len(copied.libs) > 0,
+			Base:         start.base,
+		})
+		return
+	}
 	fmt.Fprintln(stdout, wtPath)
 }
 
+// branchStart is where the branch of a new worktree comes from, as
+// branchStartFor decides.
+type branchStart struct {
+	// from is the ref addWorktree creates the branch from, empty to check
+	// out an existing branch or track the remote's.
+	from string
+	// base is the ref reported as the branch's base, empty for a branch
+	// that already exists.
+	base    string
+	fetch   string
+	created bool
+}
+
+// branchStartFor decides how wt new gets branch, given its --from (which
+// may be empty) and fetch mode. A branch with a --from is created from it,
+// an existing one is checked out, and any other is created from
+// newBranchBase's choice, which is reported on stderr.
+func branchStartFor(repoRoot, branch, from, fetchMode string) (branchStart, error) {
+	start := branchStart{from: from, base: from, fetch: fetchMode, created: from != ""}
+	if start.created {
+		return start, nil
+	}
+	exists, err := gitBranchExists(repoRoot, branch)
+	if err != nil || exists {
+		return start, err
+	}
+	// Spell out where a new branch starts, which from a linked worktree is
+	// that worktree's branch rather than the main one's.
+	base, tracking, err := newBranchBase(repoRoot, branch, fetchMode)
+	if err != nil {
+		return branchStart{}, err
+	}
+	fmt.Fprintf(stderr, "creating %s from %s\n", branch, base)
+	start.base = base
+	start.created = true
+	if tracking {
+		// addWorktree finds the remote branch again and tracks it; it is
+		// known locally now, so don't fetch twice.
+		start.fetch = fetchNever
+	} else {
+		start.from = base
+	}
+	return start, nil
+}
+
+// stashForNew stashes the uncommitted changes in the current worktree for
+// wt new --stash and returns the stash commit, or "" when there is nothing
+// to move.
+func stashForNew(repoRoot, branch string) (string, error) {
+	clean, err := gitWorktreeClean(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	if clean {
+		fmt.Fprintln(stderr, "no uncommitted changes to move")
+		return "", nil
+	}
+	return gitStashPush(repoRoot, "wt new "+branch)
+}
+
+// newResult is the --json output of wt new. Created is false when
+// --switch-existing found a worktree that was already there.
+type newResult struct {
+	Branch       string `json:"branch"`
+	Path         string `json:"path"`
+	Created      bool   `json:"created"`
+	CopiedConfig bool   `json:"copiedConfig"`
+	CopiedLibs   bool   `json:"copiedLibs"`
+	Base         string `json:"base,omitempty"`
+}
+
+func printNewResult(res newResult) {
+	data, _ := json.Marshal(res)
+	fmt.Fprintln(stdout, string(data))
+}
+
 func listCmd(args []string) {
 	for _, a := range args {
 		if a == "-h" || a == "--help" || a == "help" {
@@ -169,8 +694,39 @@ func listCmd(args []string) {
 			return
 		}
 	}
-	if len(args) > 0 {
-		die(errors.New("list does not take arguments"))
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Usage = printListUsage
+	all := fs.Bool("all", false, "list worktrees of every registered repo")
+	fs.BoolVar(all, "a", false, "list worktrees of every registered repo")
+	format := fs.String("format", listFormatText, "output format: text, json, or porcelain")
+	filter := fs.String("filter", "", "only list worktrees matching this glob")
+	current := fs.Bool("current", false, "list only the current worktree")
+	bare := fs.Bool("bare", false, "with --current, print only the path")
+	_ = fs.Parse(args)
+	if fs.NArg() > 0 {
+		die(usageError(errors.New("list does not take arguments")))
+	}
+	if *current && (*all || *filter != "") {
+		die(usageError(errors.New("--current cannot be used with --all or --filter")))
+	}
+	if *bare && !*current {
+		die(usageError(errors.New("--bare requires --current")))
+	}
+	if *bare && *format != listFormatText {
+		die(usageError(errors.New("--bare cannot be used with --format")))
+	}
+	switch *format {
+	case listFormatText, listFormatJSON, listFormatPorcelain:
+	default:
+		die(usageError(fmt.Errorf("invalid --format %q: must be text, json, or porcelain", *format)))
+	}
+	if _, err := filepath.Match(*filter, ""); err != nil {
+		die(usageError(fmt.Errorf("invalid --filter %q: %w", *filter, err)))
+	}
+
+	if *all {
+		listAllRepos(*format, *filter)
+		return
 	}
 
 	repoRoot, err := gitRepoRoot()
@@ -182,81 +738,1135 @@ func listCmd(args []string) {
 	if err != nil {
 		die(err)
 	}
+	wts = filterWorktrees(wts, *filter)
+	markUpstreamGone(repoRoot, wts)
+	if *current {
+		wt, err := currentWorktree(repoRoot, wts)
+		if err != nil {
+			die(err)
+		}
+		if *bare {
+			fmt.Fprintln(stdout, wt.Path)
+			return
+		}
+		wts = []worktree{wt}
+	}
+
+	if *format == listFormatText {
+		printWorktreeList(wts, "")
+		return
+	}
+	printListEntries(*format, listEntries("", wts))
+}
+
+// currentWorktree picks the worktree at repoRoot, the top level of the
+// current directory, out of wts.
+func currentWorktree(repoRoot string, wts []worktree) (worktree, error) {
+	for _, wt := range wts {
+		if wt.Path == repoRoot {
+			return wt, nil
+		}
+	}
+	return worktree{}, notFoundError(fmt.Errorf("%s is not among the repository's worktrees", repoRoot))
+}
+
+// listAllRepos lists the worktrees of every repo in the config's repos
+// registry, plus the current repo, grouped under each repo root. A repo that
+// can't be listed is reported and the rest are still printed. With a
+// filter, repos with no matching worktrees are left out.
+func listAllRepos(format, filter string) {
+	cfg, err := loadConfig()
+	if err != nil {
+		die(err)
+	}
+	var roots []string
+	if root, err := gitRepoRoot(); err == nil {
+		roots = append(roots, root)
+	}
+	for _, r := range cfg.Repos {
+		roots = append(roots, expandHome(r))
+	}
+	roots = uniquePaths(roots)
+	if len(roots) == 0 {
+		die(errors.New("no repos to list: not in a git repository and no repos configured"))
+	}
+
+	failed := false
+	printed := 0
+	entries := []listEntry{}
+	for _, root := range roots {
+		wts, err := gitWorktrees(root)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", root, err)
+			failed = true
+			continue
+		}
+		wts = filterWorktrees(wts, filter)
+		markUpstreamGone(root, wts)
+		if format != listFormatText {
+			entries = append(entries, listEntries(root, wts)...)
+			continue
+		}
+		if filter != "" && len(wts) == 0 {
+			continue
+		}
+		if printed > 0 {
+			fmt.Fprintln(stdout)
+		}
+		printed++
+		fmt.Fprintln(stdout, root)
+		printWorktreeList(wts, "  ")
+	}
+	if format != listFormatText {
+		printListEntries(format, entries)
+	}
+	if failed {
+		exitFunc(exitError)
+	}
+}
+
+// filterWorktrees keeps the worktrees whose branch, path, or directory name
+// matches the glob pattern, which must already be valid. An empty pattern
+// keeps them all.
+func filterWorktrees(wts []worktree, pattern string) []worktree {
+	if pattern == "" {
+		return wts
+	}
+	var matched []worktree
+	for _, wt := range wts {
+		for _, s := range []string{wt.Branch, wt.Path, filepath.Base(wt.Path)} {
+			if ok, _ := filepath.Match(pattern, s); ok && s != "" {
+				matched = append(matched, wt)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Output formats accepted by wt list --format.
+const (
+	listFormatText      = "text"
+	listFormatJSON      = "json"
+	listFormatPorcelain = "porcelain"
+)
+
+// listEntry is one worktree in the machine-readable wt list formats. Repo
+// is only set by --all. Clean is nil when the worktree's status could not
+// be read (for example, its directory is missing).
+type listEntry struct {
+	Repo   string `json:"repo,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	Path   string `json:"path"`
+	// Head is the short SHA of the checked-out commit, for branch and
+	// detached worktrees alike.
+	Head  string `json:"head,omitempty"`
+	Clean *bool  `json:"clean,omitempty"`
+	// Missing is set when the worktree's directory no longer exists.
+	Missing bool `json:"missing,omitempty"`
+	// UpstreamGone is set when the branch's remote branch was deleted.
+	UpstreamGone bool `json:"upstreamGone,omitempty"`
+}
+
+// listEntries gathers the per-worktree data shared by the json and
+// porcelain formats.
+func listEntries(repo string, wts []worktree) []listEntry {
+	var paths []string
+	for _, wt := range wts {
+		if !wt.Missing {
+			paths = append(paths, wt.Path)
+		}
+	}
+	statuses, _ := worktreeCleanliness(paths)
+	short := shortHeads(repo, wts)
+
+	entries := make([]listEntry, 0, len(wts))
+	for _, wt := range wts {
+		e := listEntry{Repo: repo, Branch: wt.Branch, Path: wt.Path, Head: wt.Head, Missing: wt.Missing, UpstreamGone: wt.UpstreamGone}
+		if s, ok := short[wt.Head]; ok {
+			e.Head = s
+		}
+		if clean, ok := statuses[wt.Path]; ok && !wt.Missing {
+			e.Clean = &clean
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// shortHeads abbreviates the HEAD SHAs of wts, running git in repo (the
+// current directory when empty). If that fails the full SHAs are used.
+func shortHeads(repo string, wts []worktree) map[string]string {
+	var shas []string
+	for _, wt := range wts {
+		if wt.Head != "" {
+			shas = append(shas, wt.Head)
+		}
+	}
+	short, err := gitShortCommits(repo, shas)
+	if err != nil {
+		return nil
+	}
+	return short
+}
+
+// printListEntries writes entries as a JSON array, or in porcelain format:
+// one "key value" field per line, each record ended by a blank line, like
+// git worktree list --porcelain. Detached worktrees get a bare "detached"
+// line in place of "branch". Fields are only ever added, never changed or
+// removed, so scripts can rely on them.
+func printListEntries(format string, entries []listEntry) {
+	if format == listFormatJSON {
+		data, _ := json.Marshal(entries)
+		fmt.Fprintln(stdout, string(data))
+		return
+	}
+	for _, e := range entries {
+		if e.Repo != "" {
+			fmt.Fprintf(stdout, "repo %s\n", e.Repo)
+		}
+		if e.Branch != "" {
+			fmt.Fprintf(stdout, "branch %s\n", e.Branch)
+		} else {
+			fmt.Fprintln(stdout, "detached")
+		}
+		fmt.Fprintf(stdout, "path %s\n", e.Path)
+		if e.Clean != nil {
+			fmt.Fprintf(stdout, "clean %t\n", *e.Clean)
+		}
+		if e.Missing {
+			fmt.Fprintln(stdout, "missing")
+		}
+		if e.UpstreamGone {
+			fmt.Fprintln(stdout, "upstream-gone")
+		}
+		if e.Head != "" {
+			fmt.Fprintf(stdout, "head %s\n", e.Head)
+		}
+		fmt.Fprintln(stdout)
+	}
+}
+
+// uniquePaths returns paths cleaned and with duplicates removed, keeping the
+// first occurrence of each.
+func uniquePaths(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		out = append(out, p)
+	}
+	return out
+}
 
+func printWorktreeList(wts []worktree, indent string) {
 	for _, wt := range wts {
+		suffix := ""
+		if wt.Missing {
+			suffix = " (missing)"
+		}
 		if wt.Branch != "" {
-			fmt.Fprintf(stdout, "%s\t%s\n", wt.Branch, wt.Path)
+			if wt.UpstreamGone {
+				suffix += " ↯ gone"
+			}
+			fmt.Fprintf(stdout, "%s%s\t%s%s\n", indent, wt.Branch, wt.Path, suffix)
 			continue
 		}
-		fmt.Fprintf(stdout, "%s\n", wt.Path)
+		fmt.Fprintf(stdout, "%s%s%s\n", indent, wt.Path, suffix)
 	}
 }
 
 func goCmd(args []string) {
+	if isHelpArg(args) {
+		printGoUsage()
+		return
+	}
 	fs := flag.NewFlagSet("go", flag.ExitOnError)
 	fs.Usage = printGoUsage
+	tmux := fs.Bool("tmux", false, "open in a tmux session instead of a shell")
+	fs.BoolVar(tmux, "t", false, "open in a tmux session instead of a shell")
+	shell := fs.String("shell", "", "shell to open instead of $SHELL")
+	create := fs.Bool("create", false, "create the worktree if none matches")
+	cdFile := fs.String("cd-file", "", "write the worktree path to this file instead of opening a shell")
 	_ = fs.Parse(args)
+	if *tmux && *shell != "" {
+		die(usageError(errors.New("--shell cannot be used with --tmux")))
+	}
+	if *cdFile != "" && (*tmux || *shell != "") {
+		die(usageError(errors.New("--cd-file cannot be used with --tmux or --shell")))
+	}
 
-	name := ""
-	if fs.NArg() > 0 {
-		name = fs.Arg(0)
+	resolve := resolveBookmarkedWorktreeArg
+	if *create {
+		resolve = resolveOrCreateWorktreeArg
 	}
-	if name == "" {
-		fmt.Fprintln(stderr, "error: worktree name required")
-		fmt.Fprintln(stderr, "")
-		printGoUsage()
-		exitFunc(1)
+	targetPath, ok := resolve(fs, printGoUsage)
+	if !ok {
 		return
 	}
-
-	repoRoot, err := gitRepoRoot()
-	if err != nil {
-		die(err)
+	if *cdFile != "" {
+		writeCdFile(*cdFile, targetPath)
+		return
 	}
 
-	targetPath, err := findWorktree(repoRoot, name)
-	if err != nil {
-		die(err)
+	open := func(path string) error { return openShellWith(path, *shell) }
+	if *tmux {
+		open = openTmux
 	}
-
-	if err := openShell(targetPath); err != nil {
+	if err := open(targetPath); err != nil {
 		die(err)
 	}
 }
 
 func tmuxCmd(args []string) {
+	if isHelpArg(args) {
+		printTmuxUsage()
+		return
+	}
 	fs := flag.NewFlagSet("t", flag.ExitOnError)
 	fs.Usage = printTmuxUsage
+	noSwitch := fs.Bool("no-switch", false, "print an existing session's name instead of switching to it")
+	prune := fs.Bool("prune", false, "kill sessions of removed worktrees")
+	worktreeRoot := fs.String("worktree-root", "", "with --prune, also look under this --worktree-root")
 	_ = fs.Parse(args)
 
-	name := ""
-	if fs.NArg() > 0 {
-		name = fs.Arg(0)
+	if *worktreeRoot != "" && !*prune {
+		dieUsage("--worktree-root requires --prune", printTmuxUsage)
+		return
 	}
-	if name == "" {
-		fmt.Fprintln(stderr, "error: worktree name required")
-		fmt.Fprintln(stderr, "")
-		printTmuxUsage()
-		exitFunc(1)
+	if *prune {
+		if fs.NArg() > 0 {
+			dieUsage("--prune takes no worktree name", printTmuxUsage)
+			return
+		}
+		repoRoot, err := gitRepoRoot()
+		if err != nil {
+			die(err)
+		}
+		var roots []string
+		if *worktreeRoot != "" {
+			root, err := filepathAbs(expandHome(*worktreeRoot))
+			if err != nil {
+				die(err)
+			}
+			roots = append(roots, root)
+		}
+		killed, err := pruneTmuxSessions(repoRoot, roots)
+		for _, name := range killed {
+			fmt.Fprintf(stdout, "killed tmux session %s\n", name)
+		}
+		if err != nil {
+			die(err)
+		}
 		return
 	}
 
-	repoRoot, err := gitRepoRoot()
-	if err != nil {
-		die(err)
+	targetPath, ok := resolveBookmarkedWorktreeArg(fs, printTmuxUsage)
+	if !ok {
+		return
 	}
 
-	targetPath, err := findWorktree(repoRoot, name)
-	if err != nil {
+	if err := openTmuxWith(targetPath, *noSwitch); err != nil {
 		die(err)
 	}
+}
 
-	if err := openTmux(targetPath); err != nil {
-		die(err)
+func revealCmd(args []string) {
+	if isHelpArg(args) {
+		printRevealUsage()
+		return
 	}
-}
+	fs := flag.NewFlagSet("reveal", flag.ExitOnError)
+	fs.Usage = printRevealUsage
+	_ = fs.Parse(args)
 
+	targetPath, ok := resolveWorktreeArg(fs, printRevealUsage)
+	if !ok {
+		return
+	}
+
+	if err := revealWorktree(targetPath); err != nil {
+		die(err)
+	}
+}
+
+func baseCmd(args []string) {
+	if isHelpArg(args) {
+		printBaseUsage()
+		return
+	}
+	fs := flag.NewFlagSet("base", flag.ExitOnError)
+	fs.Usage = printBaseUsage
+	_ = fs.Parse(args)
+
+	targetPath, ok := resolveWorktreeArg(fs, printBaseUsage)
+	if !ok {
+		return
+	}
+
+	repoRoot, err := gitRepoRoot()
+	if err != nil {
+		die(err)
+	}
+	base, err := gitDefaultBranch(repoRoot)
+	if err != nil {
+		die(err)
+	}
+	sha, err := gitMergeBase(targetPath, base)
+	if err != nil {
+		die(err)
+	}
+	short, err := gitShortCommit(targetPath, sha)
+	if err != nil {
+		die(err)
+	}
+	age, err := gitCommitAge(targetPath, sha)
+	if err != nil {
+		die(err)
+	}
+	fmt.Fprintf(stdout, "%s (%s, %s)\n", short, base, age)
+}
+
+func rmCmd(args []string) {
+	if isHelpArg(args) {
+		printRmUsage()
+		return
+	}
+	fs := flag.NewFlagSet("rm", flag.ExitOnError)
+	fs.Usage = printRmUsage
+	force := fs.Bool("force", false, "remove the worktree even if its branch is protected")
+	_ = fs.Parse(args)
+
+	targetPath, ok := resolveWorktreeArg(fs, printRmUsage)
+	if !ok {
+		return
+	}
+
+	repoRoot, err := gitRepoRoot()
+	if err != nil {
+		die(err)
+	}
+	mainWT, err := gitMainWorktree(repoRoot)
+	if err != nil {
+		die(err)
+	}
+	if err := checkNotMainWorktree(mainWT, targetPath); err != nil {
+		die(err)
+	}
+	wt, err := lookupWorktree(repoRoot, targetPath)
+	if err != nil {
+		die(err)
+	}
+	if !*force {
+		cfg, err := loadConfig()
+		if err != nil {
+			die(err)
+		}
+		if err := checkProtectedBranch(cfg, wt.Branch); err != nil {
+			die(err)
+		}
+	}
+	if err := removeWorktree(repoRoot, targetPath); err != nil {
+		die(err)
+	}
+	recordJournal(repoRoot, journalEntry{Op: journalOpRm, Path: targetPath, Branch: wt.Branch})
+	fmt.Fprintf(stdout, "removed %s\n", targetPath)
+}
+
+func undoCmd(args []string) {
+	if isHelpArg(args) {
+		printUndoUsage()
+		return
+	}
+	fs := flag.NewFlagSet("undo", flag.ExitOnError)
+	fs.Usage = printUndoUsage
+	deleteBranch := fs.Bool("delete-branch", false, "also delete the branch wt new created")
+	fs.BoolVar(deleteBranch, "D", false, "also delete the branch wt new created")
+	_ = fs.Parse(args)
+
+	repoRoot, err := gitRepoRoot()
+	if err != nil {
+		die(err)
+	}
+	if err := undoLast(repoRoot, *deleteBranch); err != nil {
+		die(err)
+	}
+}
+
+func repairCmd(args []string) {
+	if isHelpArg(args) {
+		printRepairUsage()
+		return
+	}
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	fs.Usage = printRepairUsage
+	prune := fs.Bool("prune", false, "forget worktrees whose directory is still missing")
+	_ = fs.Parse(args)
+
+	repoRoot, err := gitRepoRoot()
+	if err != nil {
+		die(err)
+	}
+	if err := repairWorktrees(repoRoot, fs.Args(), *prune); err != nil {
+		die(err)
+	}
+}
+
+// repairWorktrees runs git worktree repair with paths (new locations of
+// worktrees moved by hand) and reports every worktree that is reachable
+// now but was missing or unknown before. Worktrees still missing are
+// pruned with prune, or reported otherwise.
+func repairWorktrees(repoRoot string, paths []string, prune bool) error {
+	if err := requireGitVersion(worktreeRepairGitVersion, "repairing worktrees"); err != nil {
+		return err
+	}
+	before, err := gitWorktrees(repoRoot)
+	if err != nil {
+		return err
+	}
+	wasOK := make(map[string]bool, len(before))
+	for _, wt := range before {
+		wasOK[wt.Path] = !wt.Missing
+	}
+
+	out, err := runGitOutput(repoRoot, append([]string{"worktree", "repair"}, paths...)...)
+	if err != nil {
+		return err
+	}
+	if out = strings.TrimSpace(out); out != "" {
+		fmt.Fprintln(stderr, out)
+	}
+
+	after, err := gitWorktrees(repoRoot)
+	if err != nil {
+		return err
+	}
+	label := func(wt worktree) string {
+		if wt.Branch == "" {
+			return wt.Path
+		}
+		return fmt.Sprintf("%s (%s)", wt.Path, wt.Branch)
+	}
+	var missing []worktree
+	for _, wt := range after {
+		if wt.Missing {
+			missing = append(missing, wt)
+		} else if !wasOK[wt.Path] {
+			fmt.Fprintf(stdout, "repaired %s\n", label(wt))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if !prune {
+		for _, wt := range missing {
+			fmt.Fprintf(stderr, "missing: %s\n", label(wt))
+		}
+		fmt.Fprintln(stderr, "pass the new path of a moved worktree, or --prune to forget missing ones")
+		return nil
+	}
+	if err := runGit(repoRoot, "worktree", "prune"); err != nil {
+		return err
+	}
+	for _, wt := range missing {
+		fmt.Fprintf(stdout, "pruned %s\n", label(wt))
+	}
+	return nil
+}
+
+func renameSessionCmd(args []string) {
+	if isHelpArg(args) {
+		printRenameSessionUsage()
+		return
+	}
+	fs := flag.NewFlagSet("rename-session", flag.ExitOnError)
+	fs.Usage = printRenameSessionUsage
+	_ = fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		dieUsage("old and new names required", printRenameSessionUsage)
+		return
+	}
+	oldArg := fs.Arg(0)
+
+	repoRoot, err := gitRepoRoot()
+	if err != nil {
+		die(err)
+	}
+	targetPath, err := findWorktree(repoRoot, fs.Arg(1))
+	if err != nil {
+		die(err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		die(err)
+	}
+	from, err := tmuxSessionNameFrom(cfg)
+	if err != nil {
+		die(err)
+	}
+	oldName := deriveSessionName(from, oldArg, oldArg)
+	newName := worktreeSessionName(from, targetPath)
+	renamed, err := renameTmuxSession(oldName, newName)
+	if err != nil {
+		die(err)
+	}
+	if renamed {
+		fmt.Fprintf(stdout, "renamed tmux session %s to %s\n", oldName, newName)
+	}
+}
+
+// resolveWorktreeArg resolves the worktree named by the first positional
+// argument of fs (see findWorktree). It reports a missing name with usage
+// and dies on lookup errors; ok is false when the caller should stop.
+func resolveWorktreeArg(fs *flag.FlagSet, usage func()) (string, bool) {
+	repoRoot, name, ok := worktreeArg(fs, usage)
+	if !ok {
+		return "", false
+	}
+	targetPath, err := findWorktree(repoRoot, name)
+	if err != nil {
+		die(err)
+	}
+	return targetPath, true
+}
+
+// resolveBookmarkedWorktreeArg is resolveWorktreeArg for wt go and wt t,
+// which look the name up among the config's bookmarks first.
+func resolveBookmarkedWorktreeArg(fs *flag.FlagSet, usage func()) (string, bool) {
+	repoRoot, name, ok := worktreeArg(fs, usage)
+	if !ok {
+		return "", false
+	}
+	targetPath, err := findWorktreeOrBookmark(repoRoot, name)
+	if err != nil {
+		die(err)
+	}
+	return targetPath, true
+}
+
+// resolveOrCreateWorktreeArg is resolveBookmarkedWorktreeArg for wt go
+// --create: unless the name is a bookmark or matches a worktree exactly, it
+// creates a worktree for the branch of that name. Part of a name isn't
+// enough, since it may be the start of a new branch's name.
+func resolveOrCreateWorktreeArg(fs *flag.FlagSet, usage func()) (string, bool) {
+	repoRoot, name, ok := worktreeArg(fs, usage)
+	if !ok {
+		return "", false
+	}
+	targetPath, err := findBookmark(repoRoot, name, findWorktree)
+	if errors.Is(err, errWorktreeNotFound) {
+		targetPath, err = createWorktreeFor(repoRoot, name)
+	}
+	if err != nil {
+		die(err)
+	}
+	return targetPath, true
+}
+
+// worktreeArg returns the repo root and the worktree name given as the first
+// positional argument of fs, reporting a missing name with usage.
+func worktreeArg(fs *flag.FlagSet, usage func()) (repoRoot, name string, ok bool) {
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+	if name == "" {
+		dieUsage("worktree name required", usage)
+		return "", "", false
+	}
+
+	repoRoot, err := gitRepoRoot()
+	if err != nil {
+		die(err)
+	}
+	return repoRoot, name, true
+}
+
+// createWorktreeFor runs the default wt new flow for branch: it checks out
+// the branch if it exists, else creates it from the remote's branch or the
+// current one, and copies config files per the config.
+func createWorktreeFor(repoRoot, branch string) (string, error) {
+	mainWT, err := gitMainWorktree(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	start, err := branchStartFor(repoRoot, branch, "", fetchAuto)
+	if err != nil {
+		return "", err
+	}
+	wtPath, err := addWorktree(repoRoot, mainWT, addOptions{
+		branch:     branch,
+		fromBranch: start.from,
+		copyConfig: true,
+		quietGit:   !stderrIsTerminal(),
+		fetch:      start.fetch,
+		cfg:        cfg,
+	})
+	if err != nil {
+		return "", err
+	}
+	recordJournal(repoRoot, journalEntry{Op: journalOpNew, Path: wtPath, Branch: branch, BranchCreated: start.created})
+	fmt.Fprintf(stderr, "created worktree %s\n", wtPath)
+	return wtPath, nil
+}
+
+func pruneCmd(args []string) {
+	if isHelpArg(args) {
+		printPruneUsage()
+		return
+	}
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	fs.Usage = printPruneUsage
+	merged := fs.Bool("merged", false, "remove worktrees of merged branches")
+	deleteBranch := fs.Bool("delete-branch", false, "also delete the merged branch")
+	fs.BoolVar(deleteBranch, "D", false, "also delete the merged branch")
+	dryRun := fs.Bool("dry-run", false, "show what would be removed")
+	fs.BoolVar(dryRun, "n", false, "show what would be removed")
+	failDirty := addDirtyFlags(fs)
+	_ = fs.Parse(args)
+
+	if !*merged {
+		dieUsage("--merged is required", printPruneUsage)
+		return
+	}
+
+	repoRoot, err := gitRepoRoot()
+	if err != nil {
+		die(err)
+	}
+	wts, err := gitWorktrees(repoRoot)
+	if err != nil {
+		die(err)
+	}
+	mainWT, err := gitMainWorktree(repoRoot)
+	if err != nil {
+		die(err)
+	}
+	base, err := gitDefaultBranch(repoRoot)
+	if err != nil {
+		die(err)
+	}
+	mergedBranches, err := gitMergedBranches(repoRoot, base)
+	if err != nil {
+		die(err)
+	}
+	baseHead, err := gitResolveCommit(repoRoot, base)
+	if err != nil {
+		die(err)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		die(err)
+	}
+
+	var candidates []worktree
+	for _, wt := range wts {
+		// The main worktree, the default branch itself, and protected
+		// branches are never pruned. Neither is a branch still at the base
+		// commit that never moved: git counts it as merged, but it is
+		// usually one just created by wt new with no commits yet. One that
+		// was fast-forwarded into base has commits in its reflog.
+		if checkNotMainWorktree(mainWT, wt.Path) != nil || wt.Branch == "" || wt.Branch == base || !mergedBranches[wt.Branch] ||
+			(wt.Head == baseHead && gitBranchUntouched(repoRoot, wt.Branch)) || checkProtectedBranch(cfg, wt.Branch) != nil {
+			continue
+		}
+		candidates = append(candidates, wt)
+	}
+
+	var res bulkResult
+	clean, err := skipDirty(candidates, failDirty(), &res)
+	if err != nil {
+		die(err)
+	}
+	removed := false
+	for _, wt := range clean {
+		if *dryRun {
+			fmt.Fprintf(stdout, "would remove %s (%s)\n", wt.Path, wt.Branch)
+			if *deleteBranch {
+				fmt.Fprintf(stdout, "would delete branch %s\n", wt.Branch)
+			}
+			res.done++
+			continue
+		}
+		if err := removeWorktree(repoRoot, wt.Path); err != nil {
+			res.fail(wt.Path, err)
+			continue
+		}
+		removed = true
+		fmt.Fprintf(stdout, "removed %s (%s)\n", wt.Path, wt.Branch)
+		if *deleteBranch {
+			// The branch was found merged into base above; git branch -d
+			// would check it against HEAD instead, which may be any branch.
+			if err := runGit(repoRoot, "branch", "-D", wt.Branch); err != nil {
+				res.fail(wt.Branch, err)
+				continue
+			}
+			fmt.Fprintf(stdout, "deleted branch %s\n", wt.Branch)
+		}
+		res.done++
+	}
+
+	verb := "removed"
+	if *dryRun {
+		verb = "would be removed"
+	} else if removed {
+		forgetJournal(repoRoot)
+	}
+	res.finish(verb, "dirty")
+}
+
+// bookmarkCmd saves the path of the current worktree, or the one named by
+// the second argument, as a bookmark in the main worktree's config.
+func bookmarkCmd(args []string) {
+	if isHelpArg(args) {
+		printBookmarkUsage()
+		return
+	}
+	fs := flag.NewFlagSet("bookmark", flag.ExitOnError)
+	fs.Usage = printBookmarkUsage
+	_ = fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 || fs.Arg(0) == "" {
+		dieUsage("bookmark takes a name and an optional branch", printBookmarkUsage)
+		return
+	}
+	name := fs.Arg(0)
+	if isMainWorktreeKeyword(name) {
+		dieUsage(name+" is reserved for the main worktree", printBookmarkUsage)
+		return
+	}
+
+	repoRoot, err := gitRepoRoot()
+	if err != nil {
+		die(err)
+	}
+	selector := repoRoot
+	if fs.NArg() == 2 {
+		selector = fs.Arg(1)
+	}
+	mainWT, err := gitMainWorktree(repoRoot)
+	if err != nil {
+		die(err)
+	}
+	wt, err := lookupWorktree(repoRoot, selector)
+	if err != nil {
+		die(err)
+	}
+
+	// The main worktree's config is the one every worktree can find, and a
+	// path relative to it holds on any machine the repo is cloned to.
+	path := filepath.Join(mainWT, ".wt.json")
+	target := wt.Path
+	if rel, err := filepath.Rel(mainWT, wt.Path); err == nil {
+		target = filepath.ToSlash(rel)
+	}
+	m, err := readConfigMap(path)
+	if err != nil {
+		die(err)
+	}
+	bookmarks, ok := m["bookmarks"].(map[string]any)
+	if !ok {
+		if m["bookmarks"] != nil {
+			die(fmt.Errorf("bookmarks in %s is not an object", path))
+		}
+		bookmarks = map[string]any{}
+		m["bookmarks"] = bookmarks
+	}
+	bookmarks[name] = target
+	data, _ := json.MarshalIndent(m, "", "  ")
+	data = append(data, '\n')
+	if err := osWriteFile(path, data, 0o644); err != nil {
+		die(err)
+	}
+	fmt.Fprintf(stdout, "bookmarked %s as %s in %s\n", wt.Path, name, path)
+}
+
+func configCmd(args []string) {
+	if len(args) == 0 {
+		printConfigUsage()
+		exitFunc(exitUsage)
+		return
+	}
+	switch args[0] {
+	case "-h", "--help", "help":
+		printConfigUsage()
+	case "get":
+		configGetCmd(args[1:])
+	case "set":
+		configSetCmd(args[1:])
+	default:
+		die(usageError(fmt.Errorf("unknown config command: %s", args[0])))
+	}
+}
+
+// parseConfigFlags parses the flags of wt config get and set, returning the
+// file choice for configFilePath ("" when neither --global nor --repo is
+// given) and the remaining arguments.
+func parseConfigFlags(name string, args []string) (string, []string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = printConfigUsage
+	global := fs.Bool("global", false, "use the global config file")
+	repo := fs.Bool("repo", false, "use the repo config file")
+	_ = fs.Parse(args)
+	if *global && *repo {
+		die(usageError(errors.New("--global and --repo cannot be used together")))
+	}
+	choice := ""
+	if *global {
+		choice = "g"
+	} else if *repo {
+		choice = "r"
+	}
+	return choice, fs.Args()
+}
+
+// configGetCmd prints the value of a config key: strings as they are, other
+// values as JSON. Without --global or --repo it reads the merged config.
+func configGetCmd(args []string) {
+	if isHelpArg(args) {
+		printConfigUsage()
+		return
+	}
+	choice, rest := parseConfigFlags("config get", args)
+	if len(rest) != 1 {
+		dieUsage("config get takes exactly one key", printConfigUsage)
+		return
+	}
+	key := rest[0]
+
+	var m map[string]any
+	if choice == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			die(err)
+		}
+		m = configMap(cfg)
+	} else {
+		path, err := configFilePath(choice)
+		if err != nil {
+			die(err)
+		}
+		if m, err = readConfigMap(path); err != nil {
+			die(err)
+		}
+	}
+
+	value, ok := configLookup(m, key)
+	if !ok {
+		die(notFoundError(fmt.Errorf("%s is not set", key)))
+	}
+	if s, ok := value.(string); ok {
+		fmt.Fprintln(stdout, s)
+		return
+	}
+	data, _ := json.MarshalIndent(value, "", "  ")
+	fmt.Fprintln(stdout, string(data))
+}
+
+// configSetCmd sets a config key in the repo config if it exists, else the
+// global config, keeping every other value in the file.
+func configSetCmd(args []string) {
+	if isHelpArg(args) {
+		printConfigUsage()
+		return
+	}
+	choice, rest := parseConfigFlags("config set", args)
+	if len(rest) != 2 {
+		dieUsage("config set takes a key and a value", printConfigUsage)
+		return
+	}
+	key, raw := rest[0], rest[1]
+	value, err := configValue(key, raw)
+	if err != nil {
+		die(err)
+	}
+
+	if isGlobalOnlyConfigKey(key) {
+		if choice == "r" {
+			die(usageError(fmt.Errorf("%s can only be set in the global config", key)))
+		}
+		choice = "g"
+	}
+	if choice == "" {
+		choice = "g"
+		if path, err := configFilePath("r"); err == nil {
+			if _, err := osStat(path); err == nil {
+				choice = "r"
+			}
+		}
+	}
+	path, err := jiraConfigPath(choice, "")
+	if err != nil {
+		die(err)
+	}
+	m, err := readConfigMap(path)
+	if err != nil {
+		die(err)
+	}
+	if err := configSet(m, key, value); err != nil {
+		die(err)
+	}
+	data, _ := json.MarshalIndent(m, "", "  ")
+	var check wtConfig
+	if err := json.Unmarshal(data, &check); err != nil {
+		die(fmt.Errorf("setting %s would make %s invalid: %w", key, path, err))
+	}
+	data = append(data, '\n')
+	if err := osWriteFile(path, data, 0o644); err != nil {
+		die(err)
+	}
+	fmt.Fprintf(stdout, "set %s in %s\n", key, path)
+}
+
+// globalOnlyConfigKeys are the settings loadConfig ignores in a repo config.
+var globalOnlyConfigKeys = []string{"jira.sites", "worktree.gitConfig"}
+
+// isGlobalOnlyConfigKey reports whether key is one of globalOnlyConfigKeys
+// or under one.
+func isGlobalOnlyConfigKey(key string) bool {
+	for _, k := range globalOnlyConfigKeys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// bulkResult tallies the outcome of a command that acts on several items,
+// so that they all end with the same summary line.
+type bulkResult struct {
+	done    int
+	skipped int
+	failed  int
+}
+
+// fail reports err for item on stderr and counts the item as failed.
+func (r *bulkResult) fail(item string, err error) {
+	fmt.Fprintf(stderr, "%s: %v\n", item, err)
+	r.failed++
+}
+
+// finish prints the summary line, e.g. "3 removed, 1 skipped (dirty),
+// 0 failed", and exits non-zero if any item failed.
+func (r bulkResult) finish(doneVerb, skipReason string) {
+	fmt.Fprintf(stdout, "%d %s, %d skipped (%s), %d failed\n", r.done, doneVerb, r.skipped, skipReason, r.failed)
+	if r.failed > 0 {
+		exitFunc(exitError)
+	}
+}
+
+// addDirtyFlags registers the dirty-worktree policy shared by commands that
+// act on several worktrees. --skip-dirty is the default; --skip-dirty=false
+// means --fail-dirty, which wins when both are given. The returned func
+// reports, once fs is parsed, whether dirty worktrees should fail the run.
+func addDirtyFlags(fs *flag.FlagSet) func() bool {
+	skip := fs.Bool("skip-dirty", true, "skip worktrees with uncommitted changes")
+	fail := fs.Bool("fail-dirty", false, "stop if any worktree has uncommitted changes")
+	return func() bool { return *fail || !*skip }
+}
+
+// partitionDirty splits wts into clean worktrees and ones with uncommitted
+// changes. A worktree whose status can't be read is counted as failed in res
+// and left out of both.
+func partitionDirty(wts []worktree, res *bulkResult) (clean, dirty []worktree) {
+	paths := make([]string, len(wts))
+	for i, wt := range wts {
+		paths[i] = wt.Path
+	}
+	statuses, errs := worktreeCleanliness(paths)
+	for _, wt := range wts {
+		ok, known := statuses[wt.Path]
+		if !known {
+			res.fail(wt.Path, fmt.Errorf("%w: %v", errStatusUnknown, errs[wt.Path]))
+			continue
+		}
+		if ok {
+			clean = append(clean, wt)
+		} else {
+			dirty = append(dirty, wt)
+		}
+	}
+	return clean, dirty
+}
+
+// skipDirty applies the dirty-worktree policy to wts and returns the clean
+// ones. Dirty worktrees are reported and counted as skipped, or with
+// failDirty they are all listed in an error before anything is done.
+func skipDirty(wts []worktree, failDirty bool, res *bulkResult) ([]worktree, error) {
+	clean, dirty := partitionDirty(wts, res)
+	if failDirty && len(dirty) > 0 {
+		names := make([]string, len(dirty))
+		for i, wt := range dirty {
+			names[i] = worktreeLabel(wt)
+		}
+		return nil, blockedError(fmt.Errorf("uncommitted changes in %s (commit or stash them, or use --skip-dirty)", strings.Join(names, ", ")))
+	}
+	for _, wt := range dirty {
+		fmt.Fprintf(stdout, "skipped %s (uncommitted changes)\n", worktreeLabel(wt))
+		res.skipped++
+	}
+	return clean, nil
+}
+
+// worktreeLabel names wt by its branch, or by its path when detached.
+func worktreeLabel(wt worktree) string {
+	if wt.Branch == "" {
+		return wt.Path
+	}
+	return wt.Branch
+}
+
+// Exit codes. Scripts can rely on these, so they must not change.
+const (
+	exitOK       = 0
+	exitError    = 1 // the operation failed
+	exitUsage    = 2 // bad arguments or flags
+	exitNotFound = 3 // no worktree matched
+	exitBlocked  = 4 // refused: dirty worktree, protected branch, main worktree
+)
+
+// codedError gives err the exit code die should use for it.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+func usageError(err error) error    { return &codedError{code: exitUsage, err: err} }
+func notFoundError(err error) error { return &codedError{code: exitNotFound, err: err} }
+func blockedError(err error) error  { return &codedError{code: exitBlocked, err: err} }
+
+// exitCode returns the exit code for err: the code of the first codedError
+// in its chain, or exitError.
+func exitCode(err error) int {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return exitError
+}
+
+// die prints err and exits with its exit code.
 func die(err error) {
 	fmt.Fprintln(stderr, err)
-	exitFunc(1)
+	exitFunc(exitCode(err))
+}
+
+// dieUsage reports a missing or invalid argument followed by the command's
+// usage, and exits with exitUsage.
+func dieUsage(msg string, usage func()) {
+	fmt.Fprintln(stderr, "error: "+msg)
+	fmt.Fprintln(stderr, "")
+	usage()
+	exitFunc(exitUsage)
 }
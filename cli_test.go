@@ -2,14 +2,18 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewCmdFromFlag(t *testing.T) {
@@ -139,8 +143,8 @@ func TestListCmdArgs(t *testing.T) {
 	exitFunc = func(code int) { panic(code) }
 
 	defer func() {
-		if r := recover(); r != 1 {
-			t.Fatalf("expected exit 1, got %v", r)
+		if r := recover(); r != exitUsage {
+			t.Fatalf("expected exit 2, got %v", r)
 		}
 	}()
 
@@ -219,8 +223,8 @@ func TestNewCmdBranchRequired(t *testing.T) {
 
 	exitFunc = func(code int) { panic(code) }
 	defer func() {
-		if r := recover(); r != 1 {
-			t.Fatalf("expected exit 1, got %v", r)
+		if r := recover(); r != exitUsage {
+			t.Fatalf("expected exit 2, got %v", r)
 		}
 	}()
 
@@ -350,16 +354,50 @@ func TestNewCmdCopyLibs(t *testing.T) {
 	}
 }
 
+func TestAddWorktreeCopyLibsDetectAndConfig(t *testing.T) {
+	repo := setupTestRepo(t)
+	mustWriteFile(t, filepath.Join(repo, ".venv", "bin", "python"), "py")
+	mustWriteFile(t, filepath.Join(repo, "node_modules", "a.js"), "a")
+	mustWriteFile(t, filepath.Join(repo, "web", "deps", "b.js"), "b")
+
+	wtPath, err := addWorktree(repo, repo, addOptions{branch: "detected", copyLibs: true})
+	if err != nil {
+		t.Fatalf("addWorktree: %v", err)
+	}
+	for _, rel := range []string{".venv/bin/python", "node_modules/a.js"} {
+		if _, err := os.Stat(filepath.Join(wtPath, rel)); err != nil {
+			t.Errorf("expected detected lib %s copied: %v", rel, err)
+		}
+	}
+
+	cfg := wtConfig{Copy: copySettings{Libs: []string{"web/deps"}}}
+	wtPath, err = addWorktree(repo, repo, addOptions{branch: "configured", copyLibs: true, cfg: cfg})
+	if err != nil {
+		t.Fatalf("addWorktree: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "web", "deps", "b.js")); err != nil {
+		t.Errorf("expected configured lib copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "node_modules")); !os.IsNotExist(err) {
+		t.Errorf("expected copy.libs to replace the detected libs, got %v", err)
+	}
+
+	cfg = wtConfig{Copy: copySettings{Libs: []string{"../outside"}}}
+	if _, err := addWorktree(repo, repo, addOptions{branch: "invalid", copyLibs: true, cfg: cfg}); err == nil || !strings.Contains(err.Error(), "invalid copy path") {
+		t.Fatalf("expected invalid lib path error, got %v", err)
+	}
+}
+
 func TestNewCmdCopyLibsError(t *testing.T) {
 	repo := t.TempDir()
 
 	oldExec := execCommand
 	oldExit := exitFunc
-	oldStat := osStat
+	oldLstat := osLstat
 	defer func() {
 		execCommand = oldExec
 		exitFunc = oldExit
-		osStat = oldStat
+		osLstat = oldLstat
 	}()
 
 	execCommand = func(name string, args ...string) *exec.Cmd {
@@ -378,7 +416,7 @@ func TestNewCmdCopyLibsError(t *testing.T) {
 		return exec.Command("sh", "-c", "exit 0")
 	}
 
-	osStat = func(name string) (fs.FileInfo, error) {
+	osLstat = func(name string) (fs.FileInfo, error) {
 		return nil, errors.New("stat fail")
 	}
 
@@ -595,11 +633,11 @@ func TestNewCmdCopyError(t *testing.T) {
 
 	oldExec := execCommand
 	oldExit := exitFunc
-	oldStat := osStat
+	oldLstat := osLstat
 	defer func() {
 		execCommand = oldExec
 		exitFunc = oldExit
-		osStat = oldStat
+		osLstat = oldLstat
 	}()
 
 	execCommand = func(name string, args ...string) *exec.Cmd {
@@ -618,7 +656,7 @@ func TestNewCmdCopyError(t *testing.T) {
 		return exec.Command("sh", "-c", "exit 0")
 	}
 
-	osStat = func(name string) (fs.FileInfo, error) {
+	osLstat = func(name string) (fs.FileInfo, error) {
 		return nil, errors.New("stat fail")
 	}
 
@@ -716,14 +754,39 @@ func TestGoCmdRequiresArg(t *testing.T) {
 
 	exitFunc = func(code int) { panic(code) }
 	defer func() {
-		if r := recover(); r != 1 {
-			t.Fatalf("expected exit 1, got %v", r)
+		if r := recover(); r != exitUsage {
+			t.Fatalf("expected exit 2, got %v", r)
 		}
 	}()
 
 	goCmd(nil)
 }
 
+func TestResolveWorktreeArgsRequireName(t *testing.T) {
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+	var buf bytes.Buffer
+	stderr = &buf
+
+	// Without a panicking exitFunc, each must still stop at the usage error.
+	for name, run := range map[string]func(){
+		"go":        func() { goCmd(nil) },
+		"go create": func() { goCmd([]string{"--create"}) },
+		"reveal":    func() { revealCmd(nil) },
+	} {
+		code := 0
+		exitFunc = func(c int) { code = c }
+		run()
+		if code != exitUsage {
+			t.Errorf("%s: expected exit %d, got %d", name, exitUsage, code)
+		}
+	}
+}
+
 func TestGoCmdNoWorktrees(t *testing.T) {
 	oldExec := execCommand
 	oldExit := exitFunc
@@ -790,8 +853,8 @@ func TestGoCmdNotFoundAndDefaultShell(t *testing.T) {
 	_ = os.Unsetenv("SHELL")
 	exitFunc = func(code int) { panic(code) }
 	defer func() {
-		if r := recover(); r != 1 {
-			t.Fatalf("expected exit 1, got %v", r)
+		if r := recover(); r != exitNotFound {
+			t.Fatalf("expected exit 3, got %v", r)
 		}
 	}()
 
@@ -834,6 +897,379 @@ func TestGoCmdDefaultShellSuccess(t *testing.T) {
 	goCmd([]string{"main"})
 }
 
+func TestGoCmdShellFlag(t *testing.T) {
+	repo := t.TempDir()
+
+	oldExec := execCommand
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		execCommand = oldExec
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+	t.Setenv("SHELL", "/bin/sh")
+
+	var shellRun string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name != "git" {
+			shellRun = name
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if args[0] == "rev-parse" {
+			return cmdWithOutput(repo)
+		}
+		return cmdWithOutput("worktree " + repo + "\nbranch refs/heads/main\n")
+	}
+
+	goCmd([]string{"--shell", "true", "main"})
+	if shellRun != "true" {
+		t.Fatalf("expected --shell to win over $SHELL, ran %q", shellRun)
+	}
+
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) { panic(code) }
+	for _, tt := range []struct {
+		args    []string
+		wantErr string
+		code    int
+	}{
+		{[]string{"--shell", filepath.Join(repo, "no-such-shell"), "main"}, "--shell " + filepath.Join(repo, "no-such-shell") + " is not an executable", exitError},
+		{[]string{"--shell", "fish", "--tmux", "main"}, "--shell cannot be used with --tmux", exitUsage},
+		{[]string{"--cd-file", filepath.Join(repo, "cd"), "--tmux", "main"}, "--cd-file cannot be used with --tmux or --shell", exitUsage},
+		{[]string{"--cd-file", filepath.Join(repo, "cd"), "--shell", "fish", "main"}, "--cd-file cannot be used with --tmux or --shell", exitUsage},
+	} {
+		buf.Reset()
+		shellRun = ""
+		func() {
+			defer func() {
+				if r := recover(); r != tt.code {
+					t.Fatalf("expected exit %d, got %v", tt.code, r)
+				}
+			}()
+			goCmd(tt.args)
+		}()
+		if !strings.Contains(buf.String(), tt.wantErr) || shellRun != "" {
+			t.Fatalf("expected %q and no shell, got %q (ran %q)", tt.wantErr, buf.String(), shellRun)
+		}
+	}
+}
+
+func TestGoCmdCdFile(t *testing.T) {
+	repo := t.TempDir()
+
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name != "git" {
+			t.Fatalf("expected no shell with --cd-file, ran %s", name)
+		}
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if args[0] == "rev-parse" {
+			return cmdWithOutput(repo)
+		}
+		return cmdWithOutput("worktree " + repo + "\nbranch refs/heads/main\n")
+	}
+
+	cdFile := filepath.Join(t.TempDir(), "cd")
+	goCmd([]string{"--cd-file", cdFile, "main"})
+	if data, err := os.ReadFile(cdFile); err != nil || string(data) != repo+"\n" {
+		t.Fatalf("expected %s in the cd file, got %q, %v", repo, data, err)
+	}
+}
+
+func TestGoCmdMainWorktreeKeyword(t *testing.T) {
+	repo := t.TempDir()
+	wtPath := filepath.Join(repo+"-worktrees", "main")
+
+	oldExec := execCommand
+	oldEnv := os.Getenv("SHELL")
+	defer func() {
+		execCommand = oldExec
+		_ = os.Setenv("SHELL", oldEnv)
+	}()
+	_ = os.Setenv("SHELL", "/bin/true")
+
+	// The main worktree has master checked out and a linked one has main,
+	// so only the keyword can pick the main worktree.
+	var shell *exec.Cmd
+	session := ""
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name == "/bin/true" {
+			shell = exec.Command("sh", "-c", "exit 0")
+			return shell
+		}
+		if name == "tmux" {
+			if args[0] == "new-session" {
+				session = strings.Join(args[1:], " ")
+				return exec.Command("sh", "-c", "exit 0")
+			}
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if args[0] == "rev-parse" {
+			return cmdWithOutput(wtPath)
+		}
+		return cmdWithOutput("worktree " + repo + "\nbranch refs/heads/master\n\nworktree " + wtPath + "\nbranch refs/heads/main\n")
+	}
+
+	for _, keyword := range []string{"@main", "@root"} {
+		shell = nil
+		goCmd([]string{keyword})
+		if shell == nil || shell.Dir != repo {
+			t.Fatalf("%s: expected a shell in %s, got %v", keyword, repo, shell)
+		}
+	}
+
+	oldTmux, hadTmux := os.LookupEnv("TMUX")
+	_ = os.Unsetenv("TMUX")
+	if hadTmux {
+		defer func() { _ = os.Setenv("TMUX", oldTmux) }()
+	}
+	tmuxCmd([]string{"@main"})
+	if want := "-s " + filepath.Base(repo) + " -c " + repo; session != want {
+		t.Fatalf("expected new-session %q, got %q", want, session)
+	}
+}
+
+func TestWriteCdFile(t *testing.T) {
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+	var buf bytes.Buffer
+	stderr = &buf
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "cd")
+	mustWriteFile(t, file, "/old/path\n")
+	writeCdFile(file, "/new/path")
+	if data, _ := os.ReadFile(file); string(data) != "/new/path\n" {
+		t.Fatalf("expected the file replaced, got %q", data)
+	}
+
+	writeCdFile("", "/new/path")
+	if buf.Len() != 0 {
+		t.Fatalf("expected no warnings, got %q", buf.String())
+	}
+
+	writeCdFile(filepath.Join(dir, "missing", "cd"), "/new/path")
+	if !strings.Contains(buf.String(), "warning: could not write "+filepath.Join(dir, "missing", "cd")) {
+		t.Fatalf("expected a warning for a missing directory, got %q", buf.String())
+	}
+
+}
+
+func TestWriteCdFileCleanup(t *testing.T) {
+	oldErr := stderr
+	oldCreate := osCreateTemp
+	oldRename := osRename
+	defer func() {
+		stderr = oldErr
+		osCreateTemp = oldCreate
+		osRename = oldRename
+	}()
+
+	tests := []struct {
+		name    string
+		create  func(dir, pattern string) (*os.File, error)
+		rename  func(oldpath, newpath string) error
+		wantErr string
+	}{
+		{
+			name: "write",
+			// A read-only file makes the write fail.
+			create: func(dir, pattern string) (*os.File, error) {
+				f, err := os.CreateTemp(dir, pattern)
+				if err != nil {
+					return nil, err
+				}
+				_ = f.Close()
+				return os.Open(f.Name())
+			},
+			rename:  os.Rename,
+			wantErr: "write ",
+		},
+		{
+			name:    "rename",
+			create:  os.CreateTemp,
+			rename:  func(string, string) error { return errors.New("rename failed") },
+			wantErr: "rename failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			stderr = &buf
+			osCreateTemp = tt.create
+			osRename = tt.rename
+
+			dir := t.TempDir()
+			file := filepath.Join(dir, "cd")
+			mustWriteFile(t, file, "/old/path\n")
+			writeCdFile(file, "/new/path")
+			if want := "warning: could not write " + file + ": "; !strings.Contains(buf.String(), want) ||
+				!strings.Contains(buf.String(), tt.wantErr) {
+				t.Fatalf("expected a warning with %q, got %q", tt.wantErr, buf.String())
+			}
+			if data, _ := os.ReadFile(file); string(data) != "/old/path\n" {
+				t.Fatalf("expected the file left alone, got %q", data)
+			}
+			if matches, _ := filepath.Glob(filepath.Join(dir, ".wt-cd-*")); len(matches) != 0 {
+				t.Fatalf("expected the temp file removed, got %v", matches)
+			}
+		})
+	}
+}
+
+func TestGoCmdBogusShellFallsBack(t *testing.T) {
+	repo := t.TempDir()
+
+	oldExec := execCommand
+	oldErr := stderr
+	oldEnv := os.Getenv("SHELL")
+	defer func() {
+		execCommand = oldExec
+		stderr = oldErr
+		_ = os.Setenv("SHELL", oldEnv)
+	}()
+
+	out := strings.Join([]string{
+		"worktree " + repo,
+		"branch refs/heads/main",
+		"",
+	}, "\n")
+
+	var shellRun string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name != "git" {
+			shellRun = name
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "rev-parse" {
+			return cmdWithOutput(repo)
+		}
+		if len(args) >= 2 && args[0] == "worktree" {
+			return cmdWithOutput(out)
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	var buf bytes.Buffer
+	stderr = &buf
+	_ = os.Setenv("SHELL", filepath.Join(repo, "no-such-shell"))
+	goCmd([]string{"main"})
+
+	if shellRun != "/bin/sh" {
+		t.Fatalf("expected fallback to /bin/sh, ran %q", shellRun)
+	}
+	if !strings.Contains(buf.String(), "is not executable; using /bin/sh") {
+		t.Fatalf("expected warning, got %q", buf.String())
+	}
+}
+
+func TestDefaultShell(t *testing.T) {
+	tests := []struct {
+		name    string
+		goos    string
+		comspec string
+		found   []string
+		want    string
+	}{
+		{name: "unix", goos: "linux", comspec: `C:\Windows\system32\cmd.exe`, want: "/bin/sh"},
+		{name: "comspec", goos: "windows", comspec: `C:\Windows\system32\cmd.exe`, found: []string{"pwsh"}, want: `C:\Windows\system32\cmd.exe`},
+		{name: "pwsh", goos: "windows", found: []string{"pwsh", "powershell"}, want: "pwsh"},
+		{name: "powershell", goos: "windows", found: []string{"powershell"}, want: "powershell"},
+		{name: "nothing found", goos: "windows", want: "cmd.exe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldGOOS := runtimeGOOS
+			oldLookPath := execLookPath
+			defer func() {
+				runtimeGOOS = oldGOOS
+				execLookPath = oldLookPath
+			}()
+			runtimeGOOS = tt.goos
+			t.Setenv("COMSPEC", tt.comspec)
+			execLookPath = func(name string) (string, error) {
+				for _, f := range tt.found {
+					if f == name {
+						return name, nil
+					}
+				}
+				return "", exec.ErrNotFound
+			}
+
+			if got := defaultShell(); got != tt.want {
+				t.Fatalf("defaultShell() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenShellWindowsFallback(t *testing.T) {
+	oldGOOS := runtimeGOOS
+	oldExec := execCommand
+	oldErr := stderr
+	defer func() {
+		runtimeGOOS = oldGOOS
+		execCommand = oldExec
+		stderr = oldErr
+	}()
+	runtimeGOOS = "windows"
+	t.Setenv("COMSPEC", "cmd.exe")
+	t.Setenv("SHELL", "/no/such/shell")
+
+	var shellRun string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		shellRun = name
+		return exec.Command("sh", "-c", "exit 0")
+	}
+	var buf bytes.Buffer
+	stderr = &buf
+
+	if err := openShell(t.TempDir()); err != nil {
+		t.Fatalf("openShell: %v", err)
+	}
+	if shellRun != "cmd.exe" {
+		t.Fatalf("expected fallback to cmd.exe, ran %q", shellRun)
+	}
+	if !strings.Contains(buf.String(), "is not executable; using cmd.exe") {
+		t.Fatalf("expected warning, got %q", buf.String())
+	}
+}
+
+func TestTmuxUnsupportedOnWindows(t *testing.T) {
+	oldGOOS := runtimeGOOS
+	oldExec := execCommand
+	defer func() {
+		runtimeGOOS = oldGOOS
+		execCommand = oldExec
+	}()
+	runtimeGOOS = "windows"
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		t.Fatalf("unexpected command %s %v", name, args)
+		return nil
+	}
+
+	if err := openTmux("/repo/feature"); !errors.Is(err, errTmuxUnsupported) {
+		t.Fatalf("expected unsupported error from openTmux, got %v", err)
+	}
+	if _, err := renameTmuxSession("old", "new"); !errors.Is(err, errTmuxUnsupported) {
+		t.Fatalf("expected unsupported error from renameTmuxSession, got %v", err)
+	}
+}
+
 func TestGoCmdWorktreesError(t *testing.T) {
 	oldExec := execCommand
 	oldExit := exitFunc
@@ -1127,8 +1563,8 @@ func TestTmuxCmdRequiresArg(t *testing.T) {
 
 	exitFunc = func(code int) { panic(code) }
 	defer func() {
-		if r := recover(); r != 1 {
-			t.Fatalf("expected exit 1, got %v", r)
+		if r := recover(); r != exitUsage {
+			t.Fatalf("expected exit 2, got %v", r)
 		}
 	}()
 
@@ -1164,8 +1600,8 @@ func TestTmuxCmdWorktreeNotFound(t *testing.T) {
 
 	exitFunc = func(code int) { panic(code) }
 	defer func() {
-		if r := recover(); r != 1 {
-			t.Fatalf("expected exit 1, got %v", r)
+		if r := recover(); r != exitNotFound {
+			t.Fatalf("expected exit 3, got %v", r)
 		}
 	}()
 
@@ -1364,38 +1800,142 @@ func TestOpenTmuxExistingSessionInTmux(t *testing.T) {
 	}
 }
 
-func TestOpenTmuxExistingSessionNotInTmux(t *testing.T) {
+func TestOpenTmuxNoSwitch(t *testing.T) {
 	oldExec := execCommand
-	oldEnv := os.Getenv("TMUX")
+	oldHome := osUserHomeDir
+	oldRead := osReadFile
+	oldOut := stdout
+	oldErr := stderr
 	defer func() {
 		execCommand = oldExec
-		_ = os.Setenv("TMUX", oldEnv)
+		osUserHomeDir = oldHome
+		osReadFile = oldRead
+		stdout = oldOut
+		stderr = oldErr
 	}()
-
-	_ = os.Unsetenv("TMUX")
-
-	var lastCall string
-	execCommand = func(name string, args ...string) *exec.Cmd {
-		if name == "tmux" && len(args) > 0 && args[0] == "has-session" {
-			return exec.Command("sh", "-c", "exit 0") // session exists
-		}
-		if name == "tmux" {
-			lastCall = args[0]
-			return exec.Command("sh", "-c", "exit 0")
-		}
-		return exec.Command("sh", "-c", "exit 0")
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,12345,0")
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+
+	tests := []struct {
+		name     string
+		config   string
+		flag     bool
+		exists   bool
+		wantCall string
+	}{
+		{"flag", "", true, true, ""},
+		{"config", `{"tmux":{"noSwitch":true}}`, false, true, ""},
+		{"config off", `{"tmux":{"noSwitch":false}}`, false, true, "switch-client"},
+		{"new session still switches", "", true, false, "switch-client"},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			osReadFile = func(name string) ([]byte, error) {
+				if tt.config != "" && name == "/home/test/.config/wt/config.json" {
+					return []byte(tt.config), nil
+				}
+				return nil, os.ErrNotExist
+			}
+			var lastCall string
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if name == "tmux" && args[0] == "has-session" {
+					if tt.exists {
+						return exec.Command("sh", "-c", "exit 0")
+					}
+					return exec.Command("sh", "-c", "exit 1")
+				}
+				if name == "tmux" {
+					lastCall = args[0]
+				}
+				return exec.Command("sh", "-c", "exit 0")
+			}
+			var out, errBuf bytes.Buffer
+			stdout = &out
+			stderr = &errBuf
 
-	err := openTmux("/repo/feature")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if lastCall != "attach-session" {
-		t.Fatalf("expected attach-session, got %q", lastCall)
+			if err := openTmuxWith("/repo/feature", tt.flag); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if lastCall != tt.wantCall {
+				t.Fatalf("expected tmux call %q, got %q", tt.wantCall, lastCall)
+			}
+			if tt.wantCall == "" {
+				if out.String() != "feature\n" || !strings.Contains(errBuf.String(), "session feature already exists; not switching") {
+					t.Fatalf("expected the session name, got %q / %q", out.String(), errBuf.String())
+				}
+			}
+		})
 	}
 }
 
-func TestOpenTmuxSessionName(t *testing.T) {
+func TestTmuxCmdNoSwitch(t *testing.T) {
+	oldExec := execCommand
+	oldOut := stdout
+	oldErr := stderr
+	defer func() {
+		execCommand = oldExec
+		stdout = oldOut
+		stderr = oldErr
+	}()
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,12345,0")
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name == "tmux" {
+			if args[0] != "has-session" {
+				t.Fatalf("expected no tmux %s with --no-switch", args[0])
+			}
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "worktree" {
+			return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+		}
+		return cmdWithOutput("/repo")
+	}
+	var out bytes.Buffer
+	stdout = &out
+	stderr = &bytes.Buffer{}
+
+	tmuxCmd([]string{"--no-switch", "main"})
+	if out.String() == "" {
+		t.Fatal("expected the session name to be printed")
+	}
+}
+
+func TestOpenTmuxExistingSessionNotInTmux(t *testing.T) {
+	oldExec := execCommand
+	oldEnv := os.Getenv("TMUX")
+	defer func() {
+		execCommand = oldExec
+		_ = os.Setenv("TMUX", oldEnv)
+	}()
+
+	_ = os.Unsetenv("TMUX")
+
+	var lastCall string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name == "tmux" && len(args) > 0 && args[0] == "has-session" {
+			return exec.Command("sh", "-c", "exit 0") // session exists
+		}
+		if name == "tmux" {
+			lastCall = args[0]
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	err := openTmux("/repo/feature")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lastCall != "attach-session" {
+		t.Fatalf("expected attach-session, got %q", lastCall)
+	}
+}
+
+func TestOpenTmuxSessionName(t *testing.T) {
 	oldExec := execCommand
 	oldEnv := os.Getenv("TMUX")
 	defer func() {
@@ -1425,8 +1965,26 @@ func TestOpenTmuxSessionName(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if sessionName != "my-feature" {
-		t.Fatalf("expected session name 'my-feature', got %q", sessionName)
+	// "=" makes tmux match the name exactly, not as a prefix.
+	if sessionName != "=my-feature" {
+		t.Fatalf("expected session target '=my-feature', got %q", sessionName)
+	}
+}
+
+func TestDeriveSessionName(t *testing.T) {
+	tests := []struct {
+		from, dir, branch, want string
+	}{
+		{from: "path", dir: "/repo-worktrees/login", branch: "feature/login", want: "login"},
+		{from: "path", dir: "/Users/me/Application Support/my repo", want: "my repo"},
+		{from: "path", dir: "/repo-worktrees/v1.2", want: "v1-2"},
+		{from: "branch", dir: "/repo-worktrees/login", branch: "feature/login", want: "feature-login"},
+		{from: "branch", dir: "/repo-worktrees/my login", want: "my login"},
+	}
+	for _, tt := range tests {
+		if got := deriveSessionName(tt.from, tt.dir, tt.branch); got != tt.want {
+			t.Errorf("deriveSessionName(%q, %q, %q) = %q; want %q", tt.from, tt.dir, tt.branch, got, tt.want)
+		}
 	}
 }
 
@@ -1543,6 +2101,50 @@ func TestTmuxCmdSuccess(t *testing.T) {
 	tmuxCmd([]string{"main"})
 }
 
+func TestGoCmdTmuxFlag(t *testing.T) {
+	for _, flagName := range []string{"--tmux", "-t"} {
+		t.Run(flagName, func(t *testing.T) {
+			oldExec := execCommand
+			oldEnv := os.Getenv("TMUX")
+			defer func() {
+				execCommand = oldExec
+				_ = os.Setenv("TMUX", oldEnv)
+			}()
+			_ = os.Unsetenv("TMUX")
+
+			out := "worktree /repo\nbranch refs/heads/main\n"
+			var ran []string
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if name != "git" {
+					ran = append(ran, name)
+					return exec.Command("sh", "-c", "exit 0")
+				}
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				if len(args) >= 2 && args[0] == "rev-parse" {
+					return cmdWithOutput("/repo")
+				}
+				if len(args) >= 2 && args[0] == "worktree" {
+					return cmdWithOutput(out)
+				}
+				return exec.Command("sh", "-c", "exit 0")
+			}
+
+			goCmd([]string{flagName, "main"})
+
+			if len(ran) == 0 || ran[0] != "tmux" {
+				t.Fatalf("expected tmux to be opened, ran %v", ran)
+			}
+			for _, name := range ran {
+				if name != "tmux" {
+					t.Fatalf("expected no shell, ran %v", ran)
+				}
+			}
+		})
+	}
+}
+
 func TestTmuxCmdMatchBaseAndPath(t *testing.T) {
 	repo := t.TempDir()
 
@@ -1688,3 +2290,2959 @@ func TestTmuxCmdOpenTmuxError(t *testing.T) {
 }
 
 // --- Jira tests ---
+
+func TestNewCmdConfigError(t *testing.T) {
+	repo := t.TempDir()
+
+	oldExec := execCommand
+	oldExit := exitFunc
+	oldErr := stderr
+	oldReadFile := osReadFile
+	defer func() {
+		execCommand = oldExec
+		exitFunc = oldExit
+		stderr = oldErr
+		osReadFile = oldReadFile
+	}()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "rev-parse" {
+			return cmdWithOutput(repo)
+		}
+		if len(args) >= 2 && args[0] == "worktree" && args[1] == "list" {
+			return cmdWithOutput(fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+	osReadFile = func(name string) ([]byte, error) {
+		return []byte("{bad json"), nil
+	}
+
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) { panic(code) }
+	defer func() {
+		if r := recover(); r != 1 {
+			t.Fatalf("expected exit 1, got %v", r)
+		}
+		if !strings.Contains(buf.String(), "invalid config") {
+			t.Fatalf("expected invalid config error, got %q", buf.String())
+		}
+	}()
+
+	newCmd([]string{"feature"})
+}
+
+func TestNewCmdSwitchExistingError(t *testing.T) {
+	oldExec := execCommand
+	oldExit := exitFunc
+	defer func() {
+		execCommand = oldExec
+		exitFunc = oldExit
+	}()
+
+	listCalls := 0
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "rev-parse" {
+			return cmdWithOutput("/repo")
+		}
+		if len(args) >= 2 && args[0] == "worktree" && args[1] == "list" {
+			listCalls++
+			if listCalls > 1 {
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+	exitFunc = func(code int) { panic(code) }
+	defer func() {
+		if r := recover(); r != 1 {
+			t.Fatalf("expected exit 1, got %v", r)
+		}
+	}()
+
+	newCmd([]string{"--switch-existing", "feature"})
+}
+
+func TestCommandHelpFlags(t *testing.T) {
+	oldErr := stderr
+	oldExec := execCommand
+	defer func() {
+		stderr = oldErr
+		execCommand = oldExec
+	}()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		t.Fatalf("help should not run %s %v", name, args)
+		return nil
+	}
+
+	tests := []struct {
+		name string
+		run  func([]string)
+		want []string
+	}{
+		{"new", newCmd, []string{"usage: wt new", "--from", "--copy-libs", "--no-copy-config"}},
+		{"go", goCmd, []string{"usage: wt go"}},
+		{"t", tmuxCmd, []string{"usage: wt t"}},
+		{"jira new", jiraNewCmd, []string{"usage: wt jira new", "--no-status-update"}},
+		{"jira config", jiraConfigCmd, []string{"usage: wt jira config", "--init"}},
+	}
+	for _, tt := range tests {
+		for _, arg := range []string{"-h", "--help"} {
+			var buf bytes.Buffer
+			stderr = &buf
+			tt.run([]string{arg, "ignored"})
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Fatalf("%s %s: expected %q in usage, got %q", tt.name, arg, want, buf.String())
+				}
+			}
+		}
+	}
+}
+
+func TestIsHelpArg(t *testing.T) {
+	if isHelpArg(nil) || isHelpArg([]string{"feature", "-h"}) || isHelpArg([]string{"help"}) {
+		t.Fatalf("expected only a leading -h/--help to count as help")
+	}
+	if !isHelpArg([]string{"-h"}) || !isHelpArg([]string{"--help", "x"}) {
+		t.Fatalf("expected leading help flag to be detected")
+	}
+}
+
+func TestExpandAliases(t *testing.T) {
+	aliases := map[string]string{
+		"wip":   "new --copy-libs --from develop",
+		"w":     "wip",
+		"new":   "list",
+		"loop":  "again -x",
+		"again": "loop",
+		"self":  "self --more",
+		"blank": "  ",
+		"-h":    "list",
+	}
+	tests := []struct {
+		in      []string
+		want    []string
+		wantErr string
+	}{
+		{in: []string{"wip", "mybranch"}, want: []string{"new", "--copy-libs", "--from", "develop", "mybranch"}},
+		{in: []string{"w", "mybranch"}, want: []string{"new", "--copy-libs", "--from", "develop", "mybranch"}},
+		{in: []string{"new", "x"}, want: []string{"new", "x"}},
+		{in: []string{"-h"}, want: []string{"-h"}},
+		{in: []string{"nope"}, want: []string{"nope"}},
+		{in: []string{"loop"}, wantErr: "alias loop is recursive: loop → again → loop"},
+		{in: []string{"self"}, wantErr: "alias self is recursive: self → self"},
+		{in: []string{"blank"}, wantErr: "alias blank is empty"},
+	}
+	for _, tt := range tests {
+		got, err := expandAliases(tt.in, aliases)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr || exitCode(err) != exitUsage {
+				t.Errorf("expandAliases(%v): expected usage error %q, got %v", tt.in, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandAliases(%v) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestSuggestCommand(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"lst", "list"},
+		{"lsit", "list"},
+		{"nwe", "new"},
+		{"jria", "jira"},
+		{"hlep", "help"},
+		{"nope", ""},
+		{"x", ""},
+		{"tt", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := suggestCommand(tt.in); got != tt.want {
+			t.Errorf("suggestCommand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"list", "list", 0},
+		{"lst", "list", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestListCmdAllErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		inRepo  bool
+		wantOut string
+		wantErr string
+	}{
+		{name: "invalid config", config: `{`, wantErr: "invalid config"},
+		{name: "no repos", config: `{}`, wantErr: "no repos to list"},
+		{name: "unlistable repo", config: `{"repos":["/missing","~/src/app"]}`, inRepo: true, wantOut: "/repo\n  main\t/repo\n\n/home/test/src/app\n  main\t/repo\n", wantErr: "/missing: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubWorktreesExist(t)
+			oldExec := execCommand
+			oldExit := exitFunc
+			oldOut := stdout
+			oldErr := stderr
+			oldHome := osUserHomeDir
+			oldRead := osReadFile
+			defer func() {
+				execCommand = oldExec
+				exitFunc = oldExit
+				stdout = oldOut
+				stderr = oldErr
+				osUserHomeDir = oldHome
+				osReadFile = oldRead
+			}()
+
+			osUserHomeDir = func() (string, error) { return "/home/test", nil }
+			osReadFile = func(name string) ([]byte, error) {
+				if name == "/home/test/.config/wt/config.json" {
+					return []byte(tt.config), nil
+				}
+				return nil, os.ErrNotExist
+			}
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				dir := ""
+				if len(args) > 0 && args[0] == "-C" {
+					dir = args[1]
+					args = args[2:]
+				}
+				if len(args) >= 2 && args[0] == "rev-parse" && tt.inRepo {
+					return cmdWithOutput("/repo")
+				}
+				if len(args) >= 2 && args[0] == "worktree" && dir != "/missing" {
+					return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+				}
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			exitFunc = func(code int) { panic(code) }
+			var out, errBuf bytes.Buffer
+			stdout = &out
+			stderr = &errBuf
+
+			defer func() {
+				if r := recover(); r != 1 {
+					t.Fatalf("expected exit 1, got %v", r)
+				}
+				if out.String() != tt.wantOut {
+					t.Fatalf("expected stdout %q, got %q", tt.wantOut, out.String())
+				}
+				if !strings.Contains(errBuf.String(), tt.wantErr) {
+					t.Fatalf("expected %q in stderr, got %q", tt.wantErr, errBuf.String())
+				}
+			}()
+			listCmd([]string{"-a"})
+		})
+	}
+}
+
+func TestUniquePaths(t *testing.T) {
+	got := uniquePaths([]string{"/a", "/b/", "/a/./", "/c", "/b"})
+	if strings.Join(got, ",") != "/a,/b,/c" {
+		t.Fatalf("unexpected paths %v", got)
+	}
+}
+
+func TestNewCmdIntoErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+		code int
+	}{
+		{"with from", []string{"--into", "feature", "--from", "main", "next"}, "--into and --from cannot be used together", exitUsage},
+		{"unknown worktree", []string{"--into", "missing", "next"}, "worktree not found: missing", exitNotFound},
+		{"detached worktree", []string{"--from-worktree", "detached", "next"}, "worktree /repo-worktrees/detached has no branch checked out", exitError},
+		{"lookup error", []string{"--into", "feature", "next"}, "worktree list --porcelain failed", exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			oldExit := exitFunc
+			oldErr := stderr
+			defer func() {
+				execCommand = oldExec
+				exitFunc = oldExit
+				stderr = oldErr
+			}()
+
+			listCalls := 0
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				if len(args) >= 2 && args[0] == "rev-parse" {
+					return cmdWithOutput("/repo")
+				}
+				if len(args) >= 2 && args[0] == "worktree" && args[1] == "list" {
+					listCalls++
+					if tt.name == "lookup error" && listCalls > 1 {
+						return exec.Command("sh", "-c", "exit 1")
+					}
+					return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n\nworktree /repo-worktrees/detached\ndetached\n")
+				}
+				return exec.Command("sh", "-c", "exit 0")
+			}
+			exitFunc = func(code int) { panic(code) }
+			var buf bytes.Buffer
+			stderr = &buf
+
+			defer func() {
+				if r := recover(); r != tt.code {
+					t.Fatalf("expected exit %d, got %v", tt.code, r)
+				}
+				if !strings.Contains(buf.String(), tt.want) {
+					t.Fatalf("expected %q, got %q", tt.want, buf.String())
+				}
+			}()
+			newCmd(tt.args)
+		})
+	}
+}
+
+func TestNewCmdQuietGit(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantQuiet bool
+	}{
+		{"default off a terminal", []string{"feature"}, true},
+		{"explicitly disabled", []string{"--quiet-git=false", "feature"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			oldExec := execCommand
+			oldOut := stdout
+			oldHome := osUserHomeDir
+			oldErr := stderr
+			defer func() {
+				execCommand = oldExec
+				stdout = oldOut
+				osUserHomeDir = oldHome
+				stderr = oldErr
+			}()
+			osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+			stdout = &bytes.Buffer{}
+			var errBuf bytes.Buffer
+			stderr = &errBuf
+
+			var addArgs []string
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				if len(args) >= 2 && args[0] == "rev-parse" {
+					return cmdWithOutput(repo)
+				}
+				if len(args) >= 2 && args[0] == "worktree" && args[1] == "list" {
+					return cmdWithOutput("worktree " + repo + "\nbranch refs/heads/main\n")
+				}
+				if len(args) >= 2 && args[0] == "worktree" && args[1] == "add" {
+					addArgs = args
+					return exec.Command("sh", "-c", "echo 'Preparing worktree' >&2")
+				}
+				if len(args) >= 1 && args[0] == "show-ref" {
+					return exec.Command("sh", "-c", "exit 1")
+				}
+				return exec.Command("sh", "-c", "exit 0")
+			}
+
+			newCmd(tt.args)
+			if shown := strings.Contains(errBuf.String(), "Preparing worktree"); shown == tt.wantQuiet {
+				t.Fatalf("expected git's progress shown=%v, got stderr %q", !tt.wantQuiet, errBuf.String())
+			}
+
+			quiet := false
+			for _, a := range addArgs {
+				if a == "-q" {
+					quiet = true
+				}
+			}
+			if quiet != tt.wantQuiet {
+				t.Fatalf("expected quiet=%v, got args %v", tt.wantQuiet, addArgs)
+			}
+		})
+	}
+}
+
+func TestStderrIsTerminal(t *testing.T) {
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+
+	stderr = &bytes.Buffer{}
+	if stderrIsTerminal() {
+		t.Fatalf("expected buffer not to be a terminal")
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatalf("create temp: %v", err)
+	}
+	defer f.Close()
+	stderr = f
+	if stderrIsTerminal() {
+		t.Fatalf("expected regular file not to be a terminal")
+	}
+}
+
+func TestStdoutIsTerminal(t *testing.T) {
+	oldOut := stdout
+	defer func() { stdout = oldOut }()
+
+	stdout = &bytes.Buffer{}
+	if stdoutIsTerminal() {
+		t.Fatalf("expected buffer not to be a terminal")
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatalf("create temp: %v", err)
+	}
+	defer f.Close()
+	stdout = f
+	if stdoutIsTerminal() {
+		t.Fatalf("expected regular file not to be a terminal")
+	}
+}
+
+func TestPruneCmdUsage(t *testing.T) {
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) { panic(code) }
+
+	pruneCmd([]string{"-h"})
+	if !strings.Contains(buf.String(), "usage: wt prune") {
+		t.Fatalf("expected usage, got %q", buf.String())
+	}
+
+	buf.Reset()
+	func() {
+		defer func() {
+			if r := recover(); r != exitUsage {
+				t.Fatalf("expected exit 2, got %v", r)
+			}
+		}()
+		pruneCmd(nil)
+	}()
+	if !strings.Contains(buf.String(), "--merged is required") {
+		t.Fatalf("expected --merged error, got %q", buf.String())
+	}
+}
+
+func TestPruneCmdErrors(t *testing.T) {
+	const wtList = "worktree /repo\nbranch refs/heads/main\n\nworktree /wt/a\nbranch refs/heads/a\n\nworktree /wt/b\nbranch refs/heads/b\n\nworktree /wt/detached\ndetached\n"
+	tests := []struct {
+		name     string
+		args     []string
+		fail     string
+		failCall int // fail only the nth call matching fail (1-based)
+		wantOut  string
+		wantErr  string
+	}{
+		{name: "repo root", fail: "rev-parse"},
+		{name: "worktrees", fail: "worktree list"},
+		{name: "main worktree", fail: "worktree list", failCall: 2},
+		{name: "default branch", fail: "show-ref"},
+		{name: "merged", fail: "branch --merged"},
+		{name: "base commit", fail: "rev-parse --verify"},
+		{name: "status", fail: "status", wantOut: "0 removed, 0 skipped (dirty), 2 failed", wantErr: "/wt/a: "},
+		{name: "remove", fail: "worktree remove", wantOut: "0 removed, 0 skipped (dirty), 2 failed", wantErr: "/wt/b: "},
+		{name: "delete branch", args: []string{"-D"}, fail: "branch -D", wantOut: "0 removed, 0 skipped (dirty), 2 failed", wantErr: "a: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			oldExit := exitFunc
+			oldOut := stdout
+			oldErr := stderr
+			defer func() {
+				execCommand = oldExec
+				exitFunc = oldExit
+				stdout = oldOut
+				stderr = oldErr
+			}()
+			var outBuf, errBuf bytes.Buffer
+			stdout = &outBuf
+			stderr = &errBuf
+			exitFunc = func(code int) { panic(code) }
+			stubGitVersion(t, "git version 2.40.0")
+
+			calls := 0
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				joined := strings.Join(args, " ")
+				if strings.HasPrefix(joined, tt.fail) {
+					calls++
+					if tt.failCall == 0 || calls == tt.failCall {
+						return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+					}
+				}
+				switch {
+				case strings.HasPrefix(joined, "rev-parse"):
+					return cmdWithOutput("/repo")
+				case strings.HasPrefix(joined, "worktree list"):
+					return cmdWithOutput(wtList)
+				case strings.HasPrefix(joined, "symbolic-ref"):
+					return exec.Command("sh", "-c", "exit 1")
+				case strings.HasPrefix(joined, "branch --merged"):
+					return cmdWithOutput("main\na\nb\n")
+				}
+				return cmdWithOutput("")
+			}
+
+			func() {
+				defer func() {
+					if r := recover(); r != 1 {
+						t.Fatalf("expected exit 1, got %v", r)
+					}
+				}()
+				pruneCmd(append([]string{"--merged"}, tt.args...))
+			}()
+			if !strings.Contains(outBuf.String(), tt.wantOut) {
+				t.Fatalf("expected stdout %q, got %q", tt.wantOut, outBuf.String())
+			}
+			if !strings.Contains(errBuf.String(), tt.wantErr) {
+				t.Fatalf("expected stderr %q, got %q", tt.wantErr, errBuf.String())
+			}
+		})
+	}
+}
+
+func TestSkipDirty(t *testing.T) {
+	oldExec := execCommand
+	oldOut := stdout
+	oldErr := stderr
+	defer func() {
+		execCommand = oldExec
+		stdout = oldOut
+		stderr = oldErr
+	}()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		switch args[1] {
+		case "/wt/clean":
+			return cmdWithOutput("")
+		case "/wt/broken":
+			return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+		}
+		return cmdWithOutput(" M file.txt\n")
+	}
+	wts := []worktree{
+		{Path: "/wt/clean", Branch: "clean"},
+		{Path: "/wt/dirty", Branch: "dirty"},
+		{Path: "/wt/detached"},
+		{Path: "/wt/broken", Branch: "broken"},
+	}
+
+	var out, errOut bytes.Buffer
+	stdout = &out
+	stderr = &errOut
+	var res bulkResult
+	clean, err := skipDirty(wts, false, &res)
+	if err != nil || len(clean) != 1 || clean[0].Branch != "clean" {
+		t.Fatalf("expected only the clean worktree, got %v, %v", clean, err)
+	}
+	if res != (bulkResult{skipped: 2, failed: 1}) {
+		t.Fatalf("unexpected tally %+v", res)
+	}
+	if out.String() != "skipped dirty (uncommitted changes)\nskipped /wt/detached (uncommitted changes)\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), "/wt/broken: ") {
+		t.Fatalf("expected status error reported, got %q", errOut.String())
+	}
+
+	out.Reset()
+	res = bulkResult{}
+	_, err = skipDirty(wts, true, &res)
+	if err == nil || !strings.Contains(err.Error(), "uncommitted changes in dirty, /wt/detached") {
+		t.Fatalf("expected dirty error, got %v", err)
+	}
+	if out.Len() != 0 || res.skipped != 0 {
+		t.Fatalf("expected nothing skipped, got %q, %+v", out.String(), res)
+	}
+}
+
+func TestNewCmdJSON(t *testing.T) {
+	repo := t.TempDir()
+	oldExec := execCommand
+	oldOut := stdout
+	oldHome := osUserHomeDir
+	defer func() {
+		execCommand = oldExec
+		stdout = oldOut
+		osUserHomeDir = oldHome
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	var buf bytes.Buffer
+	stdout = &buf
+
+	var addArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "rev-parse" {
+			return cmdWithOutput(repo)
+		}
+		if len(args) >= 2 && args[0] == "worktree" && args[1] == "list" {
+			return cmdWithOutput("worktree " + repo + "\nbranch refs/heads/main\n")
+		}
+		if len(args) >= 2 && args[0] == "worktree" && args[1] == "add" {
+			addArgs = args
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	mustWriteFile(t, filepath.Join(repo, ".env"), "A=1\n")
+	var errBuf bytes.Buffer
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+	stderr = &errBuf
+	newCmd([]string{"--json", "--quiet", "--quiet-git", "-l", "-f", "main", "feature"})
+
+	var got newResult
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", buf.String(), err)
+	}
+	// .env was copied, but there was no node_modules or .venv.
+	want := newResult{Branch: "feature", Path: worktreePath(repo, "feature"), Created: true, CopiedConfig: true, CopiedLibs: false, Base: "main"}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if errBuf.Len() != 0 {
+		t.Fatalf("expected no copy summary with --quiet, got %q", errBuf.String())
+	}
+	if !slices.Contains(addArgs, "-q") {
+		t.Fatalf("expected -q with --quiet-git, got %v", addArgs)
+	}
+}
+
+func TestNewCmdJSONSwitchExisting(t *testing.T) {
+	oldExec := execCommand
+	oldOut := stdout
+	oldErr := stderr
+	defer func() {
+		execCommand = oldExec
+		stdout = oldOut
+		stderr = oldErr
+	}()
+	var buf bytes.Buffer
+	stdout = &buf
+	stderr = &bytes.Buffer{}
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "rev-parse" {
+			return cmdWithOutput("/repo")
+		}
+		return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n\nworktree /wt/feature\nbranch refs/heads/feature\n")
+	}
+
+	newCmd([]string{"--json", "--switch-existing", "feature"})
+
+	want := `{"branch":"feature","path":"/wt/feature","created":false,"copiedConfig":false,"copiedLibs":false}` + "\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestTmuxSessionName(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		head    string
+		want    string
+		wantErr string
+	}{
+		{name: "default", config: `{}`, head: "feature/login", want: "login"},
+		{name: "branch", config: `{"tmux":{"sessionNameFrom":"branch"}}`, head: "feature/login.v2", want: "feature-login-v2"},
+		{name: "detached", config: `{"tmux":{"sessionNameFrom":"branch"}}`, want: "login"},
+		{name: "invalid setting", config: `{"tmux":{"sessionNameFrom":"dir"}}`, wantErr: "invalid tmux.sessionNameFrom"},
+		{name: "invalid config", config: `{`, wantErr: "invalid config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			oldHome := osUserHomeDir
+			oldRead := osReadFile
+			defer func() {
+				execCommand = oldExec
+				osUserHomeDir = oldHome
+				osReadFile = oldRead
+			}()
+			osUserHomeDir = func() (string, error) { return "/home/test", nil }
+			osReadFile = func(name string) ([]byte, error) {
+				if name == "/home/test/.config/wt/config.json" {
+					return []byte(tt.config), nil
+				}
+				return nil, os.ErrNotExist
+			}
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 2 && args[2] == "symbolic-ref" {
+					if tt.head == "" {
+						return exec.Command("sh", "-c", "exit 1")
+					}
+					return cmdWithOutput(tt.head + "\n")
+				}
+				return exec.Command("sh", "-c", "exit 1")
+			}
+
+			cfg, err := loadConfig()
+			got := ""
+			if err == nil {
+				got, err = tmuxSessionName(cfg, "/repo-worktrees/feature/login")
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("expected %q, got %q, %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestOpenTmuxConfigError(t *testing.T) {
+	oldHome := osUserHomeDir
+	oldRead := osReadFile
+	defer func() {
+		osUserHomeDir = oldHome
+		osReadFile = oldRead
+	}()
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	osReadFile = func(name string) ([]byte, error) { return []byte(`{"tmux":{"sessionNameFrom":"dir"}}`), nil }
+
+	if err := openTmux("/repo/feature"); err == nil || !strings.Contains(err.Error(), "sessionNameFrom") {
+		t.Fatalf("expected config error, got %v", err)
+	}
+
+	osReadFile = func(name string) ([]byte, error) { return []byte(`{`), nil }
+	if err := openTmux("/repo/feature"); err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Fatalf("expected invalid config error, got %v", err)
+	}
+}
+
+func TestTmuxProjectFile(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, ".tmuxinator.yml"), "name: x")
+	if err := os.Mkdir(filepath.Join(dir, ".tmuxp.yaml"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		loader     string
+		wantLoader string
+		wantFile   string
+	}{
+		{"none", "", ""},
+		{"tmuxp", "", ""},
+		{"tmuxinator", "tmuxinator", ".tmuxinator.yml"},
+		{"auto", "tmuxinator", ".tmuxinator.yml"},
+	}
+	for _, tt := range tests {
+		loader, file := tmuxProjectFile(tt.loader, dir)
+		wantFile := ""
+		if tt.wantFile != "" {
+			wantFile = filepath.Join(dir, tt.wantFile)
+		}
+		if loader != tt.wantLoader || file != wantFile {
+			t.Errorf("tmuxProjectFile(%q) = %q, %q; want %q, %q", tt.loader, loader, file, tt.wantLoader, wantFile)
+		}
+	}
+
+	mustWriteFile(t, filepath.Join(dir, ".tmuxp.yml"), "session_name: x")
+	if loader, file := tmuxProjectFile("auto", dir); loader != "tmuxp" || file != filepath.Join(dir, ".tmuxp.yml") {
+		t.Fatalf("expected tmuxp preferred with auto, got %q, %q", loader, file)
+	}
+}
+
+func TestOpenTmuxLoader(t *testing.T) {
+	tests := []struct {
+		name     string
+		loader   string
+		file     string
+		missing  bool
+		fail     bool
+		noSwitch bool // run inside tmux with --no-switch
+		wantCmd  string
+		wantErr  string
+		wantWarn string
+	}{
+		{name: "tmuxp", loader: "auto", file: ".tmuxp.yaml", wantCmd: "tmuxp load -y -s feature .tmuxp.yaml"},
+		{name: "no switch to an existing session", loader: "auto", file: ".tmuxp.yaml", noSwitch: true, wantWarn: "session feature already exists; not switching"},
+		{name: "tmuxinator", loader: "tmuxinator", file: ".tmuxinator.yml", wantCmd: "tmuxinator start -p .tmuxinator.yml -n feature"},
+		{name: "no project file", loader: "auto", wantCmd: "tmux attach-session -t =feature"},
+		{name: "loader not installed", loader: "auto", file: ".tmuxp.yaml", missing: true, wantCmd: "tmux attach-session -t =feature", wantWarn: "warning: tmuxp not found"},
+		{name: "loader fails", loader: "tmuxp", file: ".tmuxp.yaml", fail: true, wantCmd: "tmuxp load -y -s feature .tmuxp.yaml", wantErr: "tmuxp failed"},
+		{name: "invalid loader", loader: "teamocil", wantErr: "invalid tmux.loader"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "feature")
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.file != "" {
+				mustWriteFile(t, filepath.Join(dir, tt.file), "x")
+			}
+			oldExec := execCommand
+			oldLook := execLookPath
+			oldHome := osUserHomeDir
+			oldRead := osReadFile
+			oldOut := stdout
+			oldErr := stderr
+			defer func() {
+				execCommand = oldExec
+				execLookPath = oldLook
+				osUserHomeDir = oldHome
+				osReadFile = oldRead
+				stdout = oldOut
+				stderr = oldErr
+			}()
+			t.Setenv("TMUX", "")
+			if tt.noSwitch {
+				t.Setenv("TMUX", "/tmp/tmux-1000/default,12345,0")
+			}
+			var buf bytes.Buffer
+			stdout = &bytes.Buffer{}
+			stderr = &buf
+			osUserHomeDir = func() (string, error) { return "/home/test", nil }
+			osReadFile = func(name string) ([]byte, error) {
+				if name == "/home/test/.config/wt/config.json" {
+					return []byte(`{"tmux":{"loader":"` + tt.loader + `"}}`), nil
+				}
+				return nil, os.ErrNotExist
+			}
+			execLookPath = func(file string) (string, error) {
+				if tt.missing {
+					return "", errors.New("not found")
+				}
+				return "/usr/bin/" + file, nil
+			}
+			gotCmd := ""
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if name == "git" {
+					return exec.Command("sh", "-c", "exit 1")
+				}
+				if name == "tmux" && args[0] == "has-session" {
+					return exec.Command("sh", "-c", "exit 0")
+				}
+				gotCmd = strings.ReplaceAll(name+" "+strings.Join(args, " "), dir+string(filepath.Separator), "")
+				if tt.fail {
+					return exec.Command("sh", "-c", "exit 1")
+				}
+				return exec.Command("sh", "-c", "exit 0")
+			}
+
+			err := openTmuxWith(dir, tt.noSwitch)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotCmd != tt.wantCmd {
+				t.Fatalf("expected command %q, got %q", tt.wantCmd, gotCmd)
+			}
+			if !strings.Contains(buf.String(), tt.wantWarn) {
+				t.Fatalf("expected warning %q, got %q", tt.wantWarn, buf.String())
+			}
+		})
+	}
+}
+
+func TestBulkResult(t *testing.T) {
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	var out, errBuf bytes.Buffer
+	stdout = &out
+	stderr = &errBuf
+	exitCode := 0
+	exitFunc = func(code int) { exitCode = code }
+
+	res := bulkResult{done: 3, skipped: 1}
+	res.finish("removed", "dirty")
+	if out.String() != "3 removed, 1 skipped (dirty), 0 failed\n" || exitCode != 0 {
+		t.Fatalf("unexpected summary %q (exit %d)", out.String(), exitCode)
+	}
+
+	out.Reset()
+	res.fail("/wt/feature", errors.New("boom"))
+	res.finish("removed", "dirty")
+	if errBuf.String() != "/wt/feature: boom\n" {
+		t.Fatalf("expected error detail on stderr, got %q", errBuf.String())
+	}
+	if out.String() != "3 removed, 1 skipped (dirty), 1 failed\n" || exitCode != 1 {
+		t.Fatalf("unexpected summary %q (exit %d)", out.String(), exitCode)
+	}
+}
+
+func TestFileManagerOpener(t *testing.T) {
+	oldGOOS := runtimeGOOS
+	oldLook := execLookPath
+	defer func() {
+		runtimeGOOS = oldGOOS
+		execLookPath = oldLook
+	}()
+	installed := true
+	execLookPath = func(file string) (string, error) {
+		if !installed {
+			return "", errors.New("not found")
+		}
+		return "/usr/bin/" + file, nil
+	}
+
+	for goos, want := range map[string]string{"darwin": "open", "windows": "explorer", "linux": "xdg-open", "freebsd": "xdg-open"} {
+		runtimeGOOS = goos
+		if got, err := fileManagerOpener(); got != want || err != nil {
+			t.Errorf("%s: expected %q, got %q, %v", goos, want, got, err)
+		}
+	}
+
+	installed = false
+	runtimeGOOS = "linux"
+	if _, err := fileManagerOpener(); err == nil || !strings.Contains(err.Error(), "no file manager opener found: xdg-open is not installed") {
+		t.Fatalf("expected missing opener error, got %v", err)
+	}
+}
+
+func TestRevealWorktree(t *testing.T) {
+	tests := []struct {
+		name    string
+		goos    string
+		missing bool
+		fail    bool
+		wantErr string
+	}{
+		{name: "success", goos: "linux"},
+		{name: "opener fails", goos: "darwin", fail: true, wantErr: "open failed"},
+		{name: "explorer exit status ignored", goos: "windows", fail: true},
+		{name: "no opener", goos: "linux", missing: true, wantErr: "no file manager opener found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldGOOS := runtimeGOOS
+			oldLook := execLookPath
+			oldExec := execCommand
+			defer func() {
+				runtimeGOOS = oldGOOS
+				execLookPath = oldLook
+				execCommand = oldExec
+			}()
+			runtimeGOOS = tt.goos
+			execLookPath = func(file string) (string, error) {
+				if tt.missing {
+					return "", errors.New("not found")
+				}
+				return "/usr/bin/" + file, nil
+			}
+			got := ""
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				got = name + " " + strings.Join(args, " ")
+				if tt.fail {
+					return exec.Command("sh", "-c", "exit 1")
+				}
+				return exec.Command("sh", "-c", "exit 0")
+			}
+
+			err := revealWorktree("/wt/feature")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || !strings.HasSuffix(got, " /wt/feature") {
+				t.Fatalf("expected opener run on the worktree, got %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestRevealCmd(t *testing.T) {
+	oldExec := execCommand
+	oldLook := execLookPath
+	oldGOOS := runtimeGOOS
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		execCommand = oldExec
+		execLookPath = oldLook
+		runtimeGOOS = oldGOOS
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+	runtimeGOOS = "linux"
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) { panic(code) }
+	execLookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	opened := ""
+	openerFails := false
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name == "xdg-open" {
+			opened = args[0]
+			if openerFails {
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if args[0] == "rev-parse" {
+			return cmdWithOutput("/repo")
+		}
+		return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n\nworktree /wt/feature\nbranch refs/heads/feature\n")
+	}
+
+	revealCmd([]string{"feature"})
+	if opened != "/wt/feature" {
+		t.Fatalf("expected /wt/feature opened, got %q", opened)
+	}
+
+	openerFails = true
+	func() {
+		defer func() {
+			if r := recover(); r != 1 {
+				t.Fatalf("expected exit 1, got %v", r)
+			}
+		}()
+		revealCmd([]string{"feature"})
+	}()
+	if !strings.Contains(buf.String(), "xdg-open failed") {
+		t.Fatalf("expected opener error, got %q", buf.String())
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != exitNotFound {
+				t.Fatalf("expected exit %d, got %v", exitNotFound, r)
+			}
+		}()
+		revealCmd([]string{"nope"})
+	}()
+
+	buf.Reset()
+	revealCmd([]string{"--help"})
+	if !strings.Contains(buf.String(), "usage: wt reveal") {
+		t.Fatalf("expected usage, got %q", buf.String())
+	}
+}
+
+func TestCheckWorktreeTarget(t *testing.T) {
+	dir := t.TempDir()
+	worktreeDir := filepath.Join(dir, "worktree")
+	mustWriteFile(t, filepath.Join(worktreeDir, ".git"), "gitdir: /repo/.git/worktrees/worktree")
+	strayDir := filepath.Join(dir, "stray")
+	mustWriteFile(t, filepath.Join(strayDir, "file.txt"), "x")
+
+	if err := checkWorktreeTarget(filepath.Join(dir, "missing")); err != nil {
+		t.Fatalf("expected missing target to pass, got %v", err)
+	}
+	if err := checkWorktreeTarget(worktreeDir); err != nil {
+		t.Fatalf("expected existing worktree to pass, got %v", err)
+	}
+	emptyDir := filepath.Join(dir, "empty")
+	if err := os.Mkdir(emptyDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := checkWorktreeTarget(emptyDir); err != nil {
+		t.Fatalf("expected empty directory to pass, got %v", err)
+	}
+	strayFile := filepath.Join(dir, "file.txt")
+	mustWriteFile(t, strayFile, "x")
+	if err := checkWorktreeTarget(strayFile); err == nil {
+		t.Fatal("expected a file at the target to fail")
+	}
+	err := checkWorktreeTarget(strayDir)
+	if err == nil || err.Error() != "target path "+strayDir+" already exists and is not a worktree; move it aside or pass --worktree-root" {
+		t.Fatalf("expected stray target error, got %v", err)
+	}
+}
+
+func TestLookupWorktreePrefersExactMatches(t *testing.T) {
+	repo := setupTestRepo(t)
+	maintenance := setupTestWorktree(t, repo, "maintenance")
+	login := setupTestWorktree(t, repo, "feature-login")
+	setupTestWorktree(t, repo, "feature-signup")
+	// A worktree whose directory is named like another worktree's branch.
+	spike := filepath.Join(repo+"-worktrees", "hotfix")
+	mustRunCmd(t, repo, "git", "worktree", "add", "-b", "spike", spike)
+	hotfix := filepath.Join(repo+"-worktrees", "urgent")
+	mustRunCmd(t, repo, "git", "worktree", "add", "-b", "hotfix", hotfix)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"main", repo},
+		{"maintenance", maintenance},
+		{"feature-login", login},
+		{"hotfix", hotfix},
+		{"urgent", hotfix},
+		{spike, spike},
+	}
+	for _, tt := range tests {
+		wt, err := lookupWorktree(repo, tt.name)
+		if err != nil || wt.Path != tt.want {
+			t.Errorf("lookupWorktree(%q) = %q, %v; want %q", tt.name, wt.Path, err, tt.want)
+		}
+		wt, err = lookupWorktreeFuzzy(repo, tt.name)
+		if err != nil || wt.Path != tt.want {
+			t.Errorf("lookupWorktreeFuzzy(%q) = %q, %v; want %q", tt.name, wt.Path, err, tt.want)
+		}
+	}
+
+	// Only the fuzzy lookup accepts part of a name.
+	for name, want := range map[string]string{"maint": maintenance, "login": login} {
+		if _, err := lookupWorktree(repo, name); !errors.Is(err, errWorktreeNotFound) || exitCode(err) != exitNotFound {
+			t.Errorf("lookupWorktree(%q): expected not found, got %v", name, err)
+		}
+		if wt, err := lookupWorktreeFuzzy(repo, name); err != nil || wt.Path != want {
+			t.Errorf("lookupWorktreeFuzzy(%q) = %q, %v; want %q", name, wt.Path, err, want)
+		}
+	}
+
+	_, err := lookupWorktreeFuzzy(repo, "feature")
+	if err == nil || err.Error() != "feature matches several worktrees: feature-login, feature-signup" || exitCode(err) != exitUsage {
+		t.Fatalf("expected ambiguous match error, got %v", err)
+	}
+	if _, err := lookupWorktreeFuzzy(repo, "nope"); err == nil || exitCode(err) != exitNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if _, err := lookupWorktreeFuzzy(repo, ""); err == nil || err.Error() != "worktree not found: " {
+		t.Fatalf("expected empty name not found, got %v", err)
+	}
+	if _, err := lookupWorktree(repo, ""); err == nil || err.Error() != "worktree not found: " {
+		t.Fatalf("expected empty name not found, got %v", err)
+	}
+	notRepo := t.TempDir()
+	if _, err := lookupWorktreeFuzzy(notRepo, "main"); err == nil || !strings.Contains(err.Error(), "worktree list") {
+		t.Fatalf("expected the worktree list error, got %v", err)
+	}
+	if _, err := lookupWorktree(notRepo, "main"); err == nil || !strings.Contains(err.Error(), "worktree list") {
+		t.Fatalf("expected the worktree list error, got %v", err)
+	}
+}
+
+func TestRmCmdRequiresExactName(t *testing.T) {
+	repo := setupTestRepo(t)
+	wtPath := setupTestWorktree(t, repo, "feature-foo-bar")
+	restoreDir := withDir(t, repo)
+	defer restoreDir()
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	exitFunc = func(code int) { panic(code) }
+
+	func() {
+		defer func() {
+			if r := recover(); r != exitNotFound {
+				t.Fatalf("expected exit %d, got %v (stderr %q)", exitNotFound, r, errBuf.String())
+			}
+		}()
+		rmCmd([]string{"foo"})
+	}()
+	if _, err := os.Stat(wtPath); err != nil {
+		t.Fatalf("expected the worktree kept, got %v", err)
+	}
+}
+
+func TestCheckNotNested(t *testing.T) {
+	mainWT := filepath.Join(t.TempDir(), "repo")
+
+	for _, wtPath := range []string{
+		worktreePath(mainWT, "feature"),
+		filepath.Join(mainWT+"-other", "feature"),
+		"relative/feature",
+	} {
+		if err := checkNotNested(mainWT, wtPath); err != nil {
+			t.Errorf("expected %s to pass, got %v", wtPath, err)
+		}
+	}
+	nested := worktreePathUnder(mainWT, mainWT, "feature")
+	err := checkNotNested(mainWT, nested)
+	if err == nil || err.Error() != "worktree path "+nested+" is inside the main worktree "+mainWT+"; choose a --worktree-root outside the repository" {
+		t.Fatalf("expected nested error, got %v", err)
+	}
+}
+
+func TestAddWorktreeRefusesNestedRoot(t *testing.T) {
+	repo := setupTestRepo(t)
+	mustWriteFile(t, filepath.Join(repo, ".env"), "A=1")
+
+	_, err := addWorktree(repo, repo, addOptions{branch: "feature", copyConfig: true, worktreeRoot: filepath.Join(repo, "build")})
+	if err == nil || !strings.Contains(err.Error(), "is inside the main worktree") {
+		t.Fatalf("expected nested error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "build")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing created in the repo, got %v", err)
+	}
+	if exists, _ := gitBranchExists(repo, "feature"); exists {
+		t.Fatal("expected no branch created")
+	}
+}
+
+func TestAddWorktreeRefusesCaseCollision(t *testing.T) {
+	repo := setupTestRepo(t)
+	existing := setupTestWorktree(t, repo, "Feature")
+
+	// The filesystem here may well be case-sensitive, so both directories
+	// could exist; wt refuses anyway, as a macOS checkout would collide.
+	target := worktreePath(repo, "feature")
+	_, err := addWorktree(repo, repo, addOptions{branch: "feature"})
+	want := "worktree path " + target + " differs only in case from existing worktree " + existing + ", which is the same directory on a case-insensitive filesystem; choose another branch name or pass --worktree-root"
+	if err == nil || err.Error() != want {
+		t.Fatalf("expected case collision error, got %v", err)
+	}
+	if exists, _ := gitBranchExists(repo, "feature"); exists {
+		t.Fatal("expected no branch created")
+	}
+
+	// A different root doesn't collide.
+	if _, err := addWorktree(repo, repo, addOptions{branch: "feature", worktreeRoot: t.TempDir()}); err != nil {
+		t.Fatalf("expected a worktree under another root, got %v", err)
+	}
+}
+
+func TestCheckCaseCollisionGitError(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if err := checkCaseCollision("/repo", "/repo-worktrees/feature"); err == nil {
+		t.Fatal("expected the worktree list error")
+	}
+}
+
+func TestResolveWorktreeRoot(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	mustWriteFile(t, file, "x")
+
+	if got, err := resolveWorktreeRoot(""); got != "" || err != nil {
+		t.Fatalf("expected empty root unchanged, got %q, %v", got, err)
+	}
+	if got, err := resolveWorktreeRoot(dir); got != dir || err != nil {
+		t.Fatalf("expected existing dir, got %q, %v", got, err)
+	}
+	missing := filepath.Join(dir, "fast", "wts")
+	if got, err := resolveWorktreeRoot(missing); got != missing || err != nil {
+		t.Fatalf("expected missing dir accepted, got %q, %v", got, err)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Fatalf("expected %s left for addWorktree to create, got %v", missing, err)
+	}
+	defer withDir(t, dir)()
+	if got, err := resolveWorktreeRoot("fast"); err != nil || filepath.Base(got) != "fast" || !filepath.IsAbs(got) {
+		t.Fatalf("expected relative root made absolute, got %q, %v", got, err)
+	}
+	if _, err := resolveWorktreeRoot(file); err == nil || !strings.Contains(err.Error(), "is not a directory") {
+		t.Fatalf("expected not-a-directory error, got %v", err)
+	}
+}
+
+func TestResolveWorktreeRootErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		abs     error
+		stat    error
+		wantErr string
+	}{
+		{name: "abs", abs: errors.New("no cwd"), wantErr: "no cwd"},
+		{name: "stat", stat: errors.New("denied"), wantErr: "invalid worktree root: denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldAbs := filepathAbs
+			oldStat := osStat
+			defer func() {
+				filepathAbs = oldAbs
+				osStat = oldStat
+			}()
+			filepathAbs = func(path string) (string, error) { return path, tt.abs }
+			osStat = func(name string) (os.FileInfo, error) { return nil, tt.stat }
+
+			if _, err := resolveWorktreeRoot("/fast"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewCmdWorktreeRootError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	mustWriteFile(t, file, "x")
+	for _, cmd := range []func([]string){newCmd, jiraNewCmd} {
+		oldExit := exitFunc
+		oldErr := stderr
+		var buf bytes.Buffer
+		stderr = &buf
+		exitFunc = func(code int) { panic(code) }
+		func() {
+			defer func() {
+				if r := recover(); r != 1 {
+					t.Fatalf("expected exit 1, got %v", r)
+				}
+			}()
+			cmd([]string{"--worktree-root", file, "PROJ-1"})
+		}()
+		exitFunc = oldExit
+		stderr = oldErr
+		if !strings.Contains(buf.String(), "is not a directory") {
+			t.Fatalf("expected worktree root error, got %q", buf.String())
+		}
+	}
+}
+
+func TestRenameSessionCmd(t *testing.T) {
+	const wtList = "worktree /repo\nbranch refs/heads/main\n\nworktree /wt/feature-login\nbranch refs/heads/feature/login\n"
+	tests := []struct {
+		name       string
+		config     string
+		old        string
+		sessions   []string
+		wantRename string
+		wantOut    string
+	}{
+		{name: "path", config: `{}`, old: "login", sessions: []string{"login"}, wantRename: "=login feature-login", wantOut: "renamed tmux session login to feature-login\n"},
+		{name: "path given as old path", config: `{}`, old: "/wt/login", sessions: []string{"login"}, wantRename: "=login feature-login", wantOut: "renamed tmux session login to feature-login\n"},
+		{name: "branch", config: `{"tmux":{"sessionNameFrom":"branch"}}`, old: "bugfix/login", sessions: []string{"bugfix-login"}, wantRename: "=bugfix-login feature-login", wantOut: "renamed tmux session bugfix-login to feature-login\n"},
+		{name: "no session", config: `{}`, old: "login"},
+		{name: "same name", config: `{}`, old: "feature-login", sessions: []string{"feature-login"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			oldHome := osUserHomeDir
+			oldRead := osReadFile
+			oldOut := stdout
+			defer func() {
+				execCommand = oldExec
+				osUserHomeDir = oldHome
+				osReadFile = oldRead
+				stdout = oldOut
+			}()
+			var out bytes.Buffer
+			stdout = &out
+			osUserHomeDir = func() (string, error) { return "/home/test", nil }
+			osReadFile = func(name string) ([]byte, error) {
+				if name == "/home/test/.config/wt/config.json" {
+					return []byte(tt.config), nil
+				}
+				return nil, os.ErrNotExist
+			}
+
+			renamed := ""
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if name == "tmux" {
+					switch args[0] {
+					case "has-session":
+						if slices.Contains(tt.sessions, strings.TrimPrefix(args[2], "=")) {
+							return exec.Command("sh", "-c", "exit 0")
+						}
+						return exec.Command("sh", "-c", "exit 1")
+					case "rename-session":
+						renamed = strings.Join(args[2:], " ")
+						return exec.Command("sh", "-c", "exit 0")
+					}
+				}
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				switch args[0] {
+				case "rev-parse":
+					return cmdWithOutput("/repo")
+				case "symbolic-ref":
+					return cmdWithOutput("feature/login\n")
+				}
+				return cmdWithOutput(wtList)
+			}
+
+			renameSessionCmd([]string{tt.old, "feature/login"})
+			if renamed != tt.wantRename {
+				t.Fatalf("expected rename %q, got %q", tt.wantRename, renamed)
+			}
+			if out.String() != tt.wantOut {
+				t.Fatalf("expected output %q, got %q", tt.wantOut, out.String())
+			}
+		})
+	}
+}
+
+func TestRenameSessionCmdErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		fail    string
+		wantErr string
+		code    int
+	}{
+		{name: "missing args", args: []string{"login"}, wantErr: "old and new names required", code: exitUsage},
+		{name: "repo root", args: []string{"login", "feature"}, fail: "rev-parse", wantErr: "rev-parse", code: exitError},
+		{name: "unknown worktree", args: []string{"login", "nope"}, wantErr: "nope", code: exitNotFound},
+		{name: "config", args: []string{"login", "feature"}, config: `{"tmux":{"sessionNameFrom":"dir"}}`, wantErr: "invalid tmux.sessionNameFrom", code: exitError},
+		{name: "invalid config", args: []string{"login", "feature"}, config: `{`, wantErr: "invalid config", code: exitError},
+		{name: "rename", args: []string{"login", "feature"}, fail: "rename-session", wantErr: "tmux rename-session failed", code: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			oldExit := exitFunc
+			oldErr := stderr
+			oldHome := osUserHomeDir
+			oldRead := osReadFile
+			defer func() {
+				execCommand = oldExec
+				exitFunc = oldExit
+				stderr = oldErr
+				osUserHomeDir = oldHome
+				osReadFile = oldRead
+			}()
+			var buf bytes.Buffer
+			stderr = &buf
+			exitFunc = func(code int) { panic(code) }
+			osUserHomeDir = func() (string, error) { return "/home/test", nil }
+			osReadFile = func(name string) ([]byte, error) {
+				if tt.config != "" && name == "/home/test/.config/wt/config.json" {
+					return []byte(tt.config), nil
+				}
+				return nil, os.ErrNotExist
+			}
+
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				if args[0] == tt.fail {
+					return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+				}
+				switch args[0] {
+				case "has-session", "rename-session":
+					return exec.Command("sh", "-c", "exit 0")
+				case "rev-parse":
+					return cmdWithOutput("/repo")
+				}
+				return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n\nworktree /wt/feature\nbranch refs/heads/feature\n")
+			}
+
+			func() {
+				defer func() {
+					if r := recover(); r != tt.code {
+						t.Fatalf("expected exit %d, got %v", tt.code, r)
+					}
+				}()
+				renameSessionCmd(tt.args)
+			}()
+			if !strings.Contains(buf.String(), tt.wantErr) {
+				t.Fatalf("expected %q in stderr, got %q", tt.wantErr, buf.String())
+			}
+		})
+	}
+}
+
+func TestRenameSessionCmdHelp(t *testing.T) {
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+	var buf bytes.Buffer
+	stderr = &buf
+
+	renameSessionCmd([]string{"--help"})
+	if !strings.Contains(buf.String(), "usage: wt rename-session") {
+		t.Fatalf("expected usage, got %q", buf.String())
+	}
+}
+
+func TestTmuxCmdPrune(t *testing.T) {
+	const wtList = "worktree /src/repo\nbranch refs/heads/main\n\nworktree /src/repo-worktrees/feature/login\nbranch refs/heads/feature/login\n"
+	const sessions = "repo\t/src/repo\n" +
+		"login\t/src/repo-worktrees/feature/login\n" +
+		"old\t/src/repo-worktrees/old\n" +
+		"bugfix-crash\t/tmp/roots/repo-worktrees/bugfix/crash\n" +
+		"crash\t/tmp/roots/repo-worktrees/bugfix/crash\n" +
+		"renamed\t/src/repo-worktrees/gone\n" +
+		"other\t/oss/repo-worktrees/other\n" +
+		"nested\t/oss/src/repo-worktrees/nested\n" +
+		"repo-worktrees\t/src/repo-worktrees\n" +
+		"notes\t/home/test/notes\n" +
+		"odd line\n"
+	tests := []struct {
+		name     string
+		args     []string
+		config   string
+		sessions string
+		want     []string
+	}{
+		{name: "path", config: `{}`, sessions: sessions, want: []string{"old"}},
+		{name: "branch", config: `{"tmux":{"sessionNameFrom":"branch"}}`, sessions: sessions, want: []string{"old"}},
+		{name: "worktree root", args: []string{"--worktree-root", "/tmp/roots"}, config: `{}`, sessions: sessions, want: []string{"old", "crash"}},
+		{name: "worktree root by branch", args: []string{"--worktree-root", "/tmp/roots"}, config: `{"tmux":{"sessionNameFrom":"branch"}}`, sessions: sessions, want: []string{"old", "bugfix-crash"}},
+		{name: "no server", config: `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			oldHome := osUserHomeDir
+			oldRead := osReadFile
+			oldOut := stdout
+			defer func() {
+				execCommand = oldExec
+				osUserHomeDir = oldHome
+				osReadFile = oldRead
+				stdout = oldOut
+			}()
+			var out bytes.Buffer
+			stdout = &out
+			osUserHomeDir = func() (string, error) { return "/home/test", nil }
+			osReadFile = func(name string) ([]byte, error) {
+				if name == "/home/test/.config/wt/config.json" {
+					return []byte(tt.config), nil
+				}
+				return nil, os.ErrNotExist
+			}
+
+			var killed []string
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if name == "tmux" {
+					switch args[0] {
+					case "list-sessions":
+						if tt.sessions == "" {
+							return exec.Command("sh", "-c", "echo 'no server running' >&2; exit 1")
+						}
+						return cmdWithOutput(tt.sessions)
+					case "kill-session":
+						killed = append(killed, args[2])
+						return exec.Command("sh", "-c", "exit 0")
+					}
+				}
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				if args[0] == "rev-parse" {
+					return cmdWithOutput("/src/repo")
+				}
+				return cmdWithOutput(wtList)
+			}
+
+			tmuxCmd(append([]string{"--prune"}, tt.args...))
+			var want []string
+			wantOut := ""
+			for _, name := range tt.want {
+				want = append(want, "="+name)
+				wantOut += "killed tmux session " + name + "\n"
+			}
+			if !slices.Equal(killed, want) {
+				t.Fatalf("expected kills %q, got %q", want, killed)
+			}
+			if out.String() != wantOut {
+				t.Fatalf("expected output %q, got %q", wantOut, out.String())
+			}
+		})
+	}
+}
+
+func TestTmuxCmdPruneErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		fail    string
+		empty   bool
+		goos    string
+		wantErr string
+		wantOut string
+		code    int
+	}{
+		{name: "name given", args: []string{"--prune", "feature"}, wantErr: "--prune takes no worktree name", code: exitUsage},
+		{name: "root without prune", args: []string{"--worktree-root", "/tmp/roots", "feature"}, wantErr: "--worktree-root requires --prune", code: exitUsage},
+		{name: "root path", args: []string{"--prune", "--worktree-root", "roots"}, fail: "abs", wantErr: "no cwd", code: exitError},
+		{name: "repo root", fail: "rev-parse", wantErr: "rev-parse", code: exitError},
+		{name: "windows", goos: "windows", wantErr: "not supported on Windows", code: exitError},
+		{name: "invalid config", config: `{`, wantErr: "invalid config", code: exitError},
+		{name: "config", config: `{"tmux":{"sessionNameFrom":"dir"}}`, wantErr: "invalid tmux.sessionNameFrom", code: exitError},
+		{name: "worktree list", fail: "worktree", wantErr: "worktree", code: exitError},
+		{name: "no worktrees", empty: true, wantErr: "no worktrees found", code: exitError},
+		{name: "kill", fail: "kill-session", wantErr: "tmux kill-session b failed", wantOut: "killed tmux session a\n", code: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			oldExit := exitFunc
+			oldErr := stderr
+			oldOut := stdout
+			oldHome := osUserHomeDir
+			oldRead := osReadFile
+			oldGOOS := runtimeGOOS
+			defer func() {
+				execCommand = oldExec
+				exitFunc = oldExit
+				stderr = oldErr
+				stdout = oldOut
+				osUserHomeDir = oldHome
+				osReadFile = oldRead
+				runtimeGOOS = oldGOOS
+			}()
+			var buf, out bytes.Buffer
+			stderr = &buf
+			stdout = &out
+			exitFunc = func(code int) { panic(code) }
+			if tt.goos != "" {
+				runtimeGOOS = tt.goos
+			}
+			osUserHomeDir = func() (string, error) { return "/home/test", nil }
+			osReadFile = func(name string) ([]byte, error) {
+				if tt.config != "" && name == "/home/test/.config/wt/config.json" {
+					return []byte(tt.config), nil
+				}
+				return nil, os.ErrNotExist
+			}
+			oldAbs := filepathAbs
+			defer func() { filepathAbs = oldAbs }()
+			if tt.fail == "abs" {
+				filepathAbs = func(string) (string, error) { return "", errors.New("no cwd") }
+			}
+
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				if args[0] == tt.fail && (args[0] != "kill-session" || args[2] == "=b") {
+					return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+				}
+				switch args[0] {
+				case "list-sessions":
+					return cmdWithOutput("a\t/src/repo-worktrees/a\nb\t/src/repo-worktrees/b\n")
+				case "kill-session":
+					return exec.Command("sh", "-c", "exit 0")
+				case "rev-parse":
+					return cmdWithOutput("/src/repo")
+				}
+				if tt.empty {
+					return cmdWithOutput("")
+				}
+				return cmdWithOutput("worktree /src/repo\nbranch refs/heads/main\n")
+			}
+
+			args := tt.args
+			if args == nil {
+				args = []string{"--prune"}
+			}
+			func() {
+				defer func() {
+					if r := recover(); r != tt.code {
+						t.Fatalf("expected exit %d, got %v", tt.code, r)
+					}
+				}()
+				tmuxCmd(args)
+			}()
+			if !strings.Contains(buf.String(), tt.wantErr) {
+				t.Fatalf("expected %q in stderr, got %q", tt.wantErr, buf.String())
+			}
+			if out.String() != tt.wantOut {
+				t.Fatalf("expected output %q, got %q", tt.wantOut, out.String())
+			}
+		})
+	}
+}
+
+func TestWorktreeCmdsMissingNameReturn(t *testing.T) {
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+	stderr = &bytes.Buffer{}
+
+	for name, cmd := range map[string]func([]string){"go": goCmd, "t": tmuxCmd, "reveal": revealCmd, "base": baseCmd, "rm": rmCmd} {
+		code := 0
+		exitFunc = func(c int) { code = c }
+		cmd(nil)
+		if code != exitUsage {
+			t.Fatalf("%s: expected exit 2, got %d", name, code)
+		}
+	}
+}
+
+func TestBaseCmdErrors(t *testing.T) {
+	const wtList = "worktree /repo\nbranch refs/heads/main\n\nworktree /wt/feature\nbranch refs/heads/feature\n"
+	tests := []struct {
+		name    string
+		fail    string
+		failNth int
+		wantErr string
+	}{
+		{name: "repo root", fail: "rev-parse --show-toplevel", failNth: 2, wantErr: "rev-parse"},
+		{name: "default branch", fail: "show-ref", wantErr: "could not determine the default branch"},
+		{name: "merge-base", fail: "merge-base", wantErr: "no merge-base with main"},
+		{name: "short sha", fail: "rev-parse --short", wantErr: "rev-parse --short"},
+		{name: "age", fail: "log", wantErr: "git log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			oldExit := exitFunc
+			oldErr := stderr
+			defer func() {
+				execCommand = oldExec
+				exitFunc = oldExit
+				stderr = oldErr
+			}()
+			var buf bytes.Buffer
+			stderr = &buf
+			exitFunc = func(code int) { panic(code) }
+
+			seen := 0
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				joined := strings.Join(args, " ")
+				if strings.HasPrefix(joined, tt.fail) {
+					seen++
+					if tt.failNth == 0 || seen == tt.failNth {
+						return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+					}
+				}
+				switch {
+				case strings.HasPrefix(joined, "rev-parse --show-toplevel"):
+					return cmdWithOutput("/repo")
+				case strings.HasPrefix(joined, "worktree list"):
+					return cmdWithOutput(wtList)
+				case strings.HasPrefix(joined, "symbolic-ref"):
+					return exec.Command("sh", "-c", "exit 1")
+				case strings.HasPrefix(joined, "merge-base"):
+					return cmdWithOutput("abc123def\n")
+				case strings.HasPrefix(joined, "rev-parse --short"):
+					return cmdWithOutput("abc123d\n")
+				}
+				return cmdWithOutput("")
+			}
+
+			func() {
+				defer func() {
+					if r := recover(); r != 1 {
+						t.Fatalf("expected exit 1, got %v", r)
+					}
+				}()
+				baseCmd([]string{"feature"})
+			}()
+			if !strings.Contains(buf.String(), tt.wantErr) {
+				t.Fatalf("expected %q, got %q", tt.wantErr, buf.String())
+			}
+		})
+	}
+
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+	var buf bytes.Buffer
+	stderr = &buf
+	baseCmd([]string{"--help"})
+	if !strings.Contains(buf.String(), "usage: wt base") {
+		t.Fatalf("expected usage, got %q", buf.String())
+	}
+}
+
+func TestListCmdFormats(t *testing.T) {
+	mainHead := strings.Repeat("a", 40)
+	detachedHead := strings.Repeat("b", 40)
+	out := "worktree /repo\nHEAD " + mainHead + "\nbranch refs/heads/main\n\nworktree /repo-wt\nHEAD " + detachedHead + "\ndetached\n"
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"text", "main\t/repo\n/repo-wt\n"},
+		{"porcelain", "branch main\npath /repo\nclean false\nhead aaaaaaa\n\ndetached\npath /repo-wt\nhead bbbbbbb\n\n"},
+		{"json", `[{"branch":"main","path":"/repo","head":"aaaaaaa","clean":false},{"path":"/repo-wt","head":"bbbbbbb"}]` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			stubWorktreesExist(t)
+			oldExec := execCommand
+			oldStdout := stdout
+			defer func() {
+				execCommand = oldExec
+				stdout = oldStdout
+			}()
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				dir := ""
+				if len(args) > 0 && args[0] == "-C" {
+					dir = args[1]
+					args = args[2:]
+				}
+				switch {
+				case args[0] == "rev-parse":
+					return cmdWithOutput("/repo")
+				case args[0] == "worktree":
+					return cmdWithOutput(out)
+				case args[0] == "status" && dir == "/repo":
+					return cmdWithOutput(" M file.txt\n")
+				case args[0] == "log":
+					return cmdWithOutput(mainHead + " aaaaaaa\n" + detachedHead + " bbbbbbb\n")
+				}
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			var buf bytes.Buffer
+			stdout = &buf
+
+			listCmd([]string{"--format=" + tt.format})
+
+			if buf.String() != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestListEntriesFullHeadFallback(t *testing.T) {
+	oldExec := execCommand
+	oldCheck := worktreeCleanCheck
+	defer func() {
+		execCommand = oldExec
+		worktreeCleanCheck = oldCheck
+	}()
+	execCommand = func(name string, args ...string) *exec.Cmd { return exec.Command("sh", "-c", "exit 1") }
+	worktreeCleanCheck = func(string) (bool, error) { return true, nil }
+
+	head := strings.Repeat("c", 40)
+	entries := listEntries("/repo", []worktree{{Path: "/repo", Branch: "main", Head: head}, {Path: "/unborn", Branch: "new"}})
+	if entries[0].Head != head || entries[1].Head != "" {
+		t.Fatalf("expected the full SHA when git can't abbreviate it, got %+v", entries)
+	}
+}
+
+func TestPartitionDirtyStatusError(t *testing.T) {
+	oldCheck := worktreeCleanCheck
+	oldStderr := stderr
+	defer func() {
+		worktreeCleanCheck = oldCheck
+		stderr = oldStderr
+	}()
+	worktreeCleanCheck = func(path string) (bool, error) {
+		switch path {
+		case "/wt/broken":
+			return false, errors.New("index locked")
+		case "/wt/dirty":
+			return false, nil
+		}
+		return true, nil
+	}
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+
+	var res bulkResult
+	clean, dirty := partitionDirty([]worktree{{Path: "/wt/clean"}, {Path: "/wt/dirty"}, {Path: "/wt/broken"}}, &res)
+	if len(clean) != 1 || clean[0].Path != "/wt/clean" || len(dirty) != 1 || dirty[0].Path != "/wt/dirty" {
+		t.Fatalf("unexpected partition: %v, %v", clean, dirty)
+	}
+	if res.failed != 1 {
+		t.Fatalf("expected one failure, got %+v", res)
+	}
+	if want := "/wt/broken: could not read worktree status: index locked\n"; errBuf.String() != want {
+		t.Fatalf("expected %q, got %q", want, errBuf.String())
+	}
+}
+
+func TestListCmdFilter(t *testing.T) {
+	out := "worktree /repo\nbranch refs/heads/main\n\n" +
+		"worktree /wt/PROJ-1-login\nbranch refs/heads/PROJ-1-login\n\n" +
+		"worktree /wt/spike\ndetached\n\n" +
+		"worktree /wt/other-dir\nbranch refs/heads/PROJ-2\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--filter", "PROJ-*"}, "PROJ-1-login\t/wt/PROJ-1-login\nPROJ-2\t/wt/other-dir\n"},
+		{[]string{"--filter", "spike"}, "/wt/spike\n"},
+		{[]string{"--filter", "/wt/*"}, "PROJ-1-login\t/wt/PROJ-1-login\n/wt/spike\nPROJ-2\t/wt/other-dir\n"},
+		{[]string{"--filter", "nomatch"}, ""},
+		{[]string{"--filter", "main", "--format", "json"}, `[{"branch":"main","path":"/repo","clean":true}]` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			stubWorktreesExist(t)
+			oldExec := execCommand
+			oldStdout := stdout
+			defer func() {
+				execCommand = oldExec
+				stdout = oldStdout
+			}()
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				switch args[0] {
+				case "rev-parse":
+					return cmdWithOutput("/repo")
+				case "worktree":
+					return cmdWithOutput(out)
+				}
+				return cmdWithOutput("")
+			}
+			var buf bytes.Buffer
+			stdout = &buf
+
+			listCmd(tt.args)
+
+			if buf.String() != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestListCmdCurrent(t *testing.T) {
+	out := "worktree /repo\nbranch refs/heads/main\n\n" +
+		"worktree /wt/feature\nbranch refs/heads/feature\n\n" +
+		"worktree /wt/spike\ndetached\n"
+	tests := []struct {
+		top  string
+		args []string
+		want string
+	}{
+		{"/wt/feature", []string{"--current"}, "feature\t/wt/feature\n"},
+		{"/wt/feature", []string{"--current", "--bare"}, "/wt/feature\n"},
+		{"/wt/spike", []string{"--current"}, "/wt/spike\n"},
+		{"/repo", []string{"--current", "--format", "porcelain"}, "branch main\npath /repo\nclean true\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			stubWorktreesExist(t)
+			oldExec := execCommand
+			oldStdout := stdout
+			defer func() {
+				execCommand = oldExec
+				stdout = oldStdout
+			}()
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				switch args[0] {
+				case "rev-parse":
+					return cmdWithOutput(tt.top)
+				case "worktree":
+					return cmdWithOutput(out)
+				}
+				return cmdWithOutput("")
+			}
+			var buf bytes.Buffer
+			stdout = &buf
+
+			listCmd(tt.args)
+
+			if buf.String() != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestListCmdCurrentErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{"--current", "--all"}, exitUsage, "--current cannot be used with --all or --filter"},
+		{[]string{"--current", "--filter", "x"}, exitUsage, "--current cannot be used with --all or --filter"},
+		{[]string{"--bare"}, exitUsage, "--bare requires --current"},
+		{[]string{"--current", "--bare", "--format", "json"}, exitUsage, "--bare cannot be used with --format"},
+		{[]string{"--current"}, exitNotFound, "/elsewhere is not among the repository's worktrees"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			oldExit := exitFunc
+			oldErr := stderr
+			oldExec := execCommand
+			defer func() {
+				exitFunc = oldExit
+				stderr = oldErr
+				execCommand = oldExec
+			}()
+			var buf bytes.Buffer
+			stderr = &buf
+			exitFunc = func(code int) { panic(code) }
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				if args[0] == "rev-parse" {
+					return cmdWithOutput("/elsewhere")
+				}
+				return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+			}
+			defer func() {
+				if r := recover(); r != tt.code {
+					t.Fatalf("expected exit %d, got %v", tt.code, r)
+				}
+				if !strings.Contains(buf.String(), tt.want) {
+					t.Fatalf("expected %q, got %q", tt.want, buf.String())
+				}
+			}()
+
+			listCmd(tt.args)
+		})
+	}
+}
+
+func TestListCmdMissingWorktree(t *testing.T) {
+	// /wt/moved isn't marked prunable, as older git doesn't, but its
+	// directory is gone.
+	out := "worktree /repo\nbranch refs/heads/main\n\nworktree /wt/gone\nbranch refs/heads/gone\nprunable gitdir file points to non-existent location\n\n" +
+		"worktree /wt/moved\nbranch refs/heads/moved\n"
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"porcelain", "branch main\npath /repo\nclean true\n\nbranch gone\npath /wt/gone\nmissing\n\nbranch moved\npath /wt/moved\nmissing\n\n"},
+		{"json", `[{"branch":"main","path":"/repo","clean":true},{"branch":"gone","path":"/wt/gone","missing":true},{"branch":"moved","path":"/wt/moved","missing":true}]` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			stubWorktreesExist(t, "/wt/moved")
+			oldExec := execCommand
+			oldStdout := stdout
+			defer func() {
+				execCommand = oldExec
+				stdout = oldStdout
+			}()
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					if args[1] == "/wt/gone" || args[1] == "/wt/moved" {
+						t.Fatalf("expected no git call in a missing worktree, got %v", args)
+					}
+					args = args[2:]
+				}
+				switch args[0] {
+				case "rev-parse":
+					return cmdWithOutput("/repo")
+				case "worktree":
+					return cmdWithOutput(out)
+				}
+				return cmdWithOutput("")
+			}
+			var buf bytes.Buffer
+			stdout = &buf
+
+			listCmd([]string{"--format", tt.format})
+
+			if buf.String() != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestRepairCmdErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		failCall int // fail the nth git call (1-based)
+		wantErr  string
+	}{
+		{name: "repo root", failCall: 1, wantErr: "rev-parse"},
+		{name: "list before", failCall: 2, wantErr: "worktree list"},
+		{name: "repair", failCall: 3, wantErr: "worktree repair"},
+		{name: "list after", failCall: 4, wantErr: "worktree list"},
+		{name: "prune", args: []string{"--prune"}, failCall: 5, wantErr: "worktree prune"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			oldExit := exitFunc
+			oldErr := stderr
+			defer func() {
+				execCommand = oldExec
+				exitFunc = oldExit
+				stderr = oldErr
+			}()
+			var buf bytes.Buffer
+			stderr = &buf
+			exitFunc = func(code int) { panic(code) }
+			stubGitVersion(t, "git version 2.40.0")
+
+			calls := 0
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) == 1 && args[0] == "--version" {
+					return cmdWithOutput("git version 2.40.0")
+				}
+				calls++
+				if calls == tt.failCall {
+					return exec.Command("sh", "-c", "exit 1")
+				}
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				switch strings.Join(args, " ") {
+				case "rev-parse --show-toplevel":
+					return cmdWithOutput("/repo")
+				case "worktree list --porcelain":
+					return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n\nworktree /wt/gone\nprunable gone\n")
+				}
+				return cmdWithOutput("")
+			}
+
+			func() {
+				defer func() {
+					if r := recover(); r != 1 {
+						t.Fatalf("expected exit 1, got %v", r)
+					}
+				}()
+				repairCmd(tt.args)
+			}()
+			if !strings.Contains(buf.String(), tt.wantErr) {
+				t.Fatalf("expected %q, got %q", tt.wantErr, buf.String())
+			}
+		})
+	}
+
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+	var buf bytes.Buffer
+	stderr = &buf
+	repairCmd([]string{"--help"})
+	if !strings.Contains(buf.String(), "usage: wt repair") {
+		t.Fatalf("expected usage, got %q", buf.String())
+	}
+}
+
+func TestRepairCmdReportsGitOutput(t *testing.T) {
+	stubWorktreesExist(t)
+	oldExec := execCommand
+	oldErr := stderr
+	defer func() {
+		execCommand = oldExec
+		stderr = oldErr
+	}()
+	var buf bytes.Buffer
+	stderr = &buf
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		switch strings.Join(args, " ") {
+		case "rev-parse --show-toplevel":
+			return cmdWithOutput("/repo")
+		case "worktree list --porcelain":
+			return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n\nworktree /wt/gone\ndetached\nprunable gone\n")
+		case "worktree repair":
+			return cmdWithOutput("repair: gitdir incorrect: /repo/.git/worktrees/x/gitdir\n")
+		}
+		return cmdWithOutput("")
+	}
+
+	repairCmd(nil)
+	want := "repair: gitdir incorrect: /repo/.git/worktrees/x/gitdir\n" +
+		"missing: /wt/gone\n" +
+		"pass the new path of a moved worktree, or --prune to forget missing ones\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestListCmdInvalidFilter(t *testing.T) {
+	oldExit := exitFunc
+	oldErr := stderr
+	oldExec := execCommand
+	defer func() {
+		exitFunc = oldExit
+		stderr = oldErr
+		execCommand = oldExec
+	}()
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) { panic(code) }
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		t.Fatalf("expected no git call, got %v", args)
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != exitUsage {
+			t.Fatalf("expected exit 2, got %v", r)
+		}
+		if !strings.Contains(buf.String(), `invalid --filter "PROJ-["`) {
+			t.Fatalf("expected filter error, got %q", buf.String())
+		}
+	}()
+
+	listCmd([]string{"--filter", "PROJ-["})
+}
+
+func TestListCmdInvalidFormat(t *testing.T) {
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) { panic(code) }
+	defer func() {
+		if r := recover(); r != exitUsage {
+			t.Fatalf("expected exit 2, got %v", r)
+		}
+		if !strings.Contains(buf.String(), `invalid --format "yaml"`) {
+			t.Fatalf("expected format error, got %q", buf.String())
+		}
+	}()
+
+	listCmd([]string{"--format", "yaml"})
+}
+
+func TestRmCmdErrors(t *testing.T) {
+	const wtList = "worktree /repo\nbranch refs/heads/main\n\nworktree /wt/feature\nbranch refs/heads/feature\n"
+	tests := []struct {
+		name     string
+		arg      string
+		failCall int // fail the nth git call (1-based), 0 for none
+		fail     string
+		wantErr  string
+		code     int
+	}{
+		{name: "main worktree", arg: "main", wantErr: "cannot remove the main worktree", code: exitBlocked},
+		{name: "repo root", arg: "feature", failCall: 3, wantErr: "rev-parse", code: exitError},
+		{name: "main worktree lookup", arg: "feature", failCall: 4, wantErr: "worktree list", code: exitError},
+		{name: "branch lookup", arg: "feature", failCall: 5, wantErr: "worktree list", code: exitError},
+		{name: "remove", arg: "feature", fail: "remove", wantErr: "boom", code: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			oldExit := exitFunc
+			oldErr := stderr
+			defer func() {
+				execCommand = oldExec
+				exitFunc = oldExit
+				stderr = oldErr
+			}()
+			var buf bytes.Buffer
+			stderr = &buf
+			exitFunc = func(code int) { panic(code) }
+			stubGitVersion(t, "git version 2.40.0")
+
+			calls := 0
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				if args[0] == "--version" {
+					return cmdWithOutput("git version 2.40.0")
+				}
+				calls++
+				if calls == tt.failCall || (tt.fail != "" && len(args) > 1 && args[1] == tt.fail) {
+					return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+				}
+				if args[0] == "rev-parse" {
+					return cmdWithOutput("/repo")
+				}
+				return cmdWithOutput(wtList)
+			}
+
+			func() {
+				defer func() {
+					if r := recover(); r != tt.code {
+						t.Fatalf("expected exit %d, got %v", tt.code, r)
+					}
+				}()
+				rmCmd([]string{tt.arg})
+			}()
+			if !strings.Contains(buf.String(), tt.wantErr) {
+				t.Fatalf("expected %q in stderr, got %q", tt.wantErr, buf.String())
+			}
+		})
+	}
+}
+
+func TestRmCmdHelp(t *testing.T) {
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+	var buf bytes.Buffer
+	stderr = &buf
+
+	rmCmd([]string{"--help"})
+	if !strings.Contains(buf.String(), "usage: wt rm") {
+		t.Fatalf("expected usage, got %q", buf.String())
+	}
+}
+
+func TestNewCmdStashErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		fail    []string
+		wantErr string
+		code    int
+	}{
+		{name: "no checkout", args: []string{"--no-checkout"}, wantErr: "--stash and --no-checkout cannot be used together", code: exitUsage},
+		{name: "status", fail: []string{"status"}, wantErr: "boom", code: exitError},
+		{name: "push", fail: []string{"stash push"}, wantErr: "boom", code: exitError},
+		{name: "restore", fail: []string{"worktree add", "stash apply"}, wantErr: "your changes are still in the stash (abc123)", code: exitError},
+		{name: "undo", fail: []string{"stash apply", "reset"}, wantErr: "undoing the partial apply failed", code: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			oldExec := execCommand
+			oldExit := exitFunc
+			oldErr := stderr
+			oldHome := osUserHomeDir
+			defer func() {
+				execCommand = oldExec
+				exitFunc = oldExit
+				stderr = oldErr
+				osUserHomeDir = oldHome
+			}()
+			osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+			var buf bytes.Buffer
+			stderr = &buf
+			exitFunc = func(code int) { panic(code) }
+
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 0 && args[0] == "-C" {
+					args = args[2:]
+				}
+				joined := strings.Join(args, " ")
+				for _, f := range tt.fail {
+					if strings.HasPrefix(joined, f) {
+						return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+					}
+				}
+				switch {
+				case strings.HasPrefix(joined, "rev-parse --show-toplevel"):
+					return cmdWithOutput(repo)
+				case strings.HasPrefix(joined, "rev-parse"):
+					return cmdWithOutput("abc123")
+				case strings.HasPrefix(joined, "worktree list"):
+					return cmdWithOutput("worktree " + repo + "\nbranch refs/heads/main\n")
+				case strings.HasPrefix(joined, "status"):
+					return cmdWithOutput(" M file.txt\n")
+				}
+				return cmdWithOutput("")
+			}
+
+			func() {
+				defer func() {
+					if r := recover(); r != tt.code {
+						t.Fatalf("expected exit %d, got %v", tt.code, r)
+					}
+				}()
+				newCmd(append(append([]string{"--stash"}, tt.args...), "feature"))
+			}()
+			if !strings.Contains(buf.String(), tt.wantErr) {
+				t.Fatalf("expected %q, got %q", tt.wantErr, buf.String())
+			}
+		})
+	}
+}
+
+func TestRenameWorktree(t *testing.T) {
+	repo := setupTestRepo(t)
+	wtPath := setupTestWorktree(t, repo, "feature")
+
+	newPath, err := renameWorktree(repo, wtPath, "feature", "team/renamed")
+	if err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if want := worktreePath(repo, "team/renamed"); newPath != want {
+		t.Fatalf("expected %s, got %s", want, newPath)
+	}
+	if got := gitOutput(t, newPath, "rev-parse", "--abbrev-ref", "HEAD"); got != "team/renamed" {
+		t.Fatalf("expected renamed branch checked out, got %q", got)
+	}
+	if _, err := os.Stat(wtPath); !os.IsNotExist(err) {
+		t.Fatalf("expected old directory gone, got %v", err)
+	}
+
+	// A directory not named after its branch keeps its path.
+	other := filepath.Join(t.TempDir(), "elsewhere")
+	mustRunCmd(t, repo, "git", "worktree", "add", "-b", "other", other)
+	if got, err := renameWorktree(repo, other, "other", "other-2"); err != nil || got != other {
+		t.Fatalf("expected path kept, got %q, %v", got, err)
+	}
+	if exists, _ := gitBranchExists(repo, "other-2"); !exists {
+		t.Fatal("expected branch renamed")
+	}
+}
+
+func TestRenameWorktreeErrors(t *testing.T) {
+	repo := setupTestRepo(t)
+	wtPath := setupTestWorktree(t, repo, "feature")
+	mustRunCmd(t, repo, "git", "branch", "taken")
+
+	tests := []struct {
+		name      string
+		newBranch string
+		want      string
+	}{
+		{"same name", "feature", "choose a new name for feature"},
+		{"empty", "", "choose a new name for feature"},
+		{"existing branch", "taken", "branch taken already exists"},
+		{"invalid name", "bad..name", "not a valid branch name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renameWorktree(repo, wtPath, "feature", tt.newBranch)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	stray := worktreePath(repo, "stray")
+	mustWriteFile(t, filepath.Join(stray, "file.txt"), "x")
+	if _, err := renameWorktree(repo, wtPath, "feature", "stray"); err == nil || err.Error() != stray+" already exists" {
+		t.Fatalf("expected target exists error, got %v", err)
+	}
+
+	// A failed move puts the old branch name back.
+	oldMkdirAll := osMkdirAll
+	osMkdirAll = func(string, os.FileMode) error { return errors.New("read-only") }
+	if _, err := renameWorktree(repo, wtPath, "feature", "team/moved"); err == nil || err.Error() != "read-only" {
+		t.Fatalf("expected mkdir error, got %v", err)
+	}
+	osMkdirAll = oldMkdirAll
+	mustRunCmd(t, repo, "git", "worktree", "lock", wtPath)
+	if _, err := renameWorktree(repo, wtPath, "feature", "moved"); err == nil {
+		t.Fatal("expected move of a locked worktree to fail")
+	}
+	if exists, _ := gitBranchExists(repo, "feature"); !exists {
+		t.Fatal("expected branch rename rolled back")
+	}
+
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		return exec.Command("does-not-exist")
+	}
+	if _, err := renameWorktree(repo, wtPath, "feature", "x"); err == nil {
+		t.Fatal("expected branch lookup error")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"plain", errors.New("boom"), exitError},
+		{"usage", usageError(errors.New("bad flag")), exitUsage},
+		{"not found", notFoundError(errors.New("worktree not found: x")), exitNotFound},
+		{"blocked", blockedError(errors.New("dirty")), exitBlocked},
+		{"wrapped", fmt.Errorf("context: %w", errMainWorktree), exitBlocked},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+	if err := notFoundError(errors.New("worktree not found: x")); err.Error() != "worktree not found: x" || errors.Unwrap(err) == nil {
+		t.Fatalf("expected the message and cause kept, got %q", err)
+	}
+}
+
+func TestDieUsesExitCode(t *testing.T) {
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+	var buf bytes.Buffer
+	stderr = &buf
+	code := -1
+	exitFunc = func(c int) { code = c }
+
+	die(notFoundError(errors.New("worktree not found: x")))
+	if code != exitNotFound || buf.String() != "worktree not found: x\n" {
+		t.Fatalf("expected exit 3 with the message, got %d %q", code, buf.String())
+	}
+
+	buf.Reset()
+	dieUsage("name required", func() { fmt.Fprintln(stderr, "usage: wt x") })
+	if code != exitUsage || buf.String() != "error: name required\n\nusage: wt x\n" {
+		t.Fatalf("expected exit 2 with usage, got %d %q", code, buf.String())
+	}
+}
+
+func TestPhaseTimer(t *testing.T) {
+	oldNow := timeNow
+	oldErr := stderr
+	defer func() {
+		timeNow = oldNow
+		stderr = oldErr
+	}()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	steps := []time.Duration{0, 1500 * time.Microsecond, 2345 * time.Millisecond, 250 * time.Microsecond}
+	timeNow = func() time.Time {
+		now = now.Add(steps[0])
+		steps = steps[1:]
+		return now
+	}
+	var buf bytes.Buffer
+	stderr = &buf
+
+	timer := newPhaseTimer()
+	timer.mark("git worktree add")
+	timer.mark("libs copy")
+	timer.mark("git worktree add")
+	timer.print()
+
+	want := "timings:\n" +
+		"  git worktree add  2ms\n" +
+		"  libs copy         2.35s\n" +
+		"  total             2.35s\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	var none *phaseTimer
+	none.mark("setup")
+	none.print()
+	if buf.Len() != 0 {
+		t.Fatalf("expected a nil timer to print nothing, got %q", buf.String())
+	}
+}
+
+// stubConfigCmd runs wt config in a fresh repo with a fresh home directory,
+// returning the repo, the global config path, and stdout and stderr.
+func stubConfigCmd(t *testing.T) (string, string, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	repo := setupTestRepo(t)
+	restoreDir := withDir(t, repo)
+	home := t.TempDir()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	t.Cleanup(func() {
+		restoreDir()
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	})
+	osUserHomeDir = func() (string, error) { return home, nil }
+	var out, errBuf bytes.Buffer
+	stdout = &out
+	stderr = &errBuf
+	exitFunc = func(code int) { panic(code) }
+	return repo, filepath.Join(home, ".config", "wt", "config.json"), &out, &errBuf
+}
+
+func TestConfigCmdSetGet(t *testing.T) {
+	repo, globalPath, out, _ := stubConfigCmd(t)
+
+	configCmd([]string{"set", "copy.libs", "node_modules,.venv"})
+	configCmd([]string{"set", "tmux.noSwitch", "true"})
+	if !strings.Contains(out.String(), "set copy.libs in "+globalPath) {
+		t.Fatalf("expected the global config written without a repo config, got %q", out.String())
+	}
+	data, _ := os.ReadFile(globalPath)
+	if want := "{\n  \"copy\": {\n    \"libs\": [\n      \"node_modules\",\n      \".venv\"\n    ]\n  },\n  \"tmux\": {\n    \"noSwitch\": true\n  }\n}\n"; string(data) != want {
+		t.Fatalf("unexpected global config:\n%s", data)
+	}
+
+	// With a repo config present it is written instead, keeping its other
+	// values, including ones wt doesn't know.
+	repoPath := filepath.Join(repo, ".wt.json")
+	mustWriteFile(t, repoPath, `{"ui": {"branchSort": "name"}, "extra": 1}`)
+	out.Reset()
+	configCmd([]string{"set", "ui.defaultAction", "tmux"})
+	if !strings.Contains(out.String(), "set ui.defaultAction in "+repoPath) {
+		t.Fatalf("expected the repo config written, got %q", out.String())
+	}
+	data, _ = os.ReadFile(repoPath)
+	if want := "{\n  \"extra\": 1,\n  \"ui\": {\n    \"branchSort\": \"name\",\n    \"defaultAction\": \"tmux\"\n  }\n}\n"; string(data) != want {
+		t.Fatalf("unexpected repo config:\n%s", data)
+	}
+	configCmd([]string{"set", "--global", "ui.branchSort", "recent"})
+	// jira.sites is only read from the global config, so it goes there
+	// even with a repo config present.
+	out.Reset()
+	configCmd([]string{"set", "jira.sites.acme.url", "https://acme.example.com"})
+	if !strings.Contains(out.String(), "set jira.sites.acme.url in "+globalPath) {
+		t.Fatalf("expected jira.sites written to the global config, got %q", out.String())
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"get", "ui.defaultAction"}, "tmux\n"},
+		{[]string{"get", "tmux.noSwitch"}, "true\n"},
+		{[]string{"get", "copy.libs"}, "[\n  \"node_modules\",\n  \".venv\"\n]\n"},
+		{[]string{"get", "ui.branchSort"}, "name\n"},
+		{[]string{"get", "--global", "ui.branchSort"}, "recent\n"},
+		{[]string{"get", "--repo", "extra"}, "1\n"},
+	} {
+		out.Reset()
+		configCmd(tt.args)
+		if out.String() != tt.want {
+			t.Errorf("wt config %v: expected %q, got %q", tt.args, tt.want, out.String())
+		}
+	}
+}
+
+func TestConfigCmdErrors(t *testing.T) {
+	repo, globalPath, out, errBuf := stubConfigCmd(t)
+	mustWriteFile(t, globalPath, `{"ui": {"branchSort": "name"}, "repos": ["a"]}`)
+
+	for _, tt := range []struct {
+		name  string
+		args  []string
+		setup func()
+		want  string
+		code  int
+	}{
+		{name: "no subcommand", args: nil, want: "usage: wt config get", code: exitUsage},
+		{name: "unknown subcommand", args: []string{"unset", "x"}, want: "unknown config command: unset", code: exitUsage},
+		{name: "both files", args: []string{"get", "--global", "--repo", "x"}, want: "--global and --repo cannot be used together", code: exitUsage},
+		{name: "get without key", args: []string{"get"}, want: "config get takes exactly one key", code: exitUsage},
+		{name: "set without value", args: []string{"set", "ui.branchSort"}, want: "config set takes a key and a value", code: exitUsage},
+		{name: "unset key", args: []string{"get", "ui.defaultAction"}, want: "ui.defaultAction is not set", code: exitNotFound},
+		{name: "unknown key", args: []string{"set", "ui.bogus", "x"}, want: `unknown config key "ui.bogus"`, code: exitUsage},
+		{name: "not an object", args: []string{"set", "--global", "repos.x", "{}"}, want: `invalid value "{}" for repos.x`, code: exitUsage},
+		{name: "invalid merged config", args: []string{"get", "ui"}, setup: func() { mustWriteFile(t, filepath.Join(repo, ".wt.json"), "{") }, want: "invalid config", code: exitError},
+		{name: "invalid file for get", args: []string{"get", "--repo", "ui"}, want: "invalid config", code: exitError},
+		{name: "invalid file for set", args: []string{"set", "ui.branchSort", "name"}, want: "invalid config", code: exitError},
+		{name: "set makes file invalid", args: []string{"set", "ui.branchSort", "name"}, setup: func() { mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"tmux": 5}`) }, want: "setting ui.branchSort would make " + filepath.Join(repo, ".wt.json") + " invalid", code: exitError},
+		{name: "existing value in the way", args: []string{"set", "tmux.noSwitch", "true"}, setup: func() { mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"tmux": 5}`) }, want: "tmux is not an object", code: exitError},
+		{name: "get outside a repo", args: []string{"get", "--repo", "ui"}, setup: func() { _ = os.Chdir(t.TempDir()) }, want: "not inside a git repository", code: exitError},
+		{name: "set outside a repo", args: []string{"set", "--repo", "ui.branchSort", "name"}, want: "not inside a git repository", code: exitError},
+		{name: "jira site in repo config", args: []string{"set", "--repo", "jira.sites.acme.url", "https://acme.example.com"}, want: "jira.sites.acme.url can only be set in the global config", code: exitUsage},
+		{name: "git config in repo config", args: []string{"set", "--repo", "worktree.gitConfig.core.hooksPath", "hooks"}, want: "worktree.gitConfig.core.hooksPath can only be set in the global config", code: exitUsage},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			out.Reset()
+			errBuf.Reset()
+			func() {
+				defer func() {
+					if r := recover(); r != tt.code {
+						t.Fatalf("expected exit %d, got %v (stderr %q)", tt.code, r, errBuf.String())
+					}
+				}()
+				configCmd(tt.args)
+			}()
+			if !strings.Contains(errBuf.String(), tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, errBuf.String())
+			}
+		})
+	}
+}
+
+func TestConfigCmdWriteError(t *testing.T) {
+	_, _, _, errBuf := stubConfigCmd(t)
+	oldWrite := osWriteFile
+	defer func() { osWriteFile = oldWrite }()
+	osWriteFile = func(string, []byte, os.FileMode) error { return errors.New("disk full") }
+
+	defer func() {
+		if r := recover(); r != exitError || !strings.Contains(errBuf.String(), "disk full") {
+			t.Fatalf("expected the write error, got %v and %q", r, errBuf.String())
+		}
+	}()
+	configCmd([]string{"set", "ui.branchSort", "name"})
+}
+
+func TestConfigCmdHelp(t *testing.T) {
+	_, _, _, errBuf := stubConfigCmd(t)
+	for _, args := range [][]string{{"help"}, {"get", "-h"}, {"set", "--help"}} {
+		errBuf.Reset()
+		configCmd(args)
+		if !strings.Contains(errBuf.String(), "usage: wt config get") {
+			t.Errorf("expected usage for %v, got %q", args, errBuf.String())
+		}
+	}
+}
+
+func TestBookmarkCmd(t *testing.T) {
+	repo, _, out, errBuf := stubConfigCmd(t)
+	wtPath := setupTestWorktree(t, repo, "PROJ-123-long-name")
+	repoPath := gitOutput(t, repo, "rev-parse", "--show-toplevel")
+	wtPath = gitOutput(t, wtPath, "rev-parse", "--show-toplevel")
+	mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"ui": {"branchSort": "name"}}`)
+
+	bookmarkCmd([]string{"home"})
+	// Bookmarking from a linked worktree still writes the main worktree's
+	// config, leaving the linked one clean.
+	if err := os.Chdir(wtPath); err != nil {
+		t.Fatal(err)
+	}
+	bookmarkCmd([]string{"api"})
+	configPath := filepath.Join(repoPath, ".wt.json")
+	if !strings.Contains(out.String(), "bookmarked "+wtPath+" as api in "+configPath) {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	if status := gitOutput(t, wtPath, "status", "--porcelain"); status != "" {
+		t.Fatalf("expected the linked worktree left clean, got %q", status)
+	}
+	m, err := readConfigMap(configPath)
+	rel := "../" + filepath.Base(repoPath) + "-worktrees/PROJ-123-long-name"
+	if err != nil || !reflect.DeepEqual(m["bookmarks"], map[string]any{"api": rel, "home": "."}) || m["ui"] == nil {
+		t.Fatalf("expected paths relative to the main worktree kept with the other settings, got %v, %v", m, err)
+	}
+	bookmarks, err := loadBookmarks(repoPath)
+	if err != nil || !reflect.DeepEqual(bookmarks, map[string]string{"api": wtPath, "home": repoPath}) {
+		t.Fatalf("expected both bookmarks resolved, got %v, %v", bookmarks, err)
+	}
+
+	// wt go and wt t find the worktree by its bookmark from any worktree,
+	// even once the branch no longer matches.
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	mustRunCmd(t, wtPath, "git", "branch", "-m", "renamed")
+	cdFile := filepath.Join(t.TempDir(), "cd")
+	goCmd([]string{"--cd-file", cdFile, "api"})
+	if data, err := os.ReadFile(cdFile); err != nil || string(data) != wtPath+"\n" {
+		t.Fatalf("expected wt go api to find %s, got %q, %v", wtPath, data, err)
+	}
+	if path, err := findWorktreeOrBookmark(repoPath, "renamed"); err != nil || path != wtPath {
+		t.Fatalf("expected names that aren't bookmarks to resolve as before, got %q, %v", path, err)
+	}
+
+	// A bookmark whose worktree is gone says how to fix it.
+	mustRunCmd(t, repo, "git", "worktree", "remove", wtPath)
+	func() {
+		defer func() {
+			if r := recover(); r != exitError {
+				t.Fatalf("expected exit %d, got %v", exitError, r)
+			}
+		}()
+		tmuxCmd([]string{"api"})
+	}()
+	if want := "bookmark api points to " + wtPath + ", which is not a worktree; update it with wt bookmark api <branch>"; !strings.Contains(errBuf.String(), want) {
+		t.Fatalf("expected %q, got %q", want, errBuf.String())
+	}
+}
+
+func TestFindWorktreeOrBookmarkErrors(t *testing.T) {
+	_, globalPath, _, _ := stubConfigCmd(t)
+
+	mustWriteFile(t, globalPath, "{")
+	if _, err := findWorktreeOrBookmark("/repo", "api"); err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Fatalf("expected the config error, got %v", err)
+	}
+
+	mustWriteFile(t, globalPath, `{"bookmarks": {"api": "/wt/api"}}`)
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if _, err := findWorktreeOrBookmark("/repo", "api"); err == nil || !strings.Contains(err.Error(), "worktree list") {
+		t.Fatalf("expected the worktree list error, got %v", err)
+	}
+
+	// The worktree list can also fail once the bookmarks are loaded.
+	lists := 0
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if !strings.Contains(strings.Join(args, " "), "worktree list") {
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		if lists++; lists == 1 {
+			return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+		}
+		return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+	}
+	if _, err := findWorktreeOrBookmark("/repo", "api"); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected the second worktree list error, got %v", err)
+	}
+}
+
+func TestLoadBookmarks(t *testing.T) {
+	oldExec := execCommand
+	oldRead := osReadFile
+	oldHome := osUserHomeDir
+	defer func() {
+		execCommand = oldExec
+		osReadFile = oldRead
+		osUserHomeDir = oldHome
+	}()
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if strings.Contains(strings.Join(args, " "), "worktree list") {
+			return cmdWithOutput("worktree /src/repo\nbranch refs/heads/main\n")
+		}
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	mainConfig := []byte(`{"bookmarks": {"api": "../repo-worktrees/api", "docs": "/elsewhere/docs"}}`)
+	var mainErr error
+	osReadFile = func(name string) ([]byte, error) {
+		switch name {
+		case "/home/test/.config/wt/config.json":
+			return []byte(`{"bookmarks": {"api": "/old/api", "notes": "/src/notes"}}`), nil
+		case filepath.Join("/src/repo", ".wt.json"):
+			return mainConfig, mainErr
+		}
+		return nil, os.ErrNotExist
+	}
+
+	bookmarks, err := loadBookmarks("/src/repo")
+	want := map[string]string{"api": filepath.Join("/src/repo-worktrees", "api"), "docs": "/elsewhere/docs", "notes": "/src/notes"}
+	if err != nil || !reflect.DeepEqual(bookmarks, want) {
+		t.Fatalf("expected %v, got %v, %v", want, bookmarks, err)
+	}
+
+	mainConfig = []byte("{")
+	if _, err := loadBookmarks("/src/repo"); err == nil || !strings.Contains(err.Error(), "invalid config "+filepath.Join("/src/repo", ".wt.json")) {
+		t.Fatalf("expected the main config error, got %v", err)
+	}
+	mainErr = errors.New("permission denied")
+	if _, err := loadBookmarks("/src/repo"); err == nil || err.Error() != "permission denied" {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+}
+
+func TestBookmarkCmdErrors(t *testing.T) {
+	repo, _, _, errBuf := stubConfigCmd(t)
+
+	for _, tt := range []struct {
+		name  string
+		args  []string
+		setup func()
+		want  string
+		code  int
+	}{
+		{name: "no name", args: nil, want: "bookmark takes a name and an optional branch", code: exitUsage},
+		{name: "too many", args: []string{"a", "b", "c"}, want: "bookmark takes a name and an optional branch", code: exitUsage},
+		{name: "reserved name", args: []string{"@main"}, want: "@main is reserved for the main worktree", code: exitUsage},
+		{name: "unknown worktree", args: []string{"api", "nope"}, want: "worktree not found: nope", code: exitNotFound},
+		{name: "bookmarks not an object", args: []string{"api"}, setup: func() { mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"bookmarks": 5}`) }, want: "bookmarks in " + filepath.Join(repo, ".wt.json") + " is not an object", code: exitError},
+		{name: "invalid config", args: []string{"api"}, setup: func() { mustWriteFile(t, filepath.Join(repo, ".wt.json"), "{") }, want: "invalid config", code: exitError},
+		{name: "outside a repo", args: []string{"api"}, setup: func() { _ = os.Chdir(t.TempDir()) }, want: "not inside a git repository", code: exitError},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			errBuf.Reset()
+			func() {
+				defer func() {
+					if r := recover(); r != tt.code {
+						t.Fatalf("expected exit %d, got %v (stderr %q)", tt.code, r, errBuf.String())
+					}
+				}()
+				bookmarkCmd(tt.args)
+			}()
+			if !strings.Contains(errBuf.String(), tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, errBuf.String())
+			}
+		})
+	}
+}
+
+func TestBookmarkCmdWorktreeListError(t *testing.T) {
+	_, _, _, errBuf := stubConfigCmd(t)
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if strings.Contains(strings.Join(args, " "), "worktree list") {
+			return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+		}
+		return oldExec(name, args...)
+	}
+
+	defer func() {
+		if r := recover(); r != exitError || !strings.Contains(errBuf.String(), "boom") {
+			t.Fatalf("expected the worktree list error, got %v and %q", r, errBuf.String())
+		}
+	}()
+	bookmarkCmd([]string{"api"})
+}
+
+func TestBookmarkCmdWriteError(t *testing.T) {
+	_, _, _, errBuf := stubConfigCmd(t)
+	oldWrite := osWriteFile
+	defer func() { osWriteFile = oldWrite }()
+	osWriteFile = func(string, []byte, os.FileMode) error { return errors.New("disk full") }
+
+	defer func() {
+		if r := recover(); r != exitError || !strings.Contains(errBuf.String(), "disk full") {
+			t.Fatalf("expected the write error, got %v and %q", r, errBuf.String())
+		}
+	}()
+	bookmarkCmd([]string{"api"})
+}
+
+func TestBookmarkCmdHelp(t *testing.T) {
+	_, _, _, errBuf := stubConfigCmd(t)
+	bookmarkCmd([]string{"--help"})
+	if !strings.Contains(errBuf.String(), "usage: wt bookmark") {
+		t.Fatalf("expected usage, got %q", errBuf.String())
+	}
+}
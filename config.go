@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 )
 
 var (
@@ -15,11 +21,97 @@ var (
 )
 
 type wtConfig struct {
-	Jira jiraConfigBlock `json:"jira"`
+	Jira     jiraConfigBlock `json:"jira"`
+	UI       uiConfig        `json:"ui,omitzero"`
+	Copy     copySettings    `json:"copy,omitzero"`
+	Tmux     tmuxConfig      `json:"tmux,omitzero"`
+	Worktree worktreeConfig  `json:"worktree,omitzero"`
+	Repos    []string        `json:"repos,omitempty"`
+	// Aliases maps a command name to the arguments it stands for, such as
+	// "wip": "new --copy-libs --from develop".
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// Bookmarks maps a short name to a worktree path, so wt go and wt t
+	// find the worktree by that name whatever its branch is called. A
+	// relative path is relative to the main worktree (see loadBookmarks).
+	Bookmarks map[string]string `json:"bookmarks,omitempty"`
+}
+
+type worktreeConfig struct {
+	// InitSubmodules is a pointer so a repo config can turn off a global
+	// true.
+	InitSubmodules *bool `json:"initSubmodules,omitempty"`
+	// Protected lists branch globs, such as "release/*", that wt will not
+	// create worktrees for or remove worktrees of without --force.
+	Protected []string `json:"protected,omitempty"`
+	// Templates maps a path relative to a new worktree to the contents to
+	// write there, with {branch}, {base} and {path} filled in.
+	Templates map[string]string `json:"templates,omitempty"`
+	// GitConfig maps git config keys, such as "user.email", to values set
+	// in each new worktree's own git config. Only the global config may
+	// set it.
+	GitConfig map[string]string `json:"gitConfig,omitempty"`
+}
+
+type tmuxConfig struct {
+	SessionNameFrom string `json:"sessionNameFrom,omitempty"`
+	Loader          string `json:"loader,omitempty"`
+	// NoSwitch makes wt print an existing session's name instead of
+	// switching the current tmux client to it, as wt t --no-switch does.
+	// It is a pointer so a repo config can turn off a global true.
+	NoSwitch *bool `json:"noSwitch,omitempty"`
+}
+
+type copySettings struct {
+	Paths    []string `json:"paths,omitempty"`
+	Symlinks string   `json:"symlinks,omitempty"`
+	// EnvOverrides sets variables in each copied .env file, replacing the
+	// value of a matching KEY=VALUE line or appending a new one.
+	EnvOverrides map[string]string `json:"envOverrides,omitempty"`
+	// UntrackedSkip lists globs for files wt new --copy-untracked leaves
+	// out, matched against the relative path and each of its elements.
+	UntrackedSkip []string `json:"untrackedSkip,omitempty"`
+	// Libs lists the library directories --copy-libs copies, relative to
+	// the repo. Unset, it is the common ones in defaultCopyLibItems.
+	Libs []string `json:"libs,omitempty"`
+}
+
+type uiConfig struct {
+	RefreshInterval string `json:"refreshInterval,omitempty"`
+	DefaultAction   string `json:"defaultAction,omitempty"`
+	BranchSort      string `json:"branchSort,omitempty"`
 }
 
 type jiraConfigBlock struct {
-	Status jiraStatusConfig `json:"status"`
+	Status       jiraStatusConfig  `json:"status"`
+	CustomFields map[string]string `json:"customFields,omitempty"`
+	// Timeout bounds each Jira request (e.g. "30s"); JIRA_TIMEOUT overrides it.
+	Timeout string `json:"timeout,omitempty"`
+	// DefaultProject is prepended to bare issue numbers, so 123 means PROJ-123.
+	DefaultProject string `json:"defaultProject,omitempty"`
+	// NotesFormat is the format of the issue notes wt jira new writes:
+	// "markdown" (the default), "org", or "plain".
+	NotesFormat string `json:"notesFormat,omitempty"`
+	// MaxDescriptionLength caps the description in the issue notes, in
+	// characters; longer ones are cut short. Zero means no limit.
+	MaxDescriptionLength int `json:"maxDescriptionLength,omitempty"`
+	// BoardID is the board whose active sprint wt jira new --sprint current
+	// uses. Unset, the issue's project must have a single scrum board.
+	BoardID int `json:"boardId,omitempty"`
+	// Sites names Jira instances besides the default one that JIRA_URL,
+	// JIRA_USER and JIRA_TOKEN describe. wt jira --site picks one, as does
+	// an issue key in one of a site's projects. Only the global config may
+	// set it.
+	Sites map[string]jiraSiteConfig `json:"sites,omitempty"`
+}
+
+// jiraSiteConfig is a Jira instance in jira.sites. Its API token is the
+// output of TokenCommand, run with sh, so it needn't be kept in the config.
+type jiraSiteConfig struct {
+	URL          string `json:"url"`
+	User         string `json:"user"`
+	TokenCommand string `json:"tokenCommand"`
+	// Projects are the project keys whose issues live on this site.
+	Projects []string `json:"projects,omitempty"`
 }
 
 type jiraStatusConfig struct {
@@ -55,6 +147,11 @@ func loadConfig() (wtConfig, error) {
 			if err := json.Unmarshal(data, &repo); err != nil {
 				return wtConfig{}, fmt.Errorf("invalid config %s: %w", repoPath, err)
 			}
+			// A site's tokenCommand runs shell, as can git config such
+			// as core.fsmonitor or core.hooksPath, so a cloned repo
+			// mustn't be able to set either.
+			repo.Jira.Sites = nil
+			repo.Worktree.GitConfig = nil
 			repoFound = true
 		} else if !errors.Is(err, os.ErrNotExist) {
 			return wtConfig{}, err
@@ -76,28 +173,250 @@ func loadConfig() (wtConfig, error) {
 func mergeConfig(global, repo wtConfig) wtConfig {
 	merged := global
 
+	merged.Jira.Status.Default = mergeStringMaps(global.Jira.Status.Default, repo.Jira.Status.Default)
 	if merged.Jira.Status.Default == nil {
 		merged.Jira.Status.Default = make(map[string]string)
 	}
-	for k, v := range repo.Jira.Status.Default {
-		merged.Jira.Status.Default[k] = v
-	}
 
-	if merged.Jira.Status.Types == nil {
-		merged.Jira.Status.Types = make(map[string]map[string]string)
+	merged.Jira.Status.Types = make(map[string]map[string]string, len(global.Jira.Status.Types))
+	for typeName, overrides := range global.Jira.Status.Types {
+		merged.Jira.Status.Types[typeName] = overrides
 	}
 	for typeName, overrides := range repo.Jira.Status.Types {
-		if merged.Jira.Status.Types[typeName] == nil {
-			merged.Jira.Status.Types[typeName] = make(map[string]string)
+		merged.Jira.Status.Types[typeName] = mergeStringMaps(merged.Jira.Status.Types[typeName], overrides)
+	}
+
+	merged.Jira.CustomFields = mergeStringMaps(global.Jira.CustomFields, repo.Jira.CustomFields)
+
+	if repo.Jira.Timeout != "" {
+		merged.Jira.Timeout = repo.Jira.Timeout
+	}
+	if repo.Jira.DefaultProject != "" {
+		merged.Jira.DefaultProject = repo.Jira.DefaultProject
+	}
+	if repo.Jira.NotesFormat != "" {
+		merged.Jira.NotesFormat = repo.Jira.NotesFormat
+	}
+	if repo.Jira.MaxDescriptionLength != 0 {
+		merged.Jira.MaxDescriptionLength = repo.Jira.MaxDescriptionLength
+	}
+	if repo.Jira.BoardID != 0 {
+		merged.Jira.BoardID = repo.Jira.BoardID
+	}
+
+	if repo.UI.RefreshInterval != "" {
+		merged.UI.RefreshInterval = repo.UI.RefreshInterval
+	}
+	if repo.UI.DefaultAction != "" {
+		merged.UI.DefaultAction = repo.UI.DefaultAction
+	}
+	if repo.UI.BranchSort != "" {
+		merged.UI.BranchSort = repo.UI.BranchSort
+	}
+	if repo.Worktree.InitSubmodules != nil {
+		merged.Worktree.InitSubmodules = repo.Worktree.InitSubmodules
+	}
+	if repo.Worktree.Protected != nil {
+		merged.Worktree.Protected = repo.Worktree.Protected
+	}
+	merged.Worktree.Templates = mergeStringMaps(global.Worktree.Templates, repo.Worktree.Templates)
+	if repo.Tmux.SessionNameFrom != "" {
+		merged.Tmux.SessionNameFrom = repo.Tmux.SessionNameFrom
+	}
+	if repo.Tmux.Loader != "" {
+		merged.Tmux.Loader = repo.Tmux.Loader
+	}
+	if repo.Tmux.NoSwitch != nil {
+		merged.Tmux.NoSwitch = repo.Tmux.NoSwitch
+	}
+	if repo.Copy.Paths != nil {
+		merged.Copy.Paths = repo.Copy.Paths
+	}
+	if repo.Copy.Symlinks != "" {
+		merged.Copy.Symlinks = repo.Copy.Symlinks
+	}
+	if repo.Copy.UntrackedSkip != nil {
+		merged.Copy.UntrackedSkip = repo.Copy.UntrackedSkip
+	}
+	if repo.Copy.Libs != nil {
+		merged.Copy.Libs = repo.Copy.Libs
+	}
+	merged.Copy.EnvOverrides = mergeStringMaps(global.Copy.EnvOverrides, repo.Copy.EnvOverrides)
+	if repo.Repos != nil {
+		merged.Repos = repo.Repos
+	}
+	merged.Aliases = mergeStringMaps(global.Aliases, repo.Aliases)
+	merged.Bookmarks = mergeStringMaps(global.Bookmarks, repo.Bookmarks)
+
+	return merged
+}
+
+// loadBookmarks returns the bookmarks of the repository at repoRoot: the
+// config's, overridden by those in the main worktree's .wt.json, where wt
+// bookmark saves them. A relative path is relative to the main worktree.
+func loadBookmarks(repoRoot string) (map[string]string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	mainWT, err := gitMainWorktree(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	var main wtConfig
+	mainPath := filepath.Join(mainWT, ".wt.json")
+	data, err := osReadFile(mainPath)
+	if err == nil {
+		if err := json.Unmarshal(data, &main); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", mainPath, err)
 		}
-		for k, v := range overrides {
-			merged.Jira.Status.Types[typeName][k] = v
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	bookmarks := mergeStringMaps(cfg.Bookmarks, main.Bookmarks)
+	for name, path := range bookmarks {
+		if !filepath.IsAbs(path) {
+			bookmarks[name] = filepath.Join(mainWT, filepath.FromSlash(path))
 		}
 	}
+	return bookmarks, nil
+}
 
+// mergeStringMaps returns a new map holding base's entries overridden by
+// over's, so merging never writes into a map the caller still holds. It
+// returns base unchanged when both are empty.
+func mergeStringMaps(base, over map[string]string) map[string]string {
+	if len(base) == 0 && len(over) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(over))
+	maps.Copy(merged, base)
+	maps.Copy(merged, over)
 	return merged
 }
 
+// customFieldIDs returns the configured Jira custom field IDs, sorted.
+func customFieldIDs(cfg wtConfig) []string {
+	ids := make([]string, 0, len(cfg.Jira.CustomFields))
+	for id := range cfg.Jira.CustomFields {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// expandHome replaces a leading "~/" in path with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := osUserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// checkProtectedBranch returns an error if branch matches a
+// worktree.protected glob. Patterns use path.Match syntax, so "release/*"
+// matches "release/1.0" but not "release/1.0/hotfix". A detached worktree
+// (empty branch) is never protected.
+func checkProtectedBranch(cfg wtConfig, branch string) error {
+	if branch == "" {
+		return nil
+	}
+	for _, pattern := range cfg.Worktree.Protected {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return blockedError(fmt.Errorf("branch %s is protected by worktree.protected (%q); use --force to override", branch, pattern))
+		}
+	}
+	return nil
+}
+
+// uiRefreshInterval parses ui.refreshInterval (e.g. "30s"). An empty value
+// disables auto-refresh.
+func uiRefreshInterval(cfg wtConfig) (time.Duration, error) {
+	if cfg.UI.RefreshInterval == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(cfg.UI.RefreshInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ui.refreshInterval %q: %w", cfg.UI.RefreshInterval, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid ui.refreshInterval %q: must not be negative", cfg.UI.RefreshInterval)
+	}
+	return d, nil
+}
+
+// uiDefaultAction returns the TUI action for Enter in the worktree list:
+// tuiActionGo for ui.defaultAction "shell" (the default) or tuiActionTmux
+// for "tmux".
+func uiDefaultAction(cfg wtConfig) (string, error) {
+	switch cfg.UI.DefaultAction {
+	case "", "shell":
+		return tuiActionGo, nil
+	case "tmux":
+		return tuiActionTmux, nil
+	}
+	return "", fmt.Errorf("invalid ui.defaultAction %q: must be \"shell\" or \"tmux\"", cfg.UI.DefaultAction)
+}
+
+// Values for ui.branchSort, the order of the TUI branch picker.
+const (
+	branchSortRecent = "recent"
+	branchSortAlpha  = "alpha"
+	branchSortNone   = "none"
+)
+
+// uiBranchSort returns ui.branchSort, defaulting to "recent". "none" keeps
+// git's order and skips the per-branch commit lookups, which are slow in
+// repos with many branches.
+func uiBranchSort(cfg wtConfig) (string, error) {
+	switch cfg.UI.BranchSort {
+	case "":
+		return branchSortRecent, nil
+	case branchSortRecent, branchSortAlpha, branchSortNone:
+		return cfg.UI.BranchSort, nil
+	}
+	return "", fmt.Errorf("invalid ui.branchSort %q: must be \"recent\", \"alpha\", or \"none\"", cfg.UI.BranchSort)
+}
+
+// copySymlinkMode returns copy.symlinks, defaulting to "follow".
+func copySymlinkMode(cfg wtConfig) (string, error) {
+	switch cfg.Copy.Symlinks {
+	case "":
+		return symlinksFollow, nil
+	case symlinksFollow, symlinksRecreate, symlinksSkip:
+		return cfg.Copy.Symlinks, nil
+	}
+	return "", fmt.Errorf("invalid copy.symlinks %q: must be \"follow\", \"recreate\", or \"skip\"", cfg.Copy.Symlinks)
+}
+
+// tmuxSessionNameFrom returns tmux.sessionNameFrom, "path" (the default) or
+// "branch".
+func tmuxSessionNameFrom(cfg wtConfig) (string, error) {
+	switch cfg.Tmux.SessionNameFrom {
+	case "", "path":
+		return "path", nil
+	case "branch":
+		return "branch", nil
+	}
+	return "", fmt.Errorf("invalid tmux.sessionNameFrom %q: must be \"branch\" or \"path\"", cfg.Tmux.SessionNameFrom)
+}
+
+// tmuxLoader returns tmux.loader: "none" (the default), "auto", "tmuxp", or
+// "tmuxinator".
+func tmuxLoader(cfg wtConfig) (string, error) {
+	switch cfg.Tmux.Loader {
+	case "", "none":
+		return "none", nil
+	case "auto", "tmuxp", "tmuxinator":
+		return cfg.Tmux.Loader, nil
+	}
+	return "", fmt.Errorf("invalid tmux.loader %q: must be \"none\", \"auto\", \"tmuxp\", or \"tmuxinator\"", cfg.Tmux.Loader)
+}
+
 func reverseSymbolic(cfg wtConfig, issueType, jiraStatusName string) string {
 	lower := strings.ToLower(issueType)
 	if m, ok := cfg.Jira.Status.Types[lower]; ok {
@@ -146,3 +465,196 @@ func resolveStatus(cfg wtConfig, issueType, symbolic string) (string, error) {
 	}
 	return "", fmt.Errorf("no status mapping for %q", symbolic)
 }
+
+// configFilePath returns the global config path for choice "g" and the repo
+// config path for "r".
+func configFilePath(choice string) (string, error) {
+	switch choice {
+	case "g":
+		home, err := osUserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".config", "wt", "config.json"), nil
+	case "r":
+		root, err := gitRepoRoot()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(root, ".wt.json"), nil
+	}
+	return "", fmt.Errorf("invalid choice: %q", choice)
+}
+
+// readConfigMap reads a config file as generic JSON, so that keys wt
+// doesn't know about survive a rewrite. A missing file reads as empty.
+func readConfigMap(path string) (map[string]any, error) {
+	data, err := osReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if m == nil {
+		m = map[string]any{}
+	}
+	return m, nil
+}
+
+// configMap converts cfg to generic JSON for configLookup.
+func configMap(cfg wtConfig) map[string]any {
+	data, _ := json.Marshal(cfg)
+	var m map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	// Cannot fail: data was just marshaled from a struct.
+	_ = dec.Decode(&m)
+	return m
+}
+
+// configKeyParts splits the dotted key into the JSON object keys it names,
+// following wtConfig's fields. Map entries may have dots in their names, so
+// once the key reaches a map of plain values, such as worktree.gitConfig,
+// the rest of it is a single entry: worktree.gitConfig.user.email sets
+// "user.email". Parts past what wtConfig knows are split on every dot.
+func configKeyParts(key string) []string {
+	parts := strings.Split(key, ".")
+	t := reflect.TypeOf(wtConfig{})
+	for i, part := range parts {
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := configField(t, part)
+			if !ok {
+				return parts
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+			if k := t.Kind(); k != reflect.Struct && k != reflect.Map {
+				return append(parts[:i:i], strings.Join(parts[i:], "."))
+			}
+		default:
+			return parts
+		}
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+	return parts
+}
+
+// configField returns the field of struct type t stored under the JSON key
+// name.
+func configField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// configLookup returns the value at the dotted key, such as
+// "worktree.protected", and whether it is set.
+func configLookup(m map[string]any, key string) (any, bool) {
+	var v any = m
+	for _, part := range configKeyParts(key) {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, v != nil
+}
+
+// configSet sets the value at the dotted key, creating the objects along
+// the way.
+func configSet(m map[string]any, key string, value any) error {
+	for _, part := range strings.Split(key, ".") {
+		if part == "" {
+			return usageError(fmt.Errorf("invalid config key %q", key))
+		}
+	}
+	parts := configKeyParts(key)
+	obj := m
+	for i, part := range parts[:len(parts)-1] {
+		next, ok := obj[part].(map[string]any)
+		if !ok {
+			if obj[part] != nil {
+				return fmt.Errorf("%s is not an object", strings.Join(parts[:i+1], "."))
+			}
+			next = map[string]any{}
+			obj[part] = next
+		}
+		obj = next
+	}
+	obj[parts[len(parts)-1]] = value
+	return nil
+}
+
+// configValue turns the command-line value for key into the JSON value it
+// is stored as, judged by what wtConfig accepts at key: the text as a
+// string, else parsed as JSON (true, 3, ["a"]), else split on commas into a
+// list of strings. A key wtConfig doesn't have is an error.
+func configValue(key, raw string) (any, error) {
+	if err := configSet(map[string]any{}, key, nil); err != nil {
+		return nil, err
+	}
+	if name, ok := strings.CutPrefix(key, "worktree.gitConfig."); ok && !strings.Contains(name, ".") {
+		return nil, usageError(fmt.Errorf("invalid git config key %q: must be section.name, such as user.email", name))
+	}
+	// A plain string only fails on an unknown key or one of another type,
+	// which is also the clearest error to report.
+	stringErr := checkConfigValue(key, raw)
+	if stringErr == nil {
+		return raw, nil
+	}
+	if strings.Contains(stringErr.Error(), "unknown field") {
+		return nil, usageError(fmt.Errorf("unknown config key %q", key))
+	}
+
+	var candidates []any
+	var parsed any
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	if dec.Decode(&parsed) == nil && !dec.More() {
+		candidates = append(candidates, parsed)
+	}
+	list := []any{}
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	candidates = append(candidates, list)
+	for _, value := range candidates {
+		if checkConfigValue(key, value) == nil {
+			return value, nil
+		}
+	}
+	return nil, usageError(fmt.Errorf("invalid value %q for %s: %w", raw, key, stringErr))
+}
+
+// checkConfigValue reports whether a config holding only value at key
+// decodes into wtConfig, rejecting unknown fields.
+func checkConfigValue(key string, value any) error {
+	m := map[string]any{}
+	// Cannot fail: configValue has already checked key.
+	_ = configSet(m, key, value)
+	data, _ := json.Marshal(m)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var cfg wtConfig
+	return dec.Decode(&cfg)
+}
@@ -0,0 +1,683 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeConfigUI(t *testing.T) {
+	global := wtConfig{UI: uiConfig{RefreshInterval: "10s"}}
+
+	merged := mergeConfig(global, wtConfig{})
+	if merged.UI.RefreshInterval != "10s" {
+		t.Fatalf("expected global interval kept, got %q", merged.UI.RefreshInterval)
+	}
+
+	merged = mergeConfig(global, wtConfig{UI: uiConfig{RefreshInterval: "1m"}})
+	if merged.UI.RefreshInterval != "1m" {
+		t.Fatalf("expected repo interval to override, got %q", merged.UI.RefreshInterval)
+	}
+
+	merged = mergeConfig(wtConfig{UI: uiConfig{DefaultAction: "tmux"}}, wtConfig{UI: uiConfig{DefaultAction: "shell"}})
+	if merged.UI.DefaultAction != "shell" {
+		t.Fatalf("expected repo default action to override, got %q", merged.UI.DefaultAction)
+	}
+
+	merged = mergeConfig(wtConfig{UI: uiConfig{BranchSort: "none"}}, wtConfig{UI: uiConfig{BranchSort: "alpha"}})
+	if merged.UI.BranchSort != "alpha" {
+		t.Fatalf("expected repo branch sort to override, got %q", merged.UI.BranchSort)
+	}
+}
+
+func TestUIDefaultAction(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"", tuiActionGo, ""},
+		{"shell", tuiActionGo, ""},
+		{"tmux", tuiActionTmux, ""},
+		{"editor", "", "invalid ui.defaultAction"},
+	}
+	for _, tt := range tests {
+		got, err := uiDefaultAction(wtConfig{UI: uiConfig{DefaultAction: tt.value}})
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("uiDefaultAction(%q): expected error %q, got %v", tt.value, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("uiDefaultAction(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestUIBranchSort(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"", branchSortRecent, ""},
+		{"recent", branchSortRecent, ""},
+		{"alpha", branchSortAlpha, ""},
+		{"none", branchSortNone, ""},
+		{"size", "", "invalid ui.branchSort"},
+	}
+	for _, tt := range tests {
+		got, err := uiBranchSort(wtConfig{UI: uiConfig{BranchSort: tt.value}})
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("uiBranchSort(%q): expected error %q, got %v", tt.value, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("uiBranchSort(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestUIRefreshInterval(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr string
+	}{
+		{"", 0, ""},
+		{"30s", 30 * time.Second, ""},
+		{"2m", 2 * time.Minute, ""},
+		{"soon", 0, "invalid ui.refreshInterval"},
+		{"-5s", 0, "must not be negative"},
+	}
+	for _, tt := range tests {
+		got, err := uiRefreshInterval(wtConfig{UI: uiConfig{RefreshInterval: tt.value}})
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("uiRefreshInterval(%q): expected error %q, got %v", tt.value, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("uiRefreshInterval(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestMergeConfigJiraTimeout(t *testing.T) {
+	global := wtConfig{Jira: jiraConfigBlock{Timeout: "10s"}}
+
+	if got := mergeConfig(global, wtConfig{}).Jira.Timeout; got != "10s" {
+		t.Fatalf("expected global timeout kept, got %q", got)
+	}
+	if got := mergeConfig(global, wtConfig{Jira: jiraConfigBlock{Timeout: "1m"}}).Jira.Timeout; got != "1m" {
+		t.Fatalf("expected repo timeout to override, got %q", got)
+	}
+}
+
+func TestMergeConfigJiraDefaultProject(t *testing.T) {
+	global := wtConfig{Jira: jiraConfigBlock{DefaultProject: "GLOB"}}
+
+	if got := mergeConfig(global, wtConfig{}).Jira.DefaultProject; got != "GLOB" {
+		t.Fatalf("expected global project kept, got %q", got)
+	}
+	if got := mergeConfig(global, wtConfig{Jira: jiraConfigBlock{DefaultProject: "REPO"}}).Jira.DefaultProject; got != "REPO" {
+		t.Fatalf("expected repo project to override, got %q", got)
+	}
+}
+
+func TestMergeConfigJiraNotesFormat(t *testing.T) {
+	global := wtConfig{Jira: jiraConfigBlock{NotesFormat: "org"}}
+
+	if got := mergeConfig(global, wtConfig{}).Jira.NotesFormat; got != "org" {
+		t.Fatalf("expected global format kept, got %q", got)
+	}
+	if got := mergeConfig(global, wtConfig{Jira: jiraConfigBlock{NotesFormat: "plain"}}).Jira.NotesFormat; got != "plain" {
+		t.Fatalf("expected repo format to override, got %q", got)
+	}
+}
+
+func TestMergeConfigJiraMaxDescriptionLength(t *testing.T) {
+	global := wtConfig{Jira: jiraConfigBlock{MaxDescriptionLength: 5000}}
+
+	if got := mergeConfig(global, wtConfig{}).Jira.MaxDescriptionLength; got != 5000 {
+		t.Fatalf("expected global limit kept, got %d", got)
+	}
+	if got := mergeConfig(global, wtConfig{Jira: jiraConfigBlock{MaxDescriptionLength: 200}}).Jira.MaxDescriptionLength; got != 200 {
+		t.Fatalf("expected repo limit to override, got %d", got)
+	}
+}
+
+func TestMergeConfigJiraBoardID(t *testing.T) {
+	global := wtConfig{Jira: jiraConfigBlock{BoardID: 7}}
+
+	if got := mergeConfig(global, wtConfig{}).Jira.BoardID; got != 7 {
+		t.Fatalf("expected global board kept, got %d", got)
+	}
+	if got := mergeConfig(global, wtConfig{Jira: jiraConfigBlock{BoardID: 12}}).Jira.BoardID; got != 12 {
+		t.Fatalf("expected repo board to override, got %d", got)
+	}
+}
+
+func TestMergeConfigJiraSites(t *testing.T) {
+	global := wtConfig{Jira: jiraConfigBlock{Sites: map[string]jiraSiteConfig{
+		"acme": {URL: "https://acme.example.com"},
+	}}}
+	if got := mergeConfig(global, wtConfig{}).Jira.Sites; len(got) != 1 || got["acme"].URL != "https://acme.example.com" {
+		t.Fatalf("expected global sites kept, got %+v", got)
+	}
+}
+
+func TestMergeConfigCopyLibs(t *testing.T) {
+	global := wtConfig{Copy: copySettings{Libs: []string{".venv"}}}
+
+	if got := mergeConfig(global, wtConfig{}).Copy.Libs; len(got) != 1 || got[0] != ".venv" {
+		t.Fatalf("expected global libs kept, got %v", got)
+	}
+	repo := wtConfig{Copy: copySettings{Libs: []string{"target"}}}
+	if got := mergeConfig(global, repo).Copy.Libs; len(got) != 1 || got[0] != "target" {
+		t.Fatalf("expected repo libs to replace global ones, got %v", got)
+	}
+}
+
+func TestMergeConfigAliases(t *testing.T) {
+	global := wtConfig{Aliases: map[string]string{"wip": "new --copy-libs", "ls": "list --all"}}
+	repo := wtConfig{Aliases: map[string]string{"wip": "new --from develop"}}
+
+	got := mergeConfig(global, repo).Aliases
+	if got["wip"] != "new --from develop" || got["ls"] != "list --all" {
+		t.Fatalf("expected repo aliases to override per name, got %v", got)
+	}
+	if got := mergeConfig(wtConfig{}, repo).Aliases; got["wip"] != "new --from develop" {
+		t.Fatalf("expected repo aliases without global ones, got %v", got)
+	}
+}
+
+func TestMergeConfigBookmarks(t *testing.T) {
+	global := wtConfig{Bookmarks: map[string]string{"api": "/wt/api", "web": "/wt/web"}}
+	repo := wtConfig{Bookmarks: map[string]string{"api": "/wt/PROJ-1"}}
+
+	got := mergeConfig(global, repo).Bookmarks
+	if got["api"] != "/wt/PROJ-1" || got["web"] != "/wt/web" {
+		t.Fatalf("expected repo bookmarks to override per name, got %v", got)
+	}
+	if got := mergeConfig(wtConfig{}, repo).Bookmarks; got["api"] != "/wt/PROJ-1" {
+		t.Fatalf("expected repo bookmarks without global ones, got %v", got)
+	}
+}
+
+func TestMergeConfigProtected(t *testing.T) {
+	global := wtConfig{Worktree: worktreeConfig{Protected: []string{"main"}}}
+
+	if got := mergeConfig(global, wtConfig{}).Worktree.Protected; len(got) != 1 || got[0] != "main" {
+		t.Fatalf("expected global patterns kept, got %v", got)
+	}
+	repo := wtConfig{Worktree: worktreeConfig{Protected: []string{}}}
+	if got := mergeConfig(global, repo).Worktree.Protected; len(got) != 0 {
+		t.Fatalf("expected repo list to replace global, got %v", got)
+	}
+}
+
+func TestCheckProtectedBranch(t *testing.T) {
+	cfg := wtConfig{Worktree: worktreeConfig{Protected: []string{"main", "release/*", "["}}}
+	tests := []struct {
+		branch  string
+		wantErr string
+	}{
+		{"main", `branch main is protected by worktree.protected ("main"); use --force to override`},
+		{"release/1.0", `branch release/1.0 is protected by worktree.protected ("release/*")`},
+		{"release/1.0/hotfix", ""},
+		{"mainline", ""},
+		{"feature", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		err := checkProtectedBranch(cfg, tt.branch)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("checkProtectedBranch(%q) = %v, want nil", tt.branch, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("checkProtectedBranch(%q) = %v, want %q", tt.branch, err, tt.wantErr)
+		}
+	}
+
+	if err := checkProtectedBranch(wtConfig{}, "main"); err != nil {
+		t.Fatalf("expected no restriction by default, got %v", err)
+	}
+}
+
+func TestMergeConfigCopyPaths(t *testing.T) {
+	global := wtConfig{Copy: copySettings{Paths: []string{"a"}}}
+
+	if got := mergeConfig(global, wtConfig{}).Copy.Paths; len(got) != 1 || got[0] != "a" {
+		t.Fatalf("expected global paths kept, got %v", got)
+	}
+	if got := mergeConfig(global, wtConfig{Copy: copySettings{Paths: []string{"b"}}}).Copy.Paths; len(got) != 1 || got[0] != "b" {
+		t.Fatalf("expected repo paths to replace global, got %v", got)
+	}
+}
+
+func TestMergeConfigCopyUntrackedSkip(t *testing.T) {
+	global := wtConfig{Copy: copySettings{UntrackedSkip: []string{"*.log"}}}
+
+	if got := mergeConfig(global, wtConfig{}).Copy.UntrackedSkip; len(got) != 1 || got[0] != "*.log" {
+		t.Fatalf("expected global skip list kept, got %v", got)
+	}
+	if got := mergeConfig(global, wtConfig{Copy: copySettings{UntrackedSkip: []string{"tmp"}}}).Copy.UntrackedSkip; len(got) != 1 || got[0] != "tmp" {
+		t.Fatalf("expected repo skip list to replace global, got %v", got)
+	}
+}
+
+func TestMergeConfigRepos(t *testing.T) {
+	global := wtConfig{Repos: []string{"/a", "/b"}}
+	if got := mergeConfig(global, wtConfig{}); len(got.Repos) != 2 {
+		t.Fatalf("expected global repos kept, got %v", got.Repos)
+	}
+	if got := mergeConfig(global, wtConfig{Repos: []string{"/c"}}); len(got.Repos) != 1 || got.Repos[0] != "/c" {
+		t.Fatalf("expected repo config to replace repos, got %v", got.Repos)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	oldHome := osUserHomeDir
+	defer func() { osUserHomeDir = oldHome }()
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+
+	tests := map[string]string{
+		"~":         "/home/test",
+		"~/src/app": "/home/test/src/app",
+		"/abs":      "/abs",
+		"~other/x":  "~other/x",
+	}
+	for in, want := range tests {
+		if got := expandHome(in); got != want {
+			t.Errorf("expandHome(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	osUserHomeDir = func() (string, error) { return "", errors.New("no home") }
+	if got := expandHome("~/src"); got != "~/src" {
+		t.Fatalf("expected path unchanged without home, got %q", got)
+	}
+}
+
+func TestMergeConfigCustomFields(t *testing.T) {
+	global := wtConfig{Jira: jiraConfigBlock{CustomFields: map[string]string{"customfield_1": "AC", "customfield_2": "Risk"}}}
+	repo := wtConfig{Jira: jiraConfigBlock{CustomFields: map[string]string{"customfield_2": "Risk Level", "customfield_3": "Notes"}}}
+
+	merged := mergeConfig(global, repo)
+	if len(merged.Jira.CustomFields) != 3 || merged.Jira.CustomFields["customfield_2"] != "Risk Level" {
+		t.Fatalf("unexpected custom fields %v", merged.Jira.CustomFields)
+	}
+	if ids := customFieldIDs(merged); strings.Join(ids, ",") != "customfield_1,customfield_2,customfield_3" {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+
+	merged = mergeConfig(wtConfig{}, repo)
+	if len(merged.Jira.CustomFields) != 2 {
+		t.Fatalf("expected repo custom fields, got %v", merged.Jira.CustomFields)
+	}
+	if merged = mergeConfig(wtConfig{}, wtConfig{}); merged.Jira.CustomFields != nil {
+		t.Fatalf("expected no custom fields, got %v", merged.Jira.CustomFields)
+	}
+}
+
+func TestMergeConfigLeavesGlobalMapsAlone(t *testing.T) {
+	global := wtConfig{
+		Jira: jiraConfigBlock{
+			Status: jiraStatusConfig{
+				Default: map[string]string{"done": "Done"},
+				Types:   map[string]map[string]string{"Bug": {"done": "Fixed"}},
+			},
+			CustomFields: map[string]string{"customfield_1": "AC"},
+		},
+		Worktree: worktreeConfig{
+			Templates: map[string]string{"NOTES.md": "notes.tmpl"},
+		},
+		Copy:      copySettings{EnvOverrides: map[string]string{"PORT": "3000"}},
+		Aliases:   map[string]string{"co": "new"},
+		Bookmarks: map[string]string{"api": "~/src/api"},
+	}
+	repo := wtConfig{
+		Jira: jiraConfigBlock{
+			Status: jiraStatusConfig{
+				Default: map[string]string{"review": "In Review"},
+				Types:   map[string]map[string]string{"Bug": {"review": "Verify"}},
+			},
+			CustomFields: map[string]string{"customfield_2": "Risk"},
+		},
+		Worktree: worktreeConfig{
+			Templates: map[string]string{".envrc": "envrc.tmpl"},
+		},
+		Copy:      copySettings{EnvOverrides: map[string]string{"HOST": "localhost"}},
+		Aliases:   map[string]string{"ls": "list"},
+		Bookmarks: map[string]string{"web": "~/src/web"},
+	}
+
+	merged := mergeConfig(global, repo)
+	if len(merged.Jira.CustomFields) != 2 || len(merged.Jira.Status.Types["Bug"]) != 2 {
+		t.Fatalf("unexpected merge %+v", merged)
+	}
+	sizes := map[string]int{
+		"status.default": len(global.Jira.Status.Default),
+		"status.types":   len(global.Jira.Status.Types["Bug"]),
+		"customFields":   len(global.Jira.CustomFields),
+		"templates":      len(global.Worktree.Templates),
+		"envOverrides":   len(global.Copy.EnvOverrides),
+		"aliases":        len(global.Aliases),
+		"bookmarks":      len(global.Bookmarks),
+	}
+	for name, size := range sizes {
+		if size != 1 {
+			t.Errorf("global %s changed to %d entries", name, size)
+		}
+	}
+}
+
+func TestTmuxLoader(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "none", false},
+		{"none", "none", false},
+		{"auto", "auto", false},
+		{"tmuxp", "tmuxp", false},
+		{"tmuxinator", "tmuxinator", false},
+		{"teamocil", "", true},
+	}
+	for _, tt := range tests {
+		got, err := tmuxLoader(wtConfig{Tmux: tmuxConfig{Loader: tt.value}})
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("tmuxLoader(%q) = %q, %v; want %q (err %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	global := wtConfig{Tmux: tmuxConfig{Loader: "auto"}}
+	if got := mergeConfig(global, wtConfig{}).Tmux.Loader; got != "auto" {
+		t.Fatalf("expected global loader kept, got %q", got)
+	}
+	if got := mergeConfig(global, wtConfig{Tmux: tmuxConfig{Loader: "none"}}).Tmux.Loader; got != "none" {
+		t.Fatalf("expected repo loader to override, got %q", got)
+	}
+}
+
+func TestTmuxSessionNameFrom(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "path", false},
+		{"path", "path", false},
+		{"branch", "branch", false},
+		{"dir", "", true},
+	}
+	for _, tt := range tests {
+		got, err := tmuxSessionNameFrom(wtConfig{Tmux: tmuxConfig{SessionNameFrom: tt.value}})
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("tmuxSessionNameFrom(%q) = %q, %v; want %q (err %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	global := wtConfig{Tmux: tmuxConfig{SessionNameFrom: "branch"}}
+	if got := mergeConfig(global, wtConfig{}).Tmux.SessionNameFrom; got != "branch" {
+		t.Fatalf("expected global setting kept, got %q", got)
+	}
+	if got := mergeConfig(global, wtConfig{Tmux: tmuxConfig{SessionNameFrom: "path"}}).Tmux.SessionNameFrom; got != "path" {
+		t.Fatalf("expected repo setting to override, got %q", got)
+	}
+}
+
+func TestCopySymlinkMode(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", symlinksFollow, false},
+		{"follow", symlinksFollow, false},
+		{"recreate", symlinksRecreate, false},
+		{"skip", symlinksSkip, false},
+		{"copy", "", true},
+	}
+	for _, tt := range tests {
+		got, err := copySymlinkMode(wtConfig{Copy: copySettings{Symlinks: tt.value}})
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("copySymlinkMode(%q) = %q, %v; want %q (err %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	global := wtConfig{Copy: copySettings{Symlinks: "skip"}}
+	if got := mergeConfig(global, wtConfig{}).Copy.Symlinks; got != "skip" {
+		t.Fatalf("expected global setting kept, got %q", got)
+	}
+	if got := mergeConfig(global, wtConfig{Copy: copySettings{Symlinks: "recreate"}}).Copy.Symlinks; got != "recreate" {
+		t.Fatalf("expected repo setting to override, got %q", got)
+	}
+}
+
+func TestMergeConfigTemplates(t *testing.T) {
+	global := wtConfig{Worktree: worktreeConfig{Templates: map[string]string{".envrc": "global", "TODO.md": "todo"}}}
+	repo := wtConfig{Worktree: worktreeConfig{Templates: map[string]string{".envrc": "repo"}}}
+
+	got := mergeConfig(global, repo).Worktree.Templates
+	if got[".envrc"] != "repo" || got["TODO.md"] != "todo" {
+		t.Fatalf("expected per-path override, got %v", got)
+	}
+	got = mergeConfig(wtConfig{}, repo).Worktree.Templates
+	if got[".envrc"] != "repo" || len(got) != 1 {
+		t.Fatalf("expected repo templates, got %v", got)
+	}
+}
+
+func TestMergeConfigGitConfig(t *testing.T) {
+	global := wtConfig{Worktree: worktreeConfig{GitConfig: map[string]string{"user.email": "me@home.example", "user.name": "Me"}}}
+	if got := mergeConfig(global, wtConfig{}).Worktree.GitConfig; got["user.email"] != "me@home.example" || len(got) != 2 {
+		t.Fatalf("expected global git config kept, got %v", got)
+	}
+}
+
+func TestMergeConfigInitSubmodules(t *testing.T) {
+	on, off := true, false
+	global := wtConfig{Worktree: worktreeConfig{InitSubmodules: &on}}
+
+	if got := mergeConfig(global, wtConfig{}).Worktree.InitSubmodules; got == nil || !*got {
+		t.Fatalf("expected global setting kept, got %v", got)
+	}
+	if got := mergeConfig(global, wtConfig{Worktree: worktreeConfig{InitSubmodules: &off}}).Worktree.InitSubmodules; got == nil || *got {
+		t.Fatalf("expected repo false to override global true, got %v", got)
+	}
+}
+
+func TestMergeConfigTmuxNoSwitch(t *testing.T) {
+	on, off := true, false
+	global := wtConfig{Tmux: tmuxConfig{NoSwitch: &on}}
+
+	if got := mergeConfig(global, wtConfig{}).Tmux.NoSwitch; got == nil || !*got {
+		t.Fatalf("expected global setting kept, got %v", got)
+	}
+	if got := mergeConfig(global, wtConfig{Tmux: tmuxConfig{NoSwitch: &off}}).Tmux.NoSwitch; got == nil || *got {
+		t.Fatalf("expected repo false to override global true, got %v", got)
+	}
+}
+
+func TestMergeConfigEnvOverrides(t *testing.T) {
+	global := wtConfig{Copy: copySettings{EnvOverrides: map[string]string{"PORT": "0", "HOST": "localhost"}}}
+	repo := wtConfig{Copy: copySettings{EnvOverrides: map[string]string{"PORT": "4000"}}}
+
+	got := mergeConfig(global, repo).Copy.EnvOverrides
+	if got["PORT"] != "4000" || got["HOST"] != "localhost" {
+		t.Fatalf("expected per-key override, got %v", got)
+	}
+	got = mergeConfig(wtConfig{}, repo).Copy.EnvOverrides
+	if got["PORT"] != "4000" || len(got) != 1 {
+		t.Fatalf("expected repo overrides, got %v", got)
+	}
+}
+
+func TestConfigFilePath(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	oldHome := osUserHomeDir
+	defer func() { osUserHomeDir = oldHome }()
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+
+	if got, err := configFilePath("g"); err != nil || got != filepath.Join("/home/test", ".config", "wt", "config.json") {
+		t.Fatalf("unexpected global path %q, %v", got, err)
+	}
+	if got, err := configFilePath("r"); err != nil || got != filepath.Join(repo, ".wt.json") {
+		t.Fatalf("unexpected repo path %q, %v", got, err)
+	}
+	if _, err := configFilePath("x"); err == nil {
+		t.Fatalf("expected an invalid choice error")
+	}
+	osUserHomeDir = func() (string, error) { return "", errors.New("no home") }
+	if _, err := configFilePath("g"); err == nil {
+		t.Fatalf("expected the home error")
+	}
+	defer withDir(t, t.TempDir())()
+	if _, err := configFilePath("r"); err == nil {
+		t.Fatalf("expected an error outside a repo")
+	}
+}
+
+func TestReadConfigMap(t *testing.T) {
+	dir := t.TempDir()
+	if m, err := readConfigMap(filepath.Join(dir, "missing.json")); err != nil || len(m) != 0 || m == nil {
+		t.Fatalf("expected an empty map for a missing file, got %v, %v", m, err)
+	}
+	if _, err := readConfigMap(dir); err == nil {
+		t.Fatalf("expected an error reading a directory")
+	}
+	path := filepath.Join(dir, "config.json")
+	mustWriteFile(t, path, "null")
+	if m, err := readConfigMap(path); err != nil || m == nil {
+		t.Fatalf("expected an empty map for null, got %v, %v", m, err)
+	}
+	mustWriteFile(t, path, "{")
+	if _, err := readConfigMap(path); err == nil || !strings.Contains(err.Error(), "invalid config "+path) {
+		t.Fatalf("expected an invalid config error, got %v", err)
+	}
+	mustWriteFile(t, path, `{"x": 12345678901234567890}`)
+	m, err := readConfigMap(path)
+	if err != nil || m["x"] != json.Number("12345678901234567890") {
+		t.Fatalf("expected the number kept exactly, got %v, %v", m, err)
+	}
+}
+
+func TestConfigLookup(t *testing.T) {
+	m := configMap(wtConfig{Copy: copySettings{Libs: []string{"vendor"}}, UI: uiConfig{BranchSort: "name"}})
+	if v, ok := configLookup(m, "ui.branchSort"); !ok || v != "name" {
+		t.Fatalf("expected name, got %v, %v", v, ok)
+	}
+	if v, ok := configLookup(m, "copy.libs"); !ok || !reflect.DeepEqual(v, []any{"vendor"}) {
+		t.Fatalf("expected [vendor], got %v, %v", v, ok)
+	}
+	for _, key := range []string{"ui.defaultAction", "ui.branchSort.x", "nope", "jira.status.types"} {
+		if v, ok := configLookup(m, key); ok {
+			t.Errorf("expected %s unset, got %v", key, v)
+		}
+	}
+	m = configMap(wtConfig{Worktree: worktreeConfig{
+		GitConfig: map[string]string{"user.email": "me@example.com"},
+		Templates: map[string]string{"notes.md": "# notes"},
+	}})
+	if v, ok := configLookup(m, "worktree.gitConfig.user.email"); !ok || v != "me@example.com" {
+		t.Fatalf("expected me@example.com, got %v, %v", v, ok)
+	}
+	if v, ok := configLookup(m, "worktree.templates.notes.md"); !ok || v != "# notes" {
+		t.Fatalf("expected # notes, got %v, %v", v, ok)
+	}
+	if v, ok := configLookup(m, "worktree.gitConfig.user"); ok {
+		t.Errorf("expected worktree.gitConfig.user unset, got %v", v)
+	}
+}
+
+func TestConfigSet(t *testing.T) {
+	m := map[string]any{"ui": map[string]any{"branchSort": "name"}, "repos": []any{"a"}}
+	if err := configSet(m, "ui.defaultAction", "tmux"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := configSet(m, "tmux.noSwitch", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{
+		"ui":    map[string]any{"branchSort": "name", "defaultAction": "tmux"},
+		"tmux":  map[string]any{"noSwitch": true},
+		"repos": []any{"a"},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("expected %v, got %v", want, m)
+	}
+	if err := configSet(m, "repos.x", "b"); err == nil || err.Error() != "repos is not an object" {
+		t.Fatalf("expected a not-an-object error, got %v", err)
+	}
+	if err := configSet(m, "worktree.gitConfig.user.email", "me@example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := configSet(m, "worktree.templates.notes.md", "# notes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantWorktree := map[string]any{
+		"gitConfig": map[string]any{"user.email": "me@example.com"},
+		"templates": map[string]any{"notes.md": "# notes"},
+	}
+	if !reflect.DeepEqual(m["worktree"], wantWorktree) {
+		t.Fatalf("expected dotted map keys kept whole, got %v", m["worktree"])
+	}
+	if err := configSet(m, "ui..x", "b"); exitCode(err) != exitUsage {
+		t.Fatalf("expected a usage error for an empty segment, got %v", err)
+	}
+}
+
+func TestConfigValue(t *testing.T) {
+	for _, tt := range []struct {
+		key, raw string
+		want     any
+	}{
+		{"ui.refreshInterval", "5s", "5s"},
+		{"ui.defaultAction", "true", "true"},
+		{"tmux.noSwitch", "true", true},
+		{"copy.libs", "node_modules, .venv,", []any{"node_modules", ".venv"}},
+		{"copy.libs", `["a,b"]`, []any{"a,b"}},
+		{"aliases.wip", "new --from develop", "new --from develop"},
+		{"copy", `{"symlinks":"skip"}`, map[string]any{"symlinks": "skip"}},
+		{"worktree.gitConfig.user.email", "me@example.com", "me@example.com"},
+		{"worktree.templates.notes.md", "# {{.Branch}}", "# {{.Branch}}"},
+		{"jira.sites.work.url", "https://work.example.com", "https://work.example.com"},
+		{"jira.status.types.Bug.In Progress", "Doing", "Doing"},
+	} {
+		got, err := configValue(tt.key, tt.raw)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("configValue(%q, %q) = %#v, %v; want %#v", tt.key, tt.raw, got, err, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		key, raw, want string
+	}{
+		{"bogus.key", "1", `unknown config key "bogus.key"`},
+		{"tmux.noSwitch", "maybe", `invalid value "maybe" for tmux.noSwitch`},
+		{"tmux.noSwitch", "true false", `invalid value "true false" for tmux.noSwitch`},
+		{"tmux.", "x", `invalid config key "tmux."`},
+		{"worktree.gitConfig.core", "x", `invalid git config key "core"`},
+		{"jira.sites.work.nope", "x", `unknown config key "jira.sites.work.nope"`},
+	} {
+		_, err := configValue(tt.key, tt.raw)
+		if err == nil || !strings.Contains(err.Error(), tt.want) || exitCode(err) != exitUsage {
+			t.Errorf("configValue(%q, %q): expected usage error %q, got %v", tt.key, tt.raw, tt.want, err)
+		}
+	}
+}
@@ -6,51 +6,203 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
 var defaultCopyConfigItems = []string{"AGENTS.md", "CLAUDE.md"}
 var defaultCopyConfigRecursive = []string{".env"}
-var defaultCopyLibItems = []string{"node_modules"}
+
+// defaultCopyLibItems leaves out vendor, which many Go and PHP repos track:
+// copying it would overwrite the checkout with the main worktree's files.
+var defaultCopyLibItems = []string{"node_modules", ".venv"}
+
+// untrackedMaxFileSize caps the files wt new --copy-untracked copies; larger
+// ones are more likely build output than scaffolding.
+const untrackedMaxFileSize = 10 << 20
+
+// Values for copy.symlinks, which controls how symlinked config files are
+// copied into a new worktree.
+const (
+	symlinksFollow   = "follow"
+	symlinksRecreate = "recreate"
+	symlinksSkip     = "skip"
+)
 
 var (
 	osMkdirAll      = os.MkdirAll
 	osStat          = os.Stat
+	osLstat         = os.Lstat
+	osReadDir       = os.ReadDir
+	osReadlink      = os.Readlink
+	osSymlink       = os.Symlink
 	osOpen          = os.Open
 	osOpenFile      = os.OpenFile
+	osCreateTemp    = os.CreateTemp
+	osRename        = os.Rename
 	filepathWalkDir = filepath.WalkDir
+	filepathAbs     = filepath.Abs
 	ioCopy          = io.Copy
 )
 
-func copyItems(srcRoot, dstRoot string, items []string) error {
+// copyStats counts the files a copy wrote, recreated symlinks included, and
+// their total size in bytes.
+type copyStats struct {
+	files int
+	bytes int64
+}
+
+func (s *copyStats) add(o copyStats) {
+	s.files += o.files
+	s.bytes += o.bytes
+}
+
+// copiedItem is what copyItems wrote for one of its items.
+type copiedItem struct {
+	name string
+	copyStats
+}
+
+// copySummary collects what wt new copied into a new worktree, for the
+// line it prints afterwards. A nil *copySummary records nothing.
+type copySummary struct {
+	config    copyStats
+	libs      []copiedItem
+	untracked copyStats
+}
+
+func (c *copySummary) addConfig(stats copyStats) {
+	if c != nil {
+		c.config.add(stats)
+	}
+}
+
+func (c *copySummary) addConfigItems(items []copiedItem) {
+	for _, item := range items {
+		c.addConfig(item.copyStats)
+	}
+}
+
+func (c *copySummary) addLibs(items []copiedItem) {
+	if c != nil {
+		c.libs = append(c.libs, items...)
+	}
+}
+
+func (c *copySummary) addUntracked(stats copyStats) {
+	if c != nil {
+		c.untracked.add(stats)
+	}
+}
+
+// String summarizes the copies on one line, such as "copied 12 config files
+// (4.1 KB), node_modules (512.0 MB), 3 untracked files (2.0 KB)", or returns
+// "" when nothing was copied.
+func (c *copySummary) String() string {
+	if c == nil {
+		return ""
+	}
+	var parts []string
+	if c.config.files > 0 {
+		noun := "config files"
+		if c.config.files == 1 {
+			noun = "config file"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s (%s)", groupDigits(c.config.files), noun, formatSize(c.config.bytes)))
+	}
+	for _, lib := range c.libs {
+		parts = append(parts, fmt.Sprintf("%s (%s)", filepath.ToSlash(lib.name), formatSize(lib.bytes)))
+	}
+	if c.untracked.files > 0 {
+		noun := "untracked files"
+		if c.untracked.files == 1 {
+			noun = "untracked file"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s (%s)", groupDigits(c.untracked.files), noun, formatSize(c.untracked.bytes)))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "copied " + strings.Join(parts, ", ")
+}
+
+// copyItems copies each item, a file or a directory relative to srcRoot, to
+// the same place under dstRoot and reports what it wrote for each one that
+// exists. Missing items are skipped.
+func copyItems(srcRoot, dstRoot string, items []string, symlinks string) ([]copiedItem, error) {
+	var copied []copiedItem
 	for _, item := range items {
 		src := filepath.Join(srcRoot, item)
-		info, err := osStat(src)
+		// Lstat first, so a broken symlink is handled like any other link
+		// instead of looking missing.
+		linfo, err := osLstat(src)
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
 				continue
 			}
-			return err
+			return copied, err
+		}
+		if linfo.Mode()&fs.ModeSymlink != 0 {
+			handled, err := copySymlink(srcRoot, src, filepath.Join(dstRoot, item), symlinks)
+			if err != nil {
+				return copied, err
+			}
+			if handled {
+				if symlinks == symlinksRecreate {
+					copied = append(copied, copiedItem{name: item, copyStats: copyStats{files: 1}})
+				}
+				continue
+			}
+		}
+		info, err := osStat(src)
+		if err != nil {
+			return copied, err
 		}
 		if info.IsDir() {
-			if err := copyDir(src, filepath.Join(dstRoot, item)); err != nil {
-				return err
+			stats, err := copyDir(src, filepath.Join(dstRoot, item))
+			if err != nil {
+				return copied, err
 			}
+			copied = append(copied, copiedItem{name: item, copyStats: stats})
 			continue
 		}
-		if err := copyFile(src, filepath.Join(dstRoot, item), info.Mode()); err != nil {
-			return err
+		n, err := copyFile(src, filepath.Join(dstRoot, item), info.Mode())
+		if err != nil {
+			return copied, err
 		}
+		copied = append(copied, copiedItem{name: item, copyStats: copyStats{files: 1, bytes: n}})
 	}
-	return nil
+	return copied, nil
 }
 
-func copyMatchingFiles(srcRoot, dstRoot string, names []string) error {
+// copyPaths copies exact paths, relative to srcRoot, into dstRoot. Each
+// path may name a file or a directory; missing paths are skipped.
+func copyPaths(srcRoot, dstRoot string, paths []string, symlinks string) ([]copiedItem, error) {
+	cleaned := make([]string, 0, len(paths))
+	for _, p := range paths {
+		rel := filepath.Clean(filepath.FromSlash(p))
+		if p == "" || rel == "." || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("invalid copy path %q: must be relative to the repository", p)
+		}
+		cleaned = append(cleaned, rel)
+	}
+	return copyItems(srcRoot, dstRoot, cleaned, symlinks)
+}
+
+// copyMatchingFiles copies every file under srcRoot whose name is in names
+// to the same relative path under dstRoot. With link set, each one is
+// symlinked back to the source file instead, so the copies stay in sync.
+// Copies, unlike links, get envOverrides applied (see copyEnvFile). The
+// stats count the copies and recreated symlinks, not links.
+func copyMatchingFiles(srcRoot, dstRoot string, names []string, symlinks string, link bool, envOverrides map[string]string) (copyStats, error) {
 	nameSet := make(map[string]bool)
 	for _, name := range names {
 		nameSet[name] = true
 	}
-	return filepathWalkDir(srcRoot, func(path string, d fs.DirEntry, err error) error {
+	var stats copyStats
+	err := filepathWalkDir(srcRoot, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			fmt.Fprintf(stderr, "warning: cannot access %s: %v\n", path, err)
 			return nil
@@ -65,16 +217,346 @@ func copyMatchingFiles(srcRoot, dstRoot string, names []string) error {
 		if err != nil {
 			return err
 		}
-		info, err := d.Info()
+		dst := filepath.Join(dstRoot, rel)
+		if link {
+			return linkFile(path, dst)
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			handled, err := copySymlink(srcRoot, path, dst, symlinks)
+			if err == nil && handled && symlinks == symlinksRecreate {
+				stats.files++
+			}
+			if err != nil || handled {
+				return err
+			}
+		}
+		info, err := osStat(path)
 		if err != nil {
 			return err
 		}
-		return copyFile(path, filepath.Join(dstRoot, rel), info.Mode())
+		var n int64
+		if len(envOverrides) > 0 {
+			n, err = copyEnvFile(path, dst, info.Mode(), envOverrides)
+		} else {
+			n, err = copyFile(path, dst, info.Mode())
+		}
+		if err != nil {
+			return err
+		}
+		stats.add(copyStats{files: 1, bytes: n})
+		return nil
 	})
+	return stats, err
+}
+
+// libItems returns the library directories wt new --copy-libs copies:
+// copy.libs when it is set, else the common ones in defaultCopyLibItems.
+// Either way only those that exist in the source are copied.
+func libItems(cfg wtConfig) []string {
+	if cfg.Copy.Libs != nil {
+		return cfg.Copy.Libs
+	}
+	return defaultCopyLibItems
 }
 
-func copyDir(src, dst string) error {
-	return filepathWalkDir(src, func(path string, d fs.DirEntry, err error) error {
+// copyUntracked copies the untracked files of the worktree at srcRoot to the
+// same paths under dstRoot and reports what it copied. Files matching
+// skip or under the library directories libs, files over
+// untrackedMaxFileSize, and files the new worktree already has are left out.
+func copyUntracked(srcRoot, dstRoot string, libs, skip []string, symlinks string) (copyStats, error) {
+	files, err := gitUntrackedFiles(srcRoot)
+	if err != nil {
+		return copyStats{}, err
+	}
+	var copied copyStats
+	for _, rel := range files {
+		if inLibDir(rel, libs) || untrackedSkipped(rel, skip) {
+			continue
+		}
+		src := filepath.Join(srcRoot, filepath.FromSlash(rel))
+		dst := filepath.Join(dstRoot, filepath.FromSlash(rel))
+		if _, err := osLstat(dst); err == nil {
+			continue
+		}
+		linfo, err := osLstat(src)
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: cannot access %s: %v\n", src, err)
+			continue
+		}
+		if linfo.Mode()&fs.ModeSymlink != 0 {
+			handled, err := copySymlink(srcRoot, src, dst, symlinks)
+			if err != nil {
+				return copied, err
+			}
+			if handled {
+				if symlinks == symlinksRecreate {
+					copied.files++
+				}
+				continue
+			}
+		}
+		info, err := osStat(src)
+		if err != nil {
+			return copied, err
+		}
+		if info.Size() > untrackedMaxFileSize {
+			fmt.Fprintf(stderr, "warning: skipping %s: larger than %s\n", rel, formatSize(untrackedMaxFileSize))
+			continue
+		}
+		n, err := copyFile(src, dst, info.Mode())
+		if err != nil {
+			return copied, err
+		}
+		copied.add(copyStats{files: 1, bytes: n})
+	}
+	return copied, nil
+}
+
+// untrackedSkipped reports whether a glob in skip matches the slash-separated
+// path rel or one of its elements.
+func untrackedSkipped(rel string, skip []string) bool {
+	for _, pattern := range skip {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		for _, elem := range strings.Split(rel, "/") {
+			if ok, _ := path.Match(pattern, elem); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inLibDir reports whether the slash-separated path rel is inside one of
+// the library directories libs. A bare name like node_modules counts at any
+// depth; a path like web/node_modules only there.
+func inLibDir(rel string, libs []string) bool {
+	for _, lib := range libs {
+		lib = path.Clean(filepath.ToSlash(lib))
+		if strings.HasPrefix(rel+"/", lib+"/") {
+			return true
+		}
+		if strings.Contains(lib, "/") {
+			continue
+		}
+		for _, elem := range strings.Split(rel, "/") {
+			if elem == lib {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyEnvFile copies the env file src to dst, setting each variable in
+// overrides. A KEY=VALUE line (optionally prefixed with "export") for an
+// overridden key gets the new value; keys with no such line are appended
+// in sorted order. Every other line is copied unchanged. It returns the
+// size of the file written.
+func copyEnvFile(src, dst string, mode fs.FileMode, overrides map[string]string) (int64, error) {
+	data, err := osReadFile(src)
+	if err != nil {
+		return 0, err
+	}
+	lines := strings.Split(string(data), "\n")
+	set := make(map[string]bool)
+	for i, line := range lines {
+		body, cr := strings.CutSuffix(line, "\r")
+		trimmed := strings.TrimLeft(body, " \t")
+		indent := body[:len(body)-len(trimmed)]
+		export := ""
+		if rest, ok := strings.CutPrefix(trimmed, "export "); ok {
+			export, trimmed = "export ", strings.TrimLeft(rest, " \t")
+		}
+		key, _, ok := strings.Cut(trimmed, "=")
+		key = strings.TrimSpace(key)
+		value, override := overrides[key]
+		if !ok || !override {
+			continue
+		}
+		lines[i] = indent + export + key + "=" + value
+		if cr {
+			lines[i] += "\r"
+		}
+		set[key] = true
+	}
+
+	var missing []string
+	for key := range overrides {
+		if !set[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	out := strings.Join(lines, "\n")
+	if len(missing) > 0 && out != "" && !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	for _, key := range missing {
+		out += key + "=" + overrides[key] + "\n"
+	}
+
+	if err := osMkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return 0, err
+	}
+	if err := osWriteFile(dst, []byte(out), mode); err != nil {
+		return 0, err
+	}
+	return int64(len(out)), nil
+}
+
+// copySymlink copies the symlink src to dst according to the copy.symlinks
+// mode and reports whether it did so. In follow mode it leaves the copy to
+// the caller, which copies the link target's content; a link whose target
+// is missing is skipped with a warning instead of failing the copy.
+//
+// Recreated links keep relative targets that stay inside srcRoot, so they
+// point at the new worktree's copy; other relative targets are made
+// absolute so they still reach the same file from the new location.
+func copySymlink(srcRoot, src, dst, symlinks string) (bool, error) {
+	switch symlinks {
+	case symlinksSkip:
+		fmt.Fprintf(stderr, "warning: skipping symlink %s\n", src)
+		return true, nil
+	case symlinksRecreate:
+		target, err := osReadlink(src)
+		if err != nil {
+			return false, err
+		}
+		if !filepath.IsAbs(target) {
+			resolved := filepath.Join(filepath.Dir(src), target)
+			if rel, err := filepath.Rel(srcRoot, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				target = resolved
+			}
+		}
+		if err := osMkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return false, err
+		}
+		return true, osSymlink(target, dst)
+	}
+	if _, err := osStat(src); errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(stderr, "warning: skipping broken symlink %s\n", src)
+		return true, nil
+	}
+	return false, nil
+}
+
+// linkFile creates dst as a symlink to the absolute path of src. A dst that
+// already exists, such as a tracked file in the new checkout, is left alone.
+func linkFile(src, dst string) error {
+	if _, err := osLstat(dst); err == nil {
+		fmt.Fprintf(stderr, "warning: not linking %s: it already exists\n", dst)
+		return nil
+	}
+	target, err := filepathAbs(src)
+	if err != nil {
+		return err
+	}
+	if err := osMkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return osSymlink(target, dst)
+}
+
+// previewConfigCopies lists what copying config files from srcRoot would
+// copy: the default config files, every .env below srcRoot, and copy.paths.
+// Directories are summarized (see previewItems).
+func previewConfigCopies(srcRoot string, cfg wtConfig) []string {
+	items := append([]string{}, defaultCopyConfigItems...)
+	for _, p := range cfg.Copy.Paths {
+		if rel := filepath.FromSlash(p); filepath.IsLocal(rel) {
+			items = append(items, rel)
+		}
+	}
+	preview := previewItems(srcRoot, items)
+
+	nameSet := make(map[string]bool)
+	for _, name := range defaultCopyConfigRecursive {
+		nameSet[name] = true
+	}
+	_ = filepathWalkDir(srcRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !nameSet[d.Name()] {
+			return nil
+		}
+		if rel, err := filepath.Rel(srcRoot, path); err == nil {
+			preview = append(preview, rel)
+		}
+		return nil
+	})
+	return preview
+}
+
+// previewLibCopies lists the library directories copying libs from srcRoot
+// would copy, each summarized by file count and size.
+func previewLibCopies(srcRoot string, cfg wtConfig) []string {
+	return previewItems(srcRoot, libItems(cfg))
+}
+
+// previewItems returns the items that exist under srcRoot, with each
+// directory summarized as "node_modules (1,234 files, 512.0 MB)" rather than
+// listed file by file.
+func previewItems(srcRoot string, items []string) []string {
+	var preview []string
+	for _, item := range items {
+		info, err := osStat(filepath.Join(srcRoot, item))
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			preview = append(preview, item)
+			continue
+		}
+		files, size := dirUsage(filepath.Join(srcRoot, item))
+		preview = append(preview, fmt.Sprintf("%s (%s files, %s)", item, groupDigits(files), formatSize(size)))
+	}
+	return preview
+}
+
+// dirUsage counts the files under dir and their total size. Entries that
+// can't be read are left out.
+func dirUsage(dir string) (files int, size int64) {
+	_ = filepathWalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		files++
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, size
+}
+
+// groupDigits formats n with thousands separators, e.g. 1234 as "1,234".
+func groupDigits(n int) string {
+	s := fmt.Sprint(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// formatSize formats a byte count in binary units, e.g. "512.0 MB".
+func formatSize(n int64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n) / 1024
+	for _, unit := range []string{"KB", "MB", "GB"} {
+		if value < 1024 {
+			return fmt.Sprintf("%.1f %s", value, unit)
+		}
+		value /= 1024
+	}
+	return fmt.Sprintf("%.1f TB", value)
+}
+
+// copyDir copies the tree at src to dst and reports the files it copied.
+func copyDir(src, dst string) (copyStats, error) {
+	var stats copyStats
+	err := filepathWalkDir(src, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			fmt.Fprintf(stderr, "warning: cannot access %s: %v\n", path, err)
 			return nil
@@ -91,28 +573,37 @@ func copyDir(src, dst string) error {
 		if err != nil {
 			return err
 		}
-		return copyFile(path, target, info.Mode())
+		n, err := copyFile(path, target, info.Mode())
+		if err != nil {
+			return err
+		}
+		stats.add(copyStats{files: 1, bytes: n})
+		return nil
 	})
+	return stats, err
 }
 
-func copyFile(src, dst string, mode fs.FileMode) error {
+// copyFile copies src to dst with the given mode and returns the number of
+// bytes copied.
+func copyFile(src, dst string, mode fs.FileMode) (int64, error) {
 	if err := osMkdirAll(filepath.Dir(dst), 0o755); err != nil {
-		return err
+		return 0, err
 	}
 	in, err := osOpen(src)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer in.Close()
 
 	out, err := osOpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer out.Close()
 
-	if _, err := ioCopy(out, in); err != nil {
-		return err
+	n, err := ioCopy(out, in)
+	if err != nil {
+		return 0, err
 	}
-	return out.Sync()
+	return n, out.Sync()
 }
@@ -6,7 +6,9 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -25,9 +27,21 @@ func TestCopyItemsAndCopyDir(t *testing.T) {
 		t.Fatalf("write: %v", err)
 	}
 
-	if err := copyItems(src, dst, []string{"node_modules", ".env", "missing"}); err != nil {
+	if err := os.WriteFile(filepath.Join(src, "node_modules", "b.txt"), []byte("bb"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	copied, err := copyItems(src, dst, []string{"node_modules", ".env", "missing"}, symlinksFollow)
+	if err != nil {
 		t.Fatalf("copy items: %v", err)
 	}
+	want := []copiedItem{
+		{name: "node_modules", copyStats: copyStats{files: 2, bytes: 3}},
+		{name: ".env", copyStats: copyStats{files: 1, bytes: 3}},
+	}
+	if !slices.Equal(copied, want) {
+		t.Fatalf("expected %+v, got %+v", want, copied)
+	}
 	if _, err := os.Stat(filepath.Join(dst, "node_modules", "a.txt")); err != nil {
 		t.Fatalf("expected copied dir: %v", err)
 	}
@@ -37,14 +51,28 @@ func TestCopyItemsAndCopyDir(t *testing.T) {
 }
 
 func TestCopyItemsStatError(t *testing.T) {
+	oldLstat := osLstat
+	defer func() { osLstat = oldLstat }()
+	osLstat = func(name string) (fs.FileInfo, error) {
+		return nil, errors.New("lstat fail")
+	}
+
+	if _, err := copyItems("/src", "/dst", []string{"file"}, symlinksFollow); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "file"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	osLstat = oldLstat
 	oldStat := osStat
 	defer func() { osStat = oldStat }()
 	osStat = func(name string) (fs.FileInfo, error) {
 		return nil, errors.New("stat fail")
 	}
-
-	if err := copyItems("/src", "/dst", []string{"file"}); err == nil {
-		t.Fatalf("expected error")
+	if _, err := copyItems(src, t.TempDir(), []string{"file"}, symlinksFollow); err == nil || err.Error() != "stat fail" {
+		t.Fatalf("expected stat error, got %v", err)
 	}
 }
 
@@ -60,7 +88,7 @@ func TestCopyItemsCopyDirError(t *testing.T) {
 		return errors.New("walk fail")
 	}
 
-	if err := copyItems(src, t.TempDir(), []string{"node_modules"}); err == nil {
+	if _, err := copyItems(src, t.TempDir(), []string{"node_modules"}, symlinksFollow); err == nil {
 		t.Fatalf("expected copy dir error")
 	}
 }
@@ -80,7 +108,7 @@ func TestCopyItemsCopyFileError(t *testing.T) {
 		return nil, errors.New("open fail")
 	}
 
-	if err := copyItems(src, dst, []string{".env"}); err == nil {
+	if _, err := copyItems(src, dst, []string{".env"}, symlinksFollow); err == nil {
 		t.Fatalf("expected copy file error")
 	}
 }
@@ -101,7 +129,7 @@ func TestCopyDirErrors(t *testing.T) {
 	filepathWalkDir = func(root string, fn fs.WalkDirFunc) error {
 		return fn(root, nil, errors.New("walk fail"))
 	}
-	if err := copyDir("/src", "/dst"); err != nil {
+	if _, err := copyDir("/src", "/dst"); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(buf.String(), "warning:") {
@@ -111,8 +139,8 @@ func TestCopyDirErrors(t *testing.T) {
 	filepathWalkDir = func(root string, fn fs.WalkDirFunc) error {
 		return fn(filepath.Join(root, "file"), fakeDirEntry{name: "file", isDir: false, infoErr: errors.New("info fail")}, nil)
 	}
-	if err := copyDir("root", "/dst"); err == nil {
-		t.Fatalf("expected info error")
+	if _, err := copyDir("root", "/dst"); err == nil {
+		t.Fatalf("expected stat error")
 	}
 
 	filepathWalkDir = func(root string, fn fs.WalkDirFunc) error {
@@ -121,14 +149,14 @@ func TestCopyDirErrors(t *testing.T) {
 	osMkdirAll = func(path string, perm fs.FileMode) error {
 		return errors.New("mkdir fail")
 	}
-	if err := copyDir("/src", "/dst"); err == nil {
+	if _, err := copyDir("/src", "/dst"); err == nil {
 		t.Fatalf("expected mkdir error")
 	}
 
 	filepathWalkDir = func(root string, fn fs.WalkDirFunc) error {
 		return fn("file", fakeDirEntry{name: "file", isDir: false}, nil)
 	}
-	if err := copyDir("", "/dst"); err == nil {
+	if _, err := copyDir("", "/dst"); err == nil {
 		t.Fatalf("expected rel error")
 	}
 }
@@ -152,9 +180,13 @@ func TestCopyMatchingFilesSuccess(t *testing.T) {
 		t.Fatalf("write: %v", err)
 	}
 
-	if err := copyMatchingFiles(src, dst, []string{".env"}); err != nil {
+	stats, err := copyMatchingFiles(src, dst, []string{".env"}, symlinksFollow, false, nil)
+	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if want := (copyStats{files: 2, bytes: 7}); stats != want {
+		t.Fatalf("expected %+v, got %+v", want, stats)
+	}
 
 	// Check root .env
 	content, err := os.ReadFile(filepath.Join(dst, ".env"))
@@ -194,26 +226,26 @@ func TestCopyMatchingFilesErrors(t *testing.T) {
 	filepathWalkDir = func(root string, fn fs.WalkDirFunc) error {
 		return fn(root, nil, errors.New("walk fail"))
 	}
-	if err := copyMatchingFiles("/src", "/dst", []string{".env"}); err != nil {
+	if _, err := copyMatchingFiles("/src", "/dst", []string{".env"}, symlinksFollow, false, nil); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if !strings.Contains(buf.String(), "warning:") {
 		t.Fatalf("expected warning, got %q", buf.String())
 	}
 
-	// Info error
+	// Stat error (the walked file does not exist)
 	filepathWalkDir = func(root string, fn fs.WalkDirFunc) error {
 		return fn(filepath.Join(root, ".env"), fakeDirEntry{name: ".env", isDir: false, infoErr: errors.New("info fail")}, nil)
 	}
-	if err := copyMatchingFiles("/src", "/dst", []string{".env"}); err == nil {
-		t.Fatalf("expected info error")
+	if _, err := copyMatchingFiles("/src", "/dst", []string{".env"}, symlinksFollow, false, nil); err == nil {
+		t.Fatalf("expected stat error")
 	}
 
 	// Rel error (relative root with absolute path)
 	filepathWalkDir = func(root string, fn fs.WalkDirFunc) error {
 		return fn("/absolute/path/.env", fakeDirEntry{name: ".env", isDir: false}, nil)
 	}
-	if err := copyMatchingFiles("relative", "/dst", []string{".env"}); err == nil {
+	if _, err := copyMatchingFiles("relative", "/dst", []string{".env"}, symlinksFollow, false, nil); err == nil {
 		t.Fatalf("expected rel error")
 	}
 }
@@ -230,11 +262,81 @@ func TestCopyMatchingFilesCopyError(t *testing.T) {
 		return nil, errors.New("open fail")
 	}
 
-	if err := copyMatchingFiles(src, t.TempDir(), []string{".env"}); err == nil {
+	if _, err := copyMatchingFiles(src, t.TempDir(), []string{".env"}, symlinksFollow, false, nil); err == nil {
 		t.Fatalf("expected copy error")
 	}
 }
 
+func TestCopyMatchingFilesLink(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	mustWriteFile(t, filepath.Join(src, ".env"), "ROOT")
+	mustWriteFile(t, filepath.Join(src, "sub", ".env"), "SUB")
+	mustWriteFile(t, filepath.Join(src, "tracked", ".env"), "SRC")
+	mustWriteFile(t, filepath.Join(dst, "tracked", ".env"), "CHECKED OUT")
+
+	oldStderr := stderr
+	defer func() { stderr = oldStderr }()
+	var buf bytes.Buffer
+	stderr = &buf
+
+	if _, err := copyMatchingFiles(src, dst, []string{".env"}, symlinksFollow, true, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, rel := range []string{".env", filepath.Join("sub", ".env")} {
+		target, err := os.Readlink(filepath.Join(dst, rel))
+		if err != nil || target != filepath.Join(src, rel) {
+			t.Fatalf("expected %s linked to the source, got %q, %v", rel, target, err)
+		}
+	}
+
+	// Rotating the source is visible through the link.
+	mustWriteFile(t, filepath.Join(src, ".env"), "ROTATED")
+	if content, _ := os.ReadFile(filepath.Join(dst, ".env")); string(content) != "ROTATED" {
+		t.Fatalf("expected linked content to follow the source, got %q", content)
+	}
+
+	// An existing file in the worktree is kept.
+	if content, _ := os.ReadFile(filepath.Join(dst, "tracked", ".env")); string(content) != "CHECKED OUT" {
+		t.Fatalf("expected existing file kept, got %q", content)
+	}
+	if !strings.Contains(buf.String(), "warning: not linking") {
+		t.Fatalf("expected warning for existing file, got %q", buf.String())
+	}
+}
+
+func TestLinkFileErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		abs   error
+		mkdir error
+		link  error
+	}{
+		{name: "abs", abs: errors.New("no cwd")},
+		{name: "mkdir", mkdir: errors.New("read-only")},
+		{name: "symlink", link: errors.New("not supported")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldAbs := filepathAbs
+			oldMkdir := osMkdirAll
+			oldSymlink := osSymlink
+			defer func() {
+				filepathAbs = oldAbs
+				osMkdirAll = oldMkdir
+				osSymlink = oldSymlink
+			}()
+			filepathAbs = func(path string) (string, error) { return path, tt.abs }
+			osMkdirAll = func(path string, perm os.FileMode) error { return tt.mkdir }
+			osSymlink = func(oldname, newname string) error { return tt.link }
+
+			if err := linkFile("/src/.env", filepath.Join(t.TempDir(), ".env")); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
 func TestCopyFileErrors(t *testing.T) {
 	oldMkdir := osMkdirAll
 	oldOpen := osOpen
@@ -250,7 +352,7 @@ func TestCopyFileErrors(t *testing.T) {
 	osMkdirAll = func(path string, perm fs.FileMode) error {
 		return errors.New("mkdir fail")
 	}
-	if err := copyFile("src", "dst", 0o644); err == nil {
+	if _, err := copyFile("src", "dst", 0o644); err == nil {
 		t.Fatalf("expected mkdir error")
 	}
 
@@ -258,7 +360,7 @@ func TestCopyFileErrors(t *testing.T) {
 	osOpen = func(name string) (*os.File, error) {
 		return nil, errors.New("open fail")
 	}
-	if err := copyFile("src", "dst", 0o644); err == nil {
+	if _, err := copyFile("src", "dst", 0o644); err == nil {
 		t.Fatalf("expected open error")
 	}
 
@@ -274,7 +376,7 @@ func TestCopyFileErrors(t *testing.T) {
 	osOpenFile = func(name string, flag int, perm fs.FileMode) (*os.File, error) {
 		return nil, errors.New("openfile fail")
 	}
-	if err := copyFile(src, filepath.Join(tmp, "dst.txt"), 0o644); err == nil {
+	if _, err := copyFile(src, filepath.Join(tmp, "dst.txt"), 0o644); err == nil {
 		t.Fatalf("expected openfile error")
 	}
 
@@ -282,7 +384,7 @@ func TestCopyFileErrors(t *testing.T) {
 	ioCopy = func(dst io.Writer, src io.Reader) (int64, error) {
 		return 0, errors.New("copy fail")
 	}
-	if err := copyFile(src, filepath.Join(tmp, "dst2.txt"), 0o644); err == nil {
+	if _, err := copyFile(src, filepath.Join(tmp, "dst2.txt"), 0o644); err == nil {
 		t.Fatalf("expected copy error")
 	}
 }
@@ -295,7 +397,7 @@ func TestCopyFileSuccess(t *testing.T) {
 	if err := os.WriteFile(src, []byte("data"), 0o644); err != nil {
 		t.Fatalf("write: %v", err)
 	}
-	if err := copyFile(src, dst, 0o644); err != nil {
+	if _, err := copyFile(src, dst, 0o644); err != nil {
 		t.Fatalf("copy: %v", err)
 	}
 	data, err := os.ReadFile(dst)
@@ -306,3 +408,591 @@ func TestCopyFileSuccess(t *testing.T) {
 		t.Fatalf("unexpected data %q", string(data))
 	}
 }
+
+func TestCopyPaths(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	mustWriteFile(t, filepath.Join(src, "config", "local.yml"), "local")
+	mustWriteFile(t, filepath.Join(src, "config", "other.yml"), "other")
+	mustWriteFile(t, filepath.Join(src, "certs", "dev.pem"), "pem")
+	mustWriteFile(t, filepath.Join(src, "nested", "config", "local.yml"), "nested")
+
+	copied, err := copyPaths(src, dst, []string{"config/local.yml", "certs/", "missing/file"}, symlinksFollow)
+	if err != nil {
+		t.Fatalf("copy paths: %v", err)
+	}
+	want := []copiedItem{
+		{name: filepath.Join("config", "local.yml"), copyStats: copyStats{files: 1, bytes: 5}},
+		{name: "certs", copyStats: copyStats{files: 1, bytes: 3}},
+	}
+	if !slices.Equal(copied, want) {
+		t.Fatalf("expected %+v, got %+v", want, copied)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "config", "local.yml"))
+	if err != nil || string(data) != "local" {
+		t.Fatalf("expected config/local.yml copied, got %q err %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "certs", "dev.pem")); err != nil {
+		t.Fatalf("expected certs dir copied: %v", err)
+	}
+	// Only the exact path is copied, not other files with the same name.
+	if _, err := os.Stat(filepath.Join(dst, "nested", "config", "local.yml")); !os.IsNotExist(err) {
+		t.Fatalf("expected nested local.yml not copied")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "config", "other.yml")); !os.IsNotExist(err) {
+		t.Fatalf("expected sibling file not copied")
+	}
+}
+
+func TestCopyPathsInvalid(t *testing.T) {
+	for _, p := range []string{"", ".", "/etc/passwd", "..", "../outside", "config/../../outside"} {
+		_, err := copyPaths("/src", "/dst", []string{p}, symlinksFollow)
+		if err == nil || !strings.Contains(err.Error(), "invalid copy path") {
+			t.Errorf("copyPaths(%q): expected invalid path error, got %v", p, err)
+		}
+	}
+}
+
+func TestCopyPathsUnreadable(t *testing.T) {
+	oldLstat := osLstat
+	defer func() { osLstat = oldLstat }()
+	osLstat = func(name string) (fs.FileInfo, error) {
+		return nil, errors.New("permission denied")
+	}
+
+	_, err := copyPaths("/src", "/dst", []string{"config/local.yml"}, symlinksFollow)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected stat error, got %v", err)
+	}
+}
+
+func TestCopySymlinkedEnv(t *testing.T) {
+	base := t.TempDir()
+	src := filepath.Join(base, "repo")
+	shared := filepath.Join(base, "shared", "shared.env")
+	mustWriteFile(t, shared, "SHARED")
+	if err := os.Chmod(shared, 0o600); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	mustWriteFile(t, filepath.Join(src, ".env.local"), "LOCAL")
+	// An absolute link, a relative link within the repo, and a relative
+	// link that leaves it.
+	links := map[string]string{
+		".env":                               shared,
+		filepath.Join("sub", ".env"):         filepath.Join("..", ".env.local"),
+		filepath.Join("sub", "deep", ".env"): filepath.Join("..", "..", "..", "shared", "shared.env"),
+	}
+	for name, target := range links {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(src, name)), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.Symlink(target, filepath.Join(src, name)); err != nil {
+			t.Fatalf("symlink: %v", err)
+		}
+	}
+
+	t.Run("follow", func(t *testing.T) {
+		dst := t.TempDir()
+		if _, err := copyMatchingFiles(src, dst, []string{".env"}, symlinksFollow, false, nil); err != nil {
+			t.Fatalf("copy: %v", err)
+		}
+		info, err := os.Lstat(filepath.Join(dst, ".env"))
+		if err != nil || info.Mode()&fs.ModeSymlink != 0 || info.Mode().Perm() != 0o600 {
+			t.Fatalf("expected regular file with the target's mode, got %v, %v", info, err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dst, "sub", ".env")); string(data) != "LOCAL" {
+			t.Fatalf("expected target content, got %q", data)
+		}
+	})
+
+	t.Run("recreate", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "wt")
+		stats, err := copyMatchingFiles(src, dst, []string{".env"}, symlinksRecreate, false, nil)
+		if err != nil {
+			t.Fatalf("copy: %v", err)
+		}
+		if stats.files != 3 || stats.bytes != 0 {
+			t.Fatalf("expected 3 links counted, got %+v", stats)
+		}
+		copied, err := copyItems(src, t.TempDir(), []string{".env"}, symlinksRecreate)
+		if want := []copiedItem{{name: ".env", copyStats: copyStats{files: 1}}}; err != nil || !slices.Equal(copied, want) {
+			t.Fatalf("expected %+v, got %+v, %v", want, copied, err)
+		}
+		want := map[string]string{
+			".env":                               shared,
+			filepath.Join("sub", ".env"):         filepath.Join("..", ".env.local"),
+			filepath.Join("sub", "deep", ".env"): shared,
+		}
+		for name, target := range want {
+			got, err := os.Readlink(filepath.Join(dst, name))
+			if err != nil || got != target {
+				t.Fatalf("%s: expected link to %q, got %q, %v", name, target, got, err)
+			}
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		oldErr := stderr
+		defer func() { stderr = oldErr }()
+		var buf bytes.Buffer
+		stderr = &buf
+
+		dst := t.TempDir()
+		if _, err := copyItems(src, dst, []string{".env", ".env.local"}, symlinksSkip); err != nil {
+			t.Fatalf("copy: %v", err)
+		}
+		if _, err := os.Lstat(filepath.Join(dst, ".env")); !os.IsNotExist(err) {
+			t.Fatalf("expected symlink skipped, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dst, ".env.local")); err != nil {
+			t.Fatalf("expected regular file copied: %v", err)
+		}
+		if !strings.Contains(buf.String(), "warning: skipping symlink") {
+			t.Fatalf("expected warning, got %q", buf.String())
+		}
+	})
+}
+
+func TestCopySymlinkBroken(t *testing.T) {
+	src := t.TempDir()
+	if err := os.Symlink(filepath.Join(src, "missing"), filepath.Join(src, ".env")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+	var buf bytes.Buffer
+	stderr = &buf
+
+	dst := t.TempDir()
+	if _, err := copyMatchingFiles(src, dst, []string{".env"}, symlinksFollow, false, nil); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dst, ".env")); !os.IsNotExist(err) {
+		t.Fatalf("expected broken symlink skipped, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "broken symlink") {
+		t.Fatalf("expected warning, got %q", buf.String())
+	}
+}
+
+func TestCopyItemsBrokenSymlink(t *testing.T) {
+	src := t.TempDir()
+	if err := os.Symlink("missing", filepath.Join(src, ".env")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+	var buf bytes.Buffer
+	stderr = &buf
+
+	dst := t.TempDir()
+	copied, err := copyItems(src, dst, []string{".env"}, symlinksRecreate)
+	if err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(dst, ".env")); err != nil || target != "missing" {
+		t.Fatalf("expected broken symlink recreated, got %q, %v", target, err)
+	}
+	if len(copied) != 1 || copied[0].name != ".env" {
+		t.Fatalf("expected .env reported, got %+v", copied)
+	}
+
+	dst = t.TempDir()
+	if _, err := copyItems(src, dst, []string{".env"}, symlinksFollow); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dst, ".env")); !os.IsNotExist(err) {
+		t.Fatalf("expected broken symlink skipped, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "broken symlink") {
+		t.Fatalf("expected warning, got %q", buf.String())
+	}
+}
+
+func TestCopySymlinkErrors(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "target"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Symlink("target", filepath.Join(src, ".env")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	oldReadlink := osReadlink
+	oldMkdir := osMkdirAll
+	defer func() {
+		osReadlink = oldReadlink
+		osMkdirAll = oldMkdir
+	}()
+
+	osReadlink = func(string) (string, error) { return "", errors.New("readlink fail") }
+	if _, err := copyItems(src, t.TempDir(), []string{".env"}, symlinksRecreate); err == nil {
+		t.Fatal("expected readlink error")
+	}
+	if _, err := copyMatchingFiles(src, t.TempDir(), []string{".env"}, symlinksRecreate, false, nil); err == nil {
+		t.Fatal("expected readlink error")
+	}
+
+	osReadlink = oldReadlink
+	osMkdirAll = func(string, os.FileMode) error { return errors.New("mkdir fail") }
+	if _, err := copyItems(src, t.TempDir(), []string{".env"}, symlinksRecreate); err == nil {
+		t.Fatal("expected mkdir error")
+	}
+}
+
+func TestCopyUntracked(t *testing.T) {
+	src := setupTestRepo(t)
+	dst := t.TempDir()
+	mustWriteFile(t, filepath.Join(src, "notes", "todo.md"), "todo")
+	mustWriteFile(t, filepath.Join(src, "app.log"), "log")
+	mustWriteFile(t, filepath.Join(src, "node_modules", "x", "index.js"), "js")
+	mustWriteFile(t, filepath.Join(src, "scratch", "keep.txt"), "scratch")
+	mustWriteFile(t, filepath.Join(src, "existing.txt"), "main")
+	mustWriteFile(t, filepath.Join(dst, "existing.txt"), "new")
+	if err := os.Symlink("notes/todo.md", filepath.Join(src, "link.md")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	big := filepath.Join(src, "big.bin")
+	mustWriteFile(t, big, "")
+	if err := os.Truncate(big, untrackedMaxFileSize+1); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	var errBuf bytes.Buffer
+	oldErr := stderr
+	stderr = &errBuf
+	defer func() { stderr = oldErr }()
+
+	stats, err := copyUntracked(src, dst, defaultCopyLibItems, []string{"*.log", "scratch"}, symlinksRecreate)
+	if want := (copyStats{files: 2, bytes: 4}); err != nil || stats != want {
+		t.Fatalf("copyUntracked = %+v, %v; want %+v", stats, err, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "notes", "todo.md")); err != nil || string(data) != "todo" {
+		t.Fatalf("expected todo.md copied, got %q, %v", data, err)
+	}
+	if target, err := os.Readlink(filepath.Join(dst, "link.md")); err != nil || target != "notes/todo.md" {
+		t.Fatalf("expected link.md recreated, got %q, %v", target, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "existing.txt")); string(data) != "new" {
+		t.Fatalf("expected existing file kept, got %q", data)
+	}
+	for _, skipped := range []string{"app.log", "node_modules", "scratch", "big.bin"} {
+		if _, err := os.Stat(filepath.Join(dst, skipped)); !os.IsNotExist(err) {
+			t.Errorf("expected %s skipped, got %v", skipped, err)
+		}
+	}
+	if !strings.Contains(errBuf.String(), "warning: skipping big.bin: larger than 10.0 MB") {
+		t.Fatalf("expected size warning, got %q", errBuf.String())
+	}
+
+	dst = t.TempDir()
+	if n, err := copyUntracked(src, dst, defaultCopyLibItems, []string{"*.log", "scratch", "big.bin", "notes"}, symlinksSkip); err != nil || n.files != 1 {
+		t.Fatalf("expected skipped symlink not counted, got %+v, %v", n, err)
+	}
+}
+
+func TestCopyUntrackedErrors(t *testing.T) {
+	src := setupTestRepo(t)
+	mustWriteFile(t, filepath.Join(src, "new.txt"), "x")
+	var errBuf bytes.Buffer
+	oldErr := stderr
+	oldLstat := osLstat
+	oldStat := osStat
+	oldOpen := osOpen
+	oldExec := execCommand
+	stderr = &errBuf
+	defer func() {
+		stderr = oldErr
+		osLstat = oldLstat
+		osStat = oldStat
+		osOpen = oldOpen
+		execCommand = oldExec
+	}()
+
+	osLstat = func(name string) (os.FileInfo, error) {
+		if strings.HasPrefix(name, src) {
+			return nil, errors.New("gone")
+		}
+		return oldLstat(name)
+	}
+	if n, err := copyUntracked(src, t.TempDir(), defaultCopyLibItems, nil, symlinksFollow); err != nil || n.files != 0 {
+		t.Fatalf("expected unreadable file skipped, got %+v, %v", n, err)
+	}
+	if !strings.Contains(errBuf.String(), "warning: cannot access "+filepath.Join(src, "new.txt")+": gone") {
+		t.Fatalf("expected access warning, got %q", errBuf.String())
+	}
+	osLstat = oldLstat
+
+	osStat = func(string) (os.FileInfo, error) { return nil, errors.New("stat fail") }
+	if _, err := copyUntracked(src, t.TempDir(), defaultCopyLibItems, nil, symlinksFollow); err == nil || err.Error() != "stat fail" {
+		t.Fatalf("expected stat error, got %v", err)
+	}
+	osStat = oldStat
+
+	osOpen = func(string) (*os.File, error) { return nil, errors.New("open fail") }
+	if _, err := copyUntracked(src, t.TempDir(), defaultCopyLibItems, nil, symlinksFollow); err == nil || err.Error() != "open fail" {
+		t.Fatalf("expected copy error, got %v", err)
+	}
+	osOpen = oldOpen
+
+	if err := os.Symlink("new.txt", filepath.Join(src, "link.txt")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	oldReadlink := osReadlink
+	osReadlink = func(string) (string, error) { return "", errors.New("readlink fail") }
+	_, err := copyUntracked(src, t.TempDir(), defaultCopyLibItems, nil, symlinksRecreate)
+	osReadlink = oldReadlink
+	if err == nil || err.Error() != "readlink fail" {
+		t.Fatalf("expected readlink error, got %v", err)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd { return exec.Command("sh", "-c", "exit 1") }
+	if _, err := copyUntracked(src, t.TempDir(), defaultCopyLibItems, nil, symlinksFollow); err == nil {
+		t.Fatal("expected git error")
+	}
+}
+
+func TestLibItems(t *testing.T) {
+	if got := libItems(wtConfig{}); strings.Join(got, ",") != "node_modules,.venv" {
+		t.Fatalf("expected the common libs by default, got %v", got)
+	}
+	cfg := wtConfig{Copy: copySettings{Libs: []string{"target"}}}
+	if got := libItems(cfg); len(got) != 1 || got[0] != "target" {
+		t.Fatalf("expected copy.libs, got %v", got)
+	}
+	cfg.Copy.Libs = []string{}
+	if got := libItems(cfg); len(got) != 0 {
+		t.Fatalf("expected an empty copy.libs to copy nothing, got %v", got)
+	}
+}
+
+func TestInLibDir(t *testing.T) {
+	libs := []string{"node_modules", "web/deps/"}
+	for rel, want := range map[string]bool{
+		"node_modules/a.js":     true,
+		"api/node_modules/a.js": true,
+		"node_modules":          true,
+		"web/deps/b.js":         true,
+		"web/deps":              true,
+		"api/web/deps/b.js":     false,
+		"web/depsfile":          false,
+		"node_modules_old/a.js": false,
+	} {
+		if got := inLibDir(rel, libs); got != want {
+			t.Errorf("inLibDir(%q) = %v, want %v", rel, got, want)
+		}
+	}
+}
+
+func TestUntrackedSkipped(t *testing.T) {
+	skip := []string{"*.log", "tmp", "build/*"}
+	for rel, want := range map[string]bool{
+		"app.log":         true,
+		"logs/app.log":    true,
+		"tmp/cache/x":     true,
+		"build/out":       true,
+		"build/sub/out":   false,
+		"src/tmpfile.txt": false,
+		"notes.md":        false,
+	} {
+		if got := untrackedSkipped(rel, skip); got != want {
+			t.Errorf("untrackedSkipped(%q) = %v, want %v", rel, got, want)
+		}
+	}
+}
+
+func TestPreviewCopies(t *testing.T) {
+	src := t.TempDir()
+	mustWriteFile(t, filepath.Join(src, "AGENTS.md"), "agents")
+	mustWriteFile(t, filepath.Join(src, ".env"), "A=1")
+	mustWriteFile(t, filepath.Join(src, "api", ".env"), "B=2")
+	mustWriteFile(t, filepath.Join(src, "certs", "ca.pem"), "0123456789")
+	mustWriteFile(t, filepath.Join(src, "node_modules", "a", "index.js"), strings.Repeat("x", 2048))
+	mustWriteFile(t, filepath.Join(src, "node_modules", "b.js"), "y")
+	cfg := wtConfig{Copy: copySettings{Paths: []string{"certs", "missing.yml", "../outside"}}}
+
+	got := previewConfigCopies(src, cfg)
+	want := []string{"AGENTS.md", "certs (1 files, 10 B)", ".env", filepath.Join("api", ".env")}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	if got := previewLibCopies(src, wtConfig{}); len(got) != 1 || got[0] != "node_modules (2 files, 2.0 KB)" {
+		t.Fatalf("unexpected lib preview %q", got)
+	}
+	mustWriteFile(t, filepath.Join(src, ".venv", "pyvenv.cfg"), "home")
+	if got := previewLibCopies(src, wtConfig{}); len(got) != 2 || got[1] != ".venv (1 files, 4 B)" {
+		t.Fatalf("expected .venv to be detected, got %q", got)
+	}
+	if got := previewLibCopies(src, wtConfig{Copy: copySettings{Libs: []string{".venv"}}}); len(got) != 1 || got[0] != ".venv (1 files, 4 B)" {
+		t.Fatalf("expected only copy.libs, got %q", got)
+	}
+	if got := previewLibCopies(t.TempDir(), wtConfig{}); len(got) != 0 {
+		t.Fatalf("expected no libs, got %q", got)
+	}
+}
+
+func TestPreviewCopiesUnreadable(t *testing.T) {
+	oldWalk := filepathWalkDir
+	defer func() { filepathWalkDir = oldWalk }()
+	filepathWalkDir = func(root string, fn fs.WalkDirFunc) error {
+		_ = fn(root, nil, errors.New("walk fail"))
+		_ = fn("relative/.env", fakeDirEntry{name: ".env"}, nil)
+		return fn(filepath.Join(root, "x"), fakeDirEntry{name: "x", infoErr: errors.New("info fail")}, nil)
+	}
+
+	if got := previewConfigCopies("/src", wtConfig{}); len(got) != 0 {
+		t.Fatalf("expected unreadable entries skipped, got %q", got)
+	}
+	if files, size := dirUsage("/src"); files != 2 || size != 0 {
+		t.Fatalf("expected 2 files of unknown size, got %d, %d", files, size)
+	}
+}
+
+func TestGroupDigitsAndFormatSize(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1234: "1,234", 1234567: "1,234,567"} {
+		if got := groupDigits(n); got != want {
+			t.Fatalf("groupDigits(%d) = %q, want %q", n, got, want)
+		}
+	}
+	for n, want := range map[int64]string{
+		512:       "512 B",
+		1536:      "1.5 KB",
+		512 << 20: "512.0 MB",
+		3 << 30:   "3.0 GB",
+		5 << 40:   "5.0 TB",
+	} {
+		if got := formatSize(n); got != want {
+			t.Fatalf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestCopySummary(t *testing.T) {
+	var none *copySummary
+	none.addConfig(copyStats{files: 1})
+	none.addLibs([]copiedItem{{name: "node_modules"}})
+	none.addUntracked(copyStats{files: 1})
+	if got := none.String(); got != "" {
+		t.Fatalf("expected nothing from a nil summary, got %q", got)
+	}
+
+	c := &copySummary{}
+	if got := c.String(); got != "" {
+		t.Fatalf("expected nothing when nothing was copied, got %q", got)
+	}
+	c.addConfigItems([]copiedItem{{name: "AGENTS.md", copyStats: copyStats{files: 1, bytes: 100}}})
+	if got, want := c.String(), "copied 1 config file (100 B)"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	c.addConfig(copyStats{files: 11, bytes: 4096})
+	c.addLibs([]copiedItem{
+		{name: "node_modules", copyStats: copyStats{files: 1234, bytes: 512 << 20}},
+		{name: filepath.Join("web", ".venv"), copyStats: copyStats{files: 3, bytes: 2048}},
+	})
+	if got, want := c.String(), "copied 12 config files (4.1 KB), node_modules (512.0 MB), web/.venv (2.0 KB)"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	c.addUntracked(copyStats{files: 1, bytes: 10})
+	if got, want := c.String(), "copied 12 config files (4.1 KB), node_modules (512.0 MB), web/.venv (2.0 KB), 1 untracked file (10 B)"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	c.addUntracked(copyStats{files: 2, bytes: 20})
+	if got, want := (&copySummary{untracked: c.untracked}).String(), "copied 3 untracked files (30 B)"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCopyEnvFile(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "override and addition",
+			in:   "# local settings\nPORT=3000\nexport HOST = localhost\n\nDEBUG=1\n",
+			want: "# local settings\nPORT=0\nexport HOST=example.test\n\nDEBUG=1\nAPI_URL=http://localhost:0\n",
+		},
+		{
+			name: "commented key untouched",
+			in:   "# PORT=1\n  PORT=2",
+			want: "# PORT=1\n  PORT=0\nAPI_URL=http://localhost:0\nHOST=example.test\n",
+		},
+		{
+			name: "crlf kept",
+			in:   "PORT=3000\r\nHOST=a\r\nAPI_URL=b\r\n",
+			want: "PORT=0\r\nHOST=example.test\r\nAPI_URL=http://localhost:0\r\n",
+		},
+		{
+			name: "empty file",
+			in:   "",
+			want: "API_URL=http://localhost:0\nHOST=example.test\nPORT=0\n",
+		},
+	}
+	overrides := map[string]string{"PORT": "0", "HOST": "example.test", "API_URL": "http://localhost:0"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), ".env")
+			dst := filepath.Join(t.TempDir(), "sub", ".env")
+			mustWriteFile(t, src, tt.in)
+			if _, err := copyEnvFile(src, dst, 0o600, overrides); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := os.ReadFile(dst)
+			if err != nil || string(got) != tt.want {
+				t.Fatalf("expected %q, got %q, %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestCopyEnvFileErrors(t *testing.T) {
+	src := filepath.Join(t.TempDir(), ".env")
+	mustWriteFile(t, src, "PORT=1\n")
+	overrides := map[string]string{"PORT": "0"}
+
+	if _, err := copyEnvFile(filepath.Join(t.TempDir(), "missing"), t.TempDir(), 0o644, overrides); err == nil {
+		t.Fatal("expected read error")
+	}
+
+	oldMkdir := osMkdirAll
+	defer func() { osMkdirAll = oldMkdir }()
+	osMkdirAll = func(path string, perm fs.FileMode) error { return errors.New("read-only") }
+	if _, err := copyEnvFile(src, filepath.Join(t.TempDir(), ".env"), 0o644, overrides); err == nil || err.Error() != "read-only" {
+		t.Fatalf("expected mkdir error, got %v", err)
+	}
+
+	osMkdirAll = oldMkdir
+	oldWrite := osWriteFile
+	defer func() { osWriteFile = oldWrite }()
+	osWriteFile = func(string, []byte, os.FileMode) error { return errors.New("disk full") }
+	if _, err := copyEnvFile(src, filepath.Join(t.TempDir(), ".env"), 0o644, overrides); err == nil || err.Error() != "disk full" {
+		t.Fatalf("expected write error, got %v", err)
+	}
+}
+
+func TestCopyMatchingFilesEnvOverrides(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	mustWriteFile(t, filepath.Join(src, ".env"), "PORT=3000\nNAME=app\n")
+	mustWriteFile(t, filepath.Join(src, "sub", ".env"), "NAME=sub\n")
+	mustWriteFile(t, filepath.Join(src, "config.env"), "PORT=3000\n")
+
+	overrides := map[string]string{"PORT": "0"}
+	if _, err := copyMatchingFiles(src, dst, []string{".env", "other"}, symlinksFollow, false, overrides); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for rel, want := range map[string]string{
+		".env":                       "PORT=0\nNAME=app\n",
+		filepath.Join("sub", ".env"): "NAME=sub\nPORT=0\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(dst, rel))
+		if err != nil || string(got) != want {
+			t.Fatalf("expected %s to be %q, got %q, %v", rel, want, got, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst, "config.env")); !os.IsNotExist(err) {
+		t.Fatalf("expected config.env not copied, got %v", err)
+	}
+}
@@ -3,15 +3,102 @@ package main
 import (
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 var execCommand = exec.Command
 
+// gitVersion is a parsed "git --version" release number.
+type gitVersion struct {
+	major, minor, patch int
+}
+
+func (v gitVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+}
+
+func (v gitVersion) less(o gitVersion) bool {
+	if v.major != o.major {
+		return v.major < o.major
+	}
+	if v.minor != o.minor {
+		return v.minor < o.minor
+	}
+	return v.patch < o.patch
+}
+
+var (
+	// minGitVersion is the oldest git wt is known to work with; it is the
+	// first release with "git worktree remove".
+	minGitVersion = gitVersion{2, 17, 0}
+	// worktreeConfigGitVersion is the first release with
+	// "git config --worktree".
+	worktreeConfigGitVersion = gitVersion{2, 20, 0}
+	// worktreeRepairGitVersion is the first release with
+	// "git worktree repair".
+	worktreeRepairGitVersion = gitVersion{2, 29, 0}
+
+	gitVersionOnce   sync.Once
+	gitVersionCached gitVersion
+	gitVersionErr    error
+)
+
+// parseGitVersion parses output such as "git version 2.39.3 (Apple Git-146)"
+// or "git version 2.45.1.windows.1".
+func parseGitVersion(out string) (gitVersion, error) {
+	fields := strings.Fields(out)
+	if len(fields) < 3 || fields[0] != "git" || fields[1] != "version" {
+		return gitVersion{}, fmt.Errorf("unrecognized git version output %q", strings.TrimSpace(out))
+	}
+	parts := strings.Split(fields[2], ".")
+	if len(parts) < 2 {
+		return gitVersion{}, fmt.Errorf("unrecognized git version %q", fields[2])
+	}
+	var nums [3]int
+	for i := 0; i < len(nums) && i < len(parts); i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			if i < 2 {
+				return gitVersion{}, fmt.Errorf("unrecognized git version %q", fields[2])
+			}
+			break
+		}
+		nums[i] = n
+	}
+	return gitVersion{nums[0], nums[1], nums[2]}, nil
+}
+
+// currentGitVersion runs "git --version" once per process and caches the
+// result.
+func currentGitVersion() (gitVersion, error) {
+	gitVersionOnce.Do(func() {
+		out, err := runGitOutput("", "--version")
+		if err != nil {
+			gitVersionErr = err
+			return
+		}
+		gitVersionCached, gitVersionErr = parseGitVersion(out)
+	})
+	return gitVersionCached, gitVersionErr
+}
+
+// requireGitVersion returns an error when the installed git is known to be
+// older than want. feature describes what needs it. Only commands that
+// depend on newer git call it, so others never spend a "git --version".
+func requireGitVersion(want gitVersion, feature string) error {
+	v, err := currentGitVersion()
+	if err != nil || !v.less(want) {
+		return nil
+	}
+	return fmt.Errorf("%s requires git %s or newer (found %s)", feature, want, v)
+}
+
 func runGit(repoRoot string, args ...string) error {
 	_, err := runGitOutput(repoRoot, args...)
 	return err
@@ -30,12 +117,88 @@ func runGitOutput(repoRoot string, args ...string) (string, error) {
 	return string(out), nil
 }
 
+// runGitProgress is runGit for slow commands whose progress is worth
+// seeing. Unless quiet, git writes to stderr as it runs, rather than into
+// the error message; stdout is left to wt's own output.
+func runGitProgress(repoRoot string, quiet bool, args ...string) error {
+	if quiet {
+		return runGit(repoRoot, args...)
+	}
+	cmdArgs := args
+	if repoRoot != "" {
+		cmdArgs = append([]string{"-C", repoRoot}, args...)
+	}
+	cmd := execCommand("git", cmdArgs...)
+	cmd.Stdout = stderr
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// errNotInRepo replaces git's "not a git repository" failure, which every
+// command hits first when run outside a repo.
+var errNotInRepo = errors.New("wt: not inside a git repository (run wt from a repo or one of its worktrees)")
+
 func gitRepoRoot() (string, error) {
 	out, err := runGitOutput("", "rev-parse", "--show-toplevel")
+	if err == nil && strings.TrimSpace(out) != "" {
+		return filepath.FromSlash(strings.TrimSpace(out)), nil
+	}
+	root, bareErr := gitBareRoot()
+	if bareErr != nil {
+		if err != nil {
+			if strings.Contains(err.Error(), "not a git repository") {
+				return "", errNotInRepo
+			}
+			return "", err
+		}
+		return "", bareErr
+	}
+	return root, nil
+}
+
+// gitBareRoot resolves the repository directory when running inside a bare
+// repository (or with GIT_DIR set), where --show-toplevel has no answer.
+func gitBareRoot() (string, error) {
+	out, err := runGitOutput("", "rev-parse", "--is-bare-repository")
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(out), nil
+	if strings.TrimSpace(out) != "true" {
+		return "", errors.New("not inside a work tree or bare repository")
+	}
+	out, err = runGitOutput("", "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(out)
+	if dir == "" {
+		return "", errors.New("could not determine git directory")
+	}
+	if !filepath.IsAbs(dir) {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", err
+		}
+		dir = abs
+	}
+	return filepath.Clean(dir), nil
+}
+
+// gitCommonDir returns the git directory shared by all of the repository's
+// worktrees.
+func gitCommonDir(repoRoot string) (string, error) {
+	out, err := runGitOutput(repoRoot, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.FromSlash(strings.TrimSpace(out))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoRoot, dir)
+	}
+	return filepath.Clean(dir), nil
 }
 
 func gitMainWorktree(repoRoot string) (string, error) {
@@ -50,7 +213,17 @@ func gitMainWorktree(repoRoot string) (string, error) {
 }
 
 func worktreePath(repoRoot, branch string) string {
-	return filepath.Join(repoRoot+"-worktrees", filepath.FromSlash(branch))
+	return worktreePathUnder("", repoRoot, branch)
+}
+
+// worktreePathUnder is worktreePath with the "<repo>-worktrees" directory
+// placed in root instead of next to the repo. An empty root keeps the
+// default layout.
+func worktreePathUnder(root, repoRoot, branch string) string {
+	if root == "" {
+		root = filepath.Dir(repoRoot)
+	}
+	return filepath.Join(root, filepath.Base(repoRoot)+"-worktrees", filepath.FromSlash(branch))
 }
 
 func gitBranches(repoRoot string) ([]string, error) {
@@ -71,8 +244,58 @@ func gitBranches(repoRoot string) ([]string, error) {
 	return branches, nil
 }
 
+// gitRemoteBranches returns the remote-tracking branches, such as
+// origin/main, leaving out symbolic refs like origin/HEAD.
+func gitRemoteBranches(repoRoot string) ([]string, error) {
+	out, err := runGitOutput(repoRoot, "branch", "-r", "--format=%(refname:short) %(symref)")
+	if err != nil {
+		return nil, err
+	}
+	return parseRemoteBranches(out), nil
+}
+
+// gitRemoteBranchesByRecent is gitRemoteBranches ordered by latest commit,
+// newest first. git sorts them in one call, where orderByRecentCommit would
+// run git log once per ref.
+func gitRemoteBranchesByRecent(repoRoot string) ([]string, error) {
+	out, err := runGitOutput(repoRoot, "for-each-ref", "--sort=-committerdate", "--format=%(refname:short) %(symref)", "refs/remotes")
+	if err != nil {
+		return nil, err
+	}
+	return parseRemoteBranches(out), nil
+}
+
+// parseRemoteBranches reads "<ref> <symref>" lines, skipping symbolic refs.
+func parseRemoteBranches(out string) []string {
+	var branches []string
+	for _, line := range strings.Split(out, "\n") {
+		ref, symref, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if ref == "" || symref != "" {
+			continue
+		}
+		branches = append(branches, ref)
+	}
+	return branches
+}
+
+// remoteBranchName strips the remote from a remote-tracking branch name:
+// origin/feature/x is feature/x.
+func remoteBranchName(ref string) string {
+	_, name, ok := strings.Cut(ref, "/")
+	if !ok {
+		return ref
+	}
+	return name
+}
+
 func gitBranchExists(repoRoot, branch string) (bool, error) {
-	_, err := runGitOutput(repoRoot, "show-ref", "--verify", "refs/heads/"+branch)
+	return gitRefExists(repoRoot, "refs/heads/"+branch)
+}
+
+// gitRefExists reports whether the full ref name, such as
+// refs/remotes/origin/main, exists.
+func gitRefExists(repoRoot, ref string) (bool, error) {
+	_, err := runGitOutput(repoRoot, "show-ref", "--verify", ref)
 	if err == nil {
 		return true, nil
 	}
@@ -83,6 +306,73 @@ func gitBranchExists(repoRoot, branch string) (bool, error) {
 	return false, err
 }
 
+// gitCurrentBase returns the branch checked out in the worktree at path, or
+// its short commit SHA when HEAD is detached.
+func gitCurrentBase(path string) (string, error) {
+	out, err := runGitOutput(path, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if branch := strings.TrimSpace(out); branch != "HEAD" {
+		return branch, nil
+	}
+	out, err = runGitOutput(path, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// gitDefaultRemote returns the remote new branches are looked up on:
+// origin if it exists, else the only remote, else "".
+func gitDefaultRemote(repoRoot string) (string, error) {
+	out, err := runGitOutput(repoRoot, "remote")
+	if err != nil {
+		return "", err
+	}
+	remotes := strings.Fields(out)
+	for _, remote := range remotes {
+		if remote == "origin" {
+			return remote, nil
+		}
+	}
+	if len(remotes) == 1 {
+		return remotes[0], nil
+	}
+	return "", nil
+}
+
+// errRemoteBranchNotFound is returned by gitFetchBranch when the remote
+// has no branch of that name.
+var errRemoteBranchNotFound = errors.New("remote branch not found")
+
+// gitFetchBranch fetches branch from remote, updating its remote-tracking
+// ref. git runs in the C locale so a missing branch can be told apart from
+// other failures by its message.
+func gitFetchBranch(repoRoot, remote, branch string) error {
+	args := []string{"fetch", "--quiet", remote, branch}
+	cmd := execCommand("git", append([]string{"-C", repoRoot}, args...)...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if strings.Contains(string(out), "couldn't find remote ref") {
+		return errRemoteBranchNotFound
+	}
+	return fmt.Errorf("git %s failed: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+}
+
+// gitResolveCommit resolves any commit-ish (branch, tag, SHA, remote ref,
+// relative ref) to a full commit SHA.
+func gitResolveCommit(repoRoot, ref string) (string, error) {
+	out, err := runGitOutput(repoRoot, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
 func gitWorktrees(repoRoot string) ([]worktree, error) {
 	out, err := runGitOutput(repoRoot, "worktree", "list", "--porcelain")
 	if err != nil {
@@ -93,7 +383,8 @@ func gitWorktrees(repoRoot string) ([]worktree, error) {
 	var wts []worktree
 	var current worktree
 	for _, line := range lines {
-		line = strings.TrimSpace(line)
+		// Only the line ending is trimmed: a path may end in a space.
+		line = strings.TrimSuffix(line, "\r")
 		if line == "" {
 			if current.Path != "" {
 				wts = append(wts, current)
@@ -101,23 +392,284 @@ func gitWorktrees(repoRoot string) ([]worktree, error) {
 			}
 			continue
 		}
+		if line == "prunable" || strings.HasPrefix(line, "prunable ") {
+			current.Missing = true
+			continue
+		}
 		parts := strings.SplitN(line, " ", 2)
 		if len(parts) != 2 {
 			continue
 		}
 		switch parts[0] {
 		case "worktree":
-			current.Path = parts[1]
+			// Git for Windows reports paths with forward slashes.
+			current.Path = filepath.FromSlash(parts[1])
 		case "branch":
 			current.Branch = strings.TrimPrefix(parts[1], "refs/heads/")
+		case "HEAD":
+			// An unborn branch is reported with an all-zero SHA.
+			if strings.Trim(parts[1], "0") != "" {
+				current.Head = parts[1]
+			}
 		}
 	}
 	if current.Path != "" {
 		wts = append(wts, current)
 	}
+	// git before 2.31 doesn't report prunable worktrees, so a moved or
+	// deleted directory is also caught by checking the path itself.
+	for i := range wts {
+		if !wts[i].Missing {
+			if _, err := osStat(wts[i].Path); errors.Is(err, os.ErrNotExist) {
+				wts[i].Missing = true
+			}
+		}
+	}
 	return wts, nil
 }
 
+// gitStashPush stashes the uncommitted changes in dir, untracked files
+// included, and returns the stash commit.
+func gitStashPush(dir, message string) (string, error) {
+	if err := runGit(dir, "stash", "push", "--include-untracked", "-m", message); err != nil {
+		return "", err
+	}
+	return gitResolveCommit(dir, "refs/stash")
+}
+
+// gitStashApply applies the stash commit sha in dir, then drops it from the
+// stash list. The stash is shared by all worktrees of a repo, so a change
+// stashed in one worktree can be applied in another. If applying fails the
+// stash is kept.
+func gitStashApply(dir, sha string) error {
+	if err := runGit(dir, "stash", "apply", "--quiet", sha); err != nil {
+		return err
+	}
+	out, err := runGitOutput(dir, "stash", "list", "--format=%H")
+	if err != nil {
+		return err
+	}
+	for i, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == sha {
+			return runGit(dir, "stash", "drop", "--quiet", fmt.Sprintf("stash@{%d}", i))
+		}
+	}
+	return nil
+}
+
+// gitStashUndo reverts a failed gitStashApply of the stash commit sha in
+// dir. Resetting restores the tracked files; the untracked ones the stash
+// brought back, recorded in its third parent, are removed as well.
+func gitStashUndo(dir, sha string) error {
+	if err := runGit(dir, "reset", "--hard", "--quiet"); err != nil {
+		return err
+	}
+	// A stash with no untracked files has no third parent.
+	if _, err := runGitOutput(dir, "rev-parse", "--verify", "--quiet", sha+"^3"); err != nil {
+		return nil
+	}
+	out, err := runGitOutput(dir, "ls-tree", "-r", "-z", "--name-only", sha+"^3")
+	if err != nil {
+		return err
+	}
+	paths := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	return runGit(dir, append([]string{"clean", "-f", "-q", "--"}, paths...)...)
+}
+
+// gitShortCommit returns the abbreviated SHA of the commit ref points to.
+func gitShortCommit(repoRoot, ref string) (string, error) {
+	out, err := runGitOutput(repoRoot, "rev-parse", "--short", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// gitShortCommits abbreviates each of shas with a single git call, mapping
+// each SHA to its short form.
+func gitShortCommits(dir string, shas []string) (map[string]string, error) {
+	if len(shas) == 0 {
+		return nil, nil
+	}
+	out, err := runGitOutput(dir, append([]string{"log", "--no-walk", "--format=%H %h"}, shas...)...)
+	if err != nil {
+		return nil, err
+	}
+	short := make(map[string]string, len(shas))
+	for _, line := range strings.Split(out, "\n") {
+		if full, abbrev, ok := strings.Cut(line, " "); ok {
+			short[full] = abbrev
+		}
+	}
+	return short, nil
+}
+
+// gitMergeBase returns the best common ancestor of HEAD in dir and base.
+func gitMergeBase(dir, base string) (string, error) {
+	out, err := runGitOutput(dir, "merge-base", "HEAD", base)
+	if err != nil {
+		return "", fmt.Errorf("no merge-base with %s: %w", base, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// gitCommitAge returns how long ago ref was committed, e.g. "3 days ago".
+func gitCommitAge(dir, ref string) (string, error) {
+	out, err := runGitOutput(dir, "log", "-1", "--format=%cr", ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// gitDefaultBranch returns the branch that others merge into: origin/HEAD's
+// target when the remote has one (the local branch if it exists, otherwise
+// the remote-tracking ref), falling back to a local main or master.
+func gitDefaultBranch(repoRoot string) (string, error) {
+	out, err := runGitOutput(repoRoot, "symbolic-ref", "--quiet", "refs/remotes/origin/HEAD")
+	if err == nil {
+		name := strings.TrimPrefix(strings.TrimSpace(out), "refs/remotes/origin/")
+		exists, err := gitBranchExists(repoRoot, name)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return name, nil
+		}
+		return "origin/" + name, nil
+	}
+	for _, name := range []string{"main", "master"} {
+		exists, err := gitBranchExists(repoRoot, name)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return name, nil
+		}
+	}
+	return "", errors.New("could not determine the default branch (no origin/HEAD, main, or master)")
+}
+
+// gitRemoteHead returns the branch refs/remotes/<remote>/HEAD points to,
+// such as main, or "" when it isn't set, as happens when the remote was
+// added by hand rather than cloned from.
+func gitRemoteHead(repoRoot, remote string) string {
+	out, err := runGitOutput(repoRoot, "symbolic-ref", "--quiet", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(out), "refs/remotes/"+remote+"/")
+}
+
+// gitMergedBranches returns the local branches fully merged into base.
+func gitMergedBranches(repoRoot, base string) (map[string]bool, error) {
+	out, err := runGitOutput(repoRoot, "branch", "--merged", base, "--format=%(refname:short)")
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			merged[line] = true
+		}
+	}
+	return merged, nil
+}
+
+// gitBranchUntouched reports whether branch has not moved since it was
+// created, so its reflog holds no more than the creation entry. A branch
+// whose reflog can't be read counts as untouched.
+func gitBranchUntouched(repoRoot, branch string) bool {
+	out, err := runGitOutput(repoRoot, "reflog", "show", "--format=%H", "refs/heads/"+branch, "--")
+	if err != nil {
+		return true
+	}
+	return len(strings.Fields(out)) <= 1
+}
+
+// gitUntrackedFiles lists the untracked, not ignored, files in the worktree
+// at path, relative to it. Untracked directories are listed file by file.
+func gitUntrackedFiles(path string) ([]string, error) {
+	out, err := runGitOutput(path, "status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(entry, "?? "):
+			files = append(files, entry[3:])
+		case entry[0] == 'R' || entry[0] == 'C':
+			// A rename or copy is followed by its source path.
+			i++
+		}
+	}
+	return files, nil
+}
+
+// gitGoneBranches returns the local branches whose upstream branch has been
+// deleted from the remote, which git reports as "[gone]" once the remote
+// has been fetched with pruning.
+func gitGoneBranches(repoRoot string) (map[string]bool, error) {
+	out, err := runGitOutput(repoRoot, "for-each-ref", "--format=%(refname:short) %(upstream:track)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	gone := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		branch, track, ok := strings.Cut(line, " ")
+		if ok && strings.TrimSpace(track) == "[gone]" {
+			gone[branch] = true
+		}
+	}
+	return gone, nil
+}
+
+// markUpstreamGone sets UpstreamGone on the worktrees in wts whose branch's
+// upstream is gone. It is best effort: if the branches can't be read, wts
+// is left as is.
+func markUpstreamGone(repoRoot string, wts []worktree) {
+	gone, err := gitGoneBranches(repoRoot)
+	if err != nil {
+		return
+	}
+	for i := range wts {
+		wts[i].UpstreamGone = wts[i].Branch != "" && gone[wts[i].Branch]
+	}
+}
+
+// gitEnableWorktreeConfig turns on extensions.worktreeConfig, without which
+// git config --worktree writes the config shared by every worktree. As
+// git-worktree(1) advises, a core.bare in the shared config is moved to the
+// main worktree's own config first, or every linked worktree of a bare
+// repository would be taken for bare too.
+func gitEnableWorktreeConfig(repoRoot string) error {
+	if err := requireGitVersion(worktreeConfigGitVersion, "worktree.gitConfig"); err != nil {
+		return err
+	}
+	// git config --get exits non-zero for an unset key.
+	out, _ := runGitOutput(repoRoot, "config", "--bool", "--get", "extensions.worktreeConfig")
+	if strings.TrimSpace(out) == "true" {
+		return nil
+	}
+	bare, _ := runGitOutput(repoRoot, "config", "--local", "--bool", "--get", "core.bare")
+	if err := runGit(repoRoot, "config", "extensions.worktreeConfig", "true"); err != nil {
+		return err
+	}
+	if strings.TrimSpace(bare) != "true" {
+		return nil
+	}
+	if err := runGit(repoRoot, "config", "--worktree", "core.bare", "true"); err != nil {
+		return err
+	}
+	return runGit(repoRoot, "config", "--local", "--unset", "core.bare")
+}
+
 func gitWorktreeClean(path string) (bool, error) {
 	out, err := runGitOutput(path, "status", "--porcelain")
 	if err != nil {
@@ -126,6 +678,49 @@ func gitWorktreeClean(path string) (bool, error) {
 	return strings.TrimSpace(out) == "", nil
 }
 
+// cleanCheckWorkers bounds how many git status processes
+// worktreeCleanliness runs at once.
+const cleanCheckWorkers = 8
+
+// worktreeCleanCheck is the per-worktree check run by worktreeCleanliness.
+var worktreeCleanCheck = gitWorktreeClean
+
+// errStatusUnknown wraps the error of a worktree whose status
+// worktreeCleanliness couldn't read.
+var errStatusUnknown = errors.New("could not read worktree status")
+
+// worktreeCleanliness checks whether each worktree in paths has uncommitted
+// changes, running up to cleanCheckWorkers checks concurrently. The first
+// result maps each path to true when it is clean; paths whose status can't be
+// read are left out of it and their errors returned in the second.
+func worktreeCleanliness(paths []string) (map[string]bool, map[string]error) {
+	results := make(map[string]bool, len(paths))
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, cleanCheckWorkers)
+	for _, path := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			clean, err := worktreeCleanCheck(path)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[path] = err
+				return
+			}
+			results[path] = clean
+		}()
+	}
+	wg.Wait()
+	return results, errs
+}
+
 func gitCommitTime(repoRoot, ref string) int64 {
 	out, err := runGitOutput(repoRoot, "log", "-1", "--format=%ct", ref)
 	if err != nil {
@@ -150,13 +745,11 @@ func gitCommitTimePath(path string) int64 {
 	return parsed
 }
 
-func orderByRecentCommit(items []string, repoRoot, orderKey string) []string {
-	type entry struct {
-		name string
-		ts   int64
-	}
-
-	entries := make([]entry, 0, len(items))
+// commitTimes returns the latest commit time (Unix seconds, 0 if unknown)
+// of each item, in order. Items are refs, or worktree paths when orderKey
+// is "worktrees".
+func commitTimes(items []string, repoRoot, orderKey string) []int64 {
+	times := make([]int64, 0, len(items))
 	for _, item := range items {
 		var ts int64
 		switch orderKey {
@@ -171,7 +764,21 @@ func orderByRecentCommit(items []string, repoRoot, orderKey string) []string {
 		default:
 			ts = gitCommitTime(repoRoot, item)
 		}
-		entries = append(entries, entry{name: item, ts: ts})
+		times = append(times, ts)
+	}
+	return times
+}
+
+func orderByRecentCommit(items []string, repoRoot, orderKey string) []string {
+	type entry struct {
+		name string
+		ts   int64
+	}
+
+	times := commitTimes(items, repoRoot, orderKey)
+	entries := make([]entry, 0, len(items))
+	for i, item := range items {
+		entries = append(entries, entry{name: item, ts: times[i]})
 	}
 
 	sort.SliceStable(entries, func(i, j int) bool {
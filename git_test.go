@@ -1,11 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestWorktreePath(t *testing.T) {
@@ -16,6 +22,17 @@ func TestWorktreePath(t *testing.T) {
 	}
 }
 
+func TestWorktreePathUnder(t *testing.T) {
+	got := worktreePathUnder("/fast", "/src/repo", "feature/one")
+	want := filepath.Join("/fast", "repo-worktrees", "feature", "one")
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if got := worktreePathUnder("", "/src/repo", "x"); got != worktreePath("/src/repo", "x") {
+		t.Fatalf("expected default layout without a root, got %q", got)
+	}
+}
+
 func TestOrderByRecentCommitWorktrees(t *testing.T) {
 	oldExec := execCommand
 	defer func() { execCommand = oldExec }()
@@ -148,6 +165,46 @@ func TestRunGit(t *testing.T) {
 	}
 }
 
+func TestRunGitProgress(t *testing.T) {
+	oldExec := execCommand
+	oldErr := stderr
+	defer func() {
+		execCommand = oldExec
+		stderr = oldErr
+	}()
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	var gotArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		gotArgs = args
+		return exec.Command("sh", "-c", "echo Preparing worktree; echo progress >&2")
+	}
+
+	if err := runGitProgress("/repo", false, "worktree", "add"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if errBuf.String() != "Preparing worktree\nprogress\n" || strings.Join(gotArgs, " ") != "-C /repo worktree add" {
+		t.Fatalf("expected git's output on stderr, got %q from %v", errBuf.String(), gotArgs)
+	}
+
+	errBuf.Reset()
+	if err := runGitProgress("/repo", true, "worktree", "add"); err != nil || errBuf.Len() != 0 {
+		t.Fatalf("expected quiet run to keep stderr clean, got %q, %v", errBuf.String(), err)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		gotArgs = args
+		return exec.Command("sh", "-c", "echo fatal: boom >&2; exit 1")
+	}
+	err := runGitProgress("", false, "submodule", "update")
+	if err == nil || err.Error() != "git submodule update failed: exit status 1" || !strings.Contains(errBuf.String(), "fatal: boom") {
+		t.Fatalf("expected the failure reported, got %v and %q", err, errBuf.String())
+	}
+	if strings.Join(gotArgs, " ") != "submodule update" {
+		t.Fatalf("expected no -C without a repo root, got %v", gotArgs)
+	}
+}
+
 func TestGitHelpersWithRepo(t *testing.T) {
 	repo := setupTestRepo(t)
 	defer withDir(t, repo)()
@@ -211,6 +268,17 @@ func TestGitBranchesErrorAndBlanks(t *testing.T) {
 	}
 }
 
+func TestGitRemoteBranchesError(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if _, err := gitRemoteBranches("/repo"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
 func TestGitBranchExistsError(t *testing.T) {
 	oldExec := execCommand
 	execCommand = func(name string, args ...string) *exec.Cmd {
@@ -223,6 +291,62 @@ func TestGitBranchExistsError(t *testing.T) {
 	}
 }
 
+func TestGitDefaultRemote(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	for out, want := range map[string]string{
+		"":                   "",
+		"upstream\norigin\n": "origin",
+		"upstream\n":         "upstream",
+		"a\nb\n":             "",
+	} {
+		execCommand = func(name string, args ...string) *exec.Cmd { return cmdWithOutput(out) }
+		if got, err := gitDefaultRemote("/repo"); got != want || err != nil {
+			t.Errorf("gitDefaultRemote with %q = %q, %v; want %q", out, got, err, want)
+		}
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd { return exec.Command("sh", "-c", "exit 1") }
+	if _, err := gitDefaultRemote("/repo"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGitFetchBranch(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	var got []string
+	var cmd *exec.Cmd
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		got = args
+		cmd = exec.Command("sh", "-c", "exit 0")
+		return cmd
+	}
+	if err := gitFetchBranch("/repo", "origin", "feature"); err != nil || strings.Join(got, " ") != "-C /repo fetch --quiet origin feature" {
+		t.Fatalf("unexpected fetch %v, %v", got, err)
+	}
+	// git's messages are matched below, so they must not be translated.
+	if !slices.Contains(cmd.Env, "LC_ALL=C") {
+		t.Fatalf("expected fetch to run in the C locale, got env %v", cmd.Env)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo \"fatal: couldn't find remote ref feature\" >&2; exit 128")
+	}
+	if err := gitFetchBranch("/repo", "origin", "feature"); !errors.Is(err, errRemoteBranchNotFound) {
+		t.Fatalf("expected remote branch not found, got %v", err)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo 'fatal: unable to access' >&2; exit 128")
+	}
+	if err := gitFetchBranch("/repo", "origin", "feature"); err == nil || errors.Is(err, errRemoteBranchNotFound) || !strings.Contains(err.Error(), "unable to access") {
+		t.Fatalf("expected fetch error, got %v", err)
+	}
+}
+
 func TestGitWorktreesParse(t *testing.T) {
 	out := strings.Join([]string{
 		"worktree /repo",
@@ -290,6 +414,31 @@ func TestGitWorktreeClean(t *testing.T) {
 	}
 }
 
+func TestGitUntrackedFiles(t *testing.T) {
+	repo := setupTestRepo(t)
+	mustWriteFile(t, filepath.Join(repo, ".gitignore"), "*.log\n")
+	mustRunCmd(t, repo, "git", "add", ".gitignore")
+	mustRunCmd(t, repo, "git", "mv", "file.txt", "renamed.txt")
+	mustWriteFile(t, filepath.Join(repo, "notes", "a b.txt"), "a")
+	mustWriteFile(t, filepath.Join(repo, "debug.log"), "x")
+	mustWriteFile(t, filepath.Join(repo, "todo.md"), "x")
+
+	files, err := gitUntrackedFiles(repo)
+	if err != nil {
+		t.Fatalf("gitUntrackedFiles: %v", err)
+	}
+	if strings.Join(files, ",") != "notes/a b.txt,todo.md" {
+		t.Fatalf("unexpected untracked files %q", files)
+	}
+
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd { return exec.Command("sh", "-c", "exit 1") }
+	if _, err := gitUntrackedFiles(repo); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 func TestGitWorktreeCleanError(t *testing.T) {
 	oldExec := execCommand
 	defer func() { execCommand = oldExec }()
@@ -302,6 +451,61 @@ func TestGitWorktreeCleanError(t *testing.T) {
 	}
 }
 
+func TestWorktreeCleanliness(t *testing.T) {
+	oldCheck := worktreeCleanCheck
+	defer func() { worktreeCleanCheck = oldCheck }()
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	worktreeCleanCheck = func(path string) (bool, error) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		switch {
+		case strings.HasSuffix(path, "broken"):
+			return false, errors.New("boom")
+		case strings.HasSuffix(path, "dirty"):
+			return false, nil
+		}
+		return true, nil
+	}
+
+	var paths []string
+	for i := range 3 * cleanCheckWorkers {
+		paths = append(paths, fmt.Sprintf("/wt/%d-clean", i))
+	}
+	paths = append(paths, "/wt/dirty", "/wt/broken")
+
+	got, errs := worktreeCleanliness(paths)
+	if len(got) != len(paths)-1 {
+		t.Fatalf("expected %d results, got %d", len(paths)-1, len(got))
+	}
+	if clean, ok := got["/wt/0-clean"]; !ok || !clean {
+		t.Fatalf("expected clean worktree, got %v, %v", clean, ok)
+	}
+	if clean, ok := got["/wt/dirty"]; !ok || clean {
+		t.Fatalf("expected dirty worktree, got %v, %v", clean, ok)
+	}
+	if _, ok := got["/wt/broken"]; ok {
+		t.Fatal("expected unreadable worktree left out")
+	}
+	if err := errs["/wt/broken"]; err == nil || err.Error() != "boom" || len(errs) != 1 {
+		t.Fatalf("expected only the unreadable worktree's error, got %v", errs)
+	}
+	if peak > cleanCheckWorkers || peak < 2 {
+		t.Fatalf("expected between 2 and %d concurrent checks, got %d", cleanCheckWorkers, peak)
+	}
+
+	if got, errs := worktreeCleanliness(nil); len(got) != 0 || len(errs) != 0 {
+		t.Fatalf("expected no results, got %v, %v", got, errs)
+	}
+}
+
 func TestGitMainWorktreeError(t *testing.T) {
 	oldExec := execCommand
 	defer func() { execCommand = oldExec }()
@@ -326,3 +530,566 @@ func TestGitMainWorktreeEmpty(t *testing.T) {
 		t.Fatalf("expected 'no worktrees found' error, got %v", err)
 	}
 }
+
+func TestGitRepoRootBareRepo(t *testing.T) {
+	src := setupTestRepo(t)
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	mustRunCmd(t, src, "git", "clone", "--bare", src, bare)
+	defer withDir(t, bare)()
+
+	root, err := gitRepoRoot()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if root != bare {
+		t.Fatalf("expected bare root %q, got %q", bare, root)
+	}
+
+	mainWT, err := gitMainWorktree(root)
+	if err != nil || mainWT != bare {
+		t.Fatalf("expected main worktree %q, got %q err %v", bare, mainWT, err)
+	}
+}
+
+func TestGitRepoRootBareFallback(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	tests := []struct {
+		name     string
+		emptyTop bool
+		bare     string
+		bareFail bool
+		common   string
+		comFail  bool
+		want     string
+		wantErr  string
+	}{
+		{name: "toplevel error absolute", bare: "true", common: "/srv/repo.git", want: "/srv/repo.git"},
+		{name: "toplevel empty", emptyTop: true, bare: "true", common: "/srv/repo.git/", want: "/srv/repo.git"},
+		{name: "not bare", bare: "false", wantErr: "show-toplevel"},
+		{name: "not bare empty toplevel", emptyTop: true, bare: "false", wantErr: "not inside a work tree"},
+		{name: "bare check error", bareFail: true, wantErr: "show-toplevel"},
+		{name: "common dir error", bare: "true", comFail: true, wantErr: "show-toplevel"},
+		{name: "common dir empty", emptyTop: true, bare: "true", common: "", wantErr: "could not determine"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				switch args[len(args)-1] {
+				case "--show-toplevel":
+					if tt.emptyTop {
+						return cmdWithOutput("")
+					}
+					return exec.Command("sh", "-c", "exit 1")
+				case "--is-bare-repository":
+					if tt.bareFail {
+						return exec.Command("sh", "-c", "exit 1")
+					}
+					return cmdWithOutput(tt.bare + "\n")
+				case "--git-common-dir":
+					if tt.comFail {
+						return exec.Command("sh", "-c", "exit 1")
+					}
+					return cmdWithOutput(tt.common + "\n")
+				}
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			got, err := gitRepoRoot()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("expected %q, got %q err %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestGitRepoRootBareRelativeDir(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	dir := t.TempDir()
+	defer withDir(t, dir)()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		switch args[len(args)-1] {
+		case "--is-bare-repository":
+			return cmdWithOutput("true\n")
+		case "--git-common-dir":
+			return cmdWithOutput(".\n")
+		}
+		return exec.Command("sh", "-c", "exit 1")
+	}
+
+	got, err := gitRepoRoot()
+	if err != nil || got != dir {
+		t.Fatalf("expected %q, got %q err %v", dir, got, err)
+	}
+}
+
+func TestGitResolveCommitError(t *testing.T) {
+	oldExec := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	defer func() { execCommand = oldExec }()
+
+	if _, err := gitResolveCommit("/repo", "nope"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestValidateBaseRefBranchCheckError(t *testing.T) {
+	oldExec := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("does-not-exist")
+	}
+	defer func() { execCommand = oldExec }()
+
+	if err := validateBaseRef("/repo", "dev"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+// stubGitVersion resets the cached git version and makes "git --version"
+// print out (or fail when out is empty).
+func stubGitVersion(t *testing.T, out string) {
+	t.Helper()
+	oldExec := execCommand
+	reset := func() {
+		gitVersionOnce = sync.Once{}
+		gitVersionCached = gitVersion{}
+		gitVersionErr = nil
+	}
+	reset()
+	t.Cleanup(func() {
+		execCommand = oldExec
+		reset()
+	})
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) == 1 && args[0] == "--version" {
+			if out == "" {
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			return cmdWithOutput(out)
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+}
+
+func TestParseGitVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    gitVersion
+		wantErr bool
+	}{
+		{in: "git version 2.39.3 (Apple Git-146)\n", want: gitVersion{2, 39, 3}},
+		{in: "git version 2.45.1.windows.1", want: gitVersion{2, 45, 1}},
+		{in: "git version 2.45.0.rc0", want: gitVersion{2, 45, 0}},
+		{in: "git version 2.17", want: gitVersion{2, 17, 0}},
+		{in: "git version 1.9.rc1", want: gitVersion{1, 9, 0}},
+		{in: "hub version 2.14.2", wantErr: true},
+		{in: "git version", wantErr: true},
+		{in: "git version 2", wantErr: true},
+		{in: "git version x.y.z", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseGitVersion(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseGitVersion(%q): expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseGitVersion(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestGitVersionLess(t *testing.T) {
+	tests := []struct {
+		a, b gitVersion
+		want bool
+	}{
+		{gitVersion{1, 9, 0}, gitVersion{2, 0, 0}, true},
+		{gitVersion{2, 16, 9}, gitVersion{2, 17, 0}, true},
+		{gitVersion{2, 17, 0}, gitVersion{2, 17, 1}, true},
+		{gitVersion{2, 17, 0}, gitVersion{2, 17, 0}, false},
+		{gitVersion{3, 0, 0}, gitVersion{2, 40, 0}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.a.less(tt.b); got != tt.want {
+			t.Errorf("%v.less(%v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCurrentGitVersionMemoized(t *testing.T) {
+	stubGitVersion(t, "git version 2.40.1")
+	calls := 0
+	stubbed := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		calls++
+		return stubbed(name, args...)
+	}
+
+	for i := 0; i < 2; i++ {
+		v, err := currentGitVersion()
+		if err != nil || v != (gitVersion{2, 40, 1}) {
+			t.Fatalf("unexpected version %v err %v", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected git --version to run once, ran %d times", calls)
+	}
+}
+
+func TestCurrentGitVersionError(t *testing.T) {
+	stubGitVersion(t, "")
+	if _, err := currentGitVersion(); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestRequireGitVersion(t *testing.T) {
+	stubGitVersion(t, "git version 2.11.0")
+	err := removeWorktree("/repo", "/repo-wt")
+	if err == nil || err.Error() != "removing worktrees requires git 2.17.0 or newer (found 2.11.0)" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := renameWorktree("/repo", "/repo-wt", "a", "b"); err == nil || !strings.HasPrefix(err.Error(), "moving worktrees requires") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := repairWorktrees("/repo", nil, false); err == nil || err.Error() != "repairing worktrees requires git 2.29.0 or newer (found 2.11.0)" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := requireGitVersion(gitVersion{2, 0, 0}, "anything"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	stubGitVersion(t, "")
+	if err := requireGitVersion(gitVersion{9, 0, 0}, "anything"); err != nil {
+		t.Fatalf("expected unknown version to pass, got %v", err)
+	}
+}
+
+func TestGitDefaultBranchFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		branches []string
+		want     string
+		wantErr  bool
+	}{
+		{name: "main", branches: []string{"main", "master"}, want: "main"},
+		{name: "master", branches: []string{"master"}, want: "master"},
+		{name: "none", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExec := execCommand
+			defer func() { execCommand = oldExec }()
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				args = args[2:]
+				if args[0] == "show-ref" {
+					for _, b := range tt.branches {
+						if args[2] == "refs/heads/"+b {
+							return cmdWithOutput("")
+						}
+					}
+				}
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			got, err := gitDefaultBranch("/repo")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("expected %q (err %v), got %q, %v", tt.want, tt.wantErr, got, err)
+			}
+		})
+	}
+}
+
+func TestGitDefaultBranchErrors(t *testing.T) {
+	for _, originHead := range []bool{true, false} {
+		oldExec := execCommand
+		execCommand = func(name string, args ...string) *exec.Cmd {
+			if args[2] == "symbolic-ref" && originHead {
+				return cmdWithOutput("refs/remotes/origin/main\n")
+			}
+			if args[2] == "show-ref" {
+				return exec.Command("does-not-exist")
+			}
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		if _, err := gitDefaultBranch("/repo"); err == nil {
+			t.Fatalf("expected error (origin/HEAD %v)", originHead)
+		}
+		execCommand = oldExec
+	}
+}
+
+func TestGitMergedBranches(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return cmdWithOutput("main\nfeature\n\n")
+	}
+	merged, err := gitMergedBranches("/repo", "main")
+	if err != nil || len(merged) != 2 || !merged["feature"] {
+		t.Fatalf("unexpected merged branches %v, err %v", merged, err)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if _, err := gitMergedBranches("/repo", "main"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGitBranchUntouched(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	tests := []struct {
+		name string
+		cmd  string
+		want bool
+	}{
+		{name: "created only", cmd: "echo aaa", want: true},
+		{name: "no reflog", cmd: "true", want: true},
+		{name: "committed", cmd: "printf 'bbb\\naaa\\n'", want: false},
+		{name: "error", cmd: "exit 1", want: true},
+	}
+	for _, tt := range tests {
+		execCommand = func(name string, args ...string) *exec.Cmd {
+			return exec.Command("sh", "-c", tt.cmd)
+		}
+		if got := gitBranchUntouched("/repo", "feature"); got != tt.want {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestGitStashErrors(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	fail := ""
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		joined := strings.Join(args[2:], " ")
+		if fail != "" && strings.HasPrefix(joined, fail) {
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		if strings.HasPrefix(joined, "stash list") {
+			return cmdWithOutput("bbb\n")
+		}
+		return cmdWithOutput("aaa\n")
+	}
+
+	fail = "stash push"
+	if _, err := gitStashPush("/repo", "msg"); err == nil {
+		t.Fatal("expected push error")
+	}
+	fail = "stash list"
+	if err := gitStashApply("/repo", "aaa"); err == nil {
+		t.Fatal("expected list error")
+	}
+	// A stash that is no longer listed has nothing left to drop.
+	fail = "stash drop"
+	if err := gitStashApply("/repo", "aaa"); err != nil {
+		t.Fatalf("expected unlisted stash to be left alone, got %v", err)
+	}
+
+	fail = "reset"
+	if err := gitStashUndo("/repo", "aaa"); err == nil {
+		t.Fatal("expected reset error")
+	}
+	fail = "rev-parse"
+	if err := gitStashUndo("/repo", "aaa"); err != nil {
+		t.Fatalf("expected a stash without untracked files to need only a reset, got %v", err)
+	}
+	fail = "ls-tree"
+	if err := gitStashUndo("/repo", "aaa"); err == nil {
+		t.Fatal("expected ls-tree error")
+	}
+}
+
+func TestGitGoneBranches(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return cmdWithOutput("main \nold [gone]\nahead [ahead 1, behind 2]\n")
+	}
+	gone, err := gitGoneBranches("/repo")
+	if err != nil || !reflect.DeepEqual(gone, map[string]bool{"old": true}) {
+		t.Fatalf("expected only old gone, got %v, %v", gone, err)
+	}
+
+	wts := []worktree{{Path: "/a", Branch: "old"}, {Path: "/b", Branch: "main"}, {Path: "/c"}}
+	markUpstreamGone("/repo", wts)
+	if !wts[0].UpstreamGone || wts[1].UpstreamGone || wts[2].UpstreamGone {
+		t.Fatalf("expected only old marked, got %+v", wts)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if _, err := gitGoneBranches("/repo"); err == nil {
+		t.Fatalf("expected error")
+	}
+	wts = []worktree{{Path: "/a", Branch: "old"}}
+	markUpstreamGone("/repo", wts)
+	if wts[0].UpstreamGone {
+		t.Fatalf("expected nothing marked on error, got %+v", wts)
+	}
+}
+
+func TestGitEnableWorktreeConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled string
+		bare    string
+		fail    string
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:    "already enabled",
+			enabled: "true\n",
+			want:    [][]string{{"config", "--bool", "--get", "extensions.worktreeConfig"}},
+		},
+		{
+			name: "not bare",
+			bare: "false\n",
+			want: [][]string{
+				{"config", "--bool", "--get", "extensions.worktreeConfig"},
+				{"config", "--local", "--bool", "--get", "core.bare"},
+				{"config", "extensions.worktreeConfig", "true"},
+			},
+		},
+		{
+			name: "bare",
+			bare: "true\n",
+			want: [][]string{
+				{"config", "--bool", "--get", "extensions.worktreeConfig"},
+				{"config", "--local", "--bool", "--get", "core.bare"},
+				{"config", "extensions.worktreeConfig", "true"},
+				{"config", "--worktree", "core.bare", "true"},
+				{"config", "--local", "--unset", "core.bare"},
+			},
+		},
+		{name: "enable fails", fail: "extensions.worktreeConfig true", wantErr: true},
+		{name: "move fails", bare: "true\n", fail: "--worktree core.bare true", wantErr: true},
+		{name: "unset fails", bare: "true\n", fail: "--unset core.bare", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubGitVersion(t, "git version 2.39.0")
+			var calls [][]string
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) == 1 && args[0] == "--version" {
+					return cmdWithOutput("git version 2.39.0")
+				}
+				args = args[2:]
+				calls = append(calls, args)
+				joined := strings.Join(args, " ")
+				switch {
+				case tt.fail != "" && strings.HasSuffix(joined, tt.fail):
+					return exec.Command("sh", "-c", "exit 1")
+				case strings.HasSuffix(joined, "--get extensions.worktreeConfig"):
+					if tt.enabled == "" {
+						return exec.Command("sh", "-c", "exit 1")
+					}
+					return cmdWithOutput(tt.enabled)
+				case strings.HasSuffix(joined, "--get core.bare"):
+					return cmdWithOutput(tt.bare)
+				}
+				return exec.Command("sh", "-c", "exit 0")
+			}
+
+			err := gitEnableWorktreeConfig("/repo")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(calls, tt.want) {
+				t.Fatalf("expected %v, got %v, %v", tt.want, calls, err)
+			}
+		})
+	}
+}
+
+func TestGitEnableWorktreeConfigOldGit(t *testing.T) {
+	stubGitVersion(t, "git version 2.19.1")
+	err := gitEnableWorktreeConfig("/repo")
+	if err == nil || !strings.Contains(err.Error(), "worktree.gitConfig requires git 2.20.0 or newer") {
+		t.Fatalf("expected a git version error, got %v", err)
+	}
+}
+
+func TestGitWorktreesHead(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	head := strings.Repeat("d", 40)
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return cmdWithOutput("worktree /repo\nHEAD " + head + "\nbranch refs/heads/main\n\n" +
+			"worktree /wt\nHEAD " + strings.Repeat("0", 40) + "\nbranch refs/heads/unborn\n\n")
+	}
+	wts, err := gitWorktrees("/repo")
+	if err != nil || len(wts) != 2 || wts[0].Head != head || wts[1].Head != "" {
+		t.Fatalf("expected the main HEAD and none for the unborn branch, got %+v, %v", wts, err)
+	}
+}
+
+func TestGitShortCommits(t *testing.T) {
+	repo := setupTestRepo(t)
+	head := gitOutput(t, repo, "rev-parse", "HEAD")
+	short, err := gitShortCommits(repo, []string{head, head})
+	if err != nil || short[head] != gitOutput(t, repo, "rev-parse", "--short", "HEAD") || len(short) != 1 {
+		t.Fatalf("unexpected short SHAs %v, %v", short, err)
+	}
+	if short, err := gitShortCommits(repo, nil); short != nil || err != nil {
+		t.Fatalf("expected nothing for no SHAs, got %v, %v", short, err)
+	}
+	if _, err := gitShortCommits(repo, []string{strings.Repeat("e", 40)}); err == nil {
+		t.Fatalf("expected an error for an unknown commit")
+	}
+}
+
+func TestGitWorktreesParseSpaces(t *testing.T) {
+	out := strings.Join([]string{
+		"worktree /Users/me/Application Support/my repo",
+		"branch refs/heads/main",
+		"",
+		"worktree /Users/me/Application Support/my repo-worktrees/login ",
+		"branch refs/heads/feature/login",
+		"",
+	}, "\r\n")
+
+	oldExec := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return cmdWithOutput(out)
+	}
+	defer func() { execCommand = oldExec }()
+
+	wts, err := gitWorktrees("/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"/Users/me/Application Support/my repo",
+		"/Users/me/Application Support/my repo-worktrees/login ",
+	}
+	if len(wts) != 2 || wts[0].Path != want[0] || wts[1].Path != want[1] || wts[1].Branch != "feature/login" {
+		t.Fatalf("expected paths %q, got %v", want, wts)
+	}
+}
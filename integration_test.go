@@ -2,9 +2,14 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -78,6 +83,102 @@ func TestIntegrationNewCmdCopiesConfig(t *testing.T) {
 	}
 }
 
+func TestIntegrationNewCmdCopySummary(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	mustWriteFile(t, filepath.Join(repo, ".env"), "SECRET=123")
+	mustWriteFile(t, filepath.Join(repo, "AGENTS.md"), "# Agents")
+	mustWriteFile(t, filepath.Join(repo, "node_modules", "pkg", "index.js"), strings.Repeat("x", 2048))
+
+	oldOut, oldErr := stdout, stderr
+	defer func() { stdout, stderr = oldOut, oldErr }()
+	stdout = &bytes.Buffer{}
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+
+	newCmd([]string{"--copy-libs", "summary"})
+	if want := "copied 2 config files (18 B), node_modules (2.0 KB)\n"; !strings.Contains(errBuf.String(), want) {
+		t.Fatalf("expected %q in stderr, got %q", want, errBuf.String())
+	}
+
+	errBuf.Reset()
+	newCmd([]string{"--quiet", "--copy-libs", "quiet"})
+	if strings.Contains(errBuf.String(), "copied") {
+		t.Fatalf("expected no summary with --quiet, got %q", errBuf.String())
+	}
+	if _, err := os.Stat(filepath.Join(worktreePath(repo, "quiet"), "node_modules", "pkg", "index.js")); err != nil {
+		t.Fatalf("expected libs copied with --quiet: %v", err)
+	}
+}
+
+func TestIntegrationWorktreePathWithSpaces(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := filepath.Join(base, "Application Support", "my repo")
+	if err := os.MkdirAll(repo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mustRunCmd(t, repo, "git", "init", "-b", "main")
+	mustRunCmd(t, repo, "git", "config", "user.email", "test@example.com")
+	mustRunCmd(t, repo, "git", "config", "user.name", "Test")
+	mustRunCmd(t, repo, "git", "config", "commit.gpgsign", "false")
+	mustWriteFile(t, filepath.Join(repo, "file.txt"), "data")
+	mustRunCmd(t, repo, "git", "add", ".")
+	mustRunCmd(t, repo, "git", "commit", "-m", "init")
+	defer withDir(t, repo)()
+
+	oldOut, oldErr, oldHome := stdout, stderr, osUserHomeDir
+	defer func() { stdout, stderr, osUserHomeDir = oldOut, oldErr, oldHome }()
+	stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+
+	newCmd([]string{"feature/login"})
+	wt := worktreePath(repo, "feature/login")
+
+	wts, err := gitWorktrees(repo)
+	if err != nil || len(wts) != 2 || wts[0].Path != repo || wts[1].Path != wt {
+		t.Fatalf("expected worktrees %q and %q, got %v, %v", repo, wt, wts, err)
+	}
+	for _, name := range []string{"login", "feature/login", wt, wt + "/"} {
+		if got, err := findWorktree(repo, name); err != nil || got != wt {
+			t.Errorf("findWorktree(%q) = %q, %v; want %q", name, got, err, wt)
+		}
+	}
+
+	oldExec, oldEnv := execCommand, os.Getenv("TMUX")
+	defer func() {
+		execCommand = oldExec
+		_ = os.Setenv("TMUX", oldEnv)
+	}()
+	_ = os.Unsetenv("TMUX")
+	var newSession []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name != "tmux" {
+			return oldExec(name, args...)
+		}
+		if args[0] == "new-session" {
+			newSession = args
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if err := openTmux(wt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"new-session", "-s", "login", "-c", wt}; !reflect.DeepEqual(newSession, want) {
+		t.Fatalf("expected %q, got %q", want, newSession)
+	}
+	if err := openTmux(repo); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"new-session", "-s", "my repo", "-c", repo}; !reflect.DeepEqual(newSession, want) {
+		t.Fatalf("expected %q, got %q", want, newSession)
+	}
+}
+
 func TestIntegrationListCmdWithRealGit(t *testing.T) {
 	repo := setupTestRepo(t)
 	defer withDir(t, repo)()
@@ -230,3 +331,1775 @@ func TestIntegrationOrderByRecentCommitWithRealGit(t *testing.T) {
 		t.Fatalf("expected main first, got %v", ordered)
 	}
 }
+
+func TestIntegrationNewCmdCopiesConfiguredPaths(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	defer func() { osUserHomeDir = oldHome }()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+
+	mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"copy":{"paths":["config/local.yml","certs"]}}`)
+	mustWriteFile(t, filepath.Join(repo, "config", "local.yml"), "db: local")
+	mustWriteFile(t, filepath.Join(repo, "certs", "dev.pem"), "pem")
+
+	oldOut := stdout
+	defer func() { stdout = oldOut }()
+	stdout = &bytes.Buffer{}
+
+	newCmd([]string{"paths"})
+
+	wtPath := worktreePath(repo, "paths")
+	content, err := os.ReadFile(filepath.Join(wtPath, "config", "local.yml"))
+	if err != nil || string(content) != "db: local" {
+		t.Fatalf("expected config/local.yml copied, got %q err %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "certs", "dev.pem")); err != nil {
+		t.Fatalf("expected certs copied: %v", err)
+	}
+}
+
+func TestIntegrationNewCmdWorktreeRoot(t *testing.T) {
+	repo := setupTestRepo(t)
+	root := filepath.Join(t.TempDir(), "fast")
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	var buf, errBuf bytes.Buffer
+	stdout = &buf
+	stderr = &errBuf
+	exitFunc = func(code int) { panic(code) }
+
+	// A refused worktree leaves no root directory behind.
+	nested := filepath.Join(repo, "fast")
+	func() {
+		defer func() {
+			if r := recover(); r != exitError {
+				t.Fatalf("expected exit 1, got %v", r)
+			}
+		}()
+		newCmd([]string{"--worktree-root", nested, "feature/fast"})
+	}()
+	if !strings.Contains(errBuf.String(), "is inside the main worktree") {
+		t.Fatalf("expected nested root refused, got %q", errBuf.String())
+	}
+	if _, err := os.Stat(nested); !os.IsNotExist(err) {
+		t.Fatalf("expected no root created for a refused worktree, got %v", err)
+	}
+	errBuf.Reset()
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("expected a bad --from to fail")
+			}
+		}()
+		newCmd([]string{"--worktree-root", root, "--from", "no-such-ref", "feature/fast"})
+	}()
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Fatalf("expected no root created for a bad --from, got %v", err)
+	}
+
+	newCmd([]string{"--worktree-root", root, "feature/fast"})
+
+	wtPath := filepath.Join(root, filepath.Base(repo)+"-worktrees", "feature", "fast")
+	if strings.TrimSpace(buf.String()) != wtPath {
+		t.Fatalf("expected %s, got %q", wtPath, buf.String())
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "file.txt")); err != nil {
+		t.Fatalf("expected worktree checked out under the root: %v", err)
+	}
+	if _, err := os.Stat(worktreePath(repo, "feature/fast")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing at the default location")
+	}
+}
+
+func TestIntegrationNewCmdLinkEnv(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+
+	mustWriteFile(t, filepath.Join(repo, ".env"), "SECRET=1")
+	mustWriteFile(t, filepath.Join(repo, "CLAUDE.md"), "# Instructions")
+
+	newCmd([]string{"--link-env", "feature"})
+
+	wtPath := worktreePath(repo, "feature")
+	if target, err := os.Readlink(filepath.Join(wtPath, ".env")); err != nil || target != filepath.Join(repo, ".env") {
+		t.Fatalf("expected .env linked to the main worktree, got %q, %v", target, err)
+	}
+	if info, err := os.Lstat(filepath.Join(wtPath, "CLAUDE.md")); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("expected other config files still copied: %v", err)
+	}
+}
+
+func TestIntegrationNewCmdEnvOverrides(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+
+	mustWriteFile(t, filepath.Join(repo, ".env"), "PORT=3000\nSECRET=1\n")
+	mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"copy":{"envOverrides":{"PORT":"3001"}}}`)
+
+	newCmd([]string{"feature"})
+	data, err := os.ReadFile(filepath.Join(worktreePath(repo, "feature"), ".env"))
+	if err != nil || string(data) != "PORT=3001\nSECRET=1\n" {
+		t.Fatalf("expected PORT overridden, got %q, %v", data, err)
+	}
+
+	newCmd([]string{"--link-env", "linked"})
+	if !strings.Contains(errBuf.String(), "warning: copy.envOverrides is not applied to linked .env files") {
+		t.Fatalf("expected link warning, got %q", errBuf.String())
+	}
+}
+
+func TestIntegrationNewCmdCopyUntracked(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+
+	mustWriteFile(t, filepath.Join(repo, ".env"), "PORT=3000\n")
+	mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"copy":{"envOverrides":{"PORT":"3001"},"untrackedSkip":["*.log"]}}`)
+	mustWriteFile(t, filepath.Join(repo, "scaffold", "seed.sql"), "seed")
+	mustWriteFile(t, filepath.Join(repo, "debug.log"), "log")
+
+	newCmd([]string{"--copy-untracked", "feature"})
+	wtPath := worktreePath(repo, "feature")
+	if data, err := os.ReadFile(filepath.Join(wtPath, "scaffold", "seed.sql")); err != nil || string(data) != "seed" {
+		t.Fatalf("expected untracked file copied, got %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(wtPath, ".env")); err != nil || string(data) != "PORT=3001\n" {
+		t.Fatalf("expected .env from the config copy, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "debug.log")); !os.IsNotExist(err) {
+		t.Fatalf("expected debug.log skipped, got %v", err)
+	}
+	if !regexp.MustCompile(`copied 1 config file \(\d+ B\), 2 untracked files \(\d+ B\)\n`).MatchString(errBuf.String()) {
+		t.Fatalf("expected untracked files in the copy summary, got %q", errBuf.String())
+	}
+
+	exitFunc = func(code int) { panic(code) }
+	errBuf.Reset()
+	func() {
+		defer func() {
+			if r := recover(); r != exitUsage {
+				t.Fatalf("expected usage exit, got %v", r)
+			}
+		}()
+		newCmd([]string{"--copy-untracked", "--no-checkout", "other"})
+	}()
+	if !strings.Contains(errBuf.String(), "--copy-untracked and --no-checkout cannot be used together") {
+		t.Fatalf("expected flag conflict error, got %q", errBuf.String())
+	}
+}
+
+func TestIntegrationNewCmdNoCheckout(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+
+	mustWriteFile(t, filepath.Join(repo, "AGENTS.md"), "agents")
+	mustWriteFile(t, filepath.Join(repo, "app", ".env"), "SECRET=1")
+	mustWriteFile(t, filepath.Join(repo, "node_modules", "pkg", "index.js"), "js")
+
+	newCmd([]string{"--no-checkout", "-l", "sparse"})
+
+	wtPath := worktreePath(repo, "sparse")
+	if _, err := os.Stat(filepath.Join(wtPath, "file.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected tracked files not checked out")
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "AGENTS.md")); err != nil {
+		t.Fatalf("expected top-level config copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "app", ".env")); !os.IsNotExist(err) {
+		t.Fatalf("expected nested config skipped")
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "node_modules")); !os.IsNotExist(err) {
+		t.Fatalf("expected libraries skipped")
+	}
+	wts, err := gitWorktrees(repo)
+	if err != nil || len(wts) != 2 {
+		t.Fatalf("expected worktree registered, got %v err %v", wts, err)
+	}
+}
+
+func TestIntegrationNewCmdFromCommitish(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+
+	mustRunCmd(t, repo, "git", "tag", "v1.0.0")
+	mustWriteFile(t, filepath.Join(repo, "later.txt"), "later")
+	mustRunCmd(t, repo, "git", "add", ".")
+	mustRunCmd(t, repo, "git", "commit", "-m", "later")
+
+	newCmd([]string{"-C", "--from", "v1.0.0", "from-tag"})
+	if _, err := os.Stat(filepath.Join(worktreePath(repo, "from-tag"), "later.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected worktree based on tag without later.txt")
+	}
+
+	newCmd([]string{"-C", "--from", "HEAD~1", "from-relative"})
+	if _, err := os.Stat(filepath.Join(worktreePath(repo, "from-relative"), "file.txt")); err != nil {
+		t.Fatalf("expected worktree from relative ref: %v", err)
+	}
+
+	// Relative refs and reflog entries are passed to git as given.
+	previous := gitOutput(t, repo, "rev-parse", "HEAD~1")
+	for _, base := range []string{"main~1", "HEAD@{1}"} {
+		branch := "from-" + strings.NewReplacer("~", "-", "@", "", "{", "", "}", "").Replace(base)
+		newCmd([]string{"-C", "--from", base, branch})
+		if got := gitOutput(t, worktreePath(repo, branch), "rev-parse", "HEAD"); got != previous {
+			t.Fatalf("expected --from %s to start at %s, got %s", base, previous, got)
+		}
+	}
+}
+
+func TestIntegrationAddWorktreeUnresolvableBase(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	_, err := addWorktree(repo, repo, addOptions{branch: "feature", fromBranch: "no-such-ref"})
+	if err == nil || !strings.Contains(err.Error(), `base "no-such-ref" does not resolve`) {
+		t.Fatalf("expected unresolvable base error, got %v", err)
+	}
+	if _, statErr := os.Stat(worktreePath(repo, "feature")); !os.IsNotExist(statErr) {
+		t.Fatalf("expected no worktree to be created")
+	}
+
+	// Where git says why a ref doesn't resolve, the error passes it on.
+	_, err = addWorktree(repo, repo, addOptions{branch: "feature", fromBranch: "HEAD@{50}"})
+	if err == nil || !strings.HasPrefix(err.Error(), `base "HEAD@{50}" does not resolve to a commit: log for 'HEAD' only has`) {
+		t.Fatalf("expected the reflog error, got %v", err)
+	}
+	_, err = addWorktree(repo, repo, addOptions{branch: "feature", fromBranch: "main~5"})
+	if err == nil || err.Error() != `base "main~5" does not resolve to a branch, tag, or commit` {
+		t.Fatalf("expected the generic error for a ref past the first commit, got %v", err)
+	}
+}
+
+func TestIntegrationAddWorktreeStrayTarget(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	stray := worktreePath(repo, "feature")
+	mustWriteFile(t, filepath.Join(stray, "notes.txt"), "keep me")
+
+	_, err := addWorktree(repo, repo, addOptions{branch: "feature"})
+	if err == nil || !strings.Contains(err.Error(), "target path "+stray+" already exists and is not a worktree") {
+		t.Fatalf("expected stray target error, got %v", err)
+	}
+	if exists, _ := gitBranchExists(repo, "feature"); exists {
+		t.Fatalf("expected no branch to be created")
+	}
+	if data, _ := os.ReadFile(filepath.Join(stray, "notes.txt")); string(data) != "keep me" {
+		t.Fatalf("expected stray directory to be left alone, got %q", data)
+	}
+}
+
+func TestIntegrationAddWorktreeEmptyTarget(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	target := worktreePath(repo, "feature")
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	wtPath, err := addWorktree(repo, repo, addOptions{branch: "feature"})
+	if err != nil || wtPath != target {
+		t.Fatalf("expected worktree in the empty directory, got %q, %v", wtPath, err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "file.txt")); err != nil {
+		t.Fatalf("expected checkout in the empty directory: %v", err)
+	}
+}
+
+func TestIntegrationNewCmdSwitchExisting(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stderr = &bytes.Buffer{}
+
+	existing := setupTestWorktree(t, repo, "feature")
+
+	var buf bytes.Buffer
+	stdout = &buf
+	newCmd([]string{"--switch-existing", "feature"})
+	if strings.TrimSpace(buf.String()) != existing {
+		t.Fatalf("expected existing path %q, got %q", existing, buf.String())
+	}
+
+	buf.Reset()
+	newCmd([]string{"--switch-existing", "fresh"})
+	if strings.TrimSpace(buf.String()) != worktreePath(repo, "fresh") {
+		t.Fatalf("expected new worktree path, got %q", buf.String())
+	}
+}
+
+func TestIntegrationListAll(t *testing.T) {
+	repo := setupTestRepo(t)
+	other := setupTestRepo(t)
+	otherWT := setupTestWorktree(t, other, "feature")
+	defer withDir(t, repo)()
+
+	home := t.TempDir()
+	mustWriteFile(t, filepath.Join(home, ".config", "wt", "config.json"),
+		fmt.Sprintf(`{"repos":[%q,%q]}`, repo, other))
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+	}()
+	osUserHomeDir = func() (string, error) { return home, nil }
+	var buf bytes.Buffer
+	stdout = &buf
+
+	listCmd([]string{"--all"})
+
+	want := fmt.Sprintf("%s\n  main\t%s\n\n%s\n  main\t%s\n  feature\t%s\n", repo, repo, other, other, otherWT)
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+
+	// Repos without a match are left out.
+	buf.Reset()
+	listCmd([]string{"--all", "--filter", "feat*"})
+	want = fmt.Sprintf("%s\n  feature\t%s\n", other, otherWT)
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+
+	// Nothing matching is an empty JSON list, not null.
+	buf.Reset()
+	listCmd([]string{"--all", "--format", "json", "--filter", "nothing*"})
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Fatalf("expected an empty list, got %q", buf.String())
+	}
+}
+
+func TestIntegrationNewCmdInto(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+
+	base := setupTestWorktree(t, repo, "feature")
+	mustWriteFile(t, filepath.Join(base, "feature.txt"), "feature")
+	mustRunCmd(t, base, "git", "add", ".")
+	mustRunCmd(t, base, "git", "commit", "-m", "feature work")
+
+	newCmd([]string{"-C", "--into", filepath.Base(base), "feature-part-2"})
+
+	if _, err := os.Stat(filepath.Join(worktreePath(repo, "feature-part-2"), "feature.txt")); err != nil {
+		t.Fatalf("expected new worktree based on feature branch: %v", err)
+	}
+}
+
+func TestIntegrationPruneMerged(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	// unmerged starts before the merges, so its HEAD lacks them.
+	unmerged := setupTestWorktree(t, repo, "unmerged")
+	mustWriteFile(t, filepath.Join(unmerged, "new.txt"), "new")
+	mustRunCmd(t, unmerged, "git", "add", ".")
+	mustRunCmd(t, unmerged, "git", "commit", "-m", "unmerged work")
+	merged := setupMergedWorktree(t, repo, "merged")
+	dirty := setupMergedWorktree(t, repo, "dirty")
+	mustWriteFile(t, filepath.Join(dirty, "scratch.txt"), "wip")
+	// A fast-forward merge leaves main at the branch's own commit.
+	ff := setupTestWorktree(t, repo, "ff")
+	mustWriteFile(t, filepath.Join(ff, "ff.txt"), "ff")
+	mustRunCmd(t, ff, "git", "add", ".")
+	mustRunCmd(t, ff, "git", "commit", "-m", "ff")
+	mustRunCmd(t, repo, "git", "merge", "--quiet", "--ff-only", "ff")
+	// Git counts a branch without commits of its own as merged, but it is
+	// new work, such as one just made by wt new.
+	fresh := setupTestWorktree(t, repo, "fresh")
+
+	oldOut := stdout
+	defer func() { stdout = oldOut }()
+	var buf bytes.Buffer
+	stdout = &buf
+
+	pruneCmd([]string{"--merged", "-n", "-D"})
+	if !strings.Contains(buf.String(), "would remove "+merged+" (merged)\nwould delete branch merged\n") ||
+		!strings.Contains(buf.String(), "would remove "+ff+" (ff)\nwould delete branch ff\n") ||
+		!strings.Contains(buf.String(), "2 would be removed, 1 skipped (dirty), 0 failed") {
+		t.Fatalf("unexpected dry-run output: %q", buf.String())
+	}
+	if _, err := os.Stat(merged); err != nil {
+		t.Fatalf("dry run removed worktree: %v", err)
+	}
+	if exists, _ := gitBranchExists(repo, "merged"); !exists {
+		t.Fatal("dry run deleted branch")
+	}
+
+	// Run from a worktree whose HEAD doesn't contain the merged branch, so
+	// the branch is deleted for being merged into main, not into HEAD.
+	defer withDir(t, unmerged)()
+	buf.Reset()
+	pruneCmd([]string{"--merged", "--delete-branch"})
+	if !strings.Contains(buf.String(), "2 removed, 1 skipped (dirty), 0 failed") {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+	for _, path := range []string{merged, ff} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s removed, got %v", path, err)
+		}
+	}
+	for _, path := range []string{dirty, unmerged, fresh} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected %s kept: %v", path, err)
+		}
+	}
+	if exists, _ := gitBranchExists(repo, "merged"); exists {
+		t.Fatal("expected merged branch deleted")
+	}
+	if exists, _ := gitBranchExists(repo, "fresh"); !exists {
+		t.Fatal("expected the branch without commits kept")
+	}
+}
+
+func TestIntegrationPruneFailDirty(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	merged := setupMergedWorktree(t, repo, "merged")
+	dirty := setupMergedWorktree(t, repo, "dirty")
+	mustWriteFile(t, filepath.Join(dirty, "scratch.txt"), "wip")
+
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	var outBuf, errBuf bytes.Buffer
+	stdout = &outBuf
+	stderr = &errBuf
+	exitFunc = func(code int) { panic(code) }
+
+	for _, flag := range []string{"--fail-dirty", "--skip-dirty=false"} {
+		errBuf.Reset()
+		func() {
+			defer func() {
+				if r := recover(); r != exitBlocked {
+					t.Fatalf("%s: expected exit 4, got %v", flag, r)
+				}
+			}()
+			pruneCmd([]string{"--merged", flag})
+		}()
+		if !strings.Contains(errBuf.String(), "uncommitted changes in dirty (commit or stash them, or use --skip-dirty)") {
+			t.Fatalf("%s: expected dirty error, got %q", flag, errBuf.String())
+		}
+		if outBuf.Len() != 0 {
+			t.Fatalf("%s: expected nothing done, got %q", flag, outBuf.String())
+		}
+		if _, err := os.Stat(merged); err != nil {
+			t.Fatalf("%s: expected merged worktree kept: %v", flag, err)
+		}
+	}
+
+	pruneCmd([]string{"--merged", "--skip-dirty"})
+	if !strings.Contains(outBuf.String(), "skipped dirty (uncommitted changes)") ||
+		!strings.Contains(outBuf.String(), "1 removed, 1 skipped (dirty), 0 failed") {
+		t.Fatalf("unexpected output: %q", outBuf.String())
+	}
+}
+
+func TestIntegrationProtectedBranches(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"worktree":{"protected":["main","release/*"]}}`)
+
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	var outBuf, errBuf bytes.Buffer
+	stdout = &outBuf
+	stderr = &errBuf
+	exitFunc = func(code int) { panic(code) }
+	expectExit := func(run func(), code int, want string) {
+		t.Helper()
+		errBuf.Reset()
+		func() {
+			defer func() {
+				if r := recover(); r != code {
+					t.Fatalf("expected exit %d, got %v", code, r)
+				}
+			}()
+			run()
+		}()
+		if !strings.Contains(errBuf.String(), want) {
+			t.Fatalf("expected %q, got %q", want, errBuf.String())
+		}
+	}
+
+	release := worktreePath(repo, "release/1.0")
+	expectExit(func() { newCmd([]string{"release/1.0"}) }, exitBlocked, `branch release/1.0 is protected by worktree.protected ("release/*")`)
+	if _, err := os.Stat(release); !os.IsNotExist(err) {
+		t.Fatalf("expected no worktree for a protected branch, got %v", err)
+	}
+
+	newCmd([]string{"--force", "release/1.0"})
+	if _, err := os.Stat(release); err != nil {
+		t.Fatalf("expected --force to create the worktree: %v", err)
+	}
+	mustWriteFile(t, filepath.Join(release, "notes.txt"), "release")
+	mustRunCmd(t, release, "git", "add", "notes.txt")
+	mustRunCmd(t, release, "git", "commit", "-m", "release")
+	mustRunCmd(t, repo, "git", "merge", "--quiet", "--no-ff", "-m", "merge release", "release/1.0")
+
+	pruneCmd([]string{"--merged"})
+	if _, err := os.Stat(release); err != nil {
+		t.Fatalf("expected prune to keep a protected worktree: %v", err)
+	}
+
+	expectExit(func() { rmCmd([]string{"release/1.0"}) }, exitBlocked, "branch release/1.0 is protected")
+	rmCmd([]string{"--force", "release/1.0"})
+	if _, err := os.Stat(release); !os.IsNotExist(err) {
+		t.Fatalf("expected --force to remove the worktree, got %v", err)
+	}
+
+	setupTestWorktree(t, repo, "feature")
+	mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{`)
+	expectExit(func() { rmCmd([]string{"feature"}) }, exitError, "invalid config")
+	expectExit(func() { pruneCmd([]string{"--merged"}) }, exitError, "invalid config")
+}
+
+func TestIntegrationBaseCmd(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	forkPoint := gitOutput(t, repo, "rev-parse", "--short", "HEAD")
+
+	feature := setupTestWorktree(t, repo, "feature")
+	mustWriteFile(t, filepath.Join(feature, "feature.txt"), "feature")
+	mustRunCmd(t, feature, "git", "add", ".")
+	mustRunCmd(t, feature, "git", "commit", "-m", "feature work")
+	mustWriteFile(t, filepath.Join(repo, "main.txt"), "main")
+	mustRunCmd(t, repo, "git", "add", ".")
+	mustRunCmd(t, repo, "git", "commit", "-m", "main work")
+
+	oldOut := stdout
+	defer func() { stdout = oldOut }()
+	var buf bytes.Buffer
+	stdout = &buf
+
+	baseCmd([]string{"feature"})
+	if !strings.HasPrefix(buf.String(), forkPoint+" (main, ") || !strings.HasSuffix(buf.String(), " ago)\n") {
+		t.Fatalf("unexpected output %q (fork point %s)", buf.String(), forkPoint)
+	}
+}
+
+func TestIntegrationRepairMovedWorktree(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	feature := setupTestWorktree(t, repo, "feature")
+	spike := setupTestWorktree(t, repo, "spike")
+	moved := filepath.Join(t.TempDir(), "feature")
+	if err := os.Rename(feature, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(spike); err != nil {
+		t.Fatal(err)
+	}
+
+	oldOut := stdout
+	oldErr := stderr
+	defer func() {
+		stdout = oldOut
+		stderr = oldErr
+	}()
+	var out, errOut bytes.Buffer
+	stdout = &out
+	stderr = &errOut
+
+	listCmd(nil)
+	want := fmt.Sprintf("main\t%s\nfeature\t%s (missing)\nspike\t%s (missing)\n", repo, feature, spike)
+	if out.String() != want {
+		t.Fatalf("expected %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	repairCmd([]string{moved})
+	if out.String() != "repaired "+moved+" (feature)\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), "missing: "+spike+" (spike)") {
+		t.Fatalf("expected spike reported missing, got %q", errOut.String())
+	}
+	if got := gitOutput(t, moved, "rev-parse", "--abbrev-ref", "HEAD"); got != "feature" {
+		t.Fatalf("expected moved worktree usable, got %q", got)
+	}
+
+	out.Reset()
+	repairCmd([]string{"--prune"})
+	if out.String() != "pruned "+spike+" (spike)\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	wts, err := gitWorktrees(repo)
+	if err != nil || len(wts) != 2 {
+		t.Fatalf("expected spike pruned, got %v, %v", wts, err)
+	}
+
+	out.Reset()
+	errOut.Reset()
+	repairCmd(nil)
+	if out.String() != "" || errOut.String() != "" {
+		t.Fatalf("expected nothing to report, got %q and %q", out.String(), errOut.String())
+	}
+}
+
+func TestIntegrationGitDefaultBranchOriginHead(t *testing.T) {
+	origin := setupTestRepo(t)
+	clone := filepath.Join(t.TempDir(), "clone")
+	mustRunCmd(t, "", "git", "clone", "-q", origin, clone)
+
+	got, err := gitDefaultBranch(clone)
+	if err != nil || got != "main" {
+		t.Fatalf("expected main, got %q, %v", got, err)
+	}
+
+	mustRunCmd(t, clone, "git", "checkout", "-q", "--detach")
+	mustRunCmd(t, clone, "git", "branch", "-D", "main")
+	got, err = gitDefaultBranch(clone)
+	if err != nil || got != "origin/main" {
+		t.Fatalf("expected origin/main, got %q, %v", got, err)
+	}
+}
+
+func TestIntegrationTmuxSessionNameFromBranch(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"tmux":{"sessionNameFrom":"branch"}}`)
+
+	oldHome := osUserHomeDir
+	defer func() { osUserHomeDir = oldHome }()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+
+	wt := setupTestWorktree(t, repo, "feature/login")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tmuxSessionName(cfg, wt)
+	if err != nil || got != "feature-login" {
+		t.Fatalf("expected feature-login, got %q, %v", got, err)
+	}
+}
+
+func TestIntegrationListAllPorcelain(t *testing.T) {
+	repo := setupTestRepo(t)
+	other := setupTestRepo(t)
+	otherWT := setupTestWorktree(t, other, "feature")
+	mustWriteFile(t, filepath.Join(otherWT, "scratch.txt"), "wip")
+	defer withDir(t, repo)()
+
+	home := t.TempDir()
+	mustWriteFile(t, filepath.Join(home, ".config", "wt", "config.json"),
+		fmt.Sprintf(`{"repos":[%q]}`, other))
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+	}()
+	osUserHomeDir = func() (string, error) { return home, nil }
+	var buf bytes.Buffer
+	stdout = &buf
+
+	listCmd([]string{"--all", "--format", "porcelain"})
+
+	head := gitOutput(t, repo, "rev-parse", "--short", "HEAD")
+	otherHead := gitOutput(t, other, "rev-parse", "--short", "HEAD")
+	want := fmt.Sprintf("repo %s\nbranch main\npath %s\nclean true\nhead %s\n\n"+
+		"repo %s\nbranch main\npath %s\nclean true\nhead %s\n\n"+
+		"repo %s\nbranch feature\npath %s\nclean false\nhead %s\n\n", repo, repo, head, other, other, otherHead, other, otherWT, otherHead)
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestIntegrationRmCmd(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	wt := setupTestWorktree(t, repo, "feature")
+
+	oldOut := stdout
+	defer func() { stdout = oldOut }()
+	var buf bytes.Buffer
+	stdout = &buf
+
+	rmCmd([]string{"feature"})
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Fatalf("expected worktree removed, got %v", err)
+	}
+	if buf.String() != "removed "+wt+"\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+// runNewStash runs wt new --stash with args in repo and returns the exit
+// code (0 when newCmd returns normally) and stderr.
+func runNewStash(t *testing.T, repo string, args ...string) (int, string) {
+	t.Helper()
+	defer withDir(t, repo)()
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	exitFunc = func(code int) { panic(code) }
+
+	code := func() (code int) {
+		defer func() {
+			if r := recover(); r != nil {
+				code = r.(int)
+			}
+		}()
+		newCmd(append([]string{"--stash", "-C"}, args...))
+		return 0
+	}()
+	return code, errBuf.String()
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v (%s)", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestIntegrationNewCmdStash(t *testing.T) {
+	repo := setupTestRepo(t)
+	mustWriteFile(t, filepath.Join(repo, "file.txt"), "changed")
+	mustWriteFile(t, filepath.Join(repo, "new.txt"), "untracked")
+
+	code, errOut := runNewStash(t, repo, "feature")
+	if code != 0 {
+		t.Fatalf("expected success, got exit %d: %s", code, errOut)
+	}
+
+	wt := worktreePath(repo, "feature")
+	for name, want := range map[string]string{"file.txt": "changed", "new.txt": "untracked"} {
+		data, err := os.ReadFile(filepath.Join(wt, name))
+		if err != nil || string(data) != want {
+			t.Fatalf("%s: expected %q in new worktree, got %q, %v", name, want, data, err)
+		}
+	}
+	if status := gitOutput(t, repo, "status", "--porcelain"); status != "" {
+		t.Fatalf("expected original worktree clean, got %q", status)
+	}
+	if list := gitOutput(t, repo, "stash", "list"); list != "" {
+		t.Fatalf("expected stash dropped, got %q", list)
+	}
+}
+
+func TestIntegrationNewCmdStashNothingToMove(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	code, errOut := runNewStash(t, repo, "feature")
+	if code != 0 || !strings.Contains(errOut, "no uncommitted changes to move") {
+		t.Fatalf("expected notice, got exit %d: %q", code, errOut)
+	}
+	if _, err := os.Stat(worktreePath(repo, "feature")); err != nil {
+		t.Fatalf("expected worktree created: %v", err)
+	}
+}
+
+func TestIntegrationNewCmdStashCreateFails(t *testing.T) {
+	repo := setupTestRepo(t)
+	mustWriteFile(t, filepath.Join(repo, "file.txt"), "changed")
+
+	// main is already checked out in repo, so git refuses a second worktree.
+	code, _ := runNewStash(t, repo, "main")
+	if code != 1 {
+		t.Fatalf("expected exit 1, got %d", code)
+	}
+	data, _ := os.ReadFile(filepath.Join(repo, "file.txt"))
+	if string(data) != "changed" {
+		t.Fatalf("expected changes restored in original worktree, got %q", data)
+	}
+	if list := gitOutput(t, repo, "stash", "list"); list != "" {
+		t.Fatalf("expected stash dropped after restore, got %q", list)
+	}
+}
+
+func TestIntegrationNewCmdStashApplyConflict(t *testing.T) {
+	repo := setupTestRepo(t)
+	mustRunCmd(t, repo, "git", "checkout", "-q", "-b", "other")
+	mustWriteFile(t, filepath.Join(repo, "file.txt"), "other")
+	mustRunCmd(t, repo, "git", "commit", "-q", "-am", "other")
+	mustRunCmd(t, repo, "git", "checkout", "-q", "main")
+	mustWriteFile(t, filepath.Join(repo, "file.txt"), "changed")
+	mustWriteFile(t, filepath.Join(repo, "notes", "new.txt"), "new")
+
+	code, errOut := runNewStash(t, repo, "-f", "other", "feature")
+	if code != 1 || !strings.Contains(errOut, "still in the stash") {
+		t.Fatalf("expected apply failure, got exit %d: %q", code, errOut)
+	}
+	wt := worktreePath(repo, "feature")
+	if status := gitOutput(t, wt, "status", "--porcelain"); status != "" {
+		t.Fatalf("expected new worktree left clean, got %q", status)
+	}
+	if _, err := os.Stat(filepath.Join(wt, "notes", "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected untracked file from the stash removed, got %v", err)
+	}
+	if list := gitOutput(t, repo, "stash", "list"); !strings.Contains(list, "wt new feature") {
+		t.Fatalf("expected stash kept, got %q", list)
+	}
+}
+
+func TestIntegrationNewCmdInitSubmodules(t *testing.T) {
+	sub := setupTestRepo(t)
+	repo := setupTestRepo(t)
+	// Local-path submodules need the file transport, which git disables
+	// for submodules by default; set it via the environment so every git
+	// subprocess (including wt's own) sees it.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+	mustRunCmd(t, repo, "git", "submodule", "add", "-q", sub, "lib")
+	mustRunCmd(t, repo, "git", "commit", "-q", "-m", "add submodule")
+	mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"worktree":{"initSubmodules":true}}`)
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+
+	newCmd([]string{"-C", "feature"})
+
+	if _, err := os.Stat(filepath.Join(worktreePath(repo, "feature"), "lib", "file.txt")); err != nil {
+		t.Fatalf("expected submodule checked out in new worktree: %v", err)
+	}
+}
+
+func TestIntegrationCommandsOutsideRepo(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+	defer withDir(t, dir)()
+
+	cmds := map[string]struct {
+		fn   func([]string)
+		args []string
+	}{
+		"new":   {newCmd, []string{"feature"}},
+		"list":  {listCmd, nil},
+		"go":    {goCmd, []string{"feature"}},
+		"t":     {tmuxCmd, []string{"feature"}},
+		"rm":    {rmCmd, []string{"feature"}},
+		"prune": {pruneCmd, []string{"--merged"}},
+	}
+	for name, cmd := range cmds {
+		t.Run(name, func(t *testing.T) {
+			oldExit := exitFunc
+			oldErr := stderr
+			defer func() {
+				exitFunc = oldExit
+				stderr = oldErr
+			}()
+			var buf bytes.Buffer
+			stderr = &buf
+			exitFunc = func(code int) { panic(code) }
+
+			func() {
+				defer func() {
+					if r := recover(); r != 1 {
+						t.Fatalf("expected exit 1, got %v", r)
+					}
+				}()
+				cmd.fn(cmd.args)
+			}()
+			if got := strings.TrimSpace(buf.String()); got != errNotInRepo.Error() {
+				t.Fatalf("expected friendly error, got %q", got)
+			}
+		})
+	}
+}
+
+// setupTestClone clones a new test repo and returns the origin and the
+// clone.
+func setupTestClone(t *testing.T) (string, string) {
+	t.Helper()
+	origin := setupTestRepo(t)
+	clone := filepath.Join(t.TempDir(), "clone")
+	mustRunCmd(t, origin, "git", "clone", "--quiet", origin, clone)
+	mustRunCmd(t, clone, "git", "config", "user.email", "test@example.com")
+	mustRunCmd(t, clone, "git", "config", "user.name", "Test")
+	mustRunCmd(t, clone, "git", "config", "commit.gpgsign", "false")
+	return origin, clone
+}
+
+func TestRemoteTrackingBranch(t *testing.T) {
+	origin, clone := setupTestClone(t)
+	mustRunCmd(t, origin, "git", "branch", "feature")
+
+	// Not fetched yet: fetchNever doesn't see it, the default fetches it.
+	if ref, err := remoteTrackingBranch(clone, "feature", fetchNever); ref != "" || err != nil {
+		t.Fatalf("expected no ref without fetching, got %q, %v", ref, err)
+	}
+	if ref, err := remoteTrackingBranch(clone, "feature", fetchAuto); ref != "origin/feature" || err != nil {
+		t.Fatalf("expected origin/feature, got %q, %v", ref, err)
+	}
+	if ref, err := remoteTrackingBranch(clone, "feature", fetchNever); ref != "origin/feature" || err != nil {
+		t.Fatalf("expected the fetched ref, got %q, %v", ref, err)
+	}
+
+	// Once fetched, only fetchAlways picks up new commits.
+	mustWriteFile(t, filepath.Join(origin, "new.txt"), "new")
+	mustRunCmd(t, origin, "git", "add", ".")
+	mustRunCmd(t, origin, "git", "commit", "-m", "new")
+	mustRunCmd(t, origin, "git", "branch", "-f", "feature", "main")
+	head := gitOutput(t, origin, "rev-parse", "main")
+	remoteTrackingBranch(clone, "feature", fetchAuto)
+	if got := gitOutput(t, clone, "rev-parse", "origin/feature"); got == head {
+		t.Fatal("expected the default not to fetch a known branch again")
+	}
+	remoteTrackingBranch(clone, "feature", fetchAlways)
+	if got := gitOutput(t, clone, "rev-parse", "origin/feature"); got != head {
+		t.Fatalf("expected --fetch to update origin/feature to %s, got %s", head, got)
+	}
+
+	if ref, err := remoteTrackingBranch(clone, "brand-new", fetchAlways); ref != "" || err != nil {
+		t.Fatalf("expected a branch missing on the remote to be new, got %q, %v", ref, err)
+	}
+	if ref, err := remoteTrackingBranch(origin, "feature", fetchAlways); ref != "" || err != nil {
+		t.Fatalf("expected no ref without a remote, got %q, %v", ref, err)
+	}
+
+	// Unreachable: the default warns and falls back, --fetch fails.
+	mustRunCmd(t, clone, "git", "remote", "set-url", "origin", filepath.Join(t.TempDir(), "gone"))
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	if ref, err := remoteTrackingBranch(clone, "other", fetchAuto); ref != "" || err != nil {
+		t.Fatalf("expected the default to fall back to the local base, got %q, %v", ref, err)
+	}
+	if !strings.Contains(errBuf.String(), "warning: could not fetch other from origin, creating it from the local base") {
+		t.Fatalf("expected a fetch warning, got %q", errBuf.String())
+	}
+	_, err := remoteTrackingBranch(clone, "other", fetchAlways)
+	if err == nil || !strings.Contains(err.Error(), "could not fetch other from origin") || !strings.Contains(err.Error(), "pass --no-fetch") {
+		t.Fatalf("expected an actionable fetch error, got %v", err)
+	}
+}
+
+func TestRemoteTrackingBranchGitErrors(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	for _, fail := range []string{"remote", "show-ref", "fetch"} {
+		execCommand = func(name string, args ...string) *exec.Cmd {
+			switch {
+			case slices.Contains(args, fail) && fail != "fetch":
+				return exec.Command("does-not-exist")
+			case slices.Contains(args, "remote"):
+				return cmdWithOutput("origin\n")
+			case slices.Contains(args, "fetch"):
+				return exec.Command("sh", "-c", "exit 0")
+			}
+			// show-ref: the ref is missing before and after the fetch.
+			if fail == "fetch" {
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		ref, err := remoteTrackingBranch("/repo", "feature", fetchAuto)
+		if fail == "fetch" {
+			if ref != "" || err != nil {
+				t.Errorf("expected no ref when the fetch leaves none, got %q, %v", ref, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("expected error when git %s fails", fail)
+		}
+	}
+}
+
+func TestIntegrationNewCmdRemoteBranch(t *testing.T) {
+	origin, clone := setupTestClone(t)
+	mustRunCmd(t, origin, "git", "branch", "feature")
+	// Tracking must not depend on git setting up upstreams by itself.
+	mustRunCmd(t, clone, "git", "config", "branch.autoSetupMerge", "false")
+	defer withDir(t, clone)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+
+	newCmd([]string{"--no-fetch", "solo"})
+	if got := gitOutput(t, clone, "for-each-ref", "--format=%(upstream:short)", "refs/heads/solo"); got != "" {
+		t.Fatalf("expected a new branch without upstream, got %q", got)
+	}
+
+	newCmd([]string{"--fetch", "feature"})
+	if got := gitOutput(t, clone, "rev-parse", "--abbrev-ref", "feature@{upstream}"); got != "origin/feature" {
+		t.Fatalf("expected feature to track origin/feature, got %q", got)
+	}
+	if !strings.Contains(errBuf.String(), "creating feature from origin/feature") {
+		t.Fatalf("expected remote base to be reported, got %q", errBuf.String())
+	}
+
+	exitFunc = func(code int) { panic(code) }
+	func() {
+		defer func() {
+			if r := recover(); r != exitUsage {
+				t.Fatalf("expected usage exit, got %v", r)
+			}
+		}()
+		newCmd([]string{"--fetch", "--no-fetch", "other"})
+	}()
+	if !strings.Contains(errBuf.String(), "--fetch and --no-fetch cannot be used together") {
+		t.Fatalf("expected flag conflict error, got %q", errBuf.String())
+	}
+}
+
+func TestIntegrationNewCmdBaseRemoteDefault(t *testing.T) {
+	origin, clone := setupTestClone(t)
+	// The clone's main falls behind the remote's.
+	mustWriteFile(t, filepath.Join(origin, "new.txt"), "new")
+	mustRunCmd(t, origin, "git", "add", ".")
+	mustRunCmd(t, origin, "git", "commit", "--quiet", "-m", "new")
+	defer withDir(t, clone)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+
+	newCmd([]string{"--base-remote-default", "fresh"})
+	if got, want := gitOutput(t, clone, "rev-parse", "fresh"), gitOutput(t, origin, "rev-parse", "main"); got != want {
+		t.Fatalf("expected fresh at the remote's main %s, got %s", want, got)
+	}
+	if got := gitOutput(t, clone, "for-each-ref", "--format=%(upstream:short)", "refs/heads/fresh"); got != "" {
+		t.Fatalf("expected fresh not to track the default branch, got %q", got)
+	}
+	if !strings.Contains(errBuf.String(), "creating fresh from origin/main") {
+		t.Fatalf("expected the base to be reported, got %q", errBuf.String())
+	}
+
+	exitFunc = func(code int) { panic(code) }
+	func() {
+		defer func() {
+			if r := recover(); r != exitUsage {
+				t.Fatalf("expected usage exit, got %v", r)
+			}
+		}()
+		newCmd([]string{"--base-remote-default", "--from", "main", "other"})
+	}()
+	if !strings.Contains(errBuf.String(), "--base-remote-default cannot be used with --from or --into") {
+		t.Fatalf("expected flag conflict error, got %q", errBuf.String())
+	}
+
+	// Offline, the default uses the last fetched origin/main; --fetch fails.
+	mustRunCmd(t, clone, "git", "remote", "set-url", "origin", filepath.Join(t.TempDir(), "gone"))
+	errBuf.Reset()
+	newCmd([]string{"--base-remote-default", "offline"})
+	if !strings.Contains(errBuf.String(), "warning: could not fetch main from origin, using the local copy") ||
+		!strings.Contains(errBuf.String(), "creating offline from origin/main") {
+		t.Fatalf("expected a fetch warning and origin/main, got %q", errBuf.String())
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != exitError {
+				t.Fatalf("expected exit %d, got %v", exitError, r)
+			}
+		}()
+		newCmd([]string{"--base-remote-default", "--fetch", "offline-fetch"})
+	}()
+	if !strings.Contains(errBuf.String(), "could not fetch main from origin: ") {
+		t.Fatalf("expected fetch error, got %q", errBuf.String())
+	}
+}
+
+func TestRemoteDefaultBase(t *testing.T) {
+	origin, clone := setupTestClone(t)
+	if got, err := remoteDefaultBase(clone, fetchAlways); err != nil || got != "origin/main" {
+		t.Fatalf("expected origin/main from origin/HEAD, got %q, %v", got, err)
+	}
+
+	// Without origin/HEAD, main and then master are tried.
+	mustRunCmd(t, clone, "git", "remote", "set-head", "origin", "--delete")
+	if got, err := remoteDefaultBase(clone, fetchNever); err != nil || got != "origin/main" {
+		t.Fatalf("expected origin/main without fetching, got %q, %v", got, err)
+	}
+	mustRunCmd(t, origin, "git", "branch", "-m", "main", "master")
+	if got, err := remoteDefaultBase(clone, fetchAlways); err != nil || got != "origin/master" {
+		t.Fatalf("expected origin/master, got %q, %v", got, err)
+	}
+	mustRunCmd(t, origin, "git", "branch", "-m", "master", "trunk")
+	mustRunCmd(t, clone, "git", "update-ref", "-d", "refs/remotes/origin/master")
+	if _, err := remoteDefaultBase(clone, fetchAlways); err == nil || !strings.Contains(err.Error(), "could not determine the default branch of origin") {
+		t.Fatalf("expected no default branch, got %v", err)
+	}
+
+	if _, err := remoteDefaultBase(setupTestRepo(t), fetchAlways); err == nil || !strings.Contains(err.Error(), "no remote to take the default branch from") {
+		t.Fatalf("expected no remote, got %v", err)
+	}
+}
+
+func TestRemoteDefaultBaseGitErrors(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	for _, fail := range []string{"remote", "show-ref"} {
+		execCommand = func(name string, args ...string) *exec.Cmd {
+			if len(args) > 0 && args[0] == "-C" {
+				args = args[2:]
+			}
+			switch {
+			case args[0] == fail:
+				// Not an exit status, so show-ref can't be read as "missing".
+				return exec.Command(filepath.Join(t.TempDir(), "missing"))
+			case args[0] == "remote":
+				return cmdWithOutput("origin\n")
+			}
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		if _, err := remoteDefaultBase("/repo", fetchNever); err == nil {
+			t.Fatalf("%s: expected error", fail)
+		}
+	}
+}
+
+func TestGitCurrentBase(t *testing.T) {
+	repo := setupTestRepo(t)
+	if got, err := gitCurrentBase(repo); err != nil || got != "main" {
+		t.Fatalf("expected main, got %q, %v", got, err)
+	}
+	mustRunCmd(t, repo, "git", "checkout", "--quiet", "--detach")
+	short := gitOutput(t, repo, "rev-parse", "--short", "HEAD")
+	if got, err := gitCurrentBase(repo); err != nil || got != short {
+		t.Fatalf("expected %s when detached, got %q, %v", short, got, err)
+	}
+	if _, err := gitCurrentBase(t.TempDir()); err == nil {
+		t.Fatal("expected error outside a repository")
+	}
+}
+
+func TestGitCurrentBaseShortError(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if strings.Contains(strings.Join(args, " "), "--short") {
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		return cmdWithOutput("HEAD\n")
+	}
+	if _, err := gitCurrentBase("/repo"); err == nil {
+		t.Fatal("expected error when the short SHA can't be read")
+	}
+}
+
+func TestNewBranchBase(t *testing.T) {
+	origin, clone := setupTestClone(t)
+	mustRunCmd(t, origin, "git", "branch", "feature")
+	if got, tracking, err := newBranchBase(clone, "feature", fetchAlways); err != nil || got != "origin/feature" || !tracking {
+		t.Fatalf("expected to track origin/feature, got %q, %v, %v", got, tracking, err)
+	}
+	if got, tracking, err := newBranchBase(clone, "solo", fetchNever); err != nil || got != "main" || tracking {
+		t.Fatalf("expected main, got %q, %v, %v", got, tracking, err)
+	}
+}
+
+func TestIntegrationNewCmdBaseFromLinkedWorktree(t *testing.T) {
+	repo := setupTestRepo(t)
+	linked := setupTestWorktree(t, repo, "topic")
+	mustWriteFile(t, filepath.Join(linked, "topic.txt"), "topic")
+	mustRunCmd(t, linked, "git", "add", "topic.txt")
+	mustRunCmd(t, linked, "git", "commit", "--quiet", "-m", "topic")
+	defer withDir(t, linked)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+
+	newCmd([]string{"--no-fetch", "feature"})
+	if !strings.Contains(errBuf.String(), "creating feature from topic") {
+		t.Fatalf("expected base to be reported, got %q", errBuf.String())
+	}
+	want := gitOutput(t, linked, "rev-parse", "topic")
+	if got := gitOutput(t, repo, "rev-parse", "feature"); got != want {
+		t.Fatalf("expected feature at topic's %s, got %s", want, got)
+	}
+}
+
+func TestNewCmdBaseError(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	oldExec := execCommand
+	oldHome := osUserHomeDir
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		execCommand = oldExec
+		osUserHomeDir = oldHome
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if strings.Contains(strings.Join(args, " "), "--abbrev-ref HEAD") {
+			return exec.Command("sh", "-c", "echo no head >&2; exit 1")
+		}
+		return oldExec(name, args...)
+	}
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	exitFunc = func(code int) { panic(code) }
+
+	defer func() {
+		if r := recover(); r != exitError {
+			t.Fatalf("expected error exit, got %v", r)
+		}
+		if !strings.Contains(errBuf.String(), "no head") {
+			t.Fatalf("expected base error, got %q", errBuf.String())
+		}
+	}()
+	newCmd([]string{"--no-fetch", "feature"})
+}
+
+func TestIntegrationGoCmdCreate(t *testing.T) {
+	repo := setupTestRepo(t)
+	setupTestWorktree(t, repo, "feature-a")
+	setupTestWorktree(t, repo, "feature-b")
+	setupTestWorktree(t, repo, "bugfix-123")
+	defer withDir(t, repo)()
+
+	oldExec := execCommand
+	oldHome := osUserHomeDir
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		execCommand = oldExec
+		osUserHomeDir = oldHome
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	t.Setenv("SHELL", "/bin/sh")
+	var opened []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name == "/bin/sh" {
+			cmd := exec.Command("sh", "-c", "exit 0")
+			opened = append(opened, "shell")
+			return cmd
+		}
+		return oldExec(name, args...)
+	}
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	exitFunc = func(code int) { panic(code) }
+
+	expectExit := func(want int, args ...string) {
+		t.Helper()
+		defer func() {
+			if r := recover(); r != want {
+				t.Fatalf("wt go %v: expected exit %d, got %v", args, want, r)
+			}
+		}()
+		goCmd(args)
+	}
+
+	expectExit(exitNotFound, "spike")
+	expectExit(exitUsage, "feature")
+	if !strings.Contains(errBuf.String(), "matches several worktrees") {
+		t.Fatalf("expected ambiguous match to fail, got %q", errBuf.String())
+	}
+	expectExit(exitUsage, "--create")
+
+	errBuf.Reset()
+	goCmd([]string{"--create", "spike"})
+	wtPath := filepath.Join(repo+"-worktrees", "spike")
+	if got := gitOutput(t, wtPath, "rev-parse", "--abbrev-ref", "HEAD"); got != "spike" {
+		t.Fatalf("expected spike checked out in %s, got %q", wtPath, got)
+	}
+	if !strings.Contains(errBuf.String(), "creating spike from main") || !strings.Contains(errBuf.String(), "created worktree "+wtPath) {
+		t.Fatalf("expected creation to be reported, got %q", errBuf.String())
+	}
+
+	errBuf.Reset()
+	goCmd([]string{"--create", "spike"})
+	if strings.Contains(errBuf.String(), "created") {
+		t.Fatalf("expected the existing worktree to be reused, got %q", errBuf.String())
+	}
+	if len(opened) != 2 {
+		t.Fatalf("expected a shell for each successful wt go, got %d", len(opened))
+	}
+
+	// Part of a worktree's name is a new branch with --create, not the
+	// worktree it is part of.
+	errBuf.Reset()
+	goCmd([]string{"--create", "fix"})
+	if !strings.Contains(errBuf.String(), "created worktree "+filepath.Join(repo+"-worktrees", "fix")) {
+		t.Fatalf("expected fix created rather than bugfix-123 opened, got %q", errBuf.String())
+	}
+	errBuf.Reset()
+	goCmd([]string{"--create", "feature"})
+	if !strings.Contains(errBuf.String(), "created worktree "+filepath.Join(repo+"-worktrees", "feature")) {
+		t.Fatalf("expected feature created, got %q", errBuf.String())
+	}
+
+	// A worktree that can't be created fails as wt new would.
+	mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"worktree":{"protected":["release/*"]}}`)
+	errBuf.Reset()
+	expectExit(exitBlocked, "--create", "release/1")
+	if !strings.Contains(errBuf.String(), "branch release/1 is protected") {
+		t.Fatalf("expected the protected branch refused, got %q", errBuf.String())
+	}
+	if err := os.Remove(filepath.Join(repo, ".wt.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	mustRunCmd(t, repo, "git", "branch", "existing")
+	errBuf.Reset()
+	goCmd([]string{"--create", "existing"})
+	if strings.Contains(errBuf.String(), "creating existing from") {
+		t.Fatalf("expected the existing branch to be checked out, got %q", errBuf.String())
+	}
+	if got := gitOutput(t, filepath.Join(repo+"-worktrees", "existing"), "rev-parse", "--abbrev-ref", "HEAD"); got != "existing" {
+		t.Fatalf("expected existing checked out, got %q", got)
+	}
+}
+
+func TestCreateWorktreeForErrors(t *testing.T) {
+	oldExec := execCommand
+	oldHome := osUserHomeDir
+	oldRead := osReadFile
+	defer func() {
+		execCommand = oldExec
+		osUserHomeDir = oldHome
+		osReadFile = oldRead
+	}()
+	home := t.TempDir()
+	repo := t.TempDir()
+	osUserHomeDir = func() (string, error) { return home, nil }
+
+	for _, fail := range []string{"worktree list", "config", "refs/heads/", "--abbrev-ref", "worktree add"} {
+		osReadFile = oldRead
+		if fail == "config" {
+			osReadFile = func(string) ([]byte, error) { return []byte("{"), nil }
+		}
+		execCommand = func(name string, args ...string) *exec.Cmd {
+			joined := strings.Join(args, " ")
+			switch {
+			case strings.Contains(joined, fail):
+				// does-not-exist fails without an exit status, which
+				// gitBranchExists can't mistake for a missing branch.
+				return exec.Command("does-not-exist")
+			case strings.Contains(joined, "worktree list"):
+				return cmdWithOutput("worktree " + repo + "\nbranch refs/heads/main\n\n")
+			case strings.Contains(joined, "refs/heads/"), strings.Contains(joined, "refs/remotes/"):
+				return exec.Command("sh", "-c", "exit 1")
+			case strings.Contains(joined, "--abbrev-ref"):
+				return cmdWithOutput("main\n")
+			}
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		if _, err := createWorktreeFor(repo, "spike"); err == nil {
+			t.Errorf("expected error when %s fails", fail)
+		}
+	}
+}
+
+func TestCreateWorktreeForRemoteBranch(t *testing.T) {
+	origin, clone := setupTestClone(t)
+	mustRunCmd(t, origin, "git", "branch", "feature")
+	mustRunCmd(t, clone, "git", "config", "branch.autoSetupMerge", "false")
+
+	oldHome := osUserHomeDir
+	oldErr := stderr
+	defer func() {
+		osUserHomeDir = oldHome
+		stderr = oldErr
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stderr = &bytes.Buffer{}
+
+	if _, err := createWorktreeFor(clone, "feature"); err != nil {
+		t.Fatalf("createWorktreeFor: %v", err)
+	}
+	if got := gitOutput(t, clone, "rev-parse", "--abbrev-ref", "feature@{upstream}"); got != "origin/feature" {
+		t.Fatalf("expected feature to track origin/feature, got %q", got)
+	}
+}
+
+func TestGoCmdCreateOutsideRepo(t *testing.T) {
+	defer withDir(t, t.TempDir())()
+	oldErr := stderr
+	oldExit := exitFunc
+	defer func() {
+		stderr = oldErr
+		exitFunc = oldExit
+	}()
+	stderr = &bytes.Buffer{}
+	exitFunc = func(code int) { panic(code) }
+	defer func() {
+		if r := recover(); r != exitError {
+			t.Fatalf("expected error exit, got %v", r)
+		}
+	}()
+	goCmd([]string{"--create", "spike"})
+}
+
+func TestIntegrationListCurrent(t *testing.T) {
+	repo := setupTestRepo(t)
+	wtPath := setupTestWorktree(t, repo, "feature")
+	sub := filepath.Join(wtPath, "sub")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	defer withDir(t, sub)()
+
+	oldOut := stdout
+	defer func() { stdout = oldOut }()
+	var buf bytes.Buffer
+	stdout = &buf
+
+	listCmd([]string{"--current", "--bare"})
+	if got := strings.TrimSpace(buf.String()); got != gitOutput(t, wtPath, "rev-parse", "--show-toplevel") {
+		t.Fatalf("expected the feature worktree, got %q", got)
+	}
+}
+
+func TestIntegrationNewCmdTimings(t *testing.T) {
+	repo := setupTestRepo(t)
+	mustWriteFile(t, filepath.Join(repo, "node_modules", "a.js"), "a")
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	stdout = &bytes.Buffer{}
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+
+	newCmd([]string{"--timings", "-l", "timed"})
+	for _, phase := range []string{"timings:", "setup", "checks", "git worktree add", "config copy", "libs copy", "templates", "total"} {
+		if !strings.Contains(errBuf.String(), phase) {
+			t.Errorf("expected %q in timings, got %q", phase, errBuf.String())
+		}
+	}
+
+	errBuf.Reset()
+	newCmd([]string{"untimed"})
+	if strings.Contains(errBuf.String(), "timings:") {
+		t.Fatalf("expected no timings without --timings, got %q", errBuf.String())
+	}
+}
+
+func TestIntegrationListUpstreamGone(t *testing.T) {
+	origin, clone := setupTestClone(t)
+	mustRunCmd(t, origin, "git", "branch", "feature")
+	mustRunCmd(t, clone, "git", "fetch", "--quiet")
+	wtPath := filepath.Join(t.TempDir(), "feature")
+	mustRunCmd(t, clone, "git", "worktree", "add", "--quiet", "--track", "-b", "feature", wtPath, "origin/feature")
+	mustRunCmd(t, origin, "git", "branch", "-D", "feature")
+	mustRunCmd(t, clone, "git", "fetch", "--quiet", "--prune")
+	defer withDir(t, clone)()
+
+	gone, err := gitGoneBranches(clone)
+	if err != nil || !reflect.DeepEqual(gone, map[string]bool{"feature": true}) {
+		t.Fatalf("expected only feature gone, got %v, %v", gone, err)
+	}
+	if clean, err := gitWorktreeClean(wtPath); err != nil || !clean {
+		t.Fatalf("expected the gone worktree to read clean, got %v, %v", clean, err)
+	}
+
+	oldOut := stdout
+	defer func() { stdout = oldOut }()
+	var buf bytes.Buffer
+	stdout = &buf
+
+	listCmd(nil)
+	if !strings.Contains(buf.String(), "feature\t"+wtPath+" ↯ gone\n") {
+		t.Fatalf("expected feature marked gone, got %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "main\t"+clone+" ↯") {
+		t.Fatalf("expected main not marked gone, got %q", buf.String())
+	}
+
+	buf.Reset()
+	listCmd([]string{"--format", "porcelain"})
+	if !strings.Contains(buf.String(), "path "+wtPath+"\nclean true\nupstream-gone\n") {
+		t.Fatalf("expected upstream-gone field, got %q", buf.String())
+	}
+
+	buf.Reset()
+	listCmd([]string{"--format", "json"})
+	var entries []listEntry
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].UpstreamGone || !entries[1].UpstreamGone {
+		t.Fatalf("expected only the feature entry gone, got %+v", entries)
+	}
+}
+
+func TestIntegrationNewCmdCdFile(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+	}()
+	osUserHomeDir = func() (string, error) { return t.TempDir(), nil }
+	var outBuf bytes.Buffer
+	stdout = &outBuf
+	stderr = &bytes.Buffer{}
+
+	cdFile := filepath.Join(t.TempDir(), "cd")
+	newCmd([]string{"--cd-file", cdFile, "feature"})
+	wtPath := strings.TrimSpace(outBuf.String())
+	if data, err := os.ReadFile(cdFile); err != nil || string(data) != wtPath+"\n" {
+		t.Fatalf("expected %s in the cd file, got %q, %v", wtPath, data, err)
+	}
+
+	// An existing worktree reused by --switch-existing is written too.
+	_ = os.Remove(cdFile)
+	newCmd([]string{"--switch-existing", "--cd-file", cdFile, "feature"})
+	if data, err := os.ReadFile(cdFile); err != nil || string(data) != wtPath+"\n" {
+		t.Fatalf("expected %s in the cd file, got %q, %v", wtPath, data, err)
+	}
+}
+
+func TestIntegrationNewCmdGitConfig(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+
+	home := t.TempDir()
+	mustWriteFile(t, filepath.Join(home, ".config", "wt", "config.json"), `{"worktree": {"gitConfig": {"user.email": "me@client.example"}}}`)
+	// A repo's own git config could run code, so it is ignored.
+	mustWriteFile(t, filepath.Join(repo, ".wt.json"), `{"worktree": {"gitConfig": {"core.hooksPath": "evil"}}}`)
+	oldHome := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	defer func() {
+		osUserHomeDir = oldHome
+		stdout = oldOut
+		stderr = oldErr
+	}()
+	osUserHomeDir = func() (string, error) { return home, nil }
+	var outBuf bytes.Buffer
+	stdout = &outBuf
+	stderr = &bytes.Buffer{}
+
+	newCmd([]string{"client"})
+	wtPath := strings.TrimSpace(outBuf.String())
+	if got := gitOutput(t, wtPath, "config", "user.email"); got != "me@client.example" {
+		t.Fatalf("expected the worktree's user.email set, got %q", got)
+	}
+	if got := gitOutput(t, repo, "config", "user.email"); got != "test@example.com" {
+		t.Fatalf("expected the main worktree's user.email kept, got %q", got)
+	}
+	if err := exec.Command("git", "-C", wtPath, "config", "core.hooksPath").Run(); err == nil {
+		t.Fatal("expected the repo config's core.hooksPath ignored")
+	}
+}
+
+func TestIntegrationGitConfigBareRepo(t *testing.T) {
+	src := setupTestRepo(t)
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	mustRunCmd(t, src, "git", "clone", "--quiet", "--bare", src, bare)
+	wtPath := filepath.Join(t.TempDir(), "feature")
+	mustRunCmd(t, bare, "git", "worktree", "add", "--quiet", "-b", "feature", wtPath)
+
+	stderr = &bytes.Buffer{}
+	defer func() { stderr = os.Stderr }()
+	applyGitConfig(bare, wtPath, map[string]string{"user.email": "me@client.example"})
+
+	if got := gitOutput(t, wtPath, "rev-parse", "--is-bare-repository"); got != "false" {
+		t.Fatalf("expected the linked worktree not to become bare, got %q", got)
+	}
+	if got := gitOutput(t, bare, "rev-parse", "--is-bare-repository"); got != "true" {
+		t.Fatalf("expected the repository to stay bare, got %q", got)
+	}
+	if got := gitOutput(t, wtPath, "config", "user.email"); got != "me@client.example" {
+		t.Fatalf("expected user.email set, got %q", got)
+	}
+}
+
+func TestIntegrationTUIRemoteBranch(t *testing.T) {
+	origin, clone := setupTestClone(t)
+	mustRunCmd(t, origin, "git", "branch", "feature")
+	mustRunCmd(t, origin, "git", "checkout", "--quiet", "-b", "recent")
+	t.Setenv("GIT_COMMITTER_DATE", "2099-01-01T00:00:00Z")
+	mustRunCmd(t, origin, "git", "commit", "--quiet", "--allow-empty", "-m", "recent")
+	mustRunCmd(t, origin, "git", "checkout", "--quiet", "main")
+	mustRunCmd(t, clone, "git", "fetch", "--quiet")
+
+	branches, err := gitRemoteBranches(clone)
+	if err != nil || !reflect.DeepEqual(branches, []string{"origin/feature", "origin/main", "origin/recent"}) {
+		t.Fatalf("expected the remote branches without origin/HEAD, got %v, %v", branches, err)
+	}
+	branches, err = gitRemoteBranchesByRecent(clone)
+	if err != nil || !reflect.DeepEqual(branches, []string{"origin/recent", "origin/feature", "origin/main"}) {
+		t.Fatalf("expected the remote branches newest first, got %v, %v", branches, err)
+	}
+
+	model := tuiModel{repoRoot: clone, mainWorktree: clone}
+	next, _ := model.selectRemoteBranch("origin/feature")
+	model = next.(tuiModel)
+	if model.pendingBranch != "feature" || model.baseBranch != "origin/feature" || !model.trackBase {
+		t.Fatalf("expected feature tracking origin/feature, got %q from %q (track %v)", model.pendingBranch, model.baseBranch, model.trackBase)
+	}
+	if err := model.createWorktree(); err != nil {
+		t.Fatalf("createWorktree: %v", err)
+	}
+	if got := gitOutput(t, clone, "rev-parse", "--abbrev-ref", "feature@{upstream}"); got != "origin/feature" {
+		t.Fatalf("expected feature to track origin/feature, got %q", got)
+	}
+
+	// A remote branch with a local branch of the same name uses that.
+	next, _ = model.selectRemoteBranch("origin/main")
+	model = next.(tuiModel)
+	if model.pendingBranch != "main" || model.baseBranch != "" || model.trackBase {
+		t.Fatalf("expected the local main, got %q from %q (track %v)", model.pendingBranch, model.baseBranch, model.trackBase)
+	}
+}
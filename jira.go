@@ -8,13 +8,22 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
 )
 
 var (
@@ -22,11 +31,64 @@ var (
 	osWriteFile = os.WriteFile
 	jiraGet     = jiraGetDefault
 	jiraPost    = jiraPostDefault
+	jiraPut     = jiraPutDefault
+	jiraSleep   = time.Sleep
+)
+
+const (
+	defaultWatchInterval = 30 * time.Second
+	defaultWatchRetries  = 3
+	defaultJiraTimeout   = 30 * time.Second
+	// defaultJiraRetryAfter is how long to wait on a 429 response that
+	// doesn't say, and maxJiraRetryAfter caps what a response may ask for.
+	defaultJiraRetryAfter = 5 * time.Second
+	maxJiraRetryAfter     = time.Minute
+	// defaultJiraRateLimitRetries is the default for jiraRateLimitRetries.
+	defaultJiraRateLimitRetries = 3
 )
 
+// jiraRateLimitRetries is how many times a request rate-limited with a 429
+// response is retried before the error is returned. wt jira new --retries
+// sets it.
+var jiraRateLimitRetries = defaultJiraRateLimitRetries
+
+// jiraSiteName is the jira.sites entry wt jira --site selected, or "" to
+// pick the site by issue key.
+var jiraSiteName string
+
+// jiraSiteTokens caches the token each site's tokenCommand printed, so it
+// runs at most once per command. The TUI looks issues up in the background,
+// so jiraSiteTokensMu guards it.
+var (
+	jiraSiteTokens   = map[string]string{}
+	jiraSiteTokensMu sync.Mutex
+)
+
+var errJiraTimeout = errors.New("jira: request timed out")
+
 type jiraIssue struct {
 	Key    string     `json:"key"`
 	Fields jiraFields `json:"fields"`
+	// RawFields keeps every field of the response undecoded so configured
+	// custom fields can be looked up by ID.
+	RawFields map[string]json.RawMessage `json:"-"`
+	// Children holds an epic's child issues when they were fetched (see
+	// jiraAddChildren).
+	Children []jiraIssue `json:"-"`
+}
+
+func (i *jiraIssue) UnmarshalJSON(data []byte) error {
+	type plain jiraIssue
+	if err := json.Unmarshal(data, (*plain)(i)); err != nil {
+		return err
+	}
+	var raw struct {
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	// Cannot fail once the typed decode above has succeeded.
+	_ = json.Unmarshal(data, &raw)
+	i.RawFields = raw.Fields
+	return nil
 }
 
 type jiraIssueType struct {
@@ -34,15 +96,35 @@ type jiraIssueType struct {
 }
 
 type jiraFields struct {
-	Summary     string         `json:"summary"`
-	Description string         `json:"description"`
-	Comment     jiraComments   `json:"comment"`
-	Status      jiraStatus     `json:"status"`
-	IssueType   jiraIssueType  `json:"issuetype"`
+	Summary     string          `json:"summary"`
+	Description string          `json:"description"`
+	Comment     jiraComments    `json:"comment"`
+	Status      jiraStatus      `json:"status"`
+	IssueType   jiraIssueType   `json:"issuetype"`
+	IssueLinks  []jiraIssueLink `json:"issuelinks"`
+}
+
+// jiraIssueLink is one entry of an issue's links. Exactly one of
+// InwardIssue and OutwardIssue is set, with only its summary and status.
+type jiraIssueLink struct {
+	Type         jiraIssueLinkType `json:"type"`
+	InwardIssue  *jiraIssue        `json:"inwardIssue"`
+	OutwardIssue *jiraIssue        `json:"outwardIssue"`
 }
 
+// jiraIssueLinkType names a link from each side, e.g. Inward "is blocked
+// by" and Outward "blocks".
+type jiraIssueLinkType struct {
+	Name    string `json:"name"`
+	Inward  string `json:"inward"`
+	Outward string `json:"outward"`
+}
+
+// jiraComments is a page of comments. The issue response includes only the
+// first page; Total counts them all.
 type jiraComments struct {
 	Comments []jiraComment `json:"comments"`
+	Total    int           `json:"total"`
 }
 
 type jiraComment struct {
@@ -56,7 +138,14 @@ type jiraAuthor struct {
 }
 
 type jiraStatus struct {
-	Name string `json:"name"`
+	Name           string             `json:"name"`
+	StatusCategory jiraStatusCategory `json:"statusCategory"`
+}
+
+// jiraStatusCategory is the workflow-independent group of a status; Key is
+// "new" (To Do), "indeterminate" (In Progress), or "done".
+type jiraStatusCategory struct {
+	Key string `json:"key"`
 }
 
 type jiraTransition struct {
@@ -65,10 +154,103 @@ type jiraTransition struct {
 	To   jiraStatus `json:"to"`
 }
 
+type jiraSearchResponse struct {
+	Issues []jiraIssue `json:"issues"`
+}
+
 type jiraTransitionsResponse struct {
 	Transitions []jiraTransition `json:"transitions"`
 }
 
+// jiraAgileItem is a board or sprint from the Jira agile API.
+type jiraAgileItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type jiraAgileResponse struct {
+	Values []jiraAgileItem `json:"values"`
+}
+
+var (
+	jiraTimeoutOnce   sync.Once
+	jiraTimeoutCached time.Duration
+	jiraTimeoutErr    error
+)
+
+// jiraTimeout returns the Jira request timeout. It is resolved once per
+// process, so a command making many requests loads the config only once.
+func jiraTimeout() (time.Duration, error) {
+	jiraTimeoutOnce.Do(func() {
+		jiraTimeoutCached, jiraTimeoutErr = resolveJiraTimeout()
+	})
+	return jiraTimeoutCached, jiraTimeoutErr
+}
+
+// resolveJiraTimeout returns JIRA_TIMEOUT if set, else jira.timeout from the
+// config, else defaultJiraTimeout.
+func resolveJiraTimeout() (time.Duration, error) {
+	value, source := osGetenv("JIRA_TIMEOUT"), "JIRA_TIMEOUT"
+	if value == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return 0, err
+		}
+		value, source = cfg.Jira.Timeout, "jira.timeout"
+	}
+	if value == "" {
+		return defaultJiraTimeout, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive duration", source, value)
+	}
+	return d, nil
+}
+
+// jiraDo sends req with the configured timeout. A request that runs out of
+// time returns an error wrapping errJiraTimeout. A 429 response is retried
+// up to jiraRateLimitRetries times after waiting as long as its Retry-After
+// header asks, so bulk runs slow down instead of failing.
+func jiraDo(req *http.Request) (*http.Response, error) {
+	timeout, err := jiraTimeout()
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: timeout}
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Do(req)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("%w after %s", errJiraTimeout, timeout)
+		}
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt > jiraRateLimitRetries {
+			return resp, err
+		}
+		resp.Body.Close()
+		wait := jiraRetryAfter(resp.Header.Get("Retry-After"))
+		fmt.Fprintf(stderr, "jira: rate limited; retrying in %s (retry %d/%d)\n", wait, attempt, jiraRateLimitRetries)
+		jiraSleep(wait)
+		if req.GetBody != nil {
+			// Requests built from a bytes.Reader can always rewind it.
+			req.Body, _ = req.GetBody()
+		}
+	}
+}
+
+// jiraRetryAfter parses a Retry-After header, given in seconds or as an
+// HTTP date, into how long to wait, capped at maxJiraRetryAfter. A missing
+// or unparsable header waits defaultJiraRetryAfter.
+func jiraRetryAfter(header string) time.Duration {
+	wait := defaultJiraRetryAfter
+	if secs, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && secs >= 0 {
+		wait = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		wait = max(at.Sub(timeNow()), 0)
+	}
+	return min(wait, maxJiraRetryAfter)
+}
+
 func jiraGetDefault(url, user, token string) ([]byte, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -76,7 +258,7 @@ func jiraGetDefault(url, user, token string) ([]byte, error) {
 	}
 	req.SetBasicAuth(user, token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := jiraDo(req)
 	if err != nil {
 		return nil, err
 	}
@@ -87,27 +269,31 @@ func jiraGetDefault(url, user, token string) ([]byte, error) {
 		return nil, err
 	}
 
-	switch resp.StatusCode {
-	case http.StatusOK:
-		return body, nil
-	case http.StatusUnauthorized:
-		return nil, errors.New("jira: authentication failed (401)")
-	case http.StatusNotFound:
-		return nil, errors.New("jira: issue not found (404)")
-	default:
-		return nil, fmt.Errorf("jira: unexpected status %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return nil, newJiraAPIError(resp.StatusCode, body)
 	}
+	return body, nil
 }
 
 func jiraPostDefault(url, user, token string, body []byte) ([]byte, error) {
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	return jiraSend("POST", url, user, token, body)
+}
+
+func jiraPutDefault(url, user, token string, body []byte) ([]byte, error) {
+	return jiraSend("PUT", url, user, token, body)
+}
+
+// jiraSend sends the JSON body with method and returns the response body,
+// or a jiraAPIError for a status other than 2xx.
+func jiraSend(method, url, user, token string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 	req.SetBasicAuth(user, token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := jiraDo(req)
 	if err != nil {
 		return nil, err
 	}
@@ -119,11 +305,81 @@ func jiraPostDefault(url, user, token string, body []byte) ([]byte, error) {
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("jira: unexpected status %d", resp.StatusCode)
+		return nil, newJiraAPIError(resp.StatusCode, respBody)
 	}
 	return respBody, nil
 }
 
+// jiraAPIError is an error response from the Jira API. Message is the first
+// entry of the JSON body's errorMessages, else of its per-field errors, and
+// empty when the body has neither.
+type jiraAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *jiraAPIError) Error() string {
+	var msg string
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		msg = "jira: authentication failed (401)"
+	case http.StatusNotFound:
+		msg = "jira: issue not found (404)"
+	case http.StatusTooManyRequests:
+		msg = "jira: rate limited (429)"
+	default:
+		msg = fmt.Sprintf("jira: unexpected status %d", e.StatusCode)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// newJiraAPIError builds the jiraAPIError for a response with the given
+// status and body. Bodies that aren't Jira's error JSON are ignored.
+func newJiraAPIError(status int, body []byte) error {
+	apiErr := &jiraAPIError{StatusCode: status}
+	var parsed struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	if json.Unmarshal(body, &parsed) != nil {
+		return apiErr
+	}
+	if len(parsed.ErrorMessages) > 0 {
+		apiErr.Message = parsed.ErrorMessages[0]
+		return apiErr
+	}
+	fields := make([]string, 0, len(parsed.Errors))
+	for field := range parsed.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	if len(fields) > 0 {
+		apiErr.Message = fields[0] + ": " + parsed.Errors[fields[0]]
+	}
+	return apiErr
+}
+
+// jiraStatusColors colors statuses by category: To Do grey, In Progress
+// blue, Done green.
+var jiraStatusColors = map[string]lipgloss.Color{
+	"new":           lipgloss.Color("245"),
+	"indeterminate": lipgloss.Color("4"),
+	"done":          lipgloss.Color("2"),
+}
+
+// jiraStatusText returns the status name, colored by its category when
+// stdout is a terminal and NO_COLOR is unset.
+func jiraStatusText(s jiraStatus) string {
+	color, ok := jiraStatusColors[s.StatusCategory.Key]
+	if !ok || !stdoutIsTerminal() || osGetenv("NO_COLOR") != "" {
+		return s.Name
+	}
+	return lipgloss.NewStyle().Foreground(color).Render(s.Name)
+}
+
 var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
 
 func slugify(s string, maxLen int) string {
@@ -153,24 +409,197 @@ func jiraBranchName(key, summary string) string {
 	return key + "-" + slug
 }
 
-func renderIssueMD(issue jiraIssue) string {
+// Values for jira.notesFormat, the format of the issue notes file that
+// wt jira new writes into the worktree.
+const (
+	notesFormatMarkdown = "markdown"
+	notesFormatOrg      = "org"
+	notesFormatPlain    = "plain"
+)
+
+// notesStyle is the syntax of one notes format.
+type notesStyle struct {
+	ext string
+	// heading renders a level 1-3 heading, including its trailing newline.
+	heading func(level int, text string) string
+	// link, if set, renders text linking to url.
+	link func(url, text string) string
+	// body, if set, adapts text from Jira, such as a description, to the
+	// format.
+	body func(text string) string
+}
+
+var notesStyles = map[string]notesStyle{
+	notesFormatMarkdown: {
+		ext:     ".md",
+		heading: func(level int, text string) string { return strings.Repeat("#", level) + " " + text + "\n" },
+	},
+	notesFormatOrg: {
+		ext:     ".org",
+		heading: func(level int, text string) string { return strings.Repeat("*", level) + " " + text + "\n" },
+		link:    func(url, text string) string { return "[[" + url + "][" + text + "]]" },
+		body:    orgBody,
+	},
+	notesFormatPlain: {
+		ext: ".txt",
+		heading: func(level int, text string) string {
+			switch level {
+			case 1:
+				return text + "\n" + strings.Repeat("=", utf8.RuneCountInString(text)) + "\n"
+			case 2:
+				return text + "\n" + strings.Repeat("-", utf8.RuneCountInString(text)) + "\n"
+			}
+			return text + ":\n"
+		},
+	},
+}
+
+// orgBody indents the lines of text that start with "*", such as Jira's
+// "* item" bullets, which org would otherwise read as headings. Indented,
+// they are list items.
+func orgBody(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "*") {
+			lines[i] = " " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// jiraNotesStyle returns the style for jira.notesFormat, defaulting to
+// markdown.
+func jiraNotesStyle(cfg wtConfig) (notesStyle, error) {
+	format := cfg.Jira.NotesFormat
+	if format == "" {
+		format = notesFormatMarkdown
+	}
+	style, ok := notesStyles[format]
+	if !ok {
+		return notesStyle{}, fmt.Errorf("invalid jira.notesFormat %q: must be \"markdown\", \"org\", or \"plain\"", format)
+	}
+	return style, nil
+}
+
+// renderIssue renders issue in style. cfg.CustomFields maps custom field
+// IDs to section titles; each one present in the issue gets its own section
+// after the description, which is cut to cfg.MaxDescriptionLength. An
+// epic's fetched children are listed before the comments. Issue keys link
+// to baseURL when it is set and the style has links (org); markdown and
+// plain notes keep keys as plain text.
+func renderIssue(issue jiraIssue, cfg jiraConfigBlock, baseURL string, style notesStyle) string {
+	customFields := cfg.CustomFields
+	browseURL := func(key string) string {
+		return strings.TrimRight(baseURL, "/") + "/browse/" + key
+	}
+	keyText := func(key string) string {
+		if baseURL == "" || style.link == nil {
+			return key
+		}
+		return style.link(browseURL(key), key)
+	}
+	body := func(text string) string {
+		if style.body == nil {
+			return text
+		}
+		return style.body(text)
+	}
+
 	var b strings.Builder
-	fmt.Fprintf(&b, "# %s: %s\n", issue.Key, issue.Fields.Summary)
+	b.WriteString(style.heading(1, keyText(issue.Key)+": "+issue.Fields.Summary))
 
 	if issue.Fields.Description != "" {
-		fmt.Fprintf(&b, "\n## Description\n\n%s\n", issue.Fields.Description)
+		description := issue.Fields.Description
+		if limit := cfg.MaxDescriptionLength; limit > 0 && utf8.RuneCountInString(description) > limit {
+			marker := "... (truncated, see Jira)"
+			if baseURL != "" {
+				marker = fmt.Sprintf("... (truncated, see Jira: %s)", browseURL(issue.Key))
+			}
+			description = strings.TrimRightFunc(string([]rune(description)[:limit]), unicode.IsSpace) + "\n\n" + marker
+		}
+		fmt.Fprintf(&b, "\n%s\n%s\n", style.heading(2, "Description"), body(description))
+	}
+
+	ids := customFieldIDs(wtConfig{Jira: cfg})
+	sort.SliceStable(ids, func(a, b int) bool { return customFields[ids[a]] < customFields[ids[b]] })
+	for _, id := range ids {
+		text := renderFieldValue(issue.RawFields[id])
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s\n%s\n", style.heading(2, customFields[id]), body(text))
+	}
+
+	if len(issue.Children) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", style.heading(2, "Child Issues"))
+		for _, c := range issue.Children {
+			fmt.Fprintf(&b, "- %s: %s (%s)\n", keyText(c.Key), c.Fields.Summary, c.Fields.Status.Name)
+		}
+	}
+
+	if len(issue.Fields.IssueLinks) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", style.heading(2, "Linked Issues"))
+		for _, l := range issue.Fields.IssueLinks {
+			relation, linked := l.Type.Outward, l.OutwardIssue
+			if linked == nil {
+				relation, linked = l.Type.Inward, l.InwardIssue
+			}
+			if linked == nil {
+				continue
+			}
+			if relation == "" {
+				relation = l.Type.Name
+			}
+			fmt.Fprintf(&b, "- %s %s: %s (%s)\n", relation, keyText(linked.Key), linked.Fields.Summary, linked.Fields.Status.Name)
+		}
 	}
 
 	if len(issue.Fields.Comment.Comments) > 0 {
-		fmt.Fprintf(&b, "\n## Comments\n")
+		fmt.Fprintf(&b, "\n%s", style.heading(2, "Comments"))
 		for _, c := range issue.Fields.Comment.Comments {
-			fmt.Fprintf(&b, "\n### %s (%s)\n\n%s\n", c.Author.DisplayName, c.Created, c.Body)
+			fmt.Fprintf(&b, "\n%s\n%s\n", style.heading(3, fmt.Sprintf("%s (%s)", c.Author.DisplayName, c.Created)), body(c.Body))
 		}
 	}
 
 	return b.String()
 }
 
+// renderFieldValue turns a raw Jira field value into markdown text: strings
+// as-is, option objects by their value or name, arrays as bullet lists, and
+// anything else as JSON. Missing and empty values render as "".
+func renderFieldValue(raw json.RawMessage) string {
+	var v any
+	if len(raw) == 0 || json.Unmarshal(raw, &v) != nil {
+		return ""
+	}
+	return strings.TrimSpace(fieldText(v))
+}
+
+func fieldText(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []any:
+		var lines []string
+		for _, item := range val {
+			if text := fieldText(item); text != "" {
+				lines = append(lines, "- "+text)
+			}
+		}
+		return strings.Join(lines, "\n")
+	case map[string]any:
+		for _, key := range []string{"value", "name", "displayName"} {
+			if s, ok := val[key].(string); ok {
+				return s
+			}
+		}
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
 var issueKeyRe = regexp.MustCompile(`^([A-Z]+-\d+)`)
 
 func jiraIssueKeyFromBranch(branch string) string {
@@ -181,6 +610,55 @@ func jiraIssueKeyFromBranch(branch string) string {
 	return m[1]
 }
 
+var (
+	issueKeyInURLRe = regexp.MustCompile(`\b[A-Z][A-Z0-9_]*-\d+\b`)
+	issueNumberRe   = regexp.MustCompile(`^\d+$`)
+)
+
+// jiraIssueKeyFromArg returns the issue key named by arg, which may be a
+// bare key, a bare issue number in jira.defaultProject, or a Jira URL pasted
+// from the browser: a /browse/PROJ-123 link, or a board URL with
+// ?selectedIssue=PROJ-123. Anything else is returned unchanged.
+func jiraIssueKeyFromArg(arg string) (string, error) {
+	if issueNumberRe.MatchString(arg) {
+		cfg, err := loadConfig()
+		if err != nil {
+			return "", err
+		}
+		if cfg.Jira.DefaultProject == "" {
+			return "", fmt.Errorf("%s is an issue number, not a key: set jira.defaultProject or pass PROJ-%s", arg, arg)
+		}
+		return strings.ToUpper(cfg.Jira.DefaultProject) + "-" + arg, nil
+	}
+	if !strings.HasPrefix(arg, "https://") && !strings.HasPrefix(arg, "http://") {
+		return arg, nil
+	}
+	u, err := url.Parse(arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid Jira URL %s: %w", arg, err)
+	}
+	if key := u.Query().Get("selectedIssue"); issueKeyInURLRe.MatchString(key) {
+		return issueKeyInURLRe.FindString(key), nil
+	}
+	if keys := issueKeyInURLRe.FindAllString(u.Path, -1); len(keys) > 0 {
+		return keys[len(keys)-1], nil
+	}
+	return "", fmt.Errorf("no issue key found in %s", arg)
+}
+
+// jiraIssueKeysFromArgs applies jiraIssueKeyFromArg to each argument.
+func jiraIssueKeysFromArgs(args []string) ([]string, error) {
+	keys := make([]string, 0, len(args))
+	for _, arg := range args {
+		key, err := jiraIssueKeyFromArg(arg)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
 func jiraEnv() (string, string, string, error) {
 	jiraURL := osGetenv("JIRA_URL")
 	jiraUser := osGetenv("JIRA_USER")
@@ -191,8 +669,108 @@ func jiraEnv() (string, string, string, error) {
 	return strings.TrimRight(jiraURL, "/"), jiraUser, jiraToken, nil
 }
 
-func jiraFetchIssue(baseURL, issueKey, user, token string) (jiraIssue, error) {
-	apiURL := fmt.Sprintf("%s/rest/api/2/issue/%s?fields=summary,description,comment,status,issuetype", baseURL, issueKey)
+// jiraSiteEnv returns the URL, user and token of the Jira site for
+// issueKey: the jira.sites entry named by --site, else the one listing the
+// key's project, else the default site from the environment (see jiraEnv).
+// Without --site a config that fails to load also means the default site;
+// the commands that need the config report the error themselves.
+func jiraSiteEnv(issueKey string) (string, string, string, error) {
+	return jiraSiteLookup(issueKey, true)
+}
+
+// jiraSiteLookup is jiraSiteEnv. Unless interactive, a site's tokenCommand
+// runs without the terminal, as the TUI needs.
+func jiraSiteLookup(issueKey string, interactive bool) (string, string, string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		if jiraSiteName == "" {
+			return jiraEnv()
+		}
+		return "", "", "", err
+	}
+	name := jiraSiteName
+	if name == "" {
+		name = jiraSiteForProject(cfg.Jira.Sites, issueKey)
+	}
+	if name == "" {
+		return jiraEnv()
+	}
+	site, ok := cfg.Jira.Sites[name]
+	if !ok {
+		if len(cfg.Jira.Sites) == 0 {
+			return "", "", "", usageError(fmt.Errorf("unknown jira site %q: no jira.sites are configured", name))
+		}
+		return "", "", "", usageError(fmt.Errorf("unknown jira site %q: jira.sites has %s", name, strings.Join(jiraSiteNames(cfg.Jira.Sites), ", ")))
+	}
+	if site.URL == "" || site.User == "" || site.TokenCommand == "" {
+		return "", "", "", fmt.Errorf("jira site %s needs url, user and tokenCommand", name)
+	}
+	jiraSiteTokensMu.Lock()
+	defer jiraSiteTokensMu.Unlock()
+	token, ok := jiraSiteTokens[name]
+	if !ok {
+		token, err = jiraSiteToken(name, site.TokenCommand, interactive)
+		if err != nil {
+			return "", "", "", err
+		}
+		jiraSiteTokens[name] = token
+	}
+	return strings.TrimRight(site.URL, "/"), site.User, token, nil
+}
+
+// jiraSiteForProject returns the site in sites whose projects include
+// issueKey's project, or "". Should several list it, the first by name
+// wins.
+func jiraSiteForProject(sites map[string]jiraSiteConfig, issueKey string) string {
+	project, _, ok := strings.Cut(issueKey, "-")
+	if !ok {
+		return ""
+	}
+	for _, name := range jiraSiteNames(sites) {
+		for _, p := range sites[name].Projects {
+			if strings.EqualFold(p, project) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// jiraSiteNames returns the names of sites, sorted.
+func jiraSiteNames(sites map[string]jiraSiteConfig) []string {
+	names := make([]string, 0, len(sites))
+	for name := range sites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jiraSiteToken runs a site's tokenCommand and returns what it printed,
+// trimmed. When interactive, its stdin and stderr are the terminal's, so a
+// password manager can prompt; otherwise it gets neither.
+func jiraSiteToken(name, command string, interactive bool) (string, error) {
+	cmd := execCommand("sh", "-c", command)
+	if interactive {
+		cmd.Stdin = stdin
+		cmd.Stderr = stderr
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("jira site %s: tokenCommand failed: %w", name, err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("jira site %s: tokenCommand printed no token", name)
+	}
+	return token, nil
+}
+
+// jiraFetchIssue fetches the fields wt uses, plus any extraFields (such as
+// configured custom fields).
+func jiraFetchIssue(baseURL, issueKey, user, token string, extraFields ...string) (jiraIssue, error) {
+	fields := append([]string{"summary", "description", "comment", "status", "issuetype", "issuelinks"}, extraFields...)
+	apiURL := fmt.Sprintf("%s/rest/api/2/issue/%s?fields=%s", baseURL, issueKey, strings.Join(fields, ","))
 	body, err := jiraGet(apiURL, user, token)
 	if err != nil {
 		return jiraIssue{}, err
@@ -204,17 +782,116 @@ func jiraFetchIssue(baseURL, issueKey, user, token string) (jiraIssue, error) {
 	return issue, nil
 }
 
-func jiraSetStatus(baseURL, issueKey, statusName, user, token string) error {
+// jiraFetchChildren returns the issues linked to the epic epicKey.
+func jiraFetchChildren(baseURL, epicKey, user, token string) ([]jiraIssue, error) {
+	jql := fmt.Sprintf(`"Epic Link" = %s ORDER BY key`, epicKey)
+	apiURL := fmt.Sprintf("%s/rest/api/2/search?jql=%s&fields=summary,status&maxResults=100", baseURL, url.QueryEscape(jql))
+	body, err := jiraGet(apiURL, user, token)
+	if err != nil {
+		return nil, err
+	}
+	var res jiraSearchResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("jira: invalid search response: %w", err)
+	}
+	return res.Issues, nil
+}
+
+// jiraAddChildren fills in issue.Children when issue is an epic. The
+// worktree is still worth creating without them, so a failed search is only
+// a warning.
+func jiraAddChildren(baseURL, user, token string, issue *jiraIssue) {
+	if !strings.EqualFold(issue.Fields.IssueType.Name, "epic") {
+		return
+	}
+	children, err := jiraFetchChildren(baseURL, issue.Key, user, token)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: could not fetch child issues of %s: %v\n", issue.Key, err)
+		return
+	}
+	issue.Children = children
+}
+
+// jiraCommentPageSize is how many comments jiraFetchComments asks for per
+// request; Jira may return fewer.
+const jiraCommentPageSize = 100
+
+// jiraFetchComments returns every comment on issueKey, requesting pages
+// until Total is reached.
+func jiraFetchComments(baseURL, issueKey, user, token string) ([]jiraComment, error) {
+	var all []jiraComment
+	for {
+		apiURL := fmt.Sprintf("%s/rest/api/2/issue/%s/comment?startAt=%d&maxResults=%d", baseURL, issueKey, len(all), jiraCommentPageSize)
+		body, err := jiraGet(apiURL, user, token)
+		if err != nil {
+			return nil, err
+		}
+		var page jiraComments
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("jira: invalid comments response: %w", err)
+		}
+		all = append(all, page.Comments...)
+		if len(page.Comments) == 0 || len(all) >= page.Total {
+			return all, nil
+		}
+	}
+}
+
+// jiraAddAllComments replaces the inline first page of comments with the
+// full list when the issue has more. A failed fetch is only a warning; the
+// inline comments are kept.
+func jiraAddAllComments(baseURL, user, token string, issue *jiraIssue) {
+	inline := issue.Fields.Comment
+	if len(inline.Comments) >= inline.Total {
+		return
+	}
+	comments, err := jiraFetchComments(baseURL, issue.Key, user, token)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: could not fetch all comments of %s: %v\n", issue.Key, err)
+		return
+	}
+	issue.Fields.Comment = jiraComments{Comments: comments, Total: len(comments)}
+}
+
+// jiraIssueExtras selects the optional extra requests wt jira new makes
+// for each issue (--children, --all-comments).
+type jiraIssueExtras struct {
+	children    bool
+	allComments bool
+}
+
+func (e jiraIssueExtras) add(baseURL, user, token string, issue *jiraIssue) {
+	if e.children {
+		jiraAddChildren(baseURL, user, token, issue)
+	}
+	if e.allComments {
+		jiraAddAllComments(baseURL, user, token, issue)
+	}
+}
+
+// jiraFetchTransitions returns the transitions available from issueKey's
+// current status.
+func jiraFetchTransitions(baseURL, issueKey, user, token string) ([]jiraTransition, error) {
 	tURL := fmt.Sprintf("%s/rest/api/2/issue/%s/transitions", baseURL, issueKey)
 	body, err := jiraGet(tURL, user, token)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var tr jiraTransitionsResponse
 	if err := json.Unmarshal(body, &tr); err != nil {
-		return fmt.Errorf("jira: invalid transitions response: %w", err)
+		return nil, fmt.Errorf("jira: invalid transitions response: %w", err)
+	}
+	return tr.Transitions, nil
+}
+
+func jiraSetStatus(baseURL, issueKey, statusName, user, token string) error {
+	transitions, err := jiraFetchTransitions(baseURL, issueKey, user, token)
+	if err != nil {
+		return err
 	}
-	for _, t := range tr.Transitions {
+	tURL := fmt.Sprintf("%s/rest/api/2/issue/%s/transitions", baseURL, issueKey)
+	available := make([]string, 0, len(transitions))
+	for _, t := range transitions {
 		if strings.EqualFold(t.To.Name, statusName) {
 			payload, _ := json.Marshal(map[string]any{
 				"transition": map[string]string{"id": t.ID},
@@ -222,14 +899,166 @@ func jiraSetStatus(baseURL, issueKey, statusName, user, token string) error {
 			_, err := jiraPost(tURL, user, token, payload)
 			return err
 		}
+		available = append(available, t.To.Name)
+	}
+	if len(available) == 0 {
+		return fmt.Errorf("jira: no transition to %q available", statusName)
+	}
+	return fmt.Errorf("jira: no transition to %q available (available: %s)", statusName, strings.Join(available, ", "))
+}
+
+// jiraIssueUpdates are the changes wt jira new makes to each issue once its
+// worktree exists: moving it into a sprint and adding a fix version.
+type jiraIssueUpdates struct {
+	// sprint is a sprint ID, or jiraSprintCurrent for the active sprint
+	// of the issue's board.
+	sprint     string
+	fixVersion string
+	// boardID is jira.boardId; when 0 the board is looked up from the
+	// issue's project.
+	boardID int
+	// activeSprints caches the active sprint of each project's board, so
+	// bulk runs look it up once.
+	activeSprints map[string]jiraAgileItem
+}
+
+const jiraSprintCurrent = "current"
+
+// parseSprintFlag checks a --sprint value: "current" or a sprint ID.
+func parseSprintFlag(value string) error {
+	if value == "" || value == jiraSprintCurrent {
+		return nil
+	}
+	if id, err := strconv.Atoi(value); err != nil || id <= 0 {
+		return usageError(fmt.Errorf("invalid --sprint %q: must be \"current\" or a sprint ID", value))
+	}
+	return nil
+}
+
+// apply makes the updates to issueKey. Failures are only warnings, since
+// the worktree has already been created.
+func (u jiraIssueUpdates) apply(baseURL, issueKey, user, token string) {
+	if u.sprint != "" {
+		sprint, err := u.resolveSprint(baseURL, issueKey, user, token)
+		if err == nil {
+			err = jiraMoveToSprint(baseURL, issueKey, sprint.ID, user, token)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: %s: could not set sprint: %v\n", issueKey, err)
+		} else {
+			fmt.Fprintf(stdout, "%s → sprint %s\n", issueKey, sprint.Name)
+		}
+	}
+	if u.fixVersion != "" {
+		if err := jiraAddFixVersion(baseURL, issueKey, u.fixVersion, user, token); err != nil {
+			fmt.Fprintf(stderr, "warning: %s: could not set fix version: %v\n", issueKey, err)
+		} else {
+			fmt.Fprintf(stdout, "%s → fix version %s\n", issueKey, u.fixVersion)
+		}
+	}
+}
+
+// resolveSprint returns the sprint named by u.sprint for issueKey.
+func (u jiraIssueUpdates) resolveSprint(baseURL, issueKey, user, token string) (jiraAgileItem, error) {
+	if u.sprint != jiraSprintCurrent {
+		id, _ := strconv.Atoi(u.sprint)
+		return jiraAgileItem{ID: id, Name: u.sprint}, nil
+	}
+	project, _, _ := strings.Cut(issueKey, "-")
+	if sprint, ok := u.activeSprints[project]; ok {
+		return sprint, nil
+	}
+	boardID := u.boardID
+	if boardID == 0 {
+		board, err := jiraProjectBoard(baseURL, project, user, token)
+		if err != nil {
+			return jiraAgileItem{}, err
+		}
+		boardID = board.ID
+	}
+	sprint, err := jiraActiveSprint(baseURL, boardID, user, token)
+	if err != nil {
+		return jiraAgileItem{}, err
+	}
+	if u.activeSprints != nil {
+		u.activeSprints[project] = sprint
+	}
+	return sprint, nil
+}
+
+// jiraProjectBoard returns project's scrum board. A project with several
+// boards needs jira.boardId to say which one's sprints to use.
+func jiraProjectBoard(baseURL, project, user, token string) (jiraAgileItem, error) {
+	bURL := fmt.Sprintf("%s/rest/agile/1.0/board?projectKeyOrId=%s&type=scrum", baseURL, url.QueryEscape(project))
+	boards, err := jiraAgileList(bURL, user, token)
+	if err != nil {
+		return jiraAgileItem{}, err
+	}
+	switch len(boards) {
+	case 0:
+		return jiraAgileItem{}, fmt.Errorf("jira: no scrum board found for project %s; set jira.boardId", project)
+	case 1:
+		return boards[0], nil
+	}
+	names := make([]string, len(boards))
+	for i, b := range boards {
+		names[i] = fmt.Sprintf("%d %s", b.ID, b.Name)
+	}
+	return jiraAgileItem{}, fmt.Errorf("jira: project %s has several scrum boards (%s); set jira.boardId", project, strings.Join(names, ", "))
+}
+
+// jiraActiveSprint returns the active sprint of the board, the first one
+// if the board runs several.
+func jiraActiveSprint(baseURL string, boardID int, user, token string) (jiraAgileItem, error) {
+	sURL := fmt.Sprintf("%s/rest/agile/1.0/board/%d/sprint?state=active", baseURL, boardID)
+	sprints, err := jiraAgileList(sURL, user, token)
+	if err != nil {
+		return jiraAgileItem{}, err
+	}
+	if len(sprints) == 0 {
+		return jiraAgileItem{}, fmt.Errorf("jira: board %d has no active sprint", boardID)
+	}
+	return sprints[0], nil
+}
+
+func jiraAgileList(listURL, user, token string) ([]jiraAgileItem, error) {
+	body, err := jiraGet(listURL, user, token)
+	if err != nil {
+		return nil, err
+	}
+	var resp jiraAgileResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("jira: invalid agile response: %w", err)
 	}
-	return fmt.Errorf("jira: no transition to %q available", statusName)
+	return resp.Values, nil
+}
+
+func jiraMoveToSprint(baseURL, issueKey string, sprintID int, user, token string) error {
+	payload, _ := json.Marshal(map[string]any{"issues": []string{issueKey}})
+	_, err := jiraPost(fmt.Sprintf("%s/rest/agile/1.0/sprint/%d/issue", baseURL, sprintID), user, token, payload)
+	return err
+}
+
+// jiraAddFixVersion adds version to the issue's fix versions, keeping the
+// ones it already has.
+func jiraAddFixVersion(baseURL, issueKey, version, user, token string) error {
+	payload, _ := json.Marshal(map[string]any{
+		"update": map[string]any{
+			"fixVersions": []any{map[string]any{"add": map[string]string{"name": version}}},
+		},
+	})
+	_, err := jiraPut(fmt.Sprintf("%s/rest/api/2/issue/%s", baseURL, issueKey), user, token, payload)
+	return err
 }
 
 func jiraCmd(args []string) {
+	args, err := jiraSiteArg(args)
+	if err != nil {
+		die(err)
+	}
 	if len(args) == 0 {
 		printJiraUsage()
-		exitFunc(1)
+		exitFunc(exitUsage)
 		return
 	}
 	switch args[0] {
@@ -242,11 +1071,46 @@ func jiraCmd(args []string) {
 	case "config":
 		jiraConfigCmd(args[1:])
 	default:
-		die(fmt.Errorf("unknown jira command: %s", args[0]))
+		// A pasted issue URL, an issue key or a bare issue number is
+		// shorthand for jira new.
+		if strings.HasPrefix(args[0], "https://") || strings.HasPrefix(args[0], "http://") ||
+			issueKeyRe.MatchString(args[0]) || issueNumberRe.MatchString(args[0]) {
+			jiraNewCmd(args)
+			return
+		}
+		die(usageError(fmt.Errorf("unknown jira command: %s", args[0])))
+	}
+}
+
+// jiraSiteArg takes a leading --site <name> or --site=<name> off args,
+// setting jiraSiteName, and returns the rest.
+func jiraSiteArg(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return args, nil
 	}
+	if name, ok := strings.CutPrefix(args[0], "--site="); ok {
+		args = args[1:]
+		jiraSiteName = name
+	} else if args[0] == "--site" {
+		if len(args) < 2 {
+			return nil, usageError(errors.New("--site needs a site name"))
+		}
+		jiraSiteName = args[1]
+		args = args[2:]
+	} else {
+		return args, nil
+	}
+	if jiraSiteName == "" {
+		return nil, usageError(errors.New("--site needs a site name"))
+	}
+	return args, nil
 }
 
 func jiraNewCmd(args []string) {
+	if isHelpArg(args) {
+		printJiraNewUsage()
+		return
+	}
 	fs := flag.NewFlagSet("jira new", flag.ExitOnError)
 	fs.Usage = printJiraNewUsage
 	tmux := fs.Bool("t", false, "open worktree in tmux after creation")
@@ -264,41 +1128,75 @@ func jiraNewCmd(args []string) {
 	fs.StringVar(fromBranch, "f", "", "base branch to create from")
 	noStatusUpdate := fs.Bool("no-status-update", false, "skip auto-transition")
 	fs.BoolVar(noStatusUpdate, "S", false, "skip auto-transition")
+	force := fs.Bool("force", false, "create a worktree even if the issue already has one")
+	worktreeRoot := fs.String("worktree-root", "", "create worktrees under this directory")
+	children := fs.Bool("children", false, "list an epic's child issues in the issue markdown")
+	allComments := fs.Bool("all-comments", false, "fetch every comment, not just the first page")
+	timings := fs.Bool("timings", false, "print how long each step took")
+	quietGit := fs.Bool("quiet-git", !stderrIsTerminal(), "silence git's progress output")
+	retries := fs.Int("retries", defaultJiraRateLimitRetries, "times to retry a rate-limited Jira request")
+	sprint := fs.String("sprint", "", `move the issue into a sprint ("current" or a sprint ID)`)
+	fixVersion := fs.String("fix-version", "", "add a fix version to the issue")
 	_ = fs.Parse(args)
+	var timer *phaseTimer
+	if *timings {
+		timer = newPhaseTimer()
+	}
+	if *retries < 0 {
+		die(usageError(fmt.Errorf("invalid --retries %d: must be a non-negative number", *retries)))
+	}
+	jiraRateLimitRetries = *retries
+	if err := parseSprintFlag(*sprint); err != nil {
+		die(err)
+	}
 
+	keys, err := jiraIssueKeysFromArgs(fs.Args())
+	if err != nil {
+		die(err)
+	}
 	issueKey := ""
-	if fs.NArg() > 0 {
-		issueKey = fs.Arg(0)
+	if len(keys) > 0 {
+		issueKey = keys[0]
 	}
 	if issueKey == "" {
-		fmt.Fprintln(stderr, "error: issue key required (e.g. PROJ-123)")
-		fmt.Fprintln(stderr, "")
-		printJiraNewUsage()
-		exitFunc(1)
+		dieUsage("issue key required (e.g. PROJ-123)", printJiraNewUsage)
 		return
 	}
-
-	baseURL, user, token, err := jiraEnv()
+	if len(keys) > 1 && (*branch != "" || *tmux) {
+		die(usageError(errors.New("-b and -t can only be used with a single issue")))
+	}
+	root, err := resolveWorktreeRoot(*worktreeRoot)
 	if err != nil {
 		die(err)
 	}
 
-	issue, err := jiraFetchIssue(baseURL, issueKey, user, token)
+	baseURL, user, token, err := jiraSiteEnv(issueKey)
 	if err != nil {
 		die(err)
 	}
 
-	branchName := *branch
-	if branchName == "" {
-		branchName = jiraBranchName(issue.Key, issue.Fields.Summary)
-	}
-
 	if *noCopyConfig {
 		*copyConfig = false
 	}
 	if *noCopyLibs {
 		*copyLibs = false
 	}
+	opts := addOptions{
+		fromBranch:     *fromBranch,
+		copyConfig:     *copyConfig,
+		copyLibs:       *copyLibs,
+		allowProtected: *force,
+		worktreeRoot:   root,
+		quietGit:       *quietGit,
+		timings:        timer,
+	}
+
+	extras := jiraIssueExtras{children: *children, allComments: *allComments}
+	updates := jiraIssueUpdates{sprint: *sprint, fixVersion: *fixVersion, activeSprints: map[string]jiraAgileItem{}}
+	if len(keys) > 1 {
+		jiraNewMulti(keys, opts, !*noStatusUpdate, *force, extras, updates)
+		return
+	}
 
 	repoRoot, err := gitRepoRoot()
 	if err != nil {
@@ -309,36 +1207,58 @@ func jiraNewCmd(args []string) {
 		die(err)
 	}
 
-	wtPath, err := addWorktree(repoRoot, mainWT, branchName, *fromBranch, *copyConfig, *copyLibs)
+	if !*force {
+		existing, found, err := worktreeForIssue(repoRoot, issueKey)
+		if err != nil {
+			die(err)
+		}
+		if found {
+			fmt.Fprintf(stderr, "%s: worktree already exists at %s (use --force to create another)\n", issueKey, existing)
+			fmt.Fprintln(stdout, existing)
+			if *tmux {
+				if err := openTmux(existing); err != nil {
+					die(err)
+				}
+			}
+			return
+		}
+	}
+
+	cfg, cfgErr := loadConfig()
+	opts.cfg = cfg
+	updates.boardID = cfg.Jira.BoardID
+	timer.mark("setup")
+
+	issue, err := jiraFetchIssue(baseURL, issueKey, user, token, customFieldIDs(cfg)...)
 	if err != nil {
 		die(err)
 	}
+	extras.add(baseURL, user, token, &issue)
+	timer.mark("jira fetch")
+
+	opts.branch = *branch
+	if opts.branch == "" {
+		opts.branch = jiraBranchName(issue.Key, issue.Fields.Summary)
+	}
 
-	md := renderIssueMD(issue)
-	mdPath := filepath.Join(wtPath, issue.Key+".md")
-	if err := osWriteFile(mdPath, []byte(md), 0o644); err != nil {
+	wtPath, err := jiraCreateIssueWorktree(repoRoot, mainWT, baseURL, issue, opts)
+	if err != nil {
 		die(err)
 	}
 
 	fmt.Fprintln(stdout, wtPath)
 
 	if !*noStatusUpdate {
-		cfg, err := loadConfig()
-		if err != nil {
-			fmt.Fprintf(stderr, "warning: config: %v\n", err)
-		} else if !hasStatusConfig(cfg) {
-			die(errors.New("no jira status mappings configured; run 'wt jira config --init'"))
-		} else {
-			target, err := resolveStatus(cfg, issue.Fields.IssueType.Name, "working")
-			if err == nil {
-				if err := jiraSetStatus(baseURL, issueKey, target, user, token); err != nil {
-					fmt.Fprintf(stderr, "warning: %v\n", err)
-				} else {
-					fmt.Fprintf(stdout, "%s → %s\n", issueKey, target)
-				}
-			}
+		if err := jiraAutoTransition(baseURL, issueKey, user, token, issue, cfg, cfgErr); err != nil {
+			die(err)
 		}
+		timer.mark("jira transition")
+	}
+	if updates.sprint != "" || updates.fixVersion != "" {
+		updates.apply(baseURL, issueKey, user, token)
+		timer.mark("jira update")
 	}
+	timer.print()
 
 	if *tmux {
 		if err := openTmux(wtPath); err != nil {
@@ -347,6 +1267,141 @@ func jiraNewCmd(args []string) {
 	}
 }
 
+// jiraNewMulti creates a worktree for each issue key. Issues that already
+// have a worktree are skipped unless force is set. A failure on one issue
+// is reported and the rest are still attempted; the command exits non-zero
+// if any issue failed. Each issue is fetched from its own site (see
+// jiraSiteEnv).
+func jiraNewMulti(keys []string, opts addOptions, statusUpdate, force bool, extras jiraIssueExtras, updates jiraIssueUpdates) {
+	repoRoot, err := gitRepoRoot()
+	if err != nil {
+		die(err)
+	}
+	mainWT, err := gitMainWorktree(repoRoot)
+	if err != nil {
+		die(err)
+	}
+	cfg, cfgErr := loadConfig()
+	opts.cfg = cfg
+	updates.boardID = cfg.Jira.BoardID
+	opts.timings.mark("setup")
+
+	var res bulkResult
+	for _, key := range keys {
+		if !force {
+			existing, found, err := worktreeForIssue(repoRoot, key)
+			if err != nil {
+				res.fail(key, err)
+				continue
+			}
+			if found {
+				fmt.Fprintf(stdout, "%s: worktree already exists at %s\n", key, existing)
+				res.skipped++
+				continue
+			}
+		}
+
+		baseURL, user, token, err := jiraSiteEnv(key)
+		if err != nil {
+			res.fail(key, err)
+			continue
+		}
+		issue, err := jiraFetchIssue(baseURL, key, user, token, customFieldIDs(cfg)...)
+		if err != nil {
+			res.fail(key, err)
+			continue
+		}
+		extras.add(baseURL, user, token, &issue)
+		opts.timings.mark("jira fetch")
+		issueOpts := opts
+		issueOpts.branch = jiraBranchName(issue.Key, issue.Fields.Summary)
+
+		wtPath, err := jiraCreateIssueWorktree(repoRoot, mainWT, baseURL, issue, issueOpts)
+		if err != nil {
+			res.fail(key, err)
+			continue
+		}
+		fmt.Fprintln(stdout, wtPath)
+		res.done++
+
+		if statusUpdate {
+			if err := jiraAutoTransition(baseURL, key, user, token, issue, cfg, cfgErr); err != nil {
+				fmt.Fprintf(stderr, "warning: %v\n", err)
+			}
+			opts.timings.mark("jira transition")
+		}
+		if updates.sprint != "" || updates.fixVersion != "" {
+			updates.apply(baseURL, key, user, token)
+			opts.timings.mark("jira update")
+		}
+	}
+
+	opts.timings.print()
+	res.finish("created", "existing")
+}
+
+// worktreeForIssue finds a worktree whose branch was made for the issue
+// key: the key itself, or the key followed by "-" (as jiraBranchName
+// generates), ignoring case.
+func worktreeForIssue(repoRoot, key string) (string, bool, error) {
+	wts, err := gitWorktrees(repoRoot)
+	if err != nil {
+		return "", false, err
+	}
+	key = strings.ToLower(key)
+	for _, wt := range wts {
+		branch := strings.ToLower(wt.Branch)
+		if branch == key || strings.HasPrefix(branch, key+"-") {
+			return wt.Path, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// jiraCreateIssueWorktree adds the worktree for issue and writes the issue
+// notes into it, in the jira.notesFormat format.
+func jiraCreateIssueWorktree(repoRoot, mainWT, baseURL string, issue jiraIssue, opts addOptions) (string, error) {
+	style, err := jiraNotesStyle(opts.cfg)
+	if err != nil {
+		return "", err
+	}
+	wtPath, err := addWorktree(repoRoot, mainWT, opts)
+	if err != nil {
+		return "", err
+	}
+	forgetJournal(repoRoot)
+	notes := renderIssue(issue, opts.cfg.Jira, baseURL, style)
+	notesPath := filepath.Join(wtPath, issue.Key+style.ext)
+	if err := osWriteFile(notesPath, []byte(notes), 0o644); err != nil {
+		return "", err
+	}
+	opts.timings.mark("notes")
+	return wtPath, nil
+}
+
+// jiraAutoTransition moves a freshly started issue to its configured
+// "working" status. Transition failures are only warnings; a missing status
+// configuration is returned as an error.
+func jiraAutoTransition(baseURL, issueKey, user, token string, issue jiraIssue, cfg wtConfig, cfgErr error) error {
+	if cfgErr != nil {
+		fmt.Fprintf(stderr, "warning: config: %v\n", cfgErr)
+		return nil
+	}
+	if !hasStatusConfig(cfg) {
+		return errors.New("no jira status mappings configured; run 'wt jira config --init'")
+	}
+	target, err := resolveStatus(cfg, issue.Fields.IssueType.Name, "working")
+	if err != nil {
+		return nil
+	}
+	if err := jiraSetStatus(baseURL, issueKey, target, user, token); err != nil {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+		return nil
+	}
+	fmt.Fprintf(stdout, "%s → %s\n", issueKey, target)
+	return nil
+}
+
 func jiraStatusCmd(args []string) {
 	if len(args) > 0 && args[0] == "sync" {
 		jiraStatusSyncCmd(args[1:])
@@ -360,13 +1415,31 @@ func jiraStatusCmd(args []string) {
 	issueKey := ""
 	statusName := ""
 
+	args, setName, err := extractSetFlag(args)
+	if err != nil {
+		die(err)
+	}
+	args, watch, err := extractWatchFlags(args)
+	if err != nil {
+		die(usageError(err))
+	}
+
 	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
-		issueKey = args[0]
+		issueKey, err = jiraIssueKeyFromArg(args[0])
+		if err != nil {
+			die(err)
+		}
 		args = args[1:]
 	}
 	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
 		statusName = args[0]
 	}
+	if setName != "" {
+		if statusName != "" {
+			die(usageError(errors.New("give the status as an argument or with --set, not both")))
+		}
+		statusName = setName
+	}
 
 	if issueKey == "" {
 		branch, err := runGitOutput("", "rev-parse", "--abbrev-ref", "HEAD")
@@ -379,11 +1452,22 @@ func jiraStatusCmd(args []string) {
 		}
 	}
 
-	baseURL, user, token, err := jiraEnv()
+	if watch.enabled && statusName != "" {
+		die(usageError(errors.New("--watch cannot be combined with setting a status")))
+	}
+
+	baseURL, user, token, err := jiraSiteEnv(issueKey)
 	if err != nil {
 		die(err)
 	}
 
+	if watch.enabled {
+		if err := jiraWatchStatus(baseURL, issueKey, user, token, watch); err != nil {
+			die(err)
+		}
+		return
+	}
+
 	if statusName != "" {
 		if err := jiraSetStatus(baseURL, issueKey, statusName, user, token); err != nil {
 			die(err)
@@ -397,23 +1481,18 @@ func jiraStatusCmd(args []string) {
 		die(err)
 	}
 
-	fmt.Fprintf(stdout, "%s: %s\n", issue.Key, issue.Fields.Status.Name)
+	fmt.Fprintf(stdout, "%s: %s\n", issue.Key, jiraStatusText(issue.Fields.Status))
 
-	tURL := fmt.Sprintf("%s/rest/api/2/issue/%s/transitions", baseURL, issueKey)
-	body, err := jiraGet(tURL, user, token)
+	transitions, err := jiraFetchTransitions(baseURL, issueKey, user, token)
 	if err != nil {
 		die(err)
 	}
-	var tr jiraTransitionsResponse
-	if err := json.Unmarshal(body, &tr); err != nil {
-		die(fmt.Errorf("jira: invalid transitions response: %w", err))
-	}
 
 	cfg, cfgErr := loadConfig()
 
-	if len(tr.Transitions) > 0 {
+	if len(transitions) > 0 {
 		fmt.Fprintln(stdout, "\nAvailable transitions:")
-		for _, t := range tr.Transitions {
+		for _, t := range transitions {
 			sym := ""
 			if cfgErr == nil && hasStatusConfig(cfg) {
 				sym = reverseSymbolic(cfg, issue.Fields.IssueType.Name, t.To.Name)
@@ -431,6 +1510,138 @@ func jiraStatusCmd(args []string) {
 	}
 }
 
+// extractSetFlag pulls "--set <name>" (or "--set=<name>") out of args so it
+// can appear before or after the issue key.
+func extractSetFlag(args []string) ([]string, string, error) {
+	return extractValueFlag(args, "set", "a status name")
+}
+
+// extractValueFlag pulls "--<flag> <value>" (or "-<flag> <value>" or
+// "--<flag>=<value>") out of args. what describes the value in the error
+// returned when it is missing.
+func extractValueFlag(args []string, flagName, what string) ([]string, string, error) {
+	rest := make([]string, 0, len(args))
+	value := ""
+	missing := fmt.Errorf("--%s requires %s", flagName, what)
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--"+flagName || a == "-"+flagName:
+			if i+1 >= len(args) || args[i+1] == "" {
+				return nil, "", missing
+			}
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--"+flagName+"="):
+			value = strings.TrimPrefix(a, "--"+flagName+"=")
+			if value == "" {
+				return nil, "", missing
+			}
+		default:
+			rest = append(rest, a)
+		}
+	}
+	return rest, value, nil
+}
+
+// jiraWatchOptions configures "wt jira status --watch".
+type jiraWatchOptions struct {
+	enabled  bool
+	until    string
+	interval time.Duration
+	retries  int
+}
+
+// extractWatchFlags pulls --watch, --until, --interval, and --retries out of
+// args. The value flags are only accepted together with --watch.
+func extractWatchFlags(args []string) ([]string, jiraWatchOptions, error) {
+	opts := jiraWatchOptions{interval: defaultWatchInterval, retries: defaultWatchRetries}
+	rest := make([]string, 0, len(args))
+	for _, a := range args {
+		if a == "--watch" || a == "-watch" {
+			opts.enabled = true
+			continue
+		}
+		rest = append(rest, a)
+	}
+
+	rest, until, err := extractValueFlag(rest, "until", "a status name")
+	if err != nil {
+		return nil, opts, err
+	}
+	rest, interval, err := extractValueFlag(rest, "interval", "a duration")
+	if err != nil {
+		return nil, opts, err
+	}
+	rest, retries, err := extractValueFlag(rest, "retries", "a number")
+	if err != nil {
+		return nil, opts, err
+	}
+	if !opts.enabled && (until != "" || interval != "" || retries != "") {
+		return nil, opts, errors.New("--until, --interval, and --retries require --watch")
+	}
+
+	opts.until = until
+	if interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return nil, opts, fmt.Errorf("invalid --interval %q: must be a positive duration", interval)
+		}
+		opts.interval = d
+	}
+	if retries != "" {
+		n, err := strconv.Atoi(retries)
+		if err != nil || n < 0 {
+			return nil, opts, fmt.Errorf("invalid --retries %q: must be a non-negative number", retries)
+		}
+		opts.retries = n
+	}
+	return rest, opts, nil
+}
+
+// jiraWatchStatus polls the issue and prints each status change until it
+// reaches opts.until (or forever when no target is set). Up to opts.retries
+// consecutive fetch errors are reported as warnings before giving up.
+func jiraWatchStatus(baseURL, issueKey, user, token string, opts jiraWatchOptions) error {
+	cfg, cfgErr := loadConfig()
+	var current jiraStatus
+	failures := 0
+	for {
+		issue, err := jiraFetchIssue(baseURL, issueKey, user, token)
+		if err != nil {
+			failures++
+			if failures > opts.retries {
+				return err
+			}
+			fmt.Fprintf(stderr, "warning: %v (retry %d/%d)\n", err, failures, opts.retries)
+			jiraSleep(opts.interval)
+			continue
+		}
+		failures = 0
+
+		status := issue.Fields.Status.Name
+		if current.Name == "" {
+			fmt.Fprintf(stdout, "%s: %s\n", issueKey, jiraStatusText(issue.Fields.Status))
+		} else if status != current.Name {
+			fmt.Fprintf(stdout, "%s: %s → %s\n", issueKey, jiraStatusText(current), jiraStatusText(issue.Fields.Status))
+		}
+		current = issue.Fields.Status
+
+		if opts.until != "" {
+			target := opts.until
+			if cfgErr == nil && hasStatusConfig(cfg) {
+				if resolved, err := resolveStatus(cfg, issue.Fields.IssueType.Name, opts.until); err == nil {
+					target = resolved
+				}
+			}
+			if strings.EqualFold(status, target) {
+				return nil
+			}
+		}
+		jiraSleep(opts.interval)
+	}
+}
+
 func jiraStatusSyncCmd(args []string) {
 	fs := flag.NewFlagSet("jira status sync", flag.ExitOnError)
 	fs.Usage = printJiraStatusUsage
@@ -440,7 +1651,11 @@ func jiraStatusSyncCmd(args []string) {
 
 	issueKey := ""
 	if fs.NArg() > 0 {
-		issueKey = fs.Arg(0)
+		key, err := jiraIssueKeyFromArg(fs.Arg(0))
+		if err != nil {
+			die(err)
+		}
+		issueKey = key
 	}
 
 	if issueKey == "" {
@@ -470,7 +1685,7 @@ func jiraStatusSyncCmd(args []string) {
 		return
 	}
 
-	baseURL, user, token, err := jiraEnv()
+	baseURL, user, token, err := jiraSiteEnv(issueKey)
 	if err != nil {
 		die(err)
 	}
@@ -502,13 +1717,42 @@ func jiraStatusSyncCmd(args []string) {
 }
 
 func jiraConfigCmd(args []string) {
+	if isHelpArg(args) {
+		printJiraConfigUsage()
+		return
+	}
 	fs := flag.NewFlagSet("jira config", flag.ExitOnError)
 	fs.Usage = printJiraConfigUsage
 	initFlag := fs.Bool("init", false, "bootstrap a template config")
+	editFlag := fs.Bool("edit", false, "open the config file in $EDITOR")
+	global := fs.Bool("global", false, "use the global config file")
+	repo := fs.Bool("repo", false, "use the repo config file")
+	validate := fs.String("validate", "", "check the status mappings against an issue's transitions")
 	_ = fs.Parse(args)
 
+	if *global && *repo {
+		die(usageError(errors.New("--global and --repo cannot be used together")))
+	}
+	if *validate != "" {
+		if *initFlag || *editFlag {
+			die(usageError(errors.New("--validate cannot be combined with --init or --edit")))
+		}
+		jiraConfigValidate(*validate)
+		return
+	}
+	choice := ""
+	if *global {
+		choice = "g"
+	} else if *repo {
+		choice = "r"
+	}
+
 	if *initFlag {
-		jiraConfigInit()
+		jiraConfigInit(choice)
+		return
+	}
+	if *editFlag {
+		jiraConfigEdit(choice)
 		return
 	}
 
@@ -556,7 +1800,7 @@ func jiraConfigCmd(args []string) {
 
 	if fs.NArg() > 0 {
 		issueKey := fs.Arg(0)
-		baseURL, user, token, err := jiraEnv()
+		baseURL, user, token, err := jiraSiteEnv(issueKey)
 		if err != nil {
 			die(err)
 		}
@@ -577,50 +1821,149 @@ func jiraConfigCmd(args []string) {
 	}
 }
 
-func jiraConfigInit() {
-	fmt.Fprintln(stdout, "Where should the config be written?")
-	fmt.Fprintln(stdout, "  [g] global  (~/.config/wt/config.json)")
-	fmt.Fprintln(stdout, "  [r] repo    (.wt.json)")
-	fmt.Fprintf(stdout, "choice [g/r]: ")
+// jiraConfigValidate checks that every status mapping that applies to the
+// issue names a status it can move to from its current one, and fails if
+// any can't. Nothing is changed in Jira.
+func jiraConfigValidate(arg string) {
+	issueKey, err := jiraIssueKeyFromArg(arg)
+	if err != nil {
+		die(err)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		die(err)
+	}
+	if !hasStatusConfig(cfg) {
+		die(errors.New("no jira.status mappings to validate; run 'wt jira config --init' to create them"))
+	}
+	baseURL, user, token, err := jiraSiteEnv(issueKey)
+	if err != nil {
+		die(err)
+	}
+	issue, err := jiraFetchIssue(baseURL, issueKey, user, token)
+	if err != nil {
+		die(err)
+	}
+	transitions, err := jiraFetchTransitions(baseURL, issueKey, user, token)
+	if err != nil {
+		die(err)
+	}
+	if n := jiraValidateMappings(cfg, issue, transitions); n > 0 {
+		die(fmt.Errorf("%d status mapping(s) can't be reached from %s", n, issue.Fields.Status.Name))
+	}
+}
+
+// jiraValidateMappings prints each status mapping that applies to issue's
+// type, marking whether its target is the current status or one of
+// transitions. It returns how many targets are neither.
+func jiraValidateMappings(cfg wtConfig, issue jiraIssue, transitions []jiraTransition) int {
+	issueType := issue.Fields.IssueType.Name
+	symbolics := make(map[string]bool)
+	for sym := range cfg.Jira.Status.Default {
+		symbolics[sym] = true
+	}
+	for sym := range cfg.Jira.Status.Types[strings.ToLower(issueType)] {
+		symbolics[sym] = true
+	}
+	keys := make([]string, 0, len(symbolics))
+	for sym := range symbolics {
+		keys = append(keys, sym)
+	}
+	sort.Strings(keys)
+
+	reachable := make(map[string]bool, len(transitions))
+	available := make([]string, 0, len(transitions))
+	for _, t := range transitions {
+		reachable[strings.ToLower(t.To.Name)] = true
+		available = append(available, t.To.Name)
+	}
+
+	current := issue.Fields.Status.Name
+	fmt.Fprintf(stdout, "%s (%s, %s):\n", issue.Key, strings.ToLower(issueType), current)
+	unreachable := 0
+	for _, sym := range keys {
+		target, _ := resolveStatus(cfg, issueType, sym)
+		note := "ok"
+		switch {
+		case strings.EqualFold(target, current):
+			note = "ok, current status"
+		case !reachable[strings.ToLower(target)]:
+			note = "not reachable"
+			unreachable++
+		}
+		fmt.Fprintf(stdout, "  %s → %s: %s\n", sym, target, note)
+	}
+	if unreachable > 0 {
+		if len(available) == 0 {
+			fmt.Fprintf(stdout, "\nno transitions are available from %s\n", current)
+		} else {
+			fmt.Fprintf(stdout, "\navailable from %s: %s\n", current, strings.Join(available, ", "))
+		}
+	}
+	return unreachable
+}
 
-	scanner := bufio.NewScanner(stdin)
-	if !scanner.Scan() {
-		die(errors.New("no input"))
+func jiraConfigInit(choice string) {
+	path, err := jiraConfigPath(choice, "Where should the config be written?")
+	if err != nil {
+		die(err)
 	}
-	choice := strings.TrimSpace(scanner.Text())
 
 	cfg := templateConfig()
 	data, _ := json.MarshalIndent(cfg, "", "  ")
 	data = append(data, '\n')
 
-	var path string
-	switch choice {
-	case "g":
-		home, err := osUserHomeDir()
-		if err != nil {
-			die(err)
-		}
-		dir := filepath.Join(home, ".config", "wt")
-		if err := osMkdirAll(dir, 0o755); err != nil {
-			die(err)
-		}
-		path = filepath.Join(dir, "config.json")
-	case "r":
-		root, err := gitRepoRoot()
-		if err != nil {
-			die(err)
-		}
-		path = filepath.Join(root, ".wt.json")
-	default:
-		die(fmt.Errorf("invalid choice: %q", choice))
-	}
-
 	if err := osWriteFile(path, data, 0o644); err != nil {
 		die(err)
 	}
 	fmt.Fprintf(stdout, "wrote %s\n", path)
 }
 
+// jiraConfigEdit opens the chosen config file in the user's editor and then
+// checks that the resulting config still loads.
+func jiraConfigEdit(choice string) {
+	path, err := jiraConfigPath(choice, "Which config should be edited?")
+	if err != nil {
+		die(err)
+	}
+	if err := openEditor(path); err != nil {
+		die(err)
+	}
+	if _, err := loadConfig(); err != nil {
+		die(err)
+	}
+	fmt.Fprintf(stdout, "%s is valid\n", path)
+}
+
+// jiraConfigPath resolves the global or repo config path for choice ("g" or
+// "r"), asking question when choice is empty. The global config directory is
+// created if needed.
+func jiraConfigPath(choice, question string) (string, error) {
+	if choice == "" {
+		fmt.Fprintln(stdout, question)
+		fmt.Fprintln(stdout, "  [g] global  (~/.config/wt/config.json)")
+		fmt.Fprintln(stdout, "  [r] repo    (.wt.json)")
+		fmt.Fprintf(stdout, "choice [g/r]: ")
+
+		scanner := bufio.NewScanner(stdin)
+		if !scanner.Scan() {
+			return "", errors.New("no input")
+		}
+		choice = strings.TrimSpace(scanner.Text())
+	}
+
+	path, err := configFilePath(choice)
+	if err != nil {
+		return "", err
+	}
+	if choice == "g" {
+		if err := osMkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
 func ghPRSymbolicStatus() (string, error) {
 	branch, err := runGitOutput("", "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
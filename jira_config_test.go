@@ -103,6 +103,45 @@ func TestLoadConfig(t *testing.T) {
 		}
 	})
 
+	t.Run("repo jira sites and git config ignored", func(t *testing.T) {
+		repo := t.TempDir()
+		osUserHomeDir = func() (string, error) { return "/home/test", nil }
+		execCommand = func(name string, args ...string) *exec.Cmd {
+			if len(args) > 0 && args[0] == "-C" {
+				args = args[2:]
+			}
+			if len(args) >= 2 && args[0] == "rev-parse" {
+				return cmdWithOutput(repo)
+			}
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		for _, global := range []string{`{"jira":{"sites":{"acme":{"url":"https://acme.example.com"}}}}`, ""} {
+			osReadFile = func(name string) ([]byte, error) {
+				if name == "/home/test/.config/wt/config.json" && global != "" {
+					return []byte(global), nil
+				}
+				if name == filepath.Join(repo, ".wt.json") {
+					return []byte(`{"jira":{"sites":{"acme":{"url":"https://evil.example.com","tokenCommand":"curl evil"},"evil":{"tokenCommand":"rm -rf ~"}}},` +
+						`"worktree":{"gitConfig":{"core.fsmonitor":"curl evil"}}}`), nil
+				}
+				return nil, os.ErrNotExist
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if global == "" && cfg.Jira.Sites != nil {
+				t.Fatalf("expected the repo's sites ignored, got %+v", cfg.Jira.Sites)
+			}
+			if global != "" && (len(cfg.Jira.Sites) != 1 || cfg.Jira.Sites["acme"].URL != "https://acme.example.com") {
+				t.Fatalf("expected only the global sites, got %+v", cfg.Jira.Sites)
+			}
+			if cfg.Worktree.GitConfig != nil {
+				t.Fatalf("expected the repo's git config ignored, got %+v", cfg.Worktree.GitConfig)
+			}
+		}
+	})
+
 	t.Run("neither exists", func(t *testing.T) {
 		osUserHomeDir = func() (string, error) { return "/home/test", nil }
 		execCommand = func(name string, args ...string) *exec.Cmd {
@@ -1859,3 +1898,330 @@ func TestJiraStatusCmdShowHint(t *testing.T) {
 		t.Fatalf("expected hint on stderr, got %q", errBuf.String())
 	}
 }
+
+func stubConfigEdit(t *testing.T, repoConfig string, editorErr bool) (*[]string, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	oldOut := stdout
+	oldErr := stderr
+	oldIn := stdin
+	oldExit := exitFunc
+	oldExec := execCommand
+	oldHomeDir := osUserHomeDir
+	oldReadFile := osReadFile
+	oldMkdir := osMkdirAll
+	t.Cleanup(func() {
+		stdout = oldOut
+		stderr = oldErr
+		stdin = oldIn
+		exitFunc = oldExit
+		execCommand = oldExec
+		osUserHomeDir = oldHomeDir
+		osReadFile = oldReadFile
+		osMkdirAll = oldMkdir
+	})
+
+	var editorCall []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name != "git" {
+			editorCall = append([]string{name}, args...)
+			if editorErr {
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "rev-parse" && args[1] == "--show-toplevel" {
+			return cmdWithOutput("/my/repo")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	osMkdirAll = func(path string, perm fs.FileMode) error { return nil }
+	osReadFile = func(name string) ([]byte, error) {
+		if name == "/my/repo/.wt.json" {
+			return []byte(repoConfig), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	exitFunc = func(code int) { panic(code) }
+
+	var out, errBuf bytes.Buffer
+	stdout = &out
+	stderr = &errBuf
+	return &editorCall, &out, &errBuf
+}
+
+func TestJiraConfigCmdEditRepo(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "myeditor --wait")
+	editorCall, out, _ := stubConfigEdit(t, `{"jira":{"status":{"default":{"done":"Done"}}}}`, false)
+
+	jiraConfigCmd([]string{"--edit", "--repo"})
+
+	if strings.Join(*editorCall, " ") != "myeditor --wait /my/repo/.wt.json" {
+		t.Fatalf("unexpected editor call %v", *editorCall)
+	}
+	if !strings.Contains(out.String(), "/my/repo/.wt.json is valid") {
+		t.Fatalf("expected validation message, got %q", out.String())
+	}
+}
+
+func TestJiraConfigCmdEditPrompt(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	editorCall, out, _ := stubConfigEdit(t, `{}`, false)
+	stdin = strings.NewReader("g\n")
+
+	jiraConfigCmd([]string{"--edit"})
+
+	if strings.Join(*editorCall, " ") != "vi /home/test/.config/wt/config.json" {
+		t.Fatalf("unexpected editor call %v", *editorCall)
+	}
+	if !strings.Contains(out.String(), "Which config should be edited?") {
+		t.Fatalf("expected prompt, got %q", out.String())
+	}
+}
+
+func TestJiraConfigCmdEditBlankEditor(t *testing.T) {
+	tests := []struct {
+		name   string
+		visual string
+		editor string
+		want   string
+	}{
+		{name: "blank editor", visual: "", editor: " ", want: "vi"},
+		{name: "blank visual", visual: "\t", editor: "myeditor", want: "myeditor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", tt.visual)
+			t.Setenv("EDITOR", tt.editor)
+			editorCall, _, _ := stubConfigEdit(t, `{}`, false)
+
+			jiraConfigCmd([]string{"--edit", "--repo"})
+
+			if strings.Join(*editorCall, " ") != tt.want+" /my/repo/.wt.json" {
+				t.Fatalf("unexpected editor call %v", *editorCall)
+			}
+		})
+	}
+}
+
+func TestJiraConfigCmdEditErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		config    string
+		editorErr bool
+		want      string
+		code      int
+	}{
+		{name: "invalid after edit", args: []string{"--edit", "--repo"}, config: `{`, want: "invalid config /my/repo/.wt.json", code: exitError},
+		{name: "editor fails", args: []string{"--edit", "--repo"}, config: `{}`, editorErr: true, want: "editor myeditor failed", code: exitError},
+		{name: "both locations", args: []string{"--edit", "--global", "--repo"}, config: `{}`, want: "--global and --repo cannot be used together", code: exitUsage},
+		{name: "no choice", args: []string{"--edit"}, config: `{}`, want: "no input", code: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", "myeditor")
+			_, _, errBuf := stubConfigEdit(t, tt.config, tt.editorErr)
+			stdin = strings.NewReader("")
+			defer func() {
+				if r := recover(); r != tt.code {
+					t.Fatalf("expected exit %d, got %v", tt.code, r)
+				}
+				if !strings.Contains(errBuf.String(), tt.want) {
+					t.Fatalf("expected %q, got %q", tt.want, errBuf.String())
+				}
+			}()
+			jiraConfigCmd(tt.args)
+		})
+	}
+}
+
+func TestJiraConfigCmdInitGlobalFlag(t *testing.T) {
+	_, out, _ := stubConfigEdit(t, `{}`, false)
+	oldWriteFile := osWriteFile
+	defer func() { osWriteFile = oldWriteFile }()
+	var writePath string
+	osWriteFile = func(name string, data []byte, perm fs.FileMode) error {
+		writePath = name
+		return nil
+	}
+
+	jiraConfigCmd([]string{"--init", "--global"})
+
+	if writePath != "/home/test/.config/wt/config.json" {
+		t.Fatalf("expected global config written, got %q", writePath)
+	}
+	if strings.Contains(out.String(), "choice [g/r]") {
+		t.Fatalf("expected no prompt with --global, got %q", out.String())
+	}
+}
+
+// stubConfigValidate stubs the config, Jira environment and GET requests for
+// wt jira config --validate. get serves the issue and transitions URLs.
+func stubConfigValidate(t *testing.T, config string, env bool, get func(url string) ([]byte, error)) (*bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	oldOut := stdout
+	oldErr := stderr
+	oldReadFile := osReadFile
+	oldHomeDir := osUserHomeDir
+	oldExec := execCommand
+	oldGetenv := osGetenv
+	oldGet := jiraGet
+	oldExit := exitFunc
+	t.Cleanup(func() {
+		stdout = oldOut
+		stderr = oldErr
+		osReadFile = oldReadFile
+		osUserHomeDir = oldHomeDir
+		execCommand = oldExec
+		osGetenv = oldGetenv
+		jiraGet = oldGet
+		exitFunc = oldExit
+	})
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	execCommand = func(name string, args ...string) *exec.Cmd { return exec.Command("sh", "-c", "exit 1") }
+	osReadFile = func(name string) ([]byte, error) {
+		if name == "/home/test/.config/wt/config.json" {
+			return []byte(config), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	osGetenv = func(key string) string {
+		if !env {
+			return ""
+		}
+		switch key {
+		case "JIRA_URL":
+			return "https://jira.example.com"
+		case "JIRA_USER":
+			return "user"
+		case "JIRA_TOKEN":
+			return "token"
+		}
+		return ""
+	}
+	jiraGet = func(url, user, token string) ([]byte, error) { return get(url) }
+	exitFunc = func(code int) { panic(code) }
+	var outBuf, errBuf bytes.Buffer
+	stdout = &outBuf
+	stderr = &errBuf
+	return &outBuf, &errBuf
+}
+
+func TestJiraConfigValidate(t *testing.T) {
+	const config = `{"jira":{"status":{"default":{"todo":"To Do","working":"In Progress","review":"In Review","done":"Done"},"types":{"story":{"review":"Code Review"}}}}}`
+	issueBody, _ := json.Marshal(jiraIssue{Key: "PROJ-123", Fields: jiraFields{
+		Status:    jiraStatus{Name: "To Do"},
+		IssueType: jiraIssueType{Name: "Story"},
+	}})
+	transitions := func(names ...string) []byte {
+		var tr jiraTransitionsResponse
+		for i, name := range names {
+			tr.Transitions = append(tr.Transitions, jiraTransition{ID: fmt.Sprint(i), To: jiraStatus{Name: name}})
+		}
+		data, _ := json.Marshal(tr)
+		return data
+	}
+	serve := func(tr []byte) func(string) ([]byte, error) {
+		return func(url string) ([]byte, error) {
+			if strings.HasSuffix(url, "/transitions") {
+				return tr, nil
+			}
+			return issueBody, nil
+		}
+	}
+
+	out, _ := stubConfigValidate(t, config, true, serve(transitions("In Progress", "code review", "Done")))
+	jiraConfigCmd([]string{"--validate", "https://jira.example.com/browse/PROJ-123"})
+	want := "PROJ-123 (story, To Do):\n" +
+		"  done → Done: ok\n" +
+		"  review → Code Review: ok\n" +
+		"  todo → To Do: ok, current status\n" +
+		"  working → In Progress: ok\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	tests := []struct {
+		name    string
+		tr      []byte
+		wantOut string
+	}{
+		{"unreachable", transitions("In Progress", "Blocked"), "  done → Done: not reachable\n  review → Code Review: not reachable\n  todo → To Do: ok, current status\n  working → In Progress: ok\n\navailable from To Do: In Progress, Blocked\n"},
+		{"no transitions", transitions(), "  working → In Progress: not reachable\n\nno transitions are available from To Do\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, errOut := stubConfigValidate(t, config, true, serve(tt.tr))
+			defer func() {
+				if r := recover(); r != exitError {
+					t.Fatalf("expected exit %d, got %v", exitError, r)
+				}
+				if !strings.HasSuffix(out.String(), tt.wantOut) {
+					t.Fatalf("expected output ending %q, got %q", tt.wantOut, out.String())
+				}
+				if !strings.Contains(errOut.String(), "status mapping(s) can't be reached from To Do") {
+					t.Fatalf("expected failure summary, got %q", errOut.String())
+				}
+			}()
+			jiraConfigCmd([]string{"--validate", "PROJ-123"})
+		})
+	}
+}
+
+func TestJiraConfigValidateErrors(t *testing.T) {
+	const config = `{"jira":{"status":{"default":{"working":"In Progress"}}}}`
+	issueBody, _ := json.Marshal(jiraIssue{Key: "PROJ-123"})
+	tests := []struct {
+		name   string
+		args   []string
+		config string
+		noEnv  bool
+		get    func(url string) ([]byte, error)
+		want   string
+		code   int
+	}{
+		{name: "with init", args: []string{"--validate", "PROJ-1", "--init"}, config: config, want: "--validate cannot be combined with --init or --edit", code: exitUsage},
+		{name: "with edit", args: []string{"--edit", "--validate", "PROJ-1"}, config: config, want: "--validate cannot be combined", code: exitUsage},
+		{name: "bad url", args: []string{"--validate", "https://jira.example.com/"}, config: config, want: "no issue key found", code: exitError},
+		{name: "bad config", args: []string{"--validate", "PROJ-1"}, config: `{`, want: "invalid config", code: exitError},
+		{name: "no mappings", args: []string{"--validate", "PROJ-1"}, config: `{}`, want: "no jira.status mappings to validate", code: exitError},
+		{name: "no env", args: []string{"--validate", "PROJ-1"}, config: config, noEnv: true, want: "JIRA_URL", code: exitError},
+		{
+			name: "issue fetch", args: []string{"--validate", "PROJ-1"}, config: config, want: "issue fail", code: exitError,
+			get: func(string) ([]byte, error) { return nil, errors.New("issue fail") },
+		},
+		{
+			name: "transitions fetch", args: []string{"--validate", "PROJ-1"}, config: config, want: "jira: invalid transitions response", code: exitError,
+			get: func(url string) ([]byte, error) {
+				if strings.HasSuffix(url, "/transitions") {
+					return []byte("{"), nil
+				}
+				return issueBody, nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			get := tt.get
+			if get == nil {
+				get = func(string) ([]byte, error) { return nil, errors.New("unexpected request") }
+			}
+			_, errOut := stubConfigValidate(t, tt.config, !tt.noEnv, get)
+			defer func() {
+				if r := recover(); r != tt.code {
+					t.Fatalf("expected exit %d, got %v", tt.code, r)
+				}
+				if !strings.Contains(errOut.String(), tt.want) {
+					t.Fatalf("expected %q, got %q", tt.want, errOut.String())
+				}
+			}()
+			jiraConfigCmd(tt.args)
+		})
+	}
+}
@@ -5,14 +5,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
 )
 
 func TestSlugify(t *testing.T) {
@@ -72,7 +80,7 @@ func TestRenderIssueMD(t *testing.T) {
 			},
 		},
 	}
-	md := renderIssueMD(issue)
+	md := renderIssue(issue, jiraConfigBlock{}, "", notesStyles[notesFormatMarkdown])
 	if !strings.Contains(md, "# PROJ-123: Fix login timeout") {
 		t.Fatalf("expected title in md: %s", md)
 	}
@@ -91,7 +99,7 @@ func TestRenderIssueMD(t *testing.T) {
 		Key:    "PROJ-456",
 		Fields: jiraFields{Summary: "Simple bug"},
 	}
-	md2 := renderIssueMD(issue2)
+	md2 := renderIssue(issue2, jiraConfigBlock{}, "", notesStyles[notesFormatMarkdown])
 	if strings.Contains(md2, "## Description") {
 		t.Fatalf("expected no description section: %s", md2)
 	}
@@ -104,7 +112,7 @@ func TestRenderIssueMD(t *testing.T) {
 		Key:    "PROJ-789",
 		Fields: jiraFields{Summary: "With desc", Description: "Some desc"},
 	}
-	md3 := renderIssueMD(issue3)
+	md3 := renderIssue(issue3, jiraConfigBlock{}, "", notesStyles[notesFormatMarkdown])
 	if !strings.Contains(md3, "## Description") {
 		t.Fatalf("expected description: %s", md3)
 	}
@@ -124,13 +132,115 @@ func TestRenderIssueMD(t *testing.T) {
 			},
 		},
 	}
-	md4 := renderIssueMD(issue4)
+	md4 := renderIssue(issue4, jiraConfigBlock{}, "", notesStyles[notesFormatMarkdown])
 	if strings.Contains(md4, "## Description") {
 		t.Fatalf("expected no description: %s", md4)
 	}
 	if !strings.Contains(md4, "## Comments") {
 		t.Fatalf("expected comments: %s", md4)
 	}
+	if strings.Contains(md4, "## Child Issues") {
+		t.Fatalf("expected no child issues: %s", md4)
+	}
+
+	// Epic with children
+	issue5 := jiraIssue{
+		Key:    "EPIC-1",
+		Fields: jiraFields{Summary: "Login revamp"},
+		Children: []jiraIssue{
+			{Key: "PROJ-1", Fields: jiraFields{Summary: "New form", Status: jiraStatus{Name: "Done"}}},
+			{Key: "PROJ-2", Fields: jiraFields{Summary: "SSO", Status: jiraStatus{Name: "To Do"}}},
+		},
+	}
+	md5 := renderIssue(issue5, jiraConfigBlock{}, "", notesStyles[notesFormatMarkdown])
+	if !strings.Contains(md5, "## Child Issues\n\n- PROJ-1: New form (Done)\n- PROJ-2: SSO (To Do)\n") {
+		t.Fatalf("expected child issues: %s", md5)
+	}
+}
+
+func TestRenderIssueMDLinkedIssues(t *testing.T) {
+	data := `{"key":"PROJ-1","fields":{"summary":"Login","issuelinks":[
+		{"type":{"name":"Blocks","inward":"is blocked by","outward":"blocks"},
+		 "outwardIssue":{"key":"PROJ-2","fields":{"summary":"Deploy","status":{"name":"To Do"}}}},
+		{"type":{"name":"Blocks","inward":"is blocked by","outward":"blocks"},
+		 "inwardIssue":{"key":"PROJ-3","fields":{"summary":"Schema","status":{"name":"Done"}}}},
+		{"type":{"name":"Relates"},
+		 "inwardIssue":{"key":"OPS-4","fields":{"summary":"Alerts","status":{"name":"Open"}}}},
+		{"type":{"name":"Broken"}}
+	]}}`
+	var issue jiraIssue
+	if err := json.Unmarshal([]byte(data), &issue); err != nil {
+		t.Fatal(err)
+	}
+
+	md := renderIssue(issue, jiraConfigBlock{}, "", notesStyles[notesFormatMarkdown])
+	want := "\n## Linked Issues\n\n" +
+		"- blocks PROJ-2: Deploy (To Do)\n" +
+		"- is blocked by PROJ-3: Schema (Done)\n" +
+		"- Relates OPS-4: Alerts (Open)\n"
+	if !strings.HasSuffix(md, want) {
+		t.Fatalf("expected linked issues %q, got %q", want, md)
+	}
+
+	if md := renderIssue(jiraIssue{Key: "PROJ-5"}, jiraConfigBlock{}, "", notesStyles[notesFormatMarkdown]); strings.Contains(md, "## Linked Issues") {
+		t.Fatalf("expected no linked issues section: %s", md)
+	}
+}
+
+func TestJiraFetchChildren(t *testing.T) {
+	oldGet := jiraGet
+	defer func() { jiraGet = oldGet }()
+
+	gotURL := ""
+	jiraGet = func(u, user, token string) ([]byte, error) {
+		gotURL = u
+		return []byte(`{"issues":[{"key":"PROJ-1","fields":{"summary":"One","status":{"name":"Done"}}}]}`), nil
+	}
+	children, err := jiraFetchChildren("https://jira.example.com", "EPIC-1", "user", "token")
+	if err != nil || len(children) != 1 || children[0].Key != "PROJ-1" || children[0].Fields.Status.Name != "Done" {
+		t.Fatalf("unexpected children %+v, %v", children, err)
+	}
+	parsed, err := url.Parse(gotURL)
+	if err != nil || parsed.Path != "/rest/api/2/search" || parsed.Query().Get("jql") != `"Epic Link" = EPIC-1 ORDER BY key` {
+		t.Fatalf("unexpected search URL %q", gotURL)
+	}
+
+	jiraGet = func(u, user, token string) ([]byte, error) { return []byte(`{`), nil }
+	if _, err := jiraFetchChildren("https://jira.example.com", "EPIC-1", "user", "token"); err == nil || !strings.Contains(err.Error(), "invalid search response") {
+		t.Fatalf("expected invalid response error, got %v", err)
+	}
+	jiraGet = func(u, user, token string) ([]byte, error) { return nil, errors.New("boom") }
+	if _, err := jiraFetchChildren("https://jira.example.com", "EPIC-1", "user", "token"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestJiraAddChildren(t *testing.T) {
+	oldGet := jiraGet
+	oldErr := stderr
+	defer func() {
+		jiraGet = oldGet
+		stderr = oldErr
+	}()
+	var buf bytes.Buffer
+	stderr = &buf
+
+	searched := false
+	jiraGet = func(u, user, token string) ([]byte, error) {
+		searched = true
+		return nil, errors.New("boom")
+	}
+	story := jiraIssue{Key: "PROJ-1", Fields: jiraFields{IssueType: jiraIssueType{Name: "Story"}}}
+	jiraAddChildren("https://jira.example.com", "user", "token", &story)
+	if searched {
+		t.Fatalf("expected no search for a non-epic")
+	}
+
+	epic := jiraIssue{Key: "EPIC-1", Fields: jiraFields{IssueType: jiraIssueType{Name: "Epic"}}}
+	jiraAddChildren("https://jira.example.com", "user", "token", &epic)
+	if epic.Children != nil || !strings.Contains(buf.String(), "warning: could not fetch child issues of EPIC-1: boom") {
+		t.Fatalf("expected warning on search failure, got %q", buf.String())
+	}
 }
 
 func TestJiraGetDefaultSuccess(t *testing.T) {
@@ -200,6 +310,184 @@ func TestJiraGetDefaultNetworkError(t *testing.T) {
 	}
 }
 
+// resetJiraTimeout drops the cached Jira timeout now and when the test
+// ends, so the test resolves it afresh.
+func resetJiraTimeout(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		jiraTimeoutOnce = sync.Once{}
+		jiraTimeoutCached = 0
+		jiraTimeoutErr = nil
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestJiraTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		config  string
+		want    time.Duration
+		wantErr string
+	}{
+		{name: "default", want: defaultJiraTimeout},
+		{name: "config", config: `{"jira":{"timeout":"5s"}}`, want: 5 * time.Second},
+		{name: "env overrides config", env: "2m", config: `{"jira":{"timeout":"5s"}}`, want: 2 * time.Minute},
+		{name: "invalid env", env: "soon", wantErr: `invalid JIRA_TIMEOUT "soon"`},
+		{name: "invalid config", config: `{"jira":{"timeout":"0s"}}`, wantErr: `invalid jira.timeout "0s"`},
+		{name: "unreadable config", config: `{`, wantErr: "invalid config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldGetenv := osGetenv
+			oldHome := osUserHomeDir
+			oldRead := osReadFile
+			defer func() {
+				osGetenv = oldGetenv
+				osUserHomeDir = oldHome
+				osReadFile = oldRead
+			}()
+			osGetenv = func(key string) string {
+				if key == "JIRA_TIMEOUT" {
+					return tt.env
+				}
+				return ""
+			}
+			osUserHomeDir = func() (string, error) { return "/home/test", nil }
+			osReadFile = func(name string) ([]byte, error) {
+				if tt.config == "" || !strings.HasPrefix(name, "/home/test") {
+					return nil, os.ErrNotExist
+				}
+				return []byte(tt.config), nil
+			}
+
+			resetJiraTimeout(t)
+			got, err := jiraTimeout()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("jiraTimeout() = %v, %v; want %v", got, err, tt.want)
+			}
+			// Later requests reuse the timeout without reading the config.
+			osReadFile = func(name string) ([]byte, error) {
+				t.Fatalf("unexpected read of %s", name)
+				return nil, nil
+			}
+			if again, err := jiraTimeout(); err != nil || again != got {
+				t.Fatalf("expected cached %v, got %v, %v", got, again, err)
+			}
+		})
+	}
+}
+
+func TestJiraRequestTimeout(t *testing.T) {
+	resetJiraTimeout(t)
+	oldGetenv := osGetenv
+	defer func() { osGetenv = oldGetenv }()
+	osGetenv = func(key string) string {
+		if key == "JIRA_TIMEOUT" {
+			return "50ms"
+		}
+		return ""
+	}
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	_, err := jiraGetDefault(srv.URL+"/rest/api/2/issue/TEST-1", "user", "token")
+	if !errors.Is(err, errJiraTimeout) || err.Error() != "jira: request timed out after 50ms" {
+		t.Fatalf("expected timeout error from GET, got %v", err)
+	}
+	_, err = jiraPostDefault(srv.URL, "user", "token", []byte(`{}`))
+	if !errors.Is(err, errJiraTimeout) {
+		t.Fatalf("expected timeout error from POST, got %v", err)
+	}
+}
+
+func TestJiraRequestInvalidTimeout(t *testing.T) {
+	resetJiraTimeout(t)
+	oldGetenv := osGetenv
+	defer func() { osGetenv = oldGetenv }()
+	osGetenv = func(key string) string {
+		if key == "JIRA_TIMEOUT" {
+			return "-1s"
+		}
+		return ""
+	}
+
+	if _, err := jiraGetDefault("http://127.0.0.1:1/bad", "user", "token"); err == nil || !strings.Contains(err.Error(), "invalid JIRA_TIMEOUT") {
+		t.Fatalf("expected timeout config error from GET, got %v", err)
+	}
+	if _, err := jiraPostDefault("http://127.0.0.1:1/bad", "user", "token", nil); err == nil || !strings.Contains(err.Error(), "invalid JIRA_TIMEOUT") {
+		t.Fatalf("expected timeout config error from POST, got %v", err)
+	}
+}
+
+func TestJiraStatusText(t *testing.T) {
+	oldTerm := stdoutIsTerminal
+	oldGetenv := osGetenv
+	defer func() {
+		stdoutIsTerminal = oldTerm
+		osGetenv = oldGetenv
+	}()
+	done := jiraStatus{Name: "Done", StatusCategory: jiraStatusCategory{Key: "done"}}
+
+	tests := []struct {
+		name    string
+		tty     bool
+		noColor string
+		status  jiraStatus
+		want    string
+	}{
+		{name: "not a terminal", status: done, want: "Done"},
+		{name: "NO_COLOR", tty: true, noColor: "1", status: done, want: "Done"},
+		{name: "unknown category", tty: true, status: jiraStatus{Name: "Blocked"}, want: "Blocked"},
+		{name: "colored", tty: true, status: done, want: lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Render("Done")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdoutIsTerminal = func() bool { return tt.tty }
+			osGetenv = func(key string) string {
+				if key == "NO_COLOR" {
+					return tt.noColor
+				}
+				return ""
+			}
+			if got := jiraStatusText(tt.status); got != tt.want {
+				t.Fatalf("jiraStatusText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for key, want := range map[string]lipgloss.Color{"new": "245", "indeterminate": "4", "done": "2"} {
+		if got := jiraStatusColors[key]; got != want {
+			t.Errorf("color for %s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestJiraStatusCategoryDecoded(t *testing.T) {
+	var issue jiraIssue
+	if err := json.Unmarshal([]byte(`{"key":"PROJ-1","fields":{"status":{"name":"In Review","statusCategory":{"key":"indeterminate","name":"In Progress"}}}}`), &issue); err != nil {
+		t.Fatal(err)
+	}
+	if got := issue.Fields.Status.StatusCategory.Key; got != "indeterminate" {
+		t.Fatalf("expected indeterminate category, got %q", got)
+	}
+}
+
 func TestJiraCmdSuccess(t *testing.T) {
 	repo := t.TempDir()
 
@@ -426,8 +714,8 @@ func TestJiraCmdMissingIssueKey(t *testing.T) {
 	exitFunc = func(code int) { panic(code) }
 
 	defer func() {
-		if r := recover(); r != 1 {
-			t.Fatalf("expected exit 1, got %v", r)
+		if r := recover(); r != exitUsage {
+			t.Fatalf("expected exit 2, got %v", r)
 		}
 		if !strings.Contains(buf.String(), "issue key required") {
 			t.Fatalf("expected issue key error, got %q", buf.String())
@@ -470,7 +758,9 @@ func TestJiraCmdAPIError(t *testing.T) {
 	oldJiraGet := jiraGet
 	oldExit := exitFunc
 	oldErr := stderr
+	oldExec := execCommand
 	defer func() {
+		execCommand = oldExec
 		osGetenv = oldGetenv
 		jiraGet = oldJiraGet
 		exitFunc = oldExit
@@ -493,6 +783,14 @@ func TestJiraCmdAPIError(t *testing.T) {
 		return nil, errors.New("jira: issue not found (404)")
 	}
 
+	// The repo is checked for an existing worktree before Jira is asked.
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if slices.Contains(args, "rev-parse") {
+			return cmdWithOutput("/repo\n")
+		}
+		return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+	}
+
 	var buf bytes.Buffer
 	stderr = &buf
 	exitFunc = func(code int) { panic(code) }
@@ -514,7 +812,9 @@ func TestJiraCmdInvalidJSON(t *testing.T) {
 	oldJiraGet := jiraGet
 	oldExit := exitFunc
 	oldErr := stderr
+	oldExec := execCommand
 	defer func() {
+		execCommand = oldExec
 		osGetenv = oldGetenv
 		jiraGet = oldJiraGet
 		exitFunc = oldExit
@@ -537,6 +837,14 @@ func TestJiraCmdInvalidJSON(t *testing.T) {
 		return []byte("not json"), nil
 	}
 
+	// The repo is checked for an existing worktree before Jira is asked.
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if slices.Contains(args, "rev-parse") {
+			return cmdWithOutput("/repo\n")
+		}
+		return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+	}
+
 	var buf bytes.Buffer
 	stderr = &buf
 	exitFunc = func(code int) { panic(code) }
@@ -967,7 +1275,7 @@ func TestAddWorktreeSuccess(t *testing.T) {
 		return exec.Command("sh", "-c", "exit 0")
 	}
 
-	wtPath, err := addWorktree(repo, repo, "test-branch", "", true, false)
+	wtPath, err := addWorktree(repo, repo, addOptions{branch: "test-branch", copyConfig: true})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -978,7 +1286,7 @@ func TestAddWorktreeSuccess(t *testing.T) {
 }
 
 func TestAddWorktreeEmptyBranch(t *testing.T) {
-	_, err := addWorktree("/repo", "/repo", "", "", true, false)
+	_, err := addWorktree("/repo", "/repo", addOptions{copyConfig: true})
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -1169,10 +1477,12 @@ func TestJiraDispatcher(t *testing.T) {
 	t.Run("config routes", func(t *testing.T) {
 		oldReadFile := osReadFile
 		oldHomeDir := osUserHomeDir
+		oldExec := execCommand
 		oldOut := stdout
 		defer func() {
 			osReadFile = oldReadFile
 			osUserHomeDir = oldHomeDir
+			execCommand = oldExec
 			stdout = oldOut
 		}()
 		osUserHomeDir = func() (string, error) { return "/home/test", nil }
@@ -1204,8 +1514,8 @@ func TestJiraDispatcher(t *testing.T) {
 			exitFunc = func(code int) { panic(code) }
 
 			defer func() {
-				if r := recover(); r != 1 {
-					t.Fatalf("expected exit 1, got %v", r)
+				if r := recover(); r != exitUsage {
+					t.Fatalf("expected exit 2, got %v", r)
 				}
 				if !strings.Contains(buf.String(), tt.want) {
 					t.Fatalf("expected %q in output, got %q", tt.want, buf.String())
@@ -1304,6 +1614,188 @@ func TestJiraEnv(t *testing.T) {
 	})
 }
 
+func TestJiraSiteEnv(t *testing.T) {
+	oldGetenv := osGetenv
+	oldExec := execCommand
+	oldRead := osReadFile
+	oldHome := osUserHomeDir
+	oldErr := stderr
+	t.Cleanup(func() {
+		osGetenv = oldGetenv
+		execCommand = oldExec
+		osReadFile = oldRead
+		osUserHomeDir = oldHome
+		stderr = oldErr
+		jiraSiteName = ""
+		jiraSiteTokens = map[string]string{}
+	})
+	stderr = &bytes.Buffer{}
+	osGetenv = func(key string) string {
+		switch key {
+		case "JIRA_URL":
+			return "https://company.example.com"
+		case "JIRA_USER":
+			return "me@company"
+		case "JIRA_TOKEN":
+			return "env-token"
+		}
+		return ""
+	}
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	const sites = `{"jira":{"sites":{
+		"acme": {"url": "https://acme.example.com/", "user": "me@acme", "tokenCommand": "pass acme", "projects": ["acme", "ops"]},
+		"broken": {"url": "https://broken.example.com", "user": "me"},
+		"failing": {"url": "https://failing.example.com", "user": "me", "tokenCommand": "fail", "projects": ["FAIL"]},
+		"silent": {"url": "https://silent.example.com", "user": "me", "tokenCommand": "empty"}
+	}}}`
+	tokenRuns := 0
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name != "sh" {
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		tokenRuns++
+		switch args[1] {
+		case "pass acme":
+			return cmdWithOutput("acme-token\n")
+		case "empty":
+			return cmdWithOutput("  \n")
+		}
+		return exec.Command("sh", "-c", "exit 1")
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		site    string
+		key     string
+		want    string
+		wantErr string
+	}{
+		{name: "default", config: sites, key: "PROJ-1", want: "https://company.example.com me@company env-token"},
+		{name: "no config", key: "ACME-1", want: "https://company.example.com me@company env-token"},
+		{name: "by project", config: sites, key: "OPS-7", want: "https://acme.example.com me@acme acme-token"},
+		{name: "by flag", config: sites, site: "acme", key: "PROJ-1", want: "https://acme.example.com me@acme acme-token"},
+		{name: "key without project", config: sites, key: "nokey", want: "https://company.example.com me@company env-token"},
+		{name: "unknown site", config: sites, site: "nope", key: "PROJ-1", wantErr: `unknown jira site "nope": jira.sites has acme, broken, failing, silent`},
+		{name: "no sites", config: `{}`, site: "acme", key: "PROJ-1", wantErr: `unknown jira site "acme": no jira.sites are configured`},
+		{name: "incomplete site", config: sites, site: "broken", key: "PROJ-1", wantErr: "jira site broken needs url, user and tokenCommand"},
+		{name: "token command fails", config: sites, key: "FAIL-1", wantErr: "jira site failing: tokenCommand failed: exit status 1"},
+		{name: "empty token", config: sites, site: "silent", key: "PROJ-1", wantErr: "jira site silent: tokenCommand printed no token"},
+		{name: "invalid config", config: `{`, key: "ACME-1", want: "https://company.example.com me@company env-token"},
+		{name: "invalid config with site", config: `{`, site: "acme", key: "ACME-1", wantErr: "invalid config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jiraSiteName = tt.site
+			jiraSiteTokens = map[string]string{}
+			osReadFile = func(name string) ([]byte, error) {
+				if tt.config != "" && name == "/home/test/.config/wt/config.json" {
+					return []byte(tt.config), nil
+				}
+				return nil, os.ErrNotExist
+			}
+			url, user, token, err := jiraSiteEnv(tt.key)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := url + " " + user + " " + token; got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("token cached", func(t *testing.T) {
+		jiraSiteName = ""
+		jiraSiteTokens = map[string]string{}
+		osReadFile = func(name string) ([]byte, error) { return []byte(sites), nil }
+		tokenRuns = 0
+		for _, key := range []string{"ACME-1", "OPS-2"} {
+			if _, _, token, err := jiraSiteEnv(key); err != nil || token != "acme-token" {
+				t.Fatalf("%s: expected the acme token, got %q, %v", key, token, err)
+			}
+		}
+		if tokenRuns != 1 {
+			t.Fatalf("expected tokenCommand run once, ran %d times", tokenRuns)
+		}
+	})
+}
+
+func TestJiraSiteArg(t *testing.T) {
+	t.Cleanup(func() { jiraSiteName = "" })
+	tests := []struct {
+		args     []string
+		wantSite string
+		wantRest []string
+		wantErr  bool
+	}{
+		{args: nil},
+		{args: []string{"new", "PROJ-1"}, wantRest: []string{"new", "PROJ-1"}},
+		{args: []string{"--site", "acme", "new", "ACME-1"}, wantSite: "acme", wantRest: []string{"new", "ACME-1"}},
+		{args: []string{"--site=acme", "status"}, wantSite: "acme", wantRest: []string{"status"}},
+		{args: []string{"--site"}, wantErr: true},
+		{args: []string{"--site="}, wantErr: true},
+	}
+	for _, tt := range tests {
+		jiraSiteName = ""
+		rest, err := jiraSiteArg(tt.args)
+		if tt.wantErr {
+			if err == nil || exitCode(err) != exitUsage || !strings.Contains(err.Error(), "--site needs a site name") {
+				t.Fatalf("%q: expected a usage error, got %v", tt.args, err)
+			}
+			continue
+		}
+		if err != nil || jiraSiteName != tt.wantSite || !slices.Equal(rest, tt.wantRest) {
+			t.Fatalf("%q: expected site %q and %q, got %q, %q, %v", tt.args, tt.wantSite, tt.wantRest, jiraSiteName, rest, err)
+		}
+	}
+}
+
+func TestJiraCmdSiteErrors(t *testing.T) {
+	oldExit := exitFunc
+	oldErr := stderr
+	oldRead := osReadFile
+	oldHome := osUserHomeDir
+	t.Cleanup(func() {
+		exitFunc = oldExit
+		stderr = oldErr
+		osReadFile = oldRead
+		osUserHomeDir = oldHome
+		jiraSiteName = ""
+	})
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) { panic(code) }
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	osReadFile = func(string) ([]byte, error) { return nil, os.ErrNotExist }
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--site"}, want: "--site needs a site name"},
+		{args: []string{"--site", "acme", "status", "ACME-1"}, want: `unknown jira site "acme"`},
+	} {
+		buf.Reset()
+		func() {
+			defer func() {
+				if r := recover(); r != exitUsage {
+					t.Fatalf("%q: expected exit %d, got %v", tt.args, exitUsage, r)
+				}
+			}()
+			jiraCmd(tt.args)
+		}()
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Fatalf("%q: expected %q in stderr, got %q", tt.args, tt.want, buf.String())
+		}
+	}
+}
+
 func TestJiraFetchIssue(t *testing.T) {
 	oldGet := jiraGet
 	defer func() { jiraGet = oldGet }()
@@ -1316,7 +1808,7 @@ func TestJiraFetchIssue(t *testing.T) {
 		}}
 		body, _ := json.Marshal(issue)
 		jiraGet = func(url, user, token string) ([]byte, error) {
-			if !strings.Contains(url, "fields=summary,description,comment,status,issuetype") {
+			if !strings.Contains(url, "fields=summary,description,comment,status,issuetype,issuelinks") {
 				t.Fatalf("expected issuetype in fields, got %q", url)
 			}
 			return body, nil
@@ -1424,6 +1916,16 @@ func TestJiraSetStatus(t *testing.T) {
 		}
 	})
 
+	t.Run("no transitions", func(t *testing.T) {
+		jiraGet = func(url, user, token string) ([]byte, error) {
+			return []byte(`{"transitions":[]}`), nil
+		}
+		err := jiraSetStatus("https://jira.example.com", "PROJ-1", "Done", "user", "token")
+		if err == nil || strings.Contains(err.Error(), "available:") {
+			t.Fatalf("expected bare no transition error, got %v", err)
+		}
+	})
+
 	t.Run("get error", func(t *testing.T) {
 		jiraGet = func(url, user, token string) ([]byte, error) {
 			return nil, errors.New("get fail")
@@ -1874,6 +2376,108 @@ func TestJiraStatusCmdInferGitError(t *testing.T) {
 	jiraStatusCmd(nil)
 }
 
+func TestJiraRateLimitRetry(t *testing.T) {
+	oldSleep := jiraSleep
+	oldErr := stderr
+	defer func() {
+		jiraSleep = oldSleep
+		stderr = oldErr
+	}()
+	var sleeps []time.Duration
+	jiraSleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	got, err := jiraGetDefault(srv.URL+"/rest/api/2/issue/TEST-1", "user", "token")
+	if err != nil || string(got) != `{"ok":true}` {
+		t.Fatalf("expected success after the retry, got %q, %v", got, err)
+	}
+	if !reflect.DeepEqual(sleeps, []time.Duration{2 * time.Second}) {
+		t.Fatalf("expected one 2s wait, got %v", sleeps)
+	}
+	if !strings.Contains(errBuf.String(), "jira: rate limited; retrying in 2s (retry 1/3)") {
+		t.Fatalf("expected a retry notice, got %q", errBuf.String())
+	}
+
+	// A POST body is sent again on the retry.
+	bodies, sleeps = nil, nil
+	if _, err := jiraPostDefault(srv.URL+"/rest/api/2/issue/TEST-1/transitions", "user", "token", []byte(`{"a":1}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(bodies, []string{`{"a":1}`, `{"a":1}`}) || len(sleeps) != 1 {
+		t.Fatalf("expected the body sent twice after one wait, got %q, %v", bodies, sleeps)
+	}
+}
+
+func TestJiraRateLimitRetriesExhausted(t *testing.T) {
+	oldSleep := jiraSleep
+	oldErr := stderr
+	oldRetries := jiraRateLimitRetries
+	defer func() {
+		jiraSleep = oldSleep
+		stderr = oldErr
+		jiraRateLimitRetries = oldRetries
+	}()
+	sleeps := 0
+	jiraSleep = func(time.Duration) { sleeps++ }
+	stderr = &bytes.Buffer{}
+	jiraRateLimitRetries = 1
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := jiraGetDefault(srv.URL+"/rest/api/2/issue/TEST-1", "user", "token")
+	var apiErr *jiraAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || err.Error() != "jira: rate limited (429)" {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+	if requests != 2 || sleeps != 1 {
+		t.Fatalf("expected 2 requests and 1 wait, got %d and %d", requests, sleeps)
+	}
+}
+
+func TestJiraRetryAfter(t *testing.T) {
+	oldNow := timeNow
+	defer func() { timeNow = oldNow }()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	for _, tt := range []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", defaultJiraRetryAfter},
+		{"soon", defaultJiraRetryAfter},
+		{"-3", defaultJiraRetryAfter},
+		{"0", 0},
+		{" 7 ", 7 * time.Second},
+		{"3600", maxJiraRetryAfter},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0},
+	} {
+		if got := jiraRetryAfter(tt.header); got != tt.want {
+			t.Errorf("jiraRetryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
 func TestJiraPostDefaultSuccess(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
@@ -1896,7 +2500,22 @@ func TestJiraPostDefaultSuccess(t *testing.T) {
 	}
 }
 
-func TestJiraPostDefaultError(t *testing.T) {
+func TestJiraPutDefault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != "PUT" || string(body) != `{"update":{}}` || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected %s request %q", r.Method, body)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if _, err := jiraPutDefault(srv.URL, "user", "token", []byte(`{"update":{}}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestJiraPostDefaultError(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 	}))
@@ -1911,6 +2530,56 @@ func TestJiraPostDefaultError(t *testing.T) {
 	}
 }
 
+func TestJiraPostDefaultErrorMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errorMessages":["Transition id '31' is not valid for this issue.","second"],"errors":{}}`))
+	}))
+	defer srv.Close()
+
+	_, err := jiraPostDefault(srv.URL, "user", "token", []byte(`{}`))
+	var apiErr *jiraAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a jiraAPIError for 400, got %v", err)
+	}
+	want := "jira: unexpected status 400: Transition id '31' is not valid for this issue."
+	if err.Error() != want {
+		t.Fatalf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestJiraGetDefaultErrorMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errorMessages":["Issue does not exist or you do not have permission to see it."]}`))
+	}))
+	defer srv.Close()
+
+	_, err := jiraGetDefault(srv.URL+"/rest/api/2/issue/NOPE-1", "user", "token")
+	want := "jira: issue not found (404): Issue does not exist or you do not have permission to see it."
+	if err == nil || err.Error() != want {
+		t.Fatalf("expected %q, got %v", want, err)
+	}
+}
+
+func TestNewJiraAPIError(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusUnauthorized, ``, "jira: authentication failed (401)"},
+		{http.StatusBadGateway, `<html>bad gateway</html>`, "jira: unexpected status 502"},
+		{http.StatusBadRequest, `{"errorMessages":[],"errors":{}}`, "jira: unexpected status 400"},
+		{http.StatusBadRequest, `{"errors":{"summary":"Summary is required.","assignee":"User does not exist."}}`, "jira: unexpected status 400: assignee: User does not exist."},
+	}
+	for _, tt := range tests {
+		if got := newJiraAPIError(tt.status, []byte(tt.body)).Error(); got != tt.want {
+			t.Errorf("newJiraAPIError(%d, %s) = %q, want %q", tt.status, tt.body, got, tt.want)
+		}
+	}
+}
+
 func TestJiraPostDefaultNetworkError(t *testing.T) {
 	_, err := jiraPostDefault("http://127.0.0.1:1/bad", "user", "token", []byte(`{}`))
 	if err == nil {
@@ -1977,3 +2646,1899 @@ func TestJiraStatusCmdTransitionsInvalidJSON(t *testing.T) {
 }
 
 // --- Config tests ---
+
+func TestExtractSetFlag(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantRest []string
+		wantName string
+		wantErr  bool
+	}{
+		{[]string{"--set", "In Review", "PROJ-1"}, []string{"PROJ-1"}, "In Review", false},
+		{[]string{"PROJ-1", "--set=Done"}, []string{"PROJ-1"}, "Done", false},
+		{[]string{"PROJ-1", "-set", "Done"}, []string{"PROJ-1"}, "Done", false},
+		{[]string{"PROJ-1"}, []string{"PROJ-1"}, "", false},
+		{[]string{"PROJ-1", "--set"}, nil, "", true},
+		{[]string{"--set="}, nil, "", true},
+	}
+	for _, tt := range tests {
+		rest, name, err := extractSetFlag(tt.args)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("extractSetFlag(%v): expected error", tt.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("extractSetFlag(%v): unexpected error %v", tt.args, err)
+			continue
+		}
+		if name != tt.wantName || strings.Join(rest, " ") != strings.Join(tt.wantRest, " ") {
+			t.Errorf("extractSetFlag(%v) = %v, %q; want %v, %q", tt.args, rest, name, tt.wantRest, tt.wantName)
+		}
+	}
+}
+
+func TestJiraStatusCmdSetFlag(t *testing.T) {
+	oldGetenv := osGetenv
+	oldGet := jiraGet
+	oldPost := jiraPost
+	oldOut := stdout
+	defer func() {
+		osGetenv = oldGetenv
+		jiraGet = oldGet
+		jiraPost = oldPost
+		stdout = oldOut
+	}()
+
+	osGetenv = func(key string) string {
+		switch key {
+		case "JIRA_URL":
+			return "https://jira.example.com"
+		case "JIRA_USER":
+			return "user"
+		case "JIRA_TOKEN":
+			return "token"
+		}
+		return ""
+	}
+
+	tr := jiraTransitionsResponse{Transitions: []jiraTransition{
+		{ID: "1", Name: "Start", To: jiraStatus{Name: "In Progress"}},
+		{ID: "4", Name: "Submit", To: jiraStatus{Name: "In Review"}},
+	}}
+	trBody, _ := json.Marshal(tr)
+	jiraGet = func(url, user, token string) ([]byte, error) {
+		return trBody, nil
+	}
+	var postBody []byte
+	jiraPost = func(url, user, token string, body []byte) ([]byte, error) {
+		postBody = body
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	stdout = &buf
+
+	jiraStatusCmd([]string{"--set", "in review", "PROJ-123"})
+
+	if !strings.Contains(string(postBody), `"id":"4"`) {
+		t.Fatalf("expected transition id 4, got %q", string(postBody))
+	}
+	if !strings.Contains(buf.String(), "PROJ-123 → in review") {
+		t.Fatalf("expected transition message, got %q", buf.String())
+	}
+}
+
+func TestJiraStatusCmdSetFlagUnavailable(t *testing.T) {
+	oldGetenv := osGetenv
+	oldGet := jiraGet
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		osGetenv = oldGetenv
+		jiraGet = oldGet
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+
+	osGetenv = func(key string) string {
+		switch key {
+		case "JIRA_URL":
+			return "https://jira.example.com"
+		case "JIRA_USER":
+			return "user"
+		case "JIRA_TOKEN":
+			return "token"
+		}
+		return ""
+	}
+
+	tr := jiraTransitionsResponse{Transitions: []jiraTransition{
+		{ID: "1", Name: "Start", To: jiraStatus{Name: "In Progress"}},
+		{ID: "2", Name: "Close", To: jiraStatus{Name: "Done"}},
+	}}
+	trBody, _ := json.Marshal(tr)
+	jiraGet = func(url, user, token string) ([]byte, error) {
+		return trBody, nil
+	}
+
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) { panic(code) }
+
+	defer func() {
+		if r := recover(); r != 1 {
+			t.Fatalf("expected exit 1, got %v", r)
+		}
+		if !strings.Contains(buf.String(), "available: In Progress, Done") {
+			t.Fatalf("expected available transitions listed, got %q", buf.String())
+		}
+	}()
+
+	jiraStatusCmd([]string{"PROJ-123", "--set", "In Review"})
+}
+
+func TestJiraStatusCmdSetFlagWithStatusArg(t *testing.T) {
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) { panic(code) }
+
+	defer func() {
+		if r := recover(); r != exitUsage {
+			t.Fatalf("expected exit %d, got %v", exitUsage, r)
+		}
+		if !strings.Contains(buf.String(), "not both") {
+			t.Fatalf("expected conflict error, got %q", buf.String())
+		}
+	}()
+
+	jiraStatusCmd([]string{"PROJ-123", "Done", "--set", "In Review"})
+}
+
+func TestJiraStatusCmdSetFlagMissingValue(t *testing.T) {
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) { panic(code) }
+
+	defer func() {
+		if r := recover(); r != 1 {
+			t.Fatalf("expected exit 1, got %v", r)
+		}
+		if !strings.Contains(buf.String(), "--set requires") {
+			t.Fatalf("expected --set error, got %q", buf.String())
+		}
+	}()
+
+	jiraStatusCmd([]string{"PROJ-123", "--set"})
+}
+
+func TestAddWorktreeCopyPathsError(t *testing.T) {
+	repo := t.TempDir()
+
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	_, err := addWorktree(repo, repo, addOptions{
+		branch:     "feature",
+		fromBranch: "main",
+		copyConfig: true,
+		cfg:        wtConfig{Copy: copySettings{Paths: []string{"../escape"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid copy path") {
+		t.Fatalf("expected invalid copy path error, got %v", err)
+	}
+}
+
+func TestAddWorktreeCopyUntrackedError(t *testing.T) {
+	repo := t.TempDir()
+
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if strings.Contains(strings.Join(args, " "), "status --porcelain") {
+			return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	_, err := addWorktree(repo, repo, addOptions{branch: "feature", fromBranch: "main", copyUntracked: true})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected git status error, got %v", err)
+	}
+}
+
+func TestAddWorktreeRemoteBranchErrors(t *testing.T) {
+	repo := t.TempDir()
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	for _, fail := range []string{"remote", "worktree add"} {
+		execCommand = func(name string, args ...string) *exec.Cmd {
+			joined := strings.Join(args, " ")
+			switch {
+			case strings.Contains(joined, fail):
+				return exec.Command("sh", "-c", "echo boom >&2; exit 1")
+			case strings.Contains(joined, " remote"):
+				return cmdWithOutput("origin\n")
+			case strings.Contains(joined, "refs/heads/"):
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			return exec.Command("sh", "-c", "exit 0")
+		}
+		_, err := addWorktree(repo, repo, addOptions{branch: "feature", fetch: fetchNever, quietGit: true})
+		if err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("expected error when %s fails, got %v", fail, err)
+		}
+	}
+}
+
+func TestAddWorktreeInvalidSymlinkMode(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	called := false
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		called = true
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	_, err := addWorktree("/repo", "/repo", addOptions{
+		branch: "feature",
+		cfg:    wtConfig{Copy: copySettings{Symlinks: "copy"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid copy.symlinks") {
+		t.Fatalf("expected invalid copy.symlinks error, got %v", err)
+	}
+	if called {
+		t.Fatal("expected no git commands before the config is validated")
+	}
+}
+
+func TestAddWorktreeInitSubmodules(t *testing.T) {
+	on := true
+	tests := []struct {
+		name       string
+		noCheckout bool
+		fail       bool
+		wantArgs   string
+		wantWarn   string
+	}{
+		{name: "success", wantArgs: "submodule update --init --quiet"},
+		{name: "failure warns", fail: true, wantArgs: "submodule update --init --quiet", wantWarn: "warning: could not initialize submodules"},
+		{name: "no checkout", noCheckout: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			oldExec := execCommand
+			oldErr := stderr
+			defer func() {
+				execCommand = oldExec
+				stderr = oldErr
+			}()
+			var buf bytes.Buffer
+			stderr = &buf
+
+			gotArgs := ""
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 2 && args[2] == "submodule" {
+					gotArgs = strings.Join(args[2:], " ")
+					if tt.fail {
+						return exec.Command("sh", "-c", "exit 1")
+					}
+				}
+				return exec.Command("sh", "-c", "exit 0")
+			}
+
+			wtPath, err := addWorktree(repo, repo, addOptions{
+				branch:     "feature",
+				fromBranch: "main",
+				noCheckout: tt.noCheckout,
+				quietGit:   true,
+				cfg:        wtConfig{Worktree: worktreeConfig{InitSubmodules: &on}},
+			})
+			if err != nil || wtPath == "" {
+				t.Fatalf("expected worktree created, got %q, %v", wtPath, err)
+			}
+			if gotArgs != tt.wantArgs {
+				t.Fatalf("expected submodule args %q, got %q", tt.wantArgs, gotArgs)
+			}
+			if !strings.Contains(buf.String(), tt.wantWarn) {
+				t.Fatalf("expected warning %q, got %q", tt.wantWarn, buf.String())
+			}
+		})
+	}
+}
+
+func TestAddWorktreeTemplates(t *testing.T) {
+	for _, noCheckout := range []bool{false, true} {
+		repo := t.TempDir()
+		oldExec := execCommand
+		oldErr := stderr
+		var buf bytes.Buffer
+		stderr = &buf
+		execCommand = func(name string, args ...string) *exec.Cmd { return exec.Command("sh", "-c", "exit 0") }
+
+		cfg := wtConfig{Worktree: worktreeConfig{Templates: map[string]string{
+			".envrc":           "export BRANCH={branch}\n",
+			"notes/TODO.md":    "# {branch} from {base}\n{path}\n",
+			"../outside.txt":   "nope",
+			"/etc/wt-test.txt": "nope",
+		}}}
+		wtPath, err := addWorktree(repo, repo, addOptions{branch: "feature", fromBranch: "main", noCheckout: noCheckout, cfg: cfg})
+		execCommand = oldExec
+		stderr = oldErr
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(wtPath, ".envrc"))
+		if err != nil || string(data) != "export BRANCH=feature\n" {
+			t.Fatalf("unexpected .envrc %q, %v", data, err)
+		}
+		data, err = os.ReadFile(filepath.Join(wtPath, "notes", "TODO.md"))
+		if err != nil || string(data) != "# feature from main\n"+wtPath+"\n" {
+			t.Fatalf("unexpected TODO.md %q, %v", data, err)
+		}
+		for _, dest := range []string{"../outside.txt", "/etc/wt-test.txt"} {
+			if !strings.Contains(buf.String(), "warning: skipping template "+dest+": path must be relative to the worktree") {
+				t.Fatalf("expected %s skipped, got %q", dest, buf.String())
+			}
+		}
+	}
+}
+
+func TestAddWorktreeGitConfig(t *testing.T) {
+	repo := t.TempDir()
+	stubGitVersion(t, "git version 2.39.0")
+	oldErr := stderr
+	defer func() { stderr = oldErr }()
+	var buf bytes.Buffer
+	stderr = &buf
+
+	var configCalls []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		joined := strings.Join(args, " ")
+		if len(args) > 2 && args[0] == "-C" && args[2] == "config" {
+			configCalls = append(configCalls, joined)
+		}
+		switch {
+		case strings.Contains(joined, "--get"), strings.HasSuffix(joined, "user.name Client Bot"):
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	cfg := wtConfig{Worktree: worktreeConfig{GitConfig: map[string]string{
+		"user.name":  "Client Bot",
+		"user.email": "me@client.example",
+	}}}
+	wtPath, err := addWorktree(repo, repo, addOptions{branch: "feature", fromBranch: "main", noCheckout: true, cfg: cfg})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"-C " + repo + " config --bool --get extensions.worktreeConfig",
+		"-C " + repo + " config --local --bool --get core.bare",
+		"-C " + repo + " config extensions.worktreeConfig true",
+		"-C " + wtPath + " config --worktree user.email me@client.example",
+		"-C " + wtPath + " config --worktree user.name Client Bot",
+	}
+	if !reflect.DeepEqual(configCalls, want) {
+		t.Fatalf("expected %q, got %q", want, configCalls)
+	}
+	if !strings.Contains(buf.String(), "warning: could not set git config user.name in "+wtPath) {
+		t.Fatalf("expected a warning for the failed key, got %q", buf.String())
+	}
+
+	// Without per-worktree config nothing is set, rather than writing the
+	// config every worktree shares.
+	configCalls = nil
+	buf.Reset()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		joined := strings.Join(args, " ")
+		if len(args) > 2 && args[0] == "-C" && args[2] == "config" {
+			configCalls = append(configCalls, joined)
+		}
+		if strings.HasSuffix(joined, "extensions.worktreeConfig true") || strings.Contains(joined, "--get") {
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+	applyGitConfig(repo, wtPath, cfg.Worktree.GitConfig)
+	if len(configCalls) != 3 || !strings.Contains(buf.String(), "warning: worktree.gitConfig not applied") {
+		t.Fatalf("expected no settings written and a warning, got %q and %q", configCalls, buf.String())
+	}
+}
+
+func TestWriteTemplatesWarnsOnFailure(t *testing.T) {
+	oldErr := stderr
+	oldWrite := osWriteFile
+	oldMkdir := osMkdirAll
+	defer func() {
+		stderr = oldErr
+		osWriteFile = oldWrite
+		osMkdirAll = oldMkdir
+	}()
+	var buf bytes.Buffer
+	stderr = &buf
+	osWriteFile = func(name string, data []byte, perm fs.FileMode) error { return errors.New("disk full") }
+	osMkdirAll = func(path string, perm fs.FileMode) error {
+		if strings.HasSuffix(path, "sub") {
+			return errors.New("read-only")
+		}
+		return nil
+	}
+
+	writeTemplates("/wt", addOptions{cfg: wtConfig{Worktree: worktreeConfig{Templates: map[string]string{
+		"a.txt":     "a",
+		"sub/b.txt": "b",
+	}}}})
+	want := "warning: could not write template a.txt: disk full\n" +
+		"warning: could not write template sub/b.txt: read-only\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestAddWorktreeNoCheckout(t *testing.T) {
+	repo := t.TempDir()
+
+	oldExec := execCommand
+	oldLstat := osLstat
+	defer func() {
+		execCommand = oldExec
+		osLstat = oldLstat
+	}()
+	var addArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "worktree" && args[1] == "add" {
+			addArgs = args
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+	osLstat = func(name string) (os.FileInfo, error) { return nil, errors.New("stat failed") }
+
+	_, err := addWorktree(repo, repo, addOptions{branch: "feature", fromBranch: "main", noCheckout: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(addArgs) < 3 || addArgs[2] != "--no-checkout" {
+		t.Fatalf("expected --no-checkout, got %v", addArgs)
+	}
+
+	_, err = addWorktree(repo, repo, addOptions{branch: "feature", fromBranch: "main", noCheckout: true, copyConfig: true})
+	if err == nil || !strings.Contains(err.Error(), "stat failed") {
+		t.Fatalf("expected copy error, got %v", err)
+	}
+}
+
+func stubJiraMulti(t *testing.T, repo string, issues map[string]jiraIssue, worktreeList string) {
+	t.Helper()
+	oldGetenv := osGetenv
+	oldJiraGet := jiraGet
+	oldJiraPost := jiraPost
+	oldJiraPut := jiraPut
+	oldExec := execCommand
+	oldWriteFile := osWriteFile
+	oldReadFile := osReadFile
+	oldHomeDir := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	t.Cleanup(func() {
+		osGetenv = oldGetenv
+		jiraGet = oldJiraGet
+		jiraPost = oldJiraPost
+		jiraPut = oldJiraPut
+		execCommand = oldExec
+		osWriteFile = oldWriteFile
+		osReadFile = oldReadFile
+		osUserHomeDir = oldHomeDir
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	})
+
+	osGetenv = func(key string) string {
+		switch key {
+		case "JIRA_URL":
+			return "https://jira.example.com"
+		case "JIRA_USER":
+			return "user"
+		case "JIRA_TOKEN":
+			return "token"
+		}
+		return ""
+	}
+	tr, _ := json.Marshal(jiraTransitionsResponse{Transitions: []jiraTransition{
+		{ID: "1", Name: "Start", To: jiraStatus{Name: "In Progress"}},
+	}})
+	jiraGet = func(url, user, token string) ([]byte, error) {
+		if strings.Contains(url, "/transitions") {
+			return tr, nil
+		}
+		for key, issue := range issues {
+			if strings.Contains(url, "/issue/"+key+"?") {
+				return json.Marshal(issue)
+			}
+		}
+		return nil, errors.New("jira: issue not found")
+	}
+	jiraPost = func(url, user, token string, body []byte) ([]byte, error) { return nil, nil }
+	jiraPut = func(url, user, token string, body []byte) ([]byte, error) {
+		t.Fatalf("unexpected PUT %s", url)
+		return nil, nil
+	}
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "rev-parse" && args[1] == "--show-toplevel" {
+			return cmdWithOutput(repo)
+		}
+		if len(args) >= 2 && args[0] == "worktree" && args[1] == "list" {
+			return cmdWithOutput(worktreeList)
+		}
+		if len(args) >= 2 && args[0] == "show-ref" {
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+	osWriteFile = func(name string, data []byte, perm fs.FileMode) error {
+		if strings.HasSuffix(name, "PROJ-4.md") {
+			return errors.New("disk full")
+		}
+		return nil
+	}
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	osReadFile = func(name string) ([]byte, error) {
+		if name == "/home/test/.config/wt/config.json" {
+			return []byte(`{"jira":{"status":{"default":{"working":"In Progress"}}}}`), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	exitFunc = func(code int) { panic(code) }
+}
+
+func TestJiraNewCmdMultipleIssues(t *testing.T) {
+	repo := t.TempDir()
+	issues := map[string]jiraIssue{
+		"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+		"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+		"PROJ-4": {Key: "PROJ-4", Fields: jiraFields{Summary: "Four"}},
+	}
+	existing := filepath.Join(repo, "..", "proj-2")
+	list := fmt.Sprintf("worktree %s\nbranch refs/heads/main\n\nworktree %s\nbranch refs/heads/PROJ-2-two\n", repo, existing)
+	stubJiraMulti(t, repo, issues, list)
+
+	var out, errBuf bytes.Buffer
+	stdout = &out
+	stderr = &errBuf
+
+	code := func() (code int) {
+		defer func() {
+			if r := recover(); r != nil {
+				code = r.(int)
+			}
+		}()
+		jiraNewCmd([]string{"PROJ-1", "PROJ-2", "PROJ-3", "PROJ-4"})
+		return 0
+	}()
+
+	if code != 1 {
+		t.Fatalf("expected exit 1, got %d", code)
+	}
+	got := out.String()
+	if !strings.Contains(got, worktreePath(repo, "PROJ-1-one")) {
+		t.Fatalf("expected PROJ-1 worktree path, got %q", got)
+	}
+	if !strings.Contains(got, "PROJ-1 → In Progress") {
+		t.Fatalf("expected PROJ-1 transition, got %q", got)
+	}
+	if !strings.Contains(got, "PROJ-2: worktree already exists at "+existing) {
+		t.Fatalf("expected PROJ-2 skipped, got %q", got)
+	}
+	if !strings.Contains(got, "1 created, 1 skipped (existing), 2 failed") {
+		t.Fatalf("expected summary, got %q", got)
+	}
+	if !strings.Contains(errBuf.String(), "PROJ-3: jira: issue not found") {
+		t.Fatalf("expected PROJ-3 error, got %q", errBuf.String())
+	}
+	if !strings.Contains(errBuf.String(), "PROJ-4: disk full") {
+		t.Fatalf("expected PROJ-4 error, got %q", errBuf.String())
+	}
+}
+
+func TestJiraNewCmdMultipleSites(t *testing.T) {
+	repo := t.TempDir()
+	issues := map[string]jiraIssue{
+		"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+		"ACME-2": {Key: "ACME-2", Fields: jiraFields{Summary: "Two"}},
+		"OPS-3":  {Key: "OPS-3", Fields: jiraFields{Summary: "Three"}},
+	}
+	stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+	t.Cleanup(func() { jiraSiteTokens = map[string]string{} })
+	osReadFile = func(name string) ([]byte, error) {
+		return []byte(`{"jira":{"sites":{
+			"acme": {"url": "https://acme.example.com", "user": "me@acme", "tokenCommand": "pass acme", "projects": ["ACME"]},
+			"ops": {"url": "https://ops.example.com", "user": "me@ops", "tokenCommand": "false", "projects": ["OPS"]}
+		}}}`), nil
+	}
+	exec0 := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name == "sh" && args[1] == "pass acme" {
+			return cmdWithOutput("acme-token\n")
+		}
+		if name == "sh" && args[1] == "false" {
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		return exec0(name, args...)
+	}
+	var fetched []string
+	get := jiraGet
+	jiraGet = func(url, user, token string) ([]byte, error) {
+		fetched = append(fetched, url[:strings.Index(url, "/rest")]+" "+user+" "+token)
+		return get(url, user, token)
+	}
+
+	var out, errBuf bytes.Buffer
+	stdout = &out
+	stderr = &errBuf
+	func() {
+		defer func() {
+			if r := recover(); r != 1 {
+				t.Fatalf("expected exit 1, got %v", r)
+			}
+		}()
+		jiraNewCmd([]string{"-S", "PROJ-1", "ACME-2", "OPS-3"})
+	}()
+
+	want := []string{"https://jira.example.com user token", "https://acme.example.com me@acme acme-token"}
+	if !slices.Equal(fetched, want) {
+		t.Fatalf("expected fetches %q, got %q", want, fetched)
+	}
+	if !strings.Contains(out.String(), "2 created, 0 skipped (existing), 1 failed") {
+		t.Fatalf("expected summary, got %q", out.String())
+	}
+	if !strings.Contains(errBuf.String(), "OPS-3: jira site ops: tokenCommand failed") {
+		t.Fatalf("expected OPS-3 site error, got %q", errBuf.String())
+	}
+
+	// A single issue whose site can't be used stops before anything is done.
+	fetched = nil
+	func() {
+		defer func() {
+			if r := recover(); r != exitError {
+				t.Fatalf("expected exit %d, got %v", exitError, r)
+			}
+		}()
+		jiraNewCmd([]string{"OPS-3"})
+	}()
+	if len(fetched) != 0 {
+		t.Fatalf("expected nothing fetched, got %q", fetched)
+	}
+}
+
+func TestJiraNewCmdMultipleIssuesAllCreated(t *testing.T) {
+	repo := t.TempDir()
+	issues := map[string]jiraIssue{
+		"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+		"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+	}
+	stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+	osReadFile = func(name string) ([]byte, error) { return nil, os.ErrNotExist }
+
+	var out, errBuf bytes.Buffer
+	stdout = &out
+	stderr = &errBuf
+
+	jiraNewCmd([]string{"PROJ-1", "PROJ-2"})
+
+	if !strings.Contains(out.String(), "2 created, 0 skipped (existing), 0 failed") {
+		t.Fatalf("expected summary, got %q", out.String())
+	}
+	if strings.Count(errBuf.String(), "no jira status mappings configured") != 2 {
+		t.Fatalf("expected a missing-mapping warning per issue, got %q", errBuf.String())
+	}
+}
+
+func TestJiraNewCmdMultipleIssuesSkipStatus(t *testing.T) {
+	repo := t.TempDir()
+	issues := map[string]jiraIssue{
+		"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+		"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+	}
+	stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+	posted := false
+	jiraPost = func(url, user, token string, body []byte) ([]byte, error) {
+		posted = true
+		return nil, nil
+	}
+
+	var out bytes.Buffer
+	stdout = &out
+
+	jiraNewCmd([]string{"-S", "PROJ-1", "PROJ-2"})
+
+	if posted {
+		t.Fatalf("expected no transitions with -S")
+	}
+	if !strings.Contains(out.String(), "2 created, 0 skipped (existing), 0 failed") {
+		t.Fatalf("expected summary, got %q", out.String())
+	}
+}
+
+func TestJiraNewCmdExistingWorktree(t *testing.T) {
+	for _, tmux := range []bool{false, true} {
+		t.Run(fmt.Sprintf("tmux=%v", tmux), func(t *testing.T) {
+			repo := t.TempDir()
+			existing := filepath.Join(repo, "..", "proj-2")
+			list := fmt.Sprintf("worktree %s\nbranch refs/heads/main\n\nworktree %s\nbranch refs/heads/PROJ-2-two\n", repo, existing)
+			stubJiraMulti(t, repo, nil, list)
+			t.Setenv("TMUX", "")
+			fetched := false
+			jiraGet = func(url, user, token string) ([]byte, error) {
+				fetched = true
+				return nil, errors.New("unexpected fetch")
+			}
+			base := execCommand
+			tmuxCalled := false
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if name == "tmux" {
+					tmuxCalled = true
+				}
+				return base(name, args...)
+			}
+
+			var out, errBuf bytes.Buffer
+			stdout = &out
+			stderr = &errBuf
+
+			args := []string{"PROJ-2"}
+			if tmux {
+				args = []string{"-t", "PROJ-2"}
+			}
+			jiraNewCmd(args)
+
+			if fetched {
+				t.Fatalf("expected no issue fetch for an existing worktree")
+			}
+			if out.String() != existing+"\n" {
+				t.Fatalf("expected existing path, got %q", out.String())
+			}
+			if !strings.Contains(errBuf.String(), "PROJ-2: worktree already exists at "+existing+" (use --force") {
+				t.Fatalf("expected existing worktree notice, got %q", errBuf.String())
+			}
+			if tmuxCalled != tmux {
+				t.Fatalf("expected tmux called=%v, got %v", tmux, tmuxCalled)
+			}
+		})
+	}
+}
+
+func TestJiraNewCmdExistingWorktreeTmuxError(t *testing.T) {
+	repo := t.TempDir()
+	list := fmt.Sprintf("worktree %s\nbranch refs/heads/main\n\nworktree /wt/proj-2\nbranch refs/heads/proj-2\n", repo)
+	stubJiraMulti(t, repo, nil, list)
+	t.Setenv("TMUX", "")
+	base := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name == "tmux" {
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		return base(name, args...)
+	}
+	var errBuf bytes.Buffer
+	stdout = &bytes.Buffer{}
+	stderr = &errBuf
+
+	func() {
+		defer func() {
+			if r := recover(); r != 1 {
+				t.Fatalf("expected exit 1, got %v", r)
+			}
+		}()
+		jiraNewCmd([]string{"-t", "PROJ-2"})
+	}()
+}
+
+func TestJiraNewCmdForce(t *testing.T) {
+	repo := t.TempDir()
+	issues := map[string]jiraIssue{
+		"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+		"PROJ-3": {Key: "PROJ-3", Fields: jiraFields{Summary: "Three"}},
+	}
+	list := fmt.Sprintf("worktree %s\nbranch refs/heads/main\n\nworktree /wt/proj-2\nbranch refs/heads/PROJ-2-two\n", repo)
+	stubJiraMulti(t, repo, issues, list)
+
+	var out bytes.Buffer
+	stdout = &out
+	stderr = &bytes.Buffer{}
+
+	jiraNewCmd([]string{"--force", "-S", "-b", "PROJ-2-again", "PROJ-2"})
+	if !strings.Contains(out.String(), worktreePath(repo, "PROJ-2-again")) {
+		t.Fatalf("expected a new worktree with --force, got %q", out.String())
+	}
+
+	out.Reset()
+	jiraNewCmd([]string{"--force", "-S", "PROJ-2", "PROJ-3"})
+	if !strings.Contains(out.String(), "2 created, 0 skipped (existing), 0 failed") {
+		t.Fatalf("expected nothing skipped with --force, got %q", out.String())
+	}
+}
+
+func TestJiraNewCmdChildren(t *testing.T) {
+	for _, args := range [][]string{{"--children", "-S", "EPIC-1"}, {"--children", "-S", "EPIC-1", "PROJ-9"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			repo := t.TempDir()
+			issues := map[string]jiraIssue{
+				"EPIC-1": {Key: "EPIC-1", Fields: jiraFields{Summary: "Revamp", IssueType: jiraIssueType{Name: "Epic"}}},
+				"PROJ-9": {Key: "PROJ-9", Fields: jiraFields{Summary: "Nine", IssueType: jiraIssueType{Name: "Story"}}},
+			}
+			stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+			base := jiraGet
+			searches := 0
+			jiraGet = func(u, user, token string) ([]byte, error) {
+				if strings.Contains(u, "/search?") {
+					searches++
+					return []byte(`{"issues":[{"key":"PROJ-1","fields":{"summary":"Child","status":{"name":"To Do"}}}]}`), nil
+				}
+				return base(u, user, token)
+			}
+			written := map[string]string{}
+			osWriteFile = func(name string, data []byte, perm fs.FileMode) error {
+				written[filepath.Base(name)] = string(data)
+				return nil
+			}
+			stdout = &bytes.Buffer{}
+
+			jiraNewCmd(args)
+
+			if searches != 1 {
+				t.Fatalf("expected one child search (epics only), got %d", searches)
+			}
+			if !strings.Contains(written["EPIC-1.md"], "- PROJ-1: Child (To Do)") {
+				t.Fatalf("expected children in epic markdown, got %q", written["EPIC-1.md"])
+			}
+		})
+	}
+}
+
+func TestJiraNewCmdSprintAndFixVersion(t *testing.T) {
+	for _, args := range [][]string{{"PROJ-1"}, {"PROJ-1", "PROJ-2"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			repo := t.TempDir()
+			issues := map[string]jiraIssue{
+				"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+				"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+			}
+			stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+			base := jiraGet
+			lookups := 0
+			jiraGet = func(u, user, token string) ([]byte, error) {
+				switch u {
+				case "https://jira.example.com/rest/agile/1.0/board?projectKeyOrId=PROJ&type=scrum":
+					lookups++
+					return []byte(`{"values":[{"id":7,"name":"PROJ board"}]}`), nil
+				case "https://jira.example.com/rest/agile/1.0/board/7/sprint?state=active":
+					return []byte(`{"values":[{"id":42,"name":"Sprint 5"}]}`), nil
+				}
+				return base(u, user, token)
+			}
+			var posts, puts []string
+			jiraPost = func(u, user, token string, body []byte) ([]byte, error) {
+				posts = append(posts, u+" "+string(body))
+				return nil, nil
+			}
+			jiraPut = func(u, user, token string, body []byte) ([]byte, error) {
+				puts = append(puts, u+" "+string(body))
+				return nil, nil
+			}
+			var out bytes.Buffer
+			stdout = &out
+
+			jiraNewCmd(append([]string{"-S", "--sprint", "current", "--fix-version", "1.2"}, args...))
+
+			if lookups != 1 {
+				t.Fatalf("expected the board looked up once, got %d", lookups)
+			}
+			for _, key := range args {
+				wantPost := `https://jira.example.com/rest/agile/1.0/sprint/42/issue {"issues":["` + key + `"]}`
+				wantPut := `https://jira.example.com/rest/api/2/issue/` + key + ` {"update":{"fixVersions":[{"add":{"name":"1.2"}}]}}`
+				if !slices.Contains(posts, wantPost) || !slices.Contains(puts, wantPut) {
+					t.Fatalf("expected %s moved to the sprint and given the version, got posts %v puts %v", key, posts, puts)
+				}
+				if !strings.Contains(out.String(), key+" → sprint Sprint 5\n"+key+" → fix version 1.2\n") {
+					t.Fatalf("expected %s's updates reported, got %q", key, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestJiraIssueUpdatesResolveSprint(t *testing.T) {
+	oldGet := jiraGet
+	defer func() { jiraGet = oldGet }()
+	responses := map[string]string{}
+	jiraGet = func(u, user, token string) ([]byte, error) {
+		body, ok := responses[u]
+		if !ok {
+			return nil, fmt.Errorf("unexpected GET %s", u)
+		}
+		return []byte(body), nil
+	}
+	const boards = "https://jira.example.com/rest/agile/1.0/board?projectKeyOrId=PROJ&type=scrum"
+	const sprints = "https://jira.example.com/rest/agile/1.0/board/9/sprint?state=active"
+	resolve := func(u jiraIssueUpdates) (jiraAgileItem, error) {
+		return u.resolveSprint("https://jira.example.com", "PROJ-1", "user", "token")
+	}
+
+	// A sprint ID is used as is; jira.boardId skips the board lookup.
+	if got, err := resolve(jiraIssueUpdates{sprint: "42"}); err != nil || got != (jiraAgileItem{ID: 42, Name: "42"}) {
+		t.Fatalf("expected sprint 42, got %+v, %v", got, err)
+	}
+	responses[sprints] = `{"values":[{"id":3,"name":"Sprint 3"},{"id":4,"name":"Sprint 4"}]}`
+	if got, err := resolve(jiraIssueUpdates{sprint: "current", boardID: 9}); err != nil || got.Name != "Sprint 3" {
+		t.Fatalf("expected the first active sprint of board 9, got %+v, %v", got, err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		boards string
+		sprint string
+		want   string
+	}{
+		{name: "no board", boards: `{"values":[]}`, want: "jira: no scrum board found for project PROJ; set jira.boardId"},
+		{name: "several boards", boards: `{"values":[{"id":9,"name":"A"},{"id":10,"name":"B"}]}`, want: "jira: project PROJ has several scrum boards (9 A, 10 B); set jira.boardId"},
+		{name: "bad boards", boards: `[`, want: "jira: invalid agile response"},
+		{name: "no boards response", want: "unexpected GET " + boards},
+		{name: "no active sprint", boards: `{"values":[{"id":9,"name":"A"}]}`, sprint: `{"values":[]}`, want: "jira: board 9 has no active sprint"},
+		{name: "no sprints response", boards: `{"values":[{"id":9,"name":"A"}]}`, want: "unexpected GET " + sprints},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clear(responses)
+			if tt.boards != "" {
+				responses[boards] = tt.boards
+			}
+			if tt.sprint != "" {
+				responses[sprints] = tt.sprint
+			}
+			if _, err := resolve(jiraIssueUpdates{sprint: "current"}); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseSprintFlag(t *testing.T) {
+	for _, value := range []string{"", "current", "42"} {
+		if err := parseSprintFlag(value); err != nil {
+			t.Errorf("parseSprintFlag(%q): unexpected error %v", value, err)
+		}
+	}
+	for _, value := range []string{"next", "0", "-3"} {
+		if err := parseSprintFlag(value); exitCode(err) != exitUsage {
+			t.Errorf("parseSprintFlag(%q): expected a usage error, got %v", value, err)
+		}
+	}
+}
+
+func TestJiraIssueUpdatesApplyWarnings(t *testing.T) {
+	oldPost, oldPut, oldOut, oldErr := jiraPost, jiraPut, stdout, stderr
+	defer func() { jiraPost, jiraPut, stdout, stderr = oldPost, oldPut, oldOut, oldErr }()
+	var out, errBuf bytes.Buffer
+	stdout, stderr = &out, &errBuf
+	jiraPost = func(u, user, token string, body []byte) ([]byte, error) {
+		return nil, errors.New("jira: unexpected status 400")
+	}
+	jiraPut = func(u, user, token string, body []byte) ([]byte, error) {
+		return nil, errors.New("jira: unexpected status 403")
+	}
+
+	jiraIssueUpdates{sprint: "42", fixVersion: "1.2"}.apply("https://jira.example.com", "PROJ-1", "user", "token")
+	want := "warning: PROJ-1: could not set sprint: jira: unexpected status 400\n" +
+		"warning: PROJ-1: could not set fix version: jira: unexpected status 403\n"
+	if errBuf.String() != want || out.Len() != 0 {
+		t.Fatalf("expected only warnings, got %q and %q", errBuf.String(), out.String())
+	}
+}
+
+func TestJiraNewCmdAllComments(t *testing.T) {
+	comment := func(body string) jiraComment {
+		return jiraComment{Author: jiraAuthor{DisplayName: "Ann"}, Body: body, Created: "2024-01-01"}
+	}
+	for _, args := range [][]string{{"-S", "PROJ-1"}, {"--all-comments", "-S", "PROJ-1"}, {"--all-comments", "-S", "PROJ-1", "PROJ-2"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			repo := t.TempDir()
+			issues := map[string]jiraIssue{
+				"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One", Comment: jiraComments{Comments: []jiraComment{comment("first")}, Total: 2}}},
+				"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two", Comment: jiraComments{Comments: []jiraComment{comment("only")}, Total: 1}}},
+			}
+			stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+			base := jiraGet
+			var fetched []string
+			jiraGet = func(u, user, token string) ([]byte, error) {
+				if strings.Contains(u, "/comment?") {
+					fetched = append(fetched, u)
+					return json.Marshal(jiraComments{Comments: []jiraComment{comment("first"), comment("second")}, Total: 2})
+				}
+				return base(u, user, token)
+			}
+			written := map[string]string{}
+			osWriteFile = func(name string, data []byte, perm fs.FileMode) error {
+				written[filepath.Base(name)] = string(data)
+				return nil
+			}
+			stdout = &bytes.Buffer{}
+
+			jiraNewCmd(args)
+
+			all := args[0] == "--all-comments"
+			if all && (len(fetched) != 1 || !strings.Contains(fetched[0], "/issue/PROJ-1/comment?")) {
+				t.Fatalf("expected only PROJ-1's comments fetched, got %v", fetched)
+			}
+			if !all && len(fetched) != 0 {
+				t.Fatalf("expected no comment requests without --all-comments, got %v", fetched)
+			}
+			if got := strings.Contains(written["PROJ-1.md"], "second"); got != all {
+				t.Fatalf("expected second comment rendered: %v, got %q", all, written["PROJ-1.md"])
+			}
+		})
+	}
+}
+
+func TestJiraFetchComments(t *testing.T) {
+	oldGet := jiraGet
+	defer func() { jiraGet = oldGet }()
+
+	var urls []string
+	jiraGet = func(u, user, token string) ([]byte, error) {
+		urls = append(urls, u)
+		parsed, _ := url.Parse(u)
+		switch parsed.Query().Get("startAt") {
+		case "0":
+			return []byte(`{"total":3,"comments":[{"body":"a"},{"body":"b"}]}`), nil
+		case "2":
+			return []byte(`{"total":3,"comments":[{"body":"c"}]}`), nil
+		}
+		return nil, errors.New("unexpected page")
+	}
+	comments, err := jiraFetchComments("https://jira.example.com", "PROJ-1", "user", "token")
+	if err != nil || len(comments) != 3 || comments[2].Body != "c" {
+		t.Fatalf("expected 3 comments, got %v, %v", comments, err)
+	}
+	if urls[0] != "https://jira.example.com/rest/api/2/issue/PROJ-1/comment?startAt=0&maxResults=100" {
+		t.Fatalf("unexpected first URL %q", urls[0])
+	}
+
+	// An empty page ends the loop even if total promised more.
+	jiraGet = func(u, user, token string) ([]byte, error) { return []byte(`{"total":5,"comments":[]}`), nil }
+	if comments, err := jiraFetchComments("https://jira.example.com", "PROJ-1", "user", "token"); err != nil || len(comments) != 0 {
+		t.Fatalf("expected no comments, got %v, %v", comments, err)
+	}
+
+	jiraGet = func(u, user, token string) ([]byte, error) { return []byte(`{`), nil }
+	if _, err := jiraFetchComments("https://jira.example.com", "PROJ-1", "user", "token"); err == nil || !strings.Contains(err.Error(), "invalid comments response") {
+		t.Fatalf("expected invalid response error, got %v", err)
+	}
+}
+
+func TestJiraAddAllCommentsWarns(t *testing.T) {
+	oldGet := jiraGet
+	oldErr := stderr
+	defer func() {
+		jiraGet = oldGet
+		stderr = oldErr
+	}()
+	var buf bytes.Buffer
+	stderr = &buf
+	jiraGet = func(u, user, token string) ([]byte, error) { return nil, errors.New("boom") }
+
+	issue := jiraIssue{Key: "PROJ-1", Fields: jiraFields{Comment: jiraComments{Comments: []jiraComment{{Body: "a"}}, Total: 4}}}
+	jiraAddAllComments("https://jira.example.com", "user", "token", &issue)
+	if len(issue.Fields.Comment.Comments) != 1 || !strings.Contains(buf.String(), "warning: could not fetch all comments of PROJ-1: boom") {
+		t.Fatalf("expected inline comments kept with a warning, got %q", buf.String())
+	}
+}
+
+func TestJiraNewCmdMultipleIssuesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		failCmd string
+		want    string
+		code    int
+	}{
+		{name: "branch flag", args: []string{"-b", "x", "PROJ-1", "PROJ-2"}, want: "-b and -t can only be used with a single issue", code: exitUsage},
+		{name: "tmux flag", args: []string{"-t", "PROJ-1", "PROJ-2"}, want: "-b and -t can only be used with a single issue", code: exitUsage},
+		{name: "negative retries", args: []string{"--retries", "-1", "PROJ-1", "PROJ-2"}, want: "invalid --retries -1: must be a non-negative number", code: exitUsage},
+		{name: "bad sprint", args: []string{"--sprint", "next", "PROJ-1"}, want: `invalid --sprint "next": must be "current" or a sprint ID`, code: exitUsage},
+		{name: "repo root", args: []string{"PROJ-1", "PROJ-2"}, failCmd: "rev-parse", want: "rev-parse --show-toplevel failed", code: exitError},
+		{name: "main worktree", args: []string{"PROJ-1", "PROJ-2"}, failCmd: "worktree-first", want: "worktree list --porcelain failed", code: exitError},
+		{name: "worktree lookup", args: []string{"PROJ-1", "PROJ-2"}, failCmd: "worktree-later", want: "PROJ-1:", code: exitError},
+		{name: "single worktree lookup", args: []string{"PROJ-1"}, failCmd: "worktree-later", want: "worktree list --porcelain failed", code: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			issues := map[string]jiraIssue{
+				"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+				"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+			}
+			stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+			base := execCommand
+			listCalls := 0
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				rest := args
+				if len(rest) > 0 && rest[0] == "-C" {
+					rest = rest[2:]
+				}
+				switch {
+				case tt.failCmd == "rev-parse" && len(rest) > 0 && rest[0] == "rev-parse":
+					return exec.Command("sh", "-c", "exit 1")
+				case len(rest) >= 2 && rest[0] == "worktree" && rest[1] == "list":
+					listCalls++
+					if tt.failCmd == "worktree-first" || (tt.failCmd == "worktree-later" && listCalls > 1) {
+						return exec.Command("sh", "-c", "exit 1")
+					}
+				}
+				return base(name, args...)
+			}
+
+			var out, errBuf bytes.Buffer
+			stdout = &out
+			stderr = &errBuf
+
+			code := func() (code int) {
+				defer func() {
+					if r := recover(); r != nil {
+						code = r.(int)
+					}
+				}()
+				jiraNewCmd(tt.args)
+				return 0
+			}()
+
+			if code != tt.code {
+				t.Fatalf("expected exit %d, got %d", tt.code, code)
+			}
+			if !strings.Contains(errBuf.String(), tt.want) {
+				t.Fatalf("expected %q in stderr, got %q", tt.want, errBuf.String())
+			}
+		})
+	}
+}
+
+func TestExtractWatchFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantRest []string
+		want     jiraWatchOptions
+		wantErr  string
+	}{
+		{
+			name:     "defaults",
+			args:     []string{"PROJ-1"},
+			wantRest: []string{"PROJ-1"},
+			want:     jiraWatchOptions{interval: defaultWatchInterval, retries: defaultWatchRetries},
+		},
+		{
+			name:     "all flags",
+			args:     []string{"--watch", "PROJ-1", "--until", "Done", "--interval=5s", "-retries", "0"},
+			wantRest: []string{"PROJ-1"},
+			want:     jiraWatchOptions{enabled: true, until: "Done", interval: 5 * time.Second, retries: 0},
+		},
+		{name: "until without watch", args: []string{"--until", "Done"}, wantErr: "require --watch"},
+		{name: "missing until", args: []string{"--watch", "--until"}, wantErr: "--until requires a status name"},
+		{name: "missing interval", args: []string{"--watch", "--interval"}, wantErr: "--interval requires a duration"},
+		{name: "missing retries", args: []string{"--watch", "--retries="}, wantErr: "--retries requires a number"},
+		{name: "bad interval", args: []string{"--watch", "--interval", "soon"}, wantErr: `invalid --interval "soon"`},
+		{name: "zero interval", args: []string{"--watch", "--interval", "0s"}, wantErr: `invalid --interval "0s"`},
+		{name: "bad retries", args: []string{"--watch", "--retries", "-1"}, wantErr: `invalid --retries "-1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rest, opts, err := extractWatchFlags(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(rest, " ") != strings.Join(tt.wantRest, " ") || opts != tt.want {
+				t.Fatalf("got %v %+v, want %v %+v", rest, opts, tt.wantRest, tt.want)
+			}
+		})
+	}
+}
+
+// stubJiraWatch serves the given statuses (an empty string means a fetch
+// error) in order and records sleeps.
+func stubJiraWatch(t *testing.T, statuses []string) *[]time.Duration {
+	t.Helper()
+	oldGetenv := osGetenv
+	oldGet := jiraGet
+	oldSleep := jiraSleep
+	oldReadFile := osReadFile
+	oldHomeDir := osUserHomeDir
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	t.Cleanup(func() {
+		osGetenv = oldGetenv
+		jiraGet = oldGet
+		jiraSleep = oldSleep
+		osReadFile = oldReadFile
+		osUserHomeDir = oldHomeDir
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	})
+
+	osGetenv = func(key string) string {
+		switch key {
+		case "JIRA_URL":
+			return "https://jira.example.com"
+		case "JIRA_USER":
+			return "user"
+		case "JIRA_TOKEN":
+			return "token"
+		}
+		return ""
+	}
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	osReadFile = func(name string) ([]byte, error) { return nil, os.ErrNotExist }
+	calls := 0
+	jiraGet = func(url, user, token string) ([]byte, error) {
+		if calls >= len(statuses) {
+			t.Fatalf("unexpected extra poll")
+		}
+		status := statuses[calls]
+		calls++
+		if status == "" {
+			return nil, errors.New("jira: connection reset")
+		}
+		return json.Marshal(jiraIssue{Key: "PROJ-1", Fields: jiraFields{
+			Status:    jiraStatus{Name: status},
+			IssueType: jiraIssueType{Name: "Story"},
+		}})
+	}
+	var sleeps []time.Duration
+	jiraSleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	exitFunc = func(code int) { panic(code) }
+	return &sleeps
+}
+
+func TestJiraStatusCmdWatchUntil(t *testing.T) {
+	sleeps := stubJiraWatch(t, []string{"To Do", "", "To Do", "In Progress", "Done"})
+	var out, errBuf bytes.Buffer
+	stdout = &out
+	stderr = &errBuf
+
+	jiraStatusCmd([]string{"--watch", "--until", "done", "--interval", "10s", "PROJ-1"})
+
+	want := "PROJ-1: To Do\nPROJ-1: To Do → In Progress\nPROJ-1: In Progress → Done\n"
+	if out.String() != want {
+		t.Fatalf("expected %q, got %q", want, out.String())
+	}
+	if !strings.Contains(errBuf.String(), "warning: jira: connection reset (retry 1/3)") {
+		t.Fatalf("expected retry warning, got %q", errBuf.String())
+	}
+	if len(*sleeps) != 4 || (*sleeps)[0] != 10*time.Second {
+		t.Fatalf("expected 4 sleeps of 10s, got %v", *sleeps)
+	}
+}
+
+func TestJiraStatusCmdWatchSymbolicUntil(t *testing.T) {
+	stubJiraWatch(t, []string{"In Progress", "Closed"})
+	osReadFile = func(name string) ([]byte, error) {
+		if name == "/home/test/.config/wt/config.json" {
+			return []byte(`{"jira":{"status":{"default":{"done":"Closed"}}}}`), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	var out bytes.Buffer
+	stdout = &out
+
+	jiraStatusCmd([]string{"PROJ-1", "--watch", "--until", "done"})
+
+	if !strings.Contains(out.String(), "PROJ-1: In Progress → Closed") {
+		t.Fatalf("expected transition to Closed, got %q", out.String())
+	}
+}
+
+func TestJiraStatusCmdWatchRetriesExhausted(t *testing.T) {
+	stubJiraWatch(t, []string{"To Do", "", ""})
+	var errBuf bytes.Buffer
+	stdout = &bytes.Buffer{}
+	stderr = &errBuf
+
+	defer func() {
+		if r := recover(); r != 1 {
+			t.Fatalf("expected exit 1, got %v", r)
+		}
+		if !strings.Contains(errBuf.String(), "connection reset") {
+			t.Fatalf("expected fetch error, got %q", errBuf.String())
+		}
+	}()
+	jiraStatusCmd([]string{"--watch", "--retries", "1", "PROJ-1"})
+}
+
+func TestJiraStatusCmdWatchForever(t *testing.T) {
+	stubJiraWatch(t, []string{"To Do", "To Do", "In Progress"})
+	polls := 0
+	jiraSleep = func(time.Duration) {
+		polls++
+		if polls == 3 {
+			panic("interrupted")
+		}
+	}
+	var out bytes.Buffer
+	stdout = &out
+
+	func() {
+		defer func() {
+			if r := recover(); r != "interrupted" {
+				t.Fatalf("expected watch to keep polling, got %v", r)
+			}
+		}()
+		jiraStatusCmd([]string{"--watch", "PROJ-1"})
+	}()
+
+	if out.String() != "PROJ-1: To Do\nPROJ-1: To Do → In Progress\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestJiraStatusCmdWatchErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"with set", []string{"--watch", "--set", "Done", "PROJ-1"}, "--watch cannot be combined"},
+		{"with positional status", []string{"--watch", "PROJ-1", "Done"}, "--watch cannot be combined"},
+		{"bad flag", []string{"--until", "Done", "PROJ-1"}, "require --watch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubJiraWatch(t, nil)
+			var errBuf bytes.Buffer
+			stderr = &errBuf
+			defer func() {
+				if r := recover(); r != exitUsage {
+					t.Fatalf("expected exit 2, got %v", r)
+				}
+				if !strings.Contains(errBuf.String(), tt.want) {
+					t.Fatalf("expected %q, got %q", tt.want, errBuf.String())
+				}
+			}()
+			jiraStatusCmd(tt.args)
+		})
+	}
+}
+
+func TestJiraIssueUnmarshalRawFields(t *testing.T) {
+	var issue jiraIssue
+	data := `{"key":"PROJ-1","fields":{"summary":"Fix","customfield_10040":"Must pass"}}`
+	if err := json.Unmarshal([]byte(data), &issue); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if issue.Fields.Summary != "Fix" {
+		t.Fatalf("expected typed fields decoded, got %+v", issue.Fields)
+	}
+	if string(issue.RawFields["customfield_10040"]) != `"Must pass"` {
+		t.Fatalf("expected raw custom field, got %q", issue.RawFields["customfield_10040"])
+	}
+	if err := json.Unmarshal([]byte(`{"fields":"nope"}`), &issue); err == nil {
+		t.Fatalf("expected error for malformed fields")
+	}
+}
+
+func TestRenderIssueMDCustomFields(t *testing.T) {
+	issue := jiraIssue{
+		Key:    "PROJ-1",
+		Fields: jiraFields{Summary: "Fix", Description: "Desc"},
+		RawFields: map[string]json.RawMessage{
+			"customfield_1": json.RawMessage(`"Given X, then Y"`),
+			"customfield_2": json.RawMessage(`{"value":"High"}`),
+			"customfield_3": json.RawMessage(`[{"name":"api"},"web",null]`),
+			"customfield_4": json.RawMessage(`null`),
+			"customfield_5": json.RawMessage(`3`),
+		},
+	}
+	md := renderIssue(issue, jiraConfigBlock{CustomFields: map[string]string{
+		"customfield_1": "Acceptance Criteria",
+		"customfield_2": "Risk",
+		"customfield_3": "Components",
+		"customfield_4": "Empty",
+		"customfield_5": "Points",
+		"customfield_6": "Missing",
+	}}, "", notesStyles[notesFormatMarkdown])
+
+	want := "# PROJ-1: Fix\n\n## Description\n\nDesc\n" +
+		"\n## Acceptance Criteria\n\nGiven X, then Y\n" +
+		"\n## Components\n\n- api\n- web\n" +
+		"\n## Points\n\n3\n" +
+		"\n## Risk\n\nHigh\n"
+	if md != want {
+		t.Fatalf("expected %q, got %q", want, md)
+	}
+}
+
+func TestRenderIssueMDCustomFieldsSameTitle(t *testing.T) {
+	issue := jiraIssue{
+		Key:    "PROJ-1",
+		Fields: jiraFields{Summary: "Fix"},
+		RawFields: map[string]json.RawMessage{
+			"customfield_1": json.RawMessage(`"first"`),
+			"customfield_2": json.RawMessage(`"second"`),
+			"customfield_3": json.RawMessage(`"third"`),
+		},
+	}
+	fields := map[string]string{"customfield_3": "Notes", "customfield_1": "Notes", "customfield_2": "Notes"}
+
+	want := "# PROJ-1: Fix\n" +
+		"\n## Notes\n\nfirst\n" +
+		"\n## Notes\n\nsecond\n" +
+		"\n## Notes\n\nthird\n"
+	for range 10 {
+		if md := renderIssue(issue, jiraConfigBlock{CustomFields: fields}, "", notesStyles[notesFormatMarkdown]); md != want {
+			t.Fatalf("expected %q, got %q", want, md)
+		}
+	}
+}
+
+func TestRenderFieldValue(t *testing.T) {
+	tests := map[string]string{
+		``:                         "",
+		`not json`:                 "",
+		`"  padded  "`:             "padded",
+		`{"displayName":"Jane"}`:   "Jane",
+		`{"id":"1"}`:               `{"id":"1"}`,
+		`true`:                     "true",
+		`[]`:                       "",
+		`[{"value":"a"},{"id":1}]`: "- a\n- {\"id\":1}",
+	}
+	for in, want := range tests {
+		if got := renderFieldValue(json.RawMessage(in)); got != want {
+			t.Errorf("renderFieldValue(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestJiraNewCmdCustomFields(t *testing.T) {
+	repo := t.TempDir()
+	issues := map[string]jiraIssue{
+		"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+	}
+	stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+	osReadFile = func(name string) ([]byte, error) {
+		if name == "/home/test/.config/wt/config.json" {
+			return []byte(`{"jira":{"customFields":{"customfield_10040":"Acceptance Criteria"}}}`), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	var fetchURL string
+	jiraGet = func(url, user, token string) ([]byte, error) {
+		fetchURL = url
+		return []byte(`{"key":"PROJ-1","fields":{"summary":"One","customfield_10040":"It works"}}`), nil
+	}
+	var written string
+	osWriteFile = func(name string, data []byte, perm fs.FileMode) error {
+		written = string(data)
+		return nil
+	}
+	stdout = &bytes.Buffer{}
+
+	jiraNewCmd([]string{"-S", "PROJ-1"})
+
+	if !strings.HasSuffix(fetchURL, "fields=summary,description,comment,status,issuetype,issuelinks,customfield_10040") {
+		t.Fatalf("expected custom field requested, got %q", fetchURL)
+	}
+	if !strings.Contains(written, "## Acceptance Criteria\n\nIt works\n") {
+		t.Fatalf("expected custom field section, got %q", written)
+	}
+}
+
+func TestJiraIssueKeyFromArg(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    string
+		wantErr string
+	}{
+		{"PROJ-123", "PROJ-123", ""},
+		{"whatever", "whatever", ""},
+		{"https://jira.example.com/browse/PROJ-123", "PROJ-123", ""},
+		{"http://jira.example.com/browse/PROJ-123?focusedCommentId=1", "PROJ-123", ""},
+		{"https://acme.atlassian.net/jira/software/projects/PROJ/boards/7?selectedIssue=PROJ-45", "PROJ-45", ""},
+		{"https://acme.atlassian.net/jira/software/c/projects/PROJ/issues/PROJ-9", "PROJ-9", ""},
+		{"https://acme.atlassian.net/jira/software/projects/PROJ/boards/7", "", "no issue key found"},
+		{"https://jira.example.com/%zz", "", "invalid Jira URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := jiraIssueKeyFromArg(tt.arg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %q, %v", tt.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("jiraIssueKeyFromArg(%q) = %q, %v; want %q", tt.arg, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJiraCmdIssueURLs(t *testing.T) {
+	repo := t.TempDir()
+	issues := map[string]jiraIssue{
+		"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+		"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+	}
+	stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+	var out bytes.Buffer
+	stdout = &out
+	stderr = &bytes.Buffer{}
+
+	jiraCmd([]string{
+		"https://jira.example.com/browse/PROJ-1",
+		"https://jira.example.com/jira/software/projects/PROJ/boards/1?selectedIssue=PROJ-2",
+	})
+
+	if !strings.Contains(out.String(), "2 created, 0 skipped (existing), 0 failed") {
+		t.Fatalf("expected both issues created, got %q", out.String())
+	}
+}
+
+func TestJiraIssueKeyFromArgNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    string
+		wantErr string
+	}{
+		{name: "default project", config: `{"jira":{"defaultProject":"proj"}}`, want: "PROJ-123"},
+		{name: "no default project", config: `{}`, wantErr: "123 is an issue number, not a key: set jira.defaultProject or pass PROJ-123"},
+		{name: "invalid config", config: `{`, wantErr: "invalid config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldHome := osUserHomeDir
+			oldRead := osReadFile
+			defer func() {
+				osUserHomeDir = oldHome
+				osReadFile = oldRead
+			}()
+			osUserHomeDir = func() (string, error) { return "/home/test", nil }
+			osReadFile = func(name string) ([]byte, error) {
+				if name == "/home/test/.config/wt/config.json" {
+					return []byte(tt.config), nil
+				}
+				return nil, os.ErrNotExist
+			}
+
+			got, err := jiraIssueKeyFromArg("123")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %q, %v", tt.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("jiraIssueKeyFromArg(123) = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJiraCmdIssueNumbers(t *testing.T) {
+	repo := t.TempDir()
+	issues := map[string]jiraIssue{
+		"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+		"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+	}
+	stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+	osReadFile = func(name string) ([]byte, error) {
+		if name == "/home/test/.config/wt/config.json" {
+			return []byte(`{"jira":{"defaultProject":"PROJ"}}`), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	var out bytes.Buffer
+	stdout = &out
+	stderr = &bytes.Buffer{}
+
+	jiraCmd([]string{"1", "PROJ-2"})
+
+	if !strings.Contains(out.String(), "2 created, 0 skipped (existing), 0 failed") {
+		t.Fatalf("expected both issues created, got %q", out.String())
+	}
+}
+
+func TestJiraCmdIssueKeys(t *testing.T) {
+	repo := t.TempDir()
+	issues := map[string]jiraIssue{
+		"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+		"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+	}
+	stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+	var out bytes.Buffer
+	stdout = &out
+	stderr = &bytes.Buffer{}
+
+	jiraCmd([]string{"PROJ-1", "PROJ-2"})
+
+	if !strings.Contains(out.String(), "2 created, 0 skipped (existing), 0 failed") {
+		t.Fatalf("expected both issues created, got %q", out.String())
+	}
+}
+
+func TestJiraCmdsInvalidIssueURL(t *testing.T) {
+	const bad = "https://jira.example.com/secure/Dashboard.jspa"
+	for name, run := range map[string]func(){
+		"new":    func() { jiraCmd([]string{bad}) },
+		"status": func() { jiraStatusCmd([]string{bad}) },
+		"sync":   func() { jiraStatusSyncCmd([]string{bad}) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			oldErr := stderr
+			oldExit := exitFunc
+			defer func() {
+				stderr = oldErr
+				exitFunc = oldExit
+			}()
+			var buf bytes.Buffer
+			stderr = &buf
+			exitFunc = func(code int) { panic(code) }
+
+			func() {
+				defer func() {
+					if r := recover(); r != 1 {
+						t.Fatalf("expected exit 1, got %v", r)
+					}
+				}()
+				run()
+			}()
+			if !strings.Contains(buf.String(), "no issue key found in "+bad) {
+				t.Fatalf("unexpected error output %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestRenderIssueNotesFormats(t *testing.T) {
+	issue := jiraIssue{
+		Key: "PROJ-1",
+		Fields: jiraFields{
+			Summary:     "Fix login",
+			Description: "Users are logged out.",
+			IssueLinks: []jiraIssueLink{{
+				Type:         jiraIssueLinkType{Outward: "blocks"},
+				OutwardIssue: &jiraIssue{Key: "PROJ-2", Fields: jiraFields{Summary: "Deploy", Status: jiraStatus{Name: "To Do"}}},
+			}},
+			Comment: jiraComments{Comments: []jiraComment{{Author: jiraAuthor{DisplayName: "Ann"}, Created: "2024-01-01", Body: "Looking"}}},
+		},
+		Children: []jiraIssue{{Key: "PROJ-3", Fields: jiraFields{Summary: "Child", Status: jiraStatus{Name: "Done"}}}},
+	}
+
+	org := renderIssue(issue, jiraConfigBlock{}, "https://jira.example.com/", notesStyles[notesFormatOrg])
+	wantOrg := "* [[https://jira.example.com/browse/PROJ-1][PROJ-1]]: Fix login\n" +
+		"\n** Description\n\nUsers are logged out.\n" +
+		"\n** Child Issues\n\n- [[https://jira.example.com/browse/PROJ-3][PROJ-3]]: Child (Done)\n" +
+		"\n** Linked Issues\n\n- blocks [[https://jira.example.com/browse/PROJ-2][PROJ-2]]: Deploy (To Do)\n" +
+		"\n** Comments\n\n*** Ann (2024-01-01)\n\nLooking\n"
+	if org != wantOrg {
+		t.Fatalf("unexpected org notes:\n%s\nwant:\n%s", org, wantOrg)
+	}
+	if org := renderIssue(issue, jiraConfigBlock{}, "", notesStyles[notesFormatOrg]); !strings.HasPrefix(org, "* PROJ-1: Fix login\n") {
+		t.Fatalf("expected plain keys without a base URL, got %q", org)
+	}
+
+	plain := renderIssue(issue, jiraConfigBlock{}, "https://jira.example.com", notesStyles[notesFormatPlain])
+	wantPlain := "PROJ-1: Fix login\n=================\n" +
+		"\nDescription\n-----------\n\nUsers are logged out.\n" +
+		"\nChild Issues\n------------\n\n- PROJ-3: Child (Done)\n" +
+		"\nLinked Issues\n-------------\n\n- blocks PROJ-2: Deploy (To Do)\n" +
+		"\nComments\n--------\n\nAnn (2024-01-01):\n\nLooking\n"
+	if plain != wantPlain {
+		t.Fatalf("unexpected plain notes:\n%s\nwant:\n%s", plain, wantPlain)
+	}
+
+	if md := renderIssue(issue, jiraConfigBlock{}, "https://jira.example.com", notesStyles[notesFormatMarkdown]); md != renderIssue(issue, jiraConfigBlock{}, "", notesStyles[notesFormatMarkdown]) {
+		t.Fatalf("expected markdown notes unchanged by the base URL, got %q", md)
+	}
+}
+
+func TestRenderIssueOrgBullets(t *testing.T) {
+	issue := jiraIssue{
+		Key: "PROJ-1",
+		Fields: jiraFields{
+			Summary:     "Fix login",
+			Description: "Steps:\n* open the app\n** tap login\nIt is *very* broken.",
+			Comment:     jiraComments{Comments: []jiraComment{{Author: jiraAuthor{DisplayName: "Ann"}, Created: "2024-01-01", Body: "* also on web"}}},
+		},
+		RawFields: map[string]json.RawMessage{"customfield_1": json.RawMessage(`"* given a user"`)},
+	}
+	cfg := jiraConfigBlock{CustomFields: map[string]string{"customfield_1": "Acceptance"}}
+
+	org := renderIssue(issue, cfg, "", notesStyles[notesFormatOrg])
+	want := "* PROJ-1: Fix login\n" +
+		"\n** Description\n\nSteps:\n * open the app\n ** tap login\nIt is *very* broken.\n" +
+		"\n** Acceptance\n\n * given a user\n" +
+		"\n** Comments\n\n*** Ann (2024-01-01)\n\n * also on web\n"
+	if org != want {
+		t.Fatalf("unexpected org notes:\n%s\nwant:\n%s", org, want)
+	}
+	if md := renderIssue(issue, cfg, "", notesStyles[notesFormatMarkdown]); !strings.Contains(md, "\n* open the app\n** tap login\n") {
+		t.Fatalf("expected markdown bodies left as they are, got %q", md)
+	}
+}
+
+func TestRenderIssueTruncatesDescription(t *testing.T) {
+	issue := jiraIssue{Key: "PROJ-1", Fields: jiraFields{Summary: "Fix", Description: "héllo world"}}
+	render := func(limit int, baseURL string) string {
+		return renderIssue(issue, jiraConfigBlock{MaxDescriptionLength: limit}, baseURL, notesStyles[notesFormatMarkdown])
+	}
+
+	// At or over the length, and with no limit, the description is whole.
+	for _, limit := range []int{0, 11, 12} {
+		if md := render(limit, ""); !strings.Contains(md, "\nhéllo world\n") || strings.Contains(md, "truncated") {
+			t.Errorf("limit %d: expected the full description, got %q", limit, md)
+		}
+	}
+	// One character short cuts it, counting characters rather than bytes.
+	if md := render(10, ""); !strings.Contains(md, "\nhéllo worl\n\n... (truncated, see Jira)\n") {
+		t.Errorf("expected the description cut to 10 characters, got %q", md)
+	}
+	// Whitespace at the cut is dropped and the marker links the issue.
+	want := "\nhéllo\n\n... (truncated, see Jira: https://jira.example.com/browse/PROJ-1)\n"
+	if md := render(6, "https://jira.example.com/"); !strings.Contains(md, want) {
+		t.Errorf("expected %q, got %q", want, md)
+	}
+}
+
+func TestJiraNotesStyle(t *testing.T) {
+	for format, ext := range map[string]string{"": ".md", "markdown": ".md", "org": ".org", "plain": ".txt"} {
+		style, err := jiraNotesStyle(wtConfig{Jira: jiraConfigBlock{NotesFormat: format}})
+		if err != nil || style.ext != ext {
+			t.Errorf("jiraNotesStyle(%q) = %q, %v; want %q", format, style.ext, err, ext)
+		}
+	}
+	if _, err := jiraNotesStyle(wtConfig{Jira: jiraConfigBlock{NotesFormat: "rst"}}); err == nil || !strings.Contains(err.Error(), `invalid jira.notesFormat "rst"`) {
+		t.Fatalf("expected invalid format error, got %v", err)
+	}
+}
+
+func TestJiraCreateIssueWorktreeNotesFormat(t *testing.T) {
+	repo := setupTestRepo(t)
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	issue := jiraIssue{Key: "PROJ-1", Fields: jiraFields{Summary: "Fix"}}
+	opts := addOptions{branch: "PROJ-1-fix", cfg: wtConfig{Jira: jiraConfigBlock{NotesFormat: "org"}}}
+	wtPath, err := jiraCreateIssueWorktree(repo, repo, "https://jira.example.com", issue, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(wtPath, "PROJ-1.org"))
+	if err != nil || string(data) != "* [[https://jira.example.com/browse/PROJ-1][PROJ-1]]: Fix\n" {
+		t.Fatalf("unexpected org notes %q, %v", data, err)
+	}
+
+	opts.cfg.Jira.NotesFormat = "rst"
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		t.Fatalf("expected no worktree for an invalid format, got %v", args)
+		return nil
+	}
+	if _, err := jiraCreateIssueWorktree(repo, repo, "", issue, opts); err == nil {
+		t.Fatal("expected invalid format error")
+	}
+}
+
+func TestJiraNewCmdQuietGit(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantQuiet bool
+	}{
+		{"default off a terminal", []string{"-S", "PROJ-1"}, true},
+		{"several issues", []string{"-S", "PROJ-1", "PROJ-2"}, true},
+		{"explicitly disabled", []string{"-S", "--quiet-git=false", "PROJ-1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			issues := map[string]jiraIssue{
+				"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+				"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+			}
+			stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+			var errBuf bytes.Buffer
+			stdout = &bytes.Buffer{}
+			stderr = &errBuf
+
+			var adds [][]string
+			stubbed := execCommand
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				if len(args) > 3 && args[2] == "worktree" && args[3] == "add" {
+					adds = append(adds, args)
+					return exec.Command("sh", "-c", "echo 'Preparing worktree' >&2")
+				}
+				return stubbed(name, args...)
+			}
+
+			jiraNewCmd(tt.args)
+			if shown := strings.Contains(errBuf.String(), "Preparing worktree"); shown == tt.wantQuiet {
+				t.Fatalf("expected git's progress shown=%v, got stderr %q", !tt.wantQuiet, errBuf.String())
+			}
+			if len(adds) == 0 {
+				t.Fatal("expected git worktree add")
+			}
+			for _, args := range adds {
+				if slices.Contains(args, "-q") != tt.wantQuiet {
+					t.Fatalf("expected quiet=%v, got args %v", tt.wantQuiet, args)
+				}
+			}
+		})
+	}
+}
+
+func TestJiraNewCmdTimings(t *testing.T) {
+	repo := t.TempDir()
+	issues := map[string]jiraIssue{
+		"PROJ-1": {Key: "PROJ-1", Fields: jiraFields{Summary: "One"}},
+		"PROJ-2": {Key: "PROJ-2", Fields: jiraFields{Summary: "Two"}},
+	}
+	stubJiraMulti(t, repo, issues, fmt.Sprintf("worktree %s\nbranch refs/heads/main\n", repo))
+
+	var errBuf bytes.Buffer
+	stdout = &bytes.Buffer{}
+	stderr = &errBuf
+
+	jiraNewCmd([]string{"--timings", "PROJ-1"})
+	for _, phase := range []string{"timings:", "setup", "jira fetch", "git worktree add", "notes", "jira transition", "total"} {
+		if !strings.Contains(errBuf.String(), phase) {
+			t.Errorf("expected %q in timings, got %q", phase, errBuf.String())
+		}
+	}
+
+	errBuf.Reset()
+	jiraNewCmd([]string{"--timings", "PROJ-1", "PROJ-2"})
+	if strings.Count(errBuf.String(), "jira fetch") != 1 || !strings.Contains(errBuf.String(), "jira transition") {
+		t.Fatalf("expected one timings table for both issues, got %q", errBuf.String())
+	}
+
+	errBuf.Reset()
+	jiraNewCmd([]string{"PROJ-1"})
+	if strings.Contains(errBuf.String(), "timings:") {
+		t.Fatalf("expected no timings without --timings, got %q", errBuf.String())
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The journal remembers the last worktree created by wt new or the TUI, or
+// removed by wt rm or the TUI, so that wt undo can reverse it. It holds a
+// single entry, kept in the repository's common git directory where every
+// worktree can see it.
+
+const (
+	journalOpNew = "new"
+	journalOpRm  = "rm"
+)
+
+const journalFile = "wt-journal.json"
+
+var osRemove = os.Remove
+
+var errNothingToUndo = errors.New("nothing to undo")
+
+type journalEntry struct {
+	Op     string `json:"op"`
+	Path   string `json:"path"`
+	Branch string `json:"branch,omitempty"`
+	// BranchCreated is set when wt new created the branch, so undo may
+	// delete it without touching a branch that existed before.
+	BranchCreated bool `json:"branchCreated,omitempty"`
+}
+
+func journalPath(repoRoot string) (string, error) {
+	dir, err := gitCommonDir(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, journalFile), nil
+}
+
+// recordJournal replaces the journal with entry. Failing to record only
+// warns, after dropping the old entry so undo can't reverse a stale one.
+func recordJournal(repoRoot string, entry journalEntry) {
+	path, err := journalPath(repoRoot)
+	if err == nil {
+		data, _ := json.Marshal(entry)
+		err = osWriteFile(path, data, 0o644)
+		if err != nil {
+			_ = osRemove(path)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: could not record this for wt undo: %v\n", err)
+	}
+}
+
+// forgetJournal drops the journal after a change wt undo can't reverse,
+// such as removing several worktrees at once, so that undo doesn't reverse
+// an older entry instead. Failing to drop it only warns.
+func forgetJournal(repoRoot string) {
+	if err := clearJournal(repoRoot); err != nil {
+		fmt.Fprintf(stderr, "warning: could not clear the wt undo journal: %v\n", err)
+	}
+}
+
+// renameJournal follows a rename of oldBranch, and the move of its
+// worktree from oldPath to newPath, so that wt undo reverses the worktree
+// under its new name. A journal about some other branch is left alone.
+func renameJournal(repoRoot, oldPath, newPath, oldBranch, newBranch string) {
+	entry, err := readJournal(repoRoot)
+	if err != nil || entry.Branch != oldBranch {
+		return
+	}
+	entry.Branch = newBranch
+	if entry.Path == oldPath {
+		entry.Path = newPath
+	}
+	recordJournal(repoRoot, entry)
+}
+
+// readJournal returns the last recorded operation, or errNothingToUndo.
+func readJournal(repoRoot string) (journalEntry, error) {
+	path, err := journalPath(repoRoot)
+	if err != nil {
+		return journalEntry{}, err
+	}
+	data, err := osReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return journalEntry{}, errNothingToUndo
+	}
+	if err != nil {
+		return journalEntry{}, err
+	}
+	var entry journalEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return journalEntry{}, fmt.Errorf("invalid undo journal %s: %w", path, err)
+	}
+	return entry, nil
+}
+
+func clearJournal(repoRoot string) error {
+	path, err := journalPath(repoRoot)
+	if err != nil {
+		return err
+	}
+	if err := osRemove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// undoLast reverses the journal entry and clears it. Undoing wt new removes
+// the worktree if it is clean, and with deleteBranch also the branch if wt
+// new created it. Undoing wt rm checks the branch out again at the old path;
+// files that were not committed are gone for good.
+func undoLast(repoRoot string, deleteBranch bool) error {
+	entry, err := readJournal(repoRoot)
+	if err != nil {
+		return err
+	}
+	switch entry.Op {
+	case journalOpNew:
+		clean, err := gitWorktreeClean(entry.Path)
+		if err != nil {
+			return fmt.Errorf("cannot undo wt new %s: %w", entry.Branch, err)
+		}
+		if !clean {
+			return blockedError(fmt.Errorf("cannot undo wt new %s: %s has uncommitted changes", entry.Branch, entry.Path))
+		}
+		if err := removeWorktree(repoRoot, entry.Path); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "removed %s\n", entry.Path)
+		if deleteBranch {
+			if !entry.BranchCreated {
+				fmt.Fprintf(stderr, "kept branch %s: it existed before wt new\n", entry.Branch)
+			} else if err := runGit(repoRoot, "branch", "-d", entry.Branch); err != nil {
+				return err
+			} else {
+				fmt.Fprintf(stdout, "deleted branch %s\n", entry.Branch)
+			}
+		}
+	case journalOpRm:
+		if entry.Branch == "" {
+			return fmt.Errorf("cannot undo wt rm %s: it had no branch checked out", entry.Path)
+		}
+		if err := runGit(repoRoot, "worktree", "add", entry.Path, entry.Branch); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "restored %s (%s)\n", entry.Path, entry.Branch)
+	default:
+		return fmt.Errorf("invalid undo journal: unknown operation %q", entry.Op)
+	}
+	return clearJournal(repoRoot)
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureUndo redirects stdout and stderr for the test and makes exitFunc
+// panic with the exit code.
+func captureUndo(t *testing.T) (*bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	oldOut := stdout
+	oldErr := stderr
+	oldExit := exitFunc
+	t.Cleanup(func() {
+		stdout = oldOut
+		stderr = oldErr
+		exitFunc = oldExit
+	})
+	var outBuf, errBuf bytes.Buffer
+	stdout = &outBuf
+	stderr = &errBuf
+	exitFunc = func(code int) { panic(code) }
+	return &outBuf, &errBuf
+}
+
+func expectUndoExit(t *testing.T, args []string, code int) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != code {
+			t.Fatalf("expected exit %d, got %v", code, r)
+		}
+	}()
+	undoCmd(args)
+}
+
+func TestUndoNew(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	out, errOut := captureUndo(t)
+
+	newCmd([]string{"feature"})
+	wtPath := worktreePath(repo, "feature")
+
+	out.Reset()
+	undoCmd([]string{"-D"})
+	if out.String() != "removed "+wtPath+"\ndeleted branch feature\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	if _, err := os.Stat(wtPath); !os.IsNotExist(err) {
+		t.Fatalf("expected worktree removed, got %v", err)
+	}
+	if exists, _ := gitBranchExists(repo, "feature"); exists {
+		t.Fatal("expected branch deleted")
+	}
+
+	expectUndoExit(t, nil, exitError)
+	if !strings.Contains(errOut.String(), "nothing to undo") {
+		t.Fatalf("expected nothing to undo, got %q", errOut.String())
+	}
+}
+
+func TestUndoNewKeepsExistingBranch(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	mustRunCmd(t, repo, "git", "branch", "existing")
+	_, errOut := captureUndo(t)
+
+	newCmd([]string{"existing"})
+	undoCmd([]string{"--delete-branch"})
+
+	if !strings.Contains(errOut.String(), "kept branch existing: it existed before wt new") {
+		t.Fatalf("expected branch kept notice, got %q", errOut.String())
+	}
+	if exists, _ := gitBranchExists(repo, "existing"); !exists {
+		t.Fatal("expected existing branch kept")
+	}
+}
+
+func TestUndoNewKeepsBranchByDefault(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	captureUndo(t)
+
+	newCmd([]string{"feature"})
+	undoCmd(nil)
+
+	if exists, _ := gitBranchExists(repo, "feature"); !exists {
+		t.Fatal("expected branch kept without -D")
+	}
+}
+
+func TestUndoNewRefusesDirty(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	_, errOut := captureUndo(t)
+
+	newCmd([]string{"feature"})
+	wtPath := worktreePath(repo, "feature")
+	mustWriteFile(t, filepath.Join(wtPath, "wip.txt"), "wip")
+
+	expectUndoExit(t, nil, exitBlocked)
+	if !strings.Contains(errOut.String(), "cannot undo wt new feature: "+wtPath+" has uncommitted changes") {
+		t.Fatalf("expected dirty error, got %q", errOut.String())
+	}
+	if _, err := os.Stat(wtPath); err != nil {
+		t.Fatalf("expected worktree kept: %v", err)
+	}
+}
+
+func TestUndoNewErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, repo, wtPath string)
+		want    string
+	}{
+		{
+			name:    "worktree gone",
+			prepare: func(t *testing.T, repo, wtPath string) { mustRunCmd(t, repo, "git", "worktree", "remove", wtPath) },
+			want:    "cannot undo wt new feature: ",
+		},
+		{
+			name: "remove fails",
+			prepare: func(t *testing.T, repo, wtPath string) {
+				mustRunCmd(t, repo, "git", "worktree", "lock", wtPath)
+			},
+			want: "cannot remove a locked working tree",
+		},
+		{
+			name: "branch not merged",
+			prepare: func(t *testing.T, repo, wtPath string) {
+				mustWriteFile(t, filepath.Join(wtPath, "new.txt"), "new")
+				mustRunCmd(t, wtPath, "git", "add", ".")
+				mustRunCmd(t, wtPath, "git", "commit", "-m", "work")
+			},
+			want: "not fully merged",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := setupTestRepo(t)
+			defer withDir(t, repo)()
+			_, errOut := captureUndo(t)
+
+			newCmd([]string{"feature"})
+			tt.prepare(t, repo, worktreePath(repo, "feature"))
+
+			expectUndoExit(t, []string{"-D"}, exitError)
+			if !strings.Contains(errOut.String(), tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, errOut.String())
+			}
+		})
+	}
+}
+
+func TestUndoRm(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	wtPath := setupTestWorktree(t, repo, "feature")
+	out, _ := captureUndo(t)
+
+	rmCmd([]string{"feature"})
+	out.Reset()
+	undoCmd(nil)
+
+	if out.String() != "restored "+wtPath+" (feature)\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "file.txt")); err != nil {
+		t.Fatalf("expected worktree restored: %v", err)
+	}
+}
+
+func TestUndoRmErrors(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	_, errOut := captureUndo(t)
+
+	recordJournal(repo, journalEntry{Op: journalOpRm, Path: filepath.Join(t.TempDir(), "detached")})
+	expectUndoExit(t, nil, exitError)
+	if !strings.Contains(errOut.String(), "it had no branch checked out") {
+		t.Fatalf("expected detached error, got %q", errOut.String())
+	}
+
+	errOut.Reset()
+	recordJournal(repo, journalEntry{Op: journalOpRm, Path: filepath.Join(t.TempDir(), "main"), Branch: "main"})
+	expectUndoExit(t, nil, exitError)
+	if !strings.Contains(errOut.String(), "already") {
+		t.Fatalf("expected git error for a checked-out branch, got %q", errOut.String())
+	}
+
+	errOut.Reset()
+	recordJournal(repo, journalEntry{Op: "mv"})
+	expectUndoExit(t, nil, exitError)
+	if !strings.Contains(errOut.String(), `unknown operation "mv"`) {
+		t.Fatalf("expected unknown operation error, got %q", errOut.String())
+	}
+}
+
+func TestUndoTUIDelete(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	out, _ := captureUndo(t)
+
+	newCmd([]string{"feature"})
+	wtPath := worktreePath(repo, "feature")
+	m := tuiModel{repoRoot: repo, pendingDelete: worktreeItem{branch: "feature", path: wtPath}}
+	if msg := deleteWorktreeCmd(m)().(deleteResultMsg); msg.err != nil {
+		t.Fatalf("unexpected error: %v", msg.err)
+	}
+
+	// Undo restores what the TUI removed rather than the older wt new.
+	out.Reset()
+	undoCmd(nil)
+	if out.String() != "restored "+wtPath+" (feature)\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestUndoTUICreate(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	mustRunCmd(t, repo, "git", "branch", "existing")
+	out, _ := captureUndo(t)
+
+	m := tuiModel{repoRoot: repo, mainWorktree: repo, pendingBranch: "tui"}
+	if err := m.createWorktree(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	undoCmd([]string{"-D"})
+	if want := "removed " + worktreePath(repo, "tui") + "\ndeleted branch tui\n"; out.String() != want {
+		t.Fatalf("expected %q, got %q", want, out.String())
+	}
+
+	m.pendingBranch = "existing"
+	if err := m.createWorktree(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry, err := readJournal(repo); err != nil || entry.Branch != "existing" || entry.BranchCreated {
+		t.Fatalf("expected an entry keeping the existing branch, got %+v, %v", entry, err)
+	}
+}
+
+func TestUndoTUIRename(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	out, _ := captureUndo(t)
+
+	newCmd([]string{"feature"})
+	item := worktreeItem{branch: "feature", path: worktreePath(repo, "feature")}
+	if msg := renameWorktreeCmd(repo, item, "renamed")().(renameResultMsg); msg.err != nil {
+		t.Fatalf("unexpected error: %v", msg.err)
+	}
+
+	// Undo removes the worktree and branch under their new names.
+	out.Reset()
+	undoCmd([]string{"-D"})
+	if want := "removed " + worktreePath(repo, "renamed") + "\ndeleted branch renamed\n"; out.String() != want {
+		t.Fatalf("expected %q, got %q", want, out.String())
+	}
+
+	// Renaming some other worktree leaves the journal alone.
+	newCmd([]string{"feature"})
+	other := setupTestWorktree(t, repo, "other")
+	if msg := renameWorktreeCmd(repo, worktreeItem{branch: "other", path: other}, "other2")().(renameResultMsg); msg.err != nil {
+		t.Fatalf("unexpected error: %v", msg.err)
+	}
+	if entry, err := readJournal(repo); err != nil || entry.Branch != "feature" || entry.Path != worktreePath(repo, "feature") {
+		t.Fatalf("expected the feature entry kept, got %+v, %v", entry, err)
+	}
+}
+
+func TestUndoAfterBulkRemoval(t *testing.T) {
+	repo := setupTestRepo(t)
+	defer withDir(t, repo)()
+	_, errOut := captureUndo(t)
+
+	setupMergedWorktree(t, repo, "merged")
+
+	newCmd([]string{"feature"})
+	pruneCmd([]string{"--merged"})
+	expectUndoExit(t, nil, exitError)
+	if !strings.Contains(errOut.String(), "nothing to undo") {
+		t.Fatalf("expected prune to drop the journal, got %q", errOut.String())
+	}
+
+	newCmd([]string{"other"})
+	item := worktreeItem{branch: "other", path: worktreePath(repo, "other")}
+	if msg := deleteMarkedCmd(repo, repo, wtConfig{}, []worktreeItem{item})().(deleteMarkedResultMsg); msg.removed != 1 {
+		t.Fatalf("expected one worktree removed, got %+v", msg)
+	}
+	errOut.Reset()
+	expectUndoExit(t, nil, exitError)
+	if !strings.Contains(errOut.String(), "nothing to undo") {
+		t.Fatalf("expected a bulk delete to drop the journal, got %q", errOut.String())
+	}
+}
+
+func TestUndoCmdUsageAndRepoError(t *testing.T) {
+	_, errOut := captureUndo(t)
+
+	undoCmd([]string{"-h"})
+	if !strings.Contains(errOut.String(), "usage: wt undo") {
+		t.Fatalf("expected usage, got %q", errOut.String())
+	}
+
+	defer withDir(t, t.TempDir())()
+	errOut.Reset()
+	expectUndoExit(t, nil, exitError)
+	if !strings.Contains(errOut.String(), "not inside a git repository") {
+		t.Fatalf("expected repo error, got %q", errOut.String())
+	}
+}
+
+func TestJournalIOErrors(t *testing.T) {
+	repo := setupTestRepo(t)
+	path := filepath.Join(repo, ".git", journalFile)
+	_, errOut := captureUndo(t)
+
+	oldWrite := osWriteFile
+	oldRead := osReadFile
+	oldRemove := osRemove
+	oldExec := execCommand
+	defer func() {
+		osWriteFile = oldWrite
+		osReadFile = oldRead
+		osRemove = oldRemove
+		execCommand = oldExec
+	}()
+
+	mustWriteFile(t, path, `{"op":"rm"}`)
+	osWriteFile = func(name string, data []byte, perm fs.FileMode) error { return errors.New("disk full") }
+	recordJournal(repo, journalEntry{Op: journalOpNew})
+	if !strings.Contains(errOut.String(), "warning: could not record this for wt undo: disk full") {
+		t.Fatalf("expected warning, got %q", errOut.String())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected stale entry dropped, got %v", err)
+	}
+	osWriteFile = oldWrite
+
+	mustWriteFile(t, path, `{`)
+	if _, err := readJournal(repo); err == nil || !strings.Contains(err.Error(), "invalid undo journal") {
+		t.Fatalf("expected invalid journal error, got %v", err)
+	}
+
+	osReadFile = func(name string) ([]byte, error) { return nil, errors.New("permission denied") }
+	if _, err := readJournal(repo); err == nil || err.Error() != "permission denied" {
+		t.Fatalf("expected read error, got %v", err)
+	}
+	osReadFile = oldRead
+
+	osRemove = func(name string) error { return errors.New("busy") }
+	if err := clearJournal(repo); err == nil || err.Error() != "busy" {
+		t.Fatalf("expected remove error, got %v", err)
+	}
+	errOut.Reset()
+	forgetJournal(repo)
+	if !strings.Contains(errOut.String(), "warning: could not clear the wt undo journal: busy") {
+		t.Fatalf("expected warning, got %q", errOut.String())
+	}
+	osRemove = oldRemove
+	if err := clearJournal(repo); err != nil {
+		t.Fatalf("expected clear to succeed, got %v", err)
+	}
+	if err := clearJournal(repo); err != nil {
+		t.Fatalf("expected clearing a missing journal to succeed, got %v", err)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd { return exec.Command("sh", "-c", "exit 1") }
+	errOut.Reset()
+	recordJournal(repo, journalEntry{Op: journalOpNew})
+	if !strings.Contains(errOut.String(), "warning: could not record this for wt undo") {
+		t.Fatalf("expected warning, got %q", errOut.String())
+	}
+	if _, err := readJournal(repo); err == nil {
+		t.Fatal("expected readJournal to fail without git")
+	}
+	if err := clearJournal(repo); err == nil {
+		t.Fatal("expected clearJournal to fail without git")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-isatty"
 )
 
 var (
@@ -14,11 +15,29 @@ var (
 	stdin    io.Reader = os.Stdin
 	exitFunc           = os.Exit
 
-	newCmdFn  = newCmd
-	listCmdFn = listCmd
-	goCmdFn   = goCmd
-	tmuxCmdFn = tmuxCmd
-	jiraCmdFn = jiraCmd
+	newCmdFn           = newCmd
+	listCmdFn          = listCmd
+	goCmdFn            = goCmd
+	tmuxCmdFn          = tmuxCmd
+	revealCmdFn        = revealCmd
+	baseCmdFn          = baseCmd
+	rmCmdFn            = rmCmd
+	undoCmdFn          = undoCmd
+	repairCmdFn        = repairCmd
+	renameSessionCmdFn = renameSessionCmd
+	pruneCmdFn         = pruneCmd
+	bookmarkCmdFn      = bookmarkCmd
+	configCmdFn        = configCmd
+	jiraCmdFn          = jiraCmd
+
+	stderrIsTerminal = func() bool {
+		f, ok := stderr.(*os.File)
+		return ok && isatty.IsTerminal(f.Fd())
+	}
+	stdoutIsTerminal = func() bool {
+		f, ok := stdout.(*os.File)
+		return ok && isatty.IsTerminal(f.Fd())
+	}
 
 	newProgram = func(model tea.Model, opts ...tea.ProgramOption) programRunner {
 		return tea.NewProgram(model, opts...)
@@ -44,23 +63,56 @@ func main() {
 		return
 	}
 
-	sub := os.Args[1]
+	args := os.Args[1:]
+	if !isCommand(args[0]) {
+		cfg, err := loadConfig()
+		if err != nil {
+			die(err)
+		}
+		args, err = expandAliases(args, cfg.Aliases)
+		if err != nil {
+			die(err)
+		}
+	}
+
+	sub := args[0]
 	switch sub {
 	case "new":
-		newCmdFn(os.Args[2:])
+		newCmdFn(args[1:])
 	case "list":
-		listCmdFn(os.Args[2:])
+		listCmdFn(args[1:])
 	case "go":
-		goCmdFn(os.Args[2:])
+		goCmdFn(args[1:])
 	case "t":
-		tmuxCmdFn(os.Args[2:])
+		tmuxCmdFn(args[1:])
+	case "reveal":
+		revealCmdFn(args[1:])
+	case "base":
+		baseCmdFn(args[1:])
+	case "rm":
+		rmCmdFn(args[1:])
+	case "undo":
+		undoCmdFn(args[1:])
+	case "repair":
+		repairCmdFn(args[1:])
+	case "rename-session":
+		renameSessionCmdFn(args[1:])
+	case "prune":
+		pruneCmdFn(args[1:])
+	case "bookmark":
+		bookmarkCmdFn(args[1:])
+	case "config":
+		configCmdFn(args[1:])
 	case "jira":
-		jiraCmdFn(os.Args[2:])
+		jiraCmdFn(args[1:])
 	case "-h", "--help", "help":
 		printUsage()
 	default:
 		fmt.Fprintf(stderr, "unknown command: %s\n", sub)
+		if suggestion := suggestCommand(sub); suggestion != "" {
+			fmt.Fprintf(stderr, "did you mean '%s'?\n", suggestion)
+		}
 		printUsage()
-		exitFunc(2)
+		exitFunc(exitUsage)
 	}
 }
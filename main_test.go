@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -275,6 +277,35 @@ func TestMainUnknownCommand(t *testing.T) {
 	main()
 }
 
+func TestMainUnknownCommandSuggestion(t *testing.T) {
+	oldArgs := os.Args
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		os.Args = oldArgs
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+
+	os.Args = []string{"wt", "lst"}
+	var buf bytes.Buffer
+	stderr = &buf
+	exitFunc = func(code int) {
+		panic(code)
+	}
+
+	defer func() {
+		if r := recover(); r != 2 {
+			t.Fatalf("expected exit 2, got %v", r)
+		}
+		if !strings.Contains(buf.String(), "did you mean 'list'?") {
+			t.Fatalf("expected suggestion, got %q", buf.String())
+		}
+	}()
+
+	main()
+}
+
 func TestMainHelp(t *testing.T) {
 	oldArgs := os.Args
 	oldErr := stderr
@@ -300,6 +331,15 @@ func TestMainDispatch(t *testing.T) {
 	oldList := listCmdFn
 	oldGo := goCmdFn
 	oldTmux := tmuxCmdFn
+	oldRm := rmCmdFn
+	oldBase := baseCmdFn
+	oldUndo := undoCmdFn
+	oldRepair := repairCmdFn
+	oldReveal := revealCmdFn
+	oldRename := renameSessionCmdFn
+	oldPrune := pruneCmdFn
+	oldBookmark := bookmarkCmdFn
+	oldConfig := configCmdFn
 	oldJira := jiraCmdFn
 	defer func() {
 		os.Args = oldArgs
@@ -307,6 +347,15 @@ func TestMainDispatch(t *testing.T) {
 		listCmdFn = oldList
 		goCmdFn = oldGo
 		tmuxCmdFn = oldTmux
+		rmCmdFn = oldRm
+		baseCmdFn = oldBase
+		undoCmdFn = oldUndo
+		repairCmdFn = oldRepair
+		revealCmdFn = oldReveal
+		renameSessionCmdFn = oldRename
+		pruneCmdFn = oldPrune
+		bookmarkCmdFn = oldBookmark
+		configCmdFn = oldConfig
 		jiraCmdFn = oldJira
 	}()
 
@@ -315,9 +364,18 @@ func TestMainDispatch(t *testing.T) {
 	listCmdFn = func(args []string) { calls["list"] = true }
 	goCmdFn = func(args []string) { calls["go"] = true }
 	tmuxCmdFn = func(args []string) { calls["t"] = true }
+	rmCmdFn = func(args []string) { calls["rm"] = true }
+	baseCmdFn = func(args []string) { calls["base"] = true }
+	undoCmdFn = func(args []string) { calls["undo"] = true }
+	repairCmdFn = func(args []string) { calls["repair"] = true }
+	revealCmdFn = func(args []string) { calls["reveal"] = true }
+	renameSessionCmdFn = func(args []string) { calls["rename-session"] = true }
+	pruneCmdFn = func(args []string) { calls["prune"] = true }
+	bookmarkCmdFn = func(args []string) { calls["bookmark"] = true }
+	configCmdFn = func(args []string) { calls["config"] = true }
 	jiraCmdFn = func(args []string) { calls["jira"] = true }
 
-	for _, cmd := range []string{"new", "list", "go", "t", "jira"} {
+	for _, cmd := range []string{"new", "list", "go", "t", "reveal", "base", "rm", "undo", "repair", "rename-session", "prune", "bookmark", "config", "jira"} {
 		os.Args = []string{"wt", cmd}
 		main()
 		if !calls[cmd] {
@@ -325,3 +383,63 @@ func TestMainDispatch(t *testing.T) {
 		}
 	}
 }
+
+func TestMainAlias(t *testing.T) {
+	oldArgs := os.Args
+	oldNew := newCmdFn
+	oldHome := osUserHomeDir
+	oldExit := exitFunc
+	oldErr := stderr
+	defer func() {
+		os.Args = oldArgs
+		newCmdFn = oldNew
+		osUserHomeDir = oldHome
+		exitFunc = oldExit
+		stderr = oldErr
+	}()
+
+	home := t.TempDir()
+	osUserHomeDir = func() (string, error) { return home, nil }
+	configPath := filepath.Join(home, ".config", "wt", "config.json")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeConfig := func(data string) {
+		if err := os.WriteFile(configPath, []byte(data), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+	var got []string
+	newCmdFn = func(args []string) { got = args }
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	exitFunc = func(code int) { panic(code) }
+
+	writeConfig(`{"aliases": {"wip": "new --copy-libs --from develop", "loop": "loop"}}`)
+	os.Args = []string{"wt", "wip", "mybranch"}
+	main()
+	if want := []string{"--copy-libs", "--from", "develop", "mybranch"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected new %v, got %v", want, got)
+	}
+
+	expectExit := func(want int, args ...string) {
+		t.Helper()
+		defer func() {
+			if r := recover(); r != want {
+				t.Fatalf("wt %v: expected exit %d, got %v", args, want, r)
+			}
+		}()
+		os.Args = append([]string{"wt"}, args...)
+		main()
+	}
+	expectExit(exitUsage, "loop")
+	if !strings.Contains(errBuf.String(), "alias loop is recursive") {
+		t.Fatalf("expected recursion error, got %q", errBuf.String())
+	}
+
+	writeConfig(`{`)
+	expectExit(exitError, "wip")
+	if !strings.Contains(errBuf.String(), "invalid config") {
+		t.Fatalf("expected config error, got %q", errBuf.String())
+	}
+}
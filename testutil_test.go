@@ -57,6 +57,18 @@ func setupTestWorktree(t *testing.T, repo, branch string) string {
 	return wtPath
 }
 
+// setupMergedWorktree creates a worktree for branch with a commit of its
+// own, merged into the repo's current branch with a merge commit.
+func setupMergedWorktree(t *testing.T, repo, branch string) string {
+	t.Helper()
+	wtPath := setupTestWorktree(t, repo, branch)
+	mustWriteFile(t, filepath.Join(wtPath, branch+".txt"), branch)
+	mustRunCmd(t, wtPath, "git", "add", ".")
+	mustRunCmd(t, wtPath, "git", "commit", "-m", branch)
+	mustRunCmd(t, repo, "git", "merge", "--quiet", "--no-ff", "-m", "merge "+branch, branch)
+	return wtPath
+}
+
 // mustRunCmd runs a command and fails the test if it errors.
 func mustRunCmd(t *testing.T, dir, name string, args ...string) {
 	t.Helper()
@@ -93,6 +105,22 @@ func withDir(t *testing.T, dir string) func() {
 	return func() { _ = os.Chdir(oldWd) }
 }
 
+// stubWorktreesExist makes osStat report every path except missing as
+// present, so worktrees in a stubbed git listing aren't flagged as missing.
+func stubWorktreesExist(t *testing.T, missing ...string) {
+	t.Helper()
+	oldStat := osStat
+	t.Cleanup(func() { osStat = oldStat })
+	osStat = func(name string) (fs.FileInfo, error) {
+		for _, m := range missing {
+			if name == m {
+				return nil, fs.ErrNotExist
+			}
+		}
+		return nil, nil
+	}
+}
+
 type stubProgram struct {
 	model tea.Model
 	err   error
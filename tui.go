@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -23,6 +25,8 @@ var (
 	frameStyle  = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240"))
 	titleStyle  = lipgloss.NewStyle().Bold(true).PaddingLeft(1)
 	headerStyle = lipgloss.NewStyle().Faint(true)
+	dirtyStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	staleColor  = lipgloss.Color("241")
 )
 
 type tuiModel struct {
@@ -34,9 +38,20 @@ type tuiModel struct {
 	status        string
 	pendingBranch string
 	pendingDelete worktreeItem
+	pendingRename worktreeItem
 	copyConfig    bool
 	copyLibs      bool
 	baseBranch    string
+	baseCommit    string
+	// remoteBranches is set while the branch picker lists remote-tracking
+	// branches (ctrl+r). trackBase makes the branch created from
+	// baseBranch track it, as when a remote branch is picked.
+	remoteBranches bool
+	trackBase      bool
+	// configPreview and libsPreview list what the copy prompts would copy;
+	// both are computed in the background when the prompts open.
+	configPreview copyPreview
+	libsPreview   copyPreview
 	input         textinput.Model
 	busyText      string
 	spinner       spinner.Model
@@ -44,6 +59,31 @@ type tuiModel struct {
 	width         int
 	height        int
 	maxBranchLen  int
+
+	// dirtyOnly hides worktrees not known to have uncommitted changes.
+	// While it is set, allItems holds the full, unfiltered worktree list.
+	dirtyOnly bool
+	allItems  []list.Item
+
+	// marked holds the paths of worktrees marked with space for a bulk
+	// delete; pendingMarked is the marked list awaiting confirmation.
+	// markedCount is how many listed worktrees are marked.
+	marked        map[string]bool
+	markedCount   int
+	pendingMarked []worktreeItem
+
+	// requested holds the paths whose clean status and commit time have
+	// been asked for since the list was last loaded (see
+	// loadVisibleDetails).
+	requested map[string]bool
+
+	refreshInterval time.Duration
+	enterAction     string
+	branchSort      string
+	cfg             wtConfig
+	// configErr is set when the config failed to load; deletes and
+	// renames are refused then (see checkConfigLoaded).
+	configErr error
 }
 
 type createResultMsg struct {
@@ -54,8 +94,59 @@ type deleteResultMsg struct {
 	err error
 }
 
+type renameResultMsg struct {
+	branch string
+	err    error
+}
+
+type deleteMarkedResultMsg struct {
+	removed int
+	// skipped describes each worktree left in place and why.
+	skipped []string
+}
+
+type revealResultMsg struct {
+	err error
+}
+
+type refreshTickMsg struct{}
+
+type cleanResultMsg struct {
+	clean map[string]bool
+}
+
+type commitTimesMsg struct {
+	times map[string]int64
+}
+
+type jiraSuggestMsg struct {
+	key    string
+	branch string
+	err    error
+}
+
+// baseCommitMsg carries the short SHA of the branch a new branch will be
+// created from, shown on the confirm prompt.
+type baseCommitMsg struct {
+	base string
+	sha  string
+}
+
+// copyPreview is the list shown under a copy prompt. done is false until
+// the background scan reports.
+type copyPreview struct {
+	items []string
+	done  bool
+}
+
+type copyPreviewMsg struct {
+	libs  bool
+	items []string
+}
+
 type branchesResultMsg struct {
 	branches []string
+	remote   bool
 	err      error
 }
 
@@ -69,6 +160,17 @@ func runTUI() (tuiAction, error) {
 	if err != nil {
 		return tuiAction{}, err
 	}
+	cfg, err := loadConfig()
+	if err != nil {
+		model.configErr = err
+	} else {
+		err = model.applyConfig(cfg)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: config: %v\n", err)
+		// The alt screen hides stderr, so repeat the warning in the TUI.
+		model.status = fmt.Sprintf("config: %v", err)
+	}
 
 	p := newProgram(model, tea.WithAltScreen())
 	finalModel, err := p.Run()
@@ -90,8 +192,8 @@ func newTUIModel(repoRoot string) (tuiModel, error) {
 		return tuiModel{}, errors.New("no worktrees found")
 	}
 	mainWT := wts[0].Path
-	items, maxLen := buildWorktreeItems(wts)
-	l := newListModel("Worktrees", items)
+	items, maxLen := buildWorktreeItems(wts, nil)
+	l := newListModel(worktreesTitle(repoRoot), items)
 
 	spin := spinner.New()
 	spin.Spinner = spinner.Dot
@@ -107,11 +209,76 @@ func newTUIModel(repoRoot string) (tuiModel, error) {
 	}, nil
 }
 
+// worktreesTitle names the worktree list after the repo, so TUIs open on
+// different repos can be told apart.
+func worktreesTitle(repoRoot string) string {
+	if repoRoot == "" {
+		return "Worktrees"
+	}
+	return "Worktrees — " + filepath.Base(repoRoot)
+}
+
+// applyConfig copies TUI-related settings from cfg onto the model. cfg is
+// kept even when a ui setting is invalid, so worktree.protected still
+// applies; each invalid setting falls back to its default and is reported.
+func (m *tuiModel) applyConfig(cfg wtConfig) error {
+	m.cfg = cfg
+	var errs []error
+	if interval, err := uiRefreshInterval(cfg); err != nil {
+		errs = append(errs, err)
+	} else {
+		m.refreshInterval = interval
+	}
+	if enter, err := uiDefaultAction(cfg); err != nil {
+		errs = append(errs, err)
+	} else {
+		m.enterAction = enter
+	}
+	if branchSort, err := uiBranchSort(cfg); err != nil {
+		errs = append(errs, err)
+	} else {
+		m.branchSort = branchSort
+	}
+	return errors.Join(errs...)
+}
+
+// checkConfigLoaded refuses actions that rely on worktree.protected when
+// the config could not be read, since the protected list is then unknown.
+func (m tuiModel) checkConfigLoaded() error {
+	if m.configErr != nil {
+		return fmt.Errorf("config did not load, so protected branches are unknown: %v", m.configErr)
+	}
+	return nil
+}
+
+// Init starts the refresh timer. Worktree details are loaded by Update once
+// the first window size shows which rows are visible.
 func (m tuiModel) Init() tea.Cmd {
+	if m.refreshInterval > 0 {
+		return refreshTickCmd(m.refreshInterval)
+	}
 	return nil
 }
 
+func refreshTickCmd(interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return refreshTickMsg{}
+	})
+}
+
+// Update handles msg, then starts loading details for any worktrees it
+// brought near the viewport.
 func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	model := next.(tuiModel)
+	if model.state != tuiStateList {
+		return model, cmd
+	}
+	model, load := model.loadVisibleDetails()
+	return model, tea.Batch(cmd, load)
+}
+
+func (m tuiModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -139,7 +306,7 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		switch msg.String() {
 		case "q":
-			if m.isFiltering() || m.state == tuiStateInputBranchName {
+			if m.isFiltering() || m.state == tuiStateInputBranchName || m.state == tuiStateInputRename {
 				break
 			}
 			m.action = tuiAction{kind: tuiActionNone}
@@ -154,6 +321,26 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.spinner, cmd = m.spinner.Update(msg)
 			return m, cmd
 		}
+	case refreshTickMsg:
+		// Only refresh while idle on the list; prompts, busy work and an
+		// in-progress filter edit are left alone until the next tick.
+		tick := refreshTickCmd(m.refreshInterval)
+		if m.state == tuiStateList && m.list.FilterState() != list.Filtering {
+			m.refreshWorktrees()
+		}
+		return m, tick
+	case cleanResultMsg:
+		m.applyCleanResults(msg.clean)
+		if m.dirtyOnly {
+			m.resizeList()
+			if m.status == dirtyScanStatus {
+				m.status = ""
+			}
+		}
+		return m, nil
+	case commitTimesMsg:
+		m.applyCommitTimes(msg.times)
+		return m, nil
 	case createResultMsg:
 		if msg.err != nil {
 			m.status = msg.err.Error()
@@ -164,6 +351,17 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = tuiStateList
 		m.busyText = ""
 		return m, nil
+	case deleteMarkedResultMsg:
+		m.status = fmt.Sprintf("removed %d worktree(s)", msg.removed)
+		if len(msg.skipped) > 0 {
+			m.status += "; skipped " + strings.Join(msg.skipped, ", ")
+		}
+		m.marked = nil
+		m.pendingMarked = nil
+		_ = m.reloadWorktrees()
+		m.state = tuiStateList
+		m.busyText = ""
+		return m, nil
 	case deleteResultMsg:
 		if msg.err != nil {
 			m.status = msg.err.Error()
@@ -174,23 +372,75 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = tuiStateList
 		m.busyText = ""
 		return m, nil
+	case renameResultMsg:
+		if msg.err != nil {
+			m.status = msg.err.Error()
+		} else {
+			_ = m.reloadWorktrees()
+			m.status = fmt.Sprintf("renamed %s to %s", m.pendingRename.branch, msg.branch)
+		}
+		m.pendingRename = worktreeItem{}
+		m.state = tuiStateList
+		m.busyText = ""
+		return m, nil
+	case jiraSuggestMsg:
+		// Drop the suggestion if the user has moved on or kept typing.
+		if m.state != tuiStateInputBranchName || strings.TrimSpace(m.input.Value()) != msg.key {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		m.input = newBranchInput(msg.branch)
+		m.status = ""
+		return m, nil
+	case revealResultMsg:
+		if msg.err != nil {
+			m.status = msg.err.Error()
+		} else {
+			m.status = "opened in file manager"
+		}
+		return m, nil
+	case copyPreviewMsg:
+		preview := copyPreview{items: msg.items, done: true}
+		if msg.libs {
+			m.libsPreview = preview
+		} else {
+			m.configPreview = preview
+		}
+		return m, nil
+	case baseCommitMsg:
+		if m.state == tuiStateConfirmNewBranch && msg.base == m.baseBranch {
+			m.baseCommit = msg.sha
+		}
+		return m, nil
 	case branchesResultMsg:
 		m.busyText = ""
+		// A failed switch to remote branches stays on the local ones.
+		back := tuiStateList
+		if msg.remote {
+			back = tuiStateNewBranch
+		}
 		if msg.err != nil {
 			m.status = msg.err.Error()
-			m.state = tuiStateList
+			m.state = back
 			return m, nil
 		}
 		if len(msg.branches) == 0 {
 			m.status = "no branches found"
-			m.state = tuiStateList
+			if msg.remote {
+				m.status = "no remote branches found"
+			}
+			m.state = back
 			return m, nil
 		}
 		items := make([]list.Item, 0, len(msg.branches))
 		for _, branch := range msg.branches {
 			items = append(items, branchItem(branch))
 		}
-		m.branches = newListModel("Select branch", items)
+		m.remoteBranches = msg.remote
+		m.branches = newListModel(branchPickerTitle(msg.remote), items)
 		if m.width > 0 && m.height > 0 {
 			innerH := m.height - 5
 			if nItems := len(msg.branches); nItems+2 < innerH {
@@ -216,6 +466,8 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateConfirmDelete(msg)
 	case tuiStateInputBranchName:
 		return m.updateInputBranchName(msg)
+	case tuiStateInputRename:
+		return m.updateInputRename(msg)
 	case tuiStateConfirmNewBranch:
 		return m.updateConfirmNewBranch(msg)
 	case tuiStateHelp:
@@ -232,14 +484,22 @@ func (m tuiModel) View() string {
 	case tuiStateList:
 		return renderFramed(m.listContent(), listFooter(m.width), m.status, m.width)
 	case tuiStateNewBranch:
-		title := titleStyle.Render("Select branch")
+		title := titleStyle.Render(branchPickerTitle(m.remoteBranches))
 		content := title + "\n" + m.branches.View()
-		return renderFramed(content, branchFooter(m.width), m.status, m.width)
+		return renderFramed(content, branchFooter(m.width, m.remoteBranches), m.status, m.width)
 	case tuiStatePromptConfig:
-		return promptView("Copy config files?", true, m.status, m.width)
+		return promptView("Copy config files?", true, m.status, m.width, m.configPreview.lines()...)
 	case tuiStatePromptLibs:
-		return promptView("Copy libs (node_modules)?", false, m.status, m.width)
+		return promptView("Copy libs?", false, m.status, m.width, m.libsPreview.lines()...)
 	case tuiStateConfirmDelete:
+		if len(m.pendingMarked) > 0 {
+			names := make([]string, len(m.pendingMarked))
+			for i, item := range m.pendingMarked {
+				names[i] = itemLabel(item)
+			}
+			prompt := fmt.Sprintf("Remove %d marked worktrees?", len(names))
+			return promptView(prompt, false, m.status, m.width, names...)
+		}
 		name := m.pendingDelete.branch
 		if name == "" {
 			name = filepath.Base(m.pendingDelete.path)
@@ -248,9 +508,17 @@ func (m tuiModel) View() string {
 	case tuiStateInputBranchName:
 		prompt := fmt.Sprintf("New branch name (from %s):", m.baseBranch)
 		content := prompt + "\n" + m.input.View()
-		return renderFramed(content, "enter: confirm  esc: back", m.status, m.width)
+		return renderFramed(content, "enter: confirm  tab: name from Jira key  esc: back", m.status, m.width)
+	case tuiStateInputRename:
+		prompt := fmt.Sprintf("Rename branch %s to:", m.pendingRename.branch)
+		content := prompt + "\n" + m.input.View()
+		return renderFramed(content, "enter: rename  esc: back", m.status, m.width)
 	case tuiStateConfirmNewBranch:
-		prompt := fmt.Sprintf("Create new branch %s from %s?", m.pendingBranch, m.baseBranch)
+		base := m.baseBranch
+		if m.baseCommit != "" {
+			base = fmt.Sprintf("%s (%s)", base, m.baseCommit)
+		}
+		prompt := fmt.Sprintf("Create new branch %s from %s?", m.pendingBranch, base)
 		return promptView(prompt, true, m.status, m.width)
 	case tuiStateBusy:
 		status := fmt.Sprintf("%s %s", m.spinner.View(), m.busyText)
@@ -263,9 +531,16 @@ func (m tuiModel) View() string {
 }
 
 func (m tuiModel) listContent() string {
-	title := titleStyle.Render("Worktrees")
+	heading := worktreesTitle(m.repoRoot)
+	if m.dirtyOnly {
+		heading += " (dirty only)"
+	}
+	title := titleStyle.Render(heading)
 	listView := m.list.View()
 	header := columnHeader(m.maxBranchLen)
+	if m.markedCount > 0 {
+		header = headerStyle.Render(checkboxBlank) + header
+	}
 	// Insert column header right before list items. Find the status bar
 	// line (ends with "item" or "items") and replace the blank line after
 	// it with the column header. This works in both Unfiltered and
@@ -287,7 +562,7 @@ func columnHeader(maxBranchLen int) string {
 	if maxBranchLen < 6 {
 		maxBranchLen = 6
 	}
-	return headerStyle.Render(fmt.Sprintf("  %-*s  %s", maxBranchLen, "Branch", "Path"))
+	return headerStyle.Render(fmt.Sprintf("  %-*s  %*s  %s", maxBranchLen, "Branch", ageWidth, "Age", "Path"))
 }
 
 func renderFramed(content, help, status string, width int) string {
@@ -312,7 +587,11 @@ func (m tuiModel) updateList(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "enter":
 				item := selectedWorktree(m.list)
 				if item.path != "" {
-					m.action = tuiAction{kind: tuiActionGo, path: item.path}
+					kind := m.enterAction
+					if kind == "" {
+						kind = tuiActionGo
+					}
+					m.action = tuiAction{kind: kind, path: item.path}
 					return m, tea.Quit
 				}
 			case "t":
@@ -321,22 +600,49 @@ func (m tuiModel) updateList(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.action = tuiAction{kind: tuiActionTmux, path: item.path}
 					return m, tea.Quit
 				}
+			case "o":
+				item := selectedWorktree(m.list)
+				if item.path != "" {
+					return m, revealWorktreeCmd(item.path)
+				}
+			case "D":
+				return m.toggleDirtyOnly()
 			case "n":
 				m.state = tuiStateBusy
 				m.busyText = "loading branches..."
 				m.status = ""
-				return m, tea.Batch(m.spinner.Tick, loadBranchesCmd(m.repoRoot))
+				return m, tea.Batch(m.spinner.Tick, loadBranchesCmd(m.repoRoot, m.branchSort, false))
+			case " ":
+				return m.toggleMark(), nil
 			case "d":
+				if err := m.checkConfigLoaded(); err != nil {
+					m.status = err.Error()
+					return m, nil
+				}
+				if marked := m.markedItems(); len(marked) > 0 {
+					m.pendingMarked = marked
+					m.state = tuiStateConfirmDelete
+					m.status = ""
+					return m, nil
+				}
 				item := selectedWorktree(m.list)
 				if item.path == "" {
 					return m, nil
 				}
-				clean, err := gitWorktreeClean(item.path)
-				if err != nil {
+				if err := checkNotMainWorktree(m.mainWorktree, item.path); err != nil {
 					m.status = err.Error()
 					return m, nil
 				}
-				if !clean {
+				if err := checkProtectedBranch(m.cfg, item.branch); err != nil {
+					m.status = fmt.Sprintf("branch %s is protected", item.branch)
+					return m, nil
+				}
+				clean, errs := worktreeCleanliness([]string{item.path})
+				if err := errs[item.path]; err != nil {
+					m.status = err.Error()
+					return m, nil
+				}
+				if !clean[item.path] {
 					m.status = "worktree has uncommitted changes"
 					return m, nil
 				}
@@ -344,6 +650,32 @@ func (m tuiModel) updateList(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.state = tuiStateConfirmDelete
 				m.status = ""
 				return m, nil
+			case "r":
+				item := selectedWorktree(m.list)
+				if item.path == "" {
+					return m, nil
+				}
+				if filepath.Clean(m.mainWorktree) == filepath.Clean(item.path) {
+					m.status = "cannot rename the main worktree"
+					return m, nil
+				}
+				if item.branch == "" {
+					m.status = "worktree has no branch to rename"
+					return m, nil
+				}
+				if err := m.checkConfigLoaded(); err != nil {
+					m.status = err.Error()
+					return m, nil
+				}
+				if err := checkProtectedBranch(m.cfg, item.branch); err != nil {
+					m.status = fmt.Sprintf("branch %s is protected", item.branch)
+					return m, nil
+				}
+				m.pendingRename = item
+				m.input = newBranchInput(item.branch)
+				m.state = tuiStateInputRename
+				m.status = ""
+				return m, nil
 			case "?":
 				m.state = tuiStateHelp
 				return m, nil
@@ -358,6 +690,14 @@ func (m tuiModel) updateList(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m tuiModel) updateBranchList(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "ctrl+n":
+			return m.newBranchFromFilter()
+		case "ctrl+r":
+			m.state = tuiStateBusy
+			m.busyText = "loading branches..."
+			return m, tea.Batch(m.spinner.Tick, loadBranchesCmd(m.repoRoot, m.branchSort, !m.remoteBranches))
+		}
 		if m.branches.FilterState() != list.Filtering {
 			switch keyMsg.String() {
 			case "esc":
@@ -365,21 +705,18 @@ func (m tuiModel) updateBranchList(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			case "enter":
 				if item, ok := m.branches.SelectedItem().(branchItem); ok {
+					if m.remoteBranches {
+						return m.selectRemoteBranch(string(item))
+					}
 					m.pendingBranch = string(item)
 					m.baseBranch = ""
-					m.copyConfig = true
-					m.copyLibs = false
-					m.state = tuiStatePromptConfig
-					m.status = ""
-					return m, nil
+					m.trackBase = false
+					return m.startCopyPrompts()
 				}
 			case "c":
 				if item, ok := m.branches.SelectedItem().(branchItem); ok {
 					m.baseBranch = string(item)
-					ti := textinput.New()
-					ti.Placeholder = "branch-name"
-					ti.Focus()
-					m.input = ti
+					m.input = newBranchInput("")
 					m.state = tuiStateInputBranchName
 					m.status = ""
 					return m, nil
@@ -439,14 +776,43 @@ func (m tuiModel) updateConfirmDelete(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 	switch keyMsg.String() {
 	case "y", "Y":
+		if len(m.pendingMarked) > 0 {
+			return m.startDeleteMarked()
+		}
 		return m.startDelete()
 	case "n", "N", "esc", "enter":
 		m.pendingDelete = worktreeItem{}
+		m.pendingMarked = nil
 		m.state = tuiStateList
 	}
 	return m, nil
 }
 
+func (m tuiModel) updateInputRename(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch keyMsg.String() {
+	case "enter":
+		name := strings.TrimSpace(m.input.Value())
+		if name == "" || name == m.pendingRename.branch {
+			return m, nil
+		}
+		m.state = tuiStateBusy
+		m.busyText = "renaming worktree..."
+		m.status = ""
+		return m, tea.Batch(m.spinner.Tick, renameWorktreeCmd(m.repoRoot, m.pendingRename, name))
+	case "esc":
+		m.pendingRename = worktreeItem{}
+		m.state = tuiStateList
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
 func (m tuiModel) updateInputBranchName(msg tea.Msg) (tea.Model, tea.Cmd) {
 	keyMsg, ok := msg.(tea.KeyMsg)
 	if !ok {
@@ -458,19 +824,135 @@ func (m tuiModel) updateInputBranchName(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if name == "" {
 			return m, nil
 		}
-		m.pendingBranch = name
-		m.state = tuiStateConfirmNewBranch
-		return m, nil
+		return m.confirmNewBranch(name)
 	case "esc":
 		m.baseBranch = ""
 		m.state = tuiStateNewBranch
 		return m, nil
+	case "tab":
+		value := strings.TrimSpace(m.input.Value())
+		if key := jiraIssueKeyFromBranch(value); key != "" && key == value {
+			m.status = fmt.Sprintf("looking up %s…", key)
+			return m, jiraSuggestCmd(key)
+		}
 	}
 	var cmd tea.Cmd
 	m.input, cmd = m.input.Update(msg)
 	return m, cmd
 }
 
+// selectRemoteBranch starts a worktree for the remote-tracking branch ref
+// picked in remote mode: a local branch of the same name tracking ref is
+// created, or the local branch is used if there already is one.
+func (m tuiModel) selectRemoteBranch(ref string) (tea.Model, tea.Cmd) {
+	name := remoteBranchName(ref)
+	exists, err := gitBranchExists(m.repoRoot, name)
+	if err != nil {
+		m.status = err.Error()
+		return m, nil
+	}
+	m.pendingBranch = name
+	m.baseBranch = ""
+	m.trackBase = false
+	if !exists {
+		m.baseBranch = ref
+		m.trackBase = true
+	}
+	return m.startCopyPrompts()
+}
+
+// newBranchFromFilter takes the branch picker's filter text as the name of
+// a new branch (ctrl+n), saving the trip through c when the name is already
+// typed. The base is the highlighted branch, or the current worktree's
+// branch when the filter matches none.
+func (m tuiModel) newBranchFromFilter() (tea.Model, tea.Cmd) {
+	name := strings.TrimSpace(m.branches.FilterValue())
+	if name == "" {
+		m.status = "type the new branch name with / first"
+		return m, nil
+	}
+	m.baseBranch = m.currentBranch()
+	if item, ok := m.branches.SelectedItem().(branchItem); ok {
+		m.baseBranch = string(item)
+	}
+	return m.confirmNewBranch(name)
+}
+
+// confirmNewBranch checks name for a branch to create from m.baseBranch and
+// moves on to the confirm prompt, or stays put with the problem in the
+// status line.
+func (m tuiModel) confirmNewBranch(name string) (tea.Model, tea.Cmd) {
+	for _, item := range m.branches.Items() {
+		if string(item.(branchItem)) == name {
+			m.status = fmt.Sprintf("branch %s already exists; select it with enter instead", name)
+			return m, nil
+		}
+	}
+	m.pendingBranch = name
+	m.trackBase = false
+	m.baseCommit = ""
+	m.status = ""
+	m.state = tuiStateConfirmNewBranch
+	return m, baseCommitCmd(m.repoRoot, m.baseBranch)
+}
+
+// currentBranch is the branch checked out in the worktree the TUI was
+// started from, which is what wt new bases new branches on, or HEAD when
+// that is unknown or detached.
+func (m tuiModel) currentBranch() string {
+	for _, item := range m.worktreeItems() {
+		if wt, ok := item.(worktreeItem); ok && wt.path == m.repoRoot && wt.branch != "" {
+			return wt.branch
+		}
+	}
+	return "HEAD"
+}
+
+// newBranchInput returns a focused branch-name input pre-filled with prefill
+// (which may be empty), with the cursor at the end so it can be edited.
+func newBranchInput(prefill string) textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "branch-name"
+	ti.SetValue(prefill)
+	ti.CursorEnd()
+	ti.Focus()
+	return ti
+}
+
+// baseCommitCmd resolves base to its short SHA in the background. A failed
+// lookup yields an empty SHA, so the prompt just shows the branch name.
+func baseCommitCmd(repoRoot, base string) tea.Cmd {
+	return func() tea.Msg {
+		sha, _ := gitShortCommit(repoRoot, base)
+		return baseCommitMsg{base: base, sha: sha}
+	}
+}
+
+// revealWorktreeCmd opens path in the file manager without leaving the
+// TUI.
+func revealWorktreeCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		return revealResultMsg{err: revealWorktree(path)}
+	}
+}
+
+// jiraSuggestCmd fetches a Jira issue and suggests a branch name from its
+// key and summary. The TUI owns the terminal, so a site's tokenCommand
+// can't prompt.
+func jiraSuggestCmd(key string) tea.Cmd {
+	return func() tea.Msg {
+		baseURL, user, token, err := jiraSiteLookup(key, false)
+		if err != nil {
+			return jiraSuggestMsg{key: key, err: err}
+		}
+		issue, err := jiraFetchIssue(baseURL, key, user, token)
+		if err != nil {
+			return jiraSuggestMsg{key: key, err: err}
+		}
+		return jiraSuggestMsg{key: key, branch: jiraBranchName(issue.Key, issue.Fields.Summary)}
+	}
+}
+
 func (m tuiModel) updateConfirmNewBranch(msg tea.Msg) (tea.Model, tea.Cmd) {
 	keyMsg, ok := msg.(tea.KeyMsg)
 	if !ok {
@@ -478,18 +960,55 @@ func (m tuiModel) updateConfirmNewBranch(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 	switch keyMsg.String() {
 	case "y", "Y", "enter":
-		m.copyConfig = true
-		m.copyLibs = false
-		m.state = tuiStatePromptConfig
-		m.status = ""
+		return m.startCopyPrompts()
 	case "n", "N", "esc":
 		m.baseBranch = ""
+		m.baseCommit = ""
 		m.pendingBranch = ""
 		m.state = tuiStateNewBranch
 	}
 	return m, nil
 }
 
+// startCopyPrompts opens the copy config prompt and starts scanning the
+// main worktree for what each copy prompt would copy. The lib scan, which
+// can be slow for a big node_modules, runs while the first prompt is up.
+func (m tuiModel) startCopyPrompts() (tea.Model, tea.Cmd) {
+	m.copyConfig = true
+	m.copyLibs = false
+	m.configPreview = copyPreview{}
+	m.libsPreview = copyPreview{}
+	m.state = tuiStatePromptConfig
+	m.status = ""
+	return m, tea.Batch(copyPreviewCmd(m.mainWorktree, m.cfg, false), copyPreviewCmd(m.mainWorktree, m.cfg, true))
+}
+
+func copyPreviewCmd(mainWT string, cfg wtConfig, libs bool) tea.Cmd {
+	return func() tea.Msg {
+		if libs {
+			return copyPreviewMsg{libs: true, items: previewLibCopies(mainWT, cfg)}
+		}
+		return copyPreviewMsg{items: previewConfigCopies(mainWT, cfg)}
+	}
+}
+
+// maxPreviewLines caps how many copy preview entries a prompt lists.
+const maxPreviewLines = 10
+
+// lines renders the preview for a prompt.
+func (p copyPreview) lines() []string {
+	switch {
+	case !p.done:
+		return []string{"scanning..."}
+	case len(p.items) == 0:
+		return []string{"nothing to copy"}
+	case len(p.items) > maxPreviewLines:
+		more := fmt.Sprintf("... and %d more", len(p.items)-maxPreviewLines+1)
+		return append(append([]string{}, p.items[:maxPreviewLines-1]...), more)
+	}
+	return p.items
+}
+
 func (m tuiModel) startCreate() (tea.Model, tea.Cmd) {
 	m.state = tuiStateBusy
 	m.busyText = "creating worktree..."
@@ -503,30 +1022,316 @@ func (m tuiModel) startDelete() (tea.Model, tea.Cmd) {
 	return m, tea.Batch(m.spinner.Tick, deleteWorktreeCmd(m))
 }
 
+func (m tuiModel) startDeleteMarked() (tea.Model, tea.Cmd) {
+	m.state = tuiStateBusy
+	m.busyText = fmt.Sprintf("removing %d worktrees...", len(m.pendingMarked))
+	return m, tea.Batch(m.spinner.Tick, deleteMarkedCmd(m.repoRoot, m.mainWorktree, m.cfg, m.pendingMarked))
+}
+
 func (m tuiModel) createWorktree() error {
 	branch := strings.TrimSpace(m.pendingBranch)
-	_, err := addWorktree(m.repoRoot, m.mainWorktree, branch, m.baseBranch, m.copyConfig, m.copyLibs)
-	return err
+	exists, err := gitBranchExists(m.repoRoot, branch)
+	if err != nil {
+		return err
+	}
+	wtPath, err := addWorktree(m.repoRoot, m.mainWorktree, addOptions{
+		branch:     branch,
+		fromBranch: m.baseBranch,
+		track:      m.trackBase,
+		copyConfig: m.copyConfig,
+		copyLibs:   m.copyLibs,
+		quietGit:   true,
+		// A fetch could prompt for credentials or warn on stderr, both
+		// hidden behind the alt screen.
+		fetch: fetchNever,
+		cfg:   m.cfg,
+	})
+	if err != nil {
+		return err
+	}
+	// With a base the branch is always new; addWorktree refuses an
+	// existing one.
+	created := m.baseBranch != "" || !exists
+	recordJournal(m.repoRoot, journalEntry{Op: journalOpNew, Path: wtPath, Branch: branch, BranchCreated: created})
+	return nil
 }
 
+// reloadWorktrees rebuilds the worktree list from git. Details already
+// loaded are kept, but every worktree counts as not yet requested so the
+// visible ones are checked again.
 func (m *tuiModel) reloadWorktrees() error {
 	wts, err := gitWorktrees(m.repoRoot)
 	if err != nil {
 		return err
 	}
-	items, maxLen := buildWorktreeItems(wts)
-	m.list.SetItems(items)
-	m.maxBranchLen = maxLen
-	if m.width > 0 && m.height > 0 {
-		innerH := m.height - 6
-		if nItems := len(items); nItems+2 < innerH {
-			innerH = nItems + 2
+	known := make(map[string]worktreeItem)
+	for _, item := range m.worktreeItems() {
+		if wt, ok := item.(worktreeItem); ok {
+			known[wt.path] = wt
 		}
-		m.list.SetSize(m.width-2, innerH)
 	}
+	items, maxLen := buildWorktreeItems(wts, nil)
+	now := timeNow()
+	for i, item := range items {
+		wt := item.(worktreeItem)
+		if old, ok := known[wt.path]; ok {
+			wt.clean = old.clean
+			wt.commitTime, wt.timeLoaded = old.commitTime, old.timeLoaded
+		}
+		items[i] = wt.withDisplay(maxLen, now)
+	}
+	m.setListItems(items)
+	m.maxBranchLen = maxLen
+	m.requested = nil
+	m.resizeList()
 	return nil
 }
 
+// resizeList fits the worktree list's height to its visible rows once the
+// window size is known.
+func (m *tuiModel) resizeList() {
+	if m.width <= 0 || m.height <= 0 {
+		return
+	}
+	innerH := m.height - 6
+	if nItems := len(m.list.Items()); nItems+2 < innerH {
+		innerH = nItems + 2
+	}
+	m.list.SetSize(m.width-2, innerH)
+}
+
+const dirtyScanStatus = "checking worktrees for uncommitted changes..."
+
+// toggleDirtyOnly switches between showing every worktree and only those
+// with uncommitted changes. Worktrees whose status is still unknown are
+// hidden until a scan reports them dirty, so if there are any every
+// worktree is requested again and loadVisibleDetails checks them all.
+func (m tuiModel) toggleDirtyOnly() (tea.Model, tea.Cmd) {
+	selected := selectedWorktree(m.list).path
+	items := m.worktreeItems()
+	m.dirtyOnly = !m.dirtyOnly
+	m.allItems = nil
+	m.setListItems(items)
+	m.resizeList()
+	m.selectWorktree(selected)
+	m.status = ""
+	if !m.dirtyOnly {
+		return m, nil
+	}
+	for _, item := range items {
+		if wt, ok := item.(worktreeItem); ok && wt.clean == cleanUnknown {
+			m.status = dirtyScanStatus
+			m.requested = nil
+			break
+		}
+	}
+	return m, nil
+}
+
+// worktreeItems returns every worktree item, including any hidden by the
+// dirty-only toggle.
+func (m tuiModel) worktreeItems() []list.Item {
+	if m.dirtyOnly {
+		return m.allItems
+	}
+	return m.list.Items()
+}
+
+// selectWorktree moves the cursor to the visible worktree at path, if any.
+func (m *tuiModel) selectWorktree(path string) {
+	for i, item := range m.list.VisibleItems() {
+		if wt, ok := item.(worktreeItem); ok && wt.path == path {
+			m.list.Select(i)
+			return
+		}
+	}
+}
+
+// refreshWorktrees reloads the worktree list while keeping the current
+// selection and any applied filter. It reports whether the reload succeeded.
+func (m *tuiModel) refreshWorktrees() bool {
+	selected := selectedWorktree(m.list).path
+	if err := m.reloadWorktrees(); err != nil {
+		m.status = err.Error()
+		return false
+	}
+	m.selectWorktree(selected)
+	return true
+}
+
+// setListItems replaces the worktree list items, re-running any active
+// filter immediately so the visible rows never flash empty. With dirtyOnly
+// set, items is kept in allItems and only the dirty ones are listed.
+func (m *tuiModel) setListItems(items []list.Item) {
+	marked := 0
+	for i, item := range items {
+		if wt, ok := item.(worktreeItem); ok {
+			wt.marked = m.marked[wt.path]
+			if wt.marked {
+				marked++
+			}
+			items[i] = wt
+		}
+	}
+	if marked != m.markedCount {
+		m.markedCount = marked
+		m.list.SetDelegate(newListDelegate(marked))
+	}
+	if m.dirtyOnly {
+		m.allItems = items
+		var dirty []list.Item
+		for _, item := range items {
+			if wt, ok := item.(worktreeItem); ok && wt.clean == cleanDirty {
+				dirty = append(dirty, item)
+			}
+		}
+		items = dirty
+	}
+	cmd := m.list.SetItems(items)
+	if cmd == nil {
+		return
+	}
+	m.list, _ = m.list.Update(cmd())
+}
+
+// toggleMark marks or unmarks the selected worktree for a bulk delete and
+// moves the cursor down, so consecutive worktrees can be marked quickly.
+// The main worktree can't be marked.
+func (m tuiModel) toggleMark() tuiModel {
+	item := selectedWorktree(m.list)
+	if item.path == "" {
+		return m
+	}
+	if err := checkNotMainWorktree(m.mainWorktree, item.path); err != nil {
+		m.status = err.Error()
+		return m
+	}
+	marked := make(map[string]bool, len(m.marked)+1)
+	for path := range m.marked {
+		marked[path] = true
+	}
+	if marked[item.path] {
+		delete(marked, item.path)
+	} else {
+		marked[item.path] = true
+	}
+	m.marked = marked
+	m.status = ""
+	index := m.list.Index()
+	m.setListItems(m.worktreeItems())
+	m.list.Select(index)
+	m.list.CursorDown()
+	return m
+}
+
+// markedItems returns the marked worktrees in list order.
+func (m tuiModel) markedItems() []worktreeItem {
+	var items []worktreeItem
+	for _, item := range m.worktreeItems() {
+		if wt, ok := item.(worktreeItem); ok && m.marked[wt.path] {
+			items = append(items, wt)
+		}
+	}
+	return items
+}
+
+// applyCleanResults records scanned clean/dirty states on the list items.
+func (m *tuiModel) applyCleanResults(results map[string]bool) {
+	items := m.worktreeItems()
+	updated := make([]list.Item, len(items))
+	for i, item := range items {
+		if wt, ok := item.(worktreeItem); ok {
+			if clean, found := results[wt.path]; found {
+				wt.clean = cleanDirty
+				if clean {
+					wt.clean = cleanClean
+				}
+			}
+			item = wt
+		}
+		updated[i] = item
+	}
+	m.setListItems(updated)
+}
+
+// applyCommitTimes records loaded commit times on the list items and
+// redraws their age column.
+func (m *tuiModel) applyCommitTimes(times map[string]int64) {
+	now := timeNow()
+	items := m.worktreeItems()
+	updated := make([]list.Item, len(items))
+	for i, item := range items {
+		if wt, ok := item.(worktreeItem); ok {
+			if ts, found := times[wt.path]; found {
+				wt.commitTime, wt.timeLoaded = ts, true
+				item = wt.withDisplay(m.maxBranchLen, now)
+			}
+		}
+		updated[i] = item
+	}
+	m.setListItems(updated)
+}
+
+// loadVisibleDetails requests the clean status and commit time of the
+// worktrees on the current page and one page either side, skipping those
+// already requested. With hundreds of worktrees only the rows the user can
+// reach soon are checked; the rest fill in as they scroll into range. In
+// dirty-only mode every worktree is checked, since one that is hidden
+// would otherwise never show up when it becomes dirty.
+func (m tuiModel) loadVisibleDetails() (tuiModel, tea.Cmd) {
+	candidates := m.allItems
+	if !m.dirtyOnly {
+		items := m.list.VisibleItems()
+		start, end := m.list.Paginator.GetSliceBounds(len(items))
+		start = max(0, start-m.list.Paginator.PerPage)
+		end = min(len(items), end+m.list.Paginator.PerPage)
+		candidates = items[start:end]
+	}
+	var paths []string
+	for _, item := range candidates {
+		if wt, ok := item.(worktreeItem); ok && !m.requested[wt.path] {
+			paths = append(paths, wt.path)
+		}
+	}
+	if len(paths) == 0 {
+		return m, nil
+	}
+	requested := make(map[string]bool, len(m.requested)+len(paths))
+	for path := range m.requested {
+		requested[path] = true
+	}
+	for _, path := range paths {
+		requested[path] = true
+	}
+	m.requested = requested
+	return m, tea.Batch(cleanScanCmd(paths), commitTimesCmd(m.repoRoot, paths))
+}
+
+// commitTimesCmd looks up the latest commit time of each worktree in the
+// background.
+func commitTimesCmd(repoRoot string, paths []string) tea.Cmd {
+	return func() tea.Msg {
+		times := commitTimes(paths, repoRoot, "worktrees")
+		result := make(map[string]int64, len(paths))
+		for i, path := range paths {
+			result[path] = times[i]
+		}
+		return commitTimesMsg{times: result}
+	}
+}
+
+// cleanScanCmd checks the status of every worktree in the background.
+// Worktrees whose status can't be read are left out and stay unknown.
+func cleanScanCmd(paths []string) tea.Cmd {
+	if len(paths) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		clean, _ := worktreeCleanliness(paths)
+		return cleanResultMsg{clean: clean}
+	}
+}
+
 func selectedWorktree(m list.Model) worktreeItem {
 	item, ok := m.SelectedItem().(worktreeItem)
 	if !ok {
@@ -535,33 +1340,87 @@ func selectedWorktree(m list.Model) worktreeItem {
 	return item
 }
 
-func buildWorktreeItems(wts []worktree) ([]list.Item, int) {
+// staleAfter is how old a worktree's latest commit must be for the TUI to
+// dim it.
+const staleAfter = 30 * 24 * time.Hour
+
+var timeNow = time.Now
+
+// buildWorktreeItems builds the list items for wts. times holds each
+// worktree's latest commit time (see commitTimes), shown as an age column;
+// with nil times the ages are left to be loaded.
+func buildWorktreeItems(wts []worktree, times []int64) ([]list.Item, int) {
 	maxName := 0
-	names := make([]string, 0, len(wts))
 	for _, wt := range wts {
-		name := wt.Branch
-		if name == "" {
-			name = filepath.Base(wt.Path)
-		}
-		names = append(names, name)
-		if len(name) > maxName {
-			maxName = len(name)
-		}
+		maxName = max(maxName, len(worktreeItem{branch: wt.Branch, path: wt.Path}.name()))
 	}
 
+	now := timeNow()
 	items := make([]list.Item, 0, len(wts))
 	for i, wt := range wts {
-		name := names[i]
-		padded := fmt.Sprintf("%-*s  %s", maxName, name, wt.Path)
-		items = append(items, worktreeItem{
-			branch:  wt.Branch,
-			path:    wt.Path,
-			display: padded,
-		})
+		item := worktreeItem{branch: wt.Branch, path: wt.Path, missing: wt.Missing}
+		if times != nil {
+			item.timeLoaded = true
+			if i < len(times) {
+				item.commitTime = times[i]
+			}
+		}
+		items = append(items, item.withDisplay(maxName, now))
 	}
 	return items, maxName
 }
 
+// name is the worktree's branch, or its directory name when detached.
+func (w worktreeItem) name() string {
+	if w.branch == "" {
+		return filepath.Base(w.path)
+	}
+	return w.branch
+}
+
+// withDisplay returns w with its display row and stale flag computed from
+// its commit time. The age column stays blank until the time is loaded.
+func (w worktreeItem) withDisplay(maxName int, now time.Time) worktreeItem {
+	age := ""
+	w.stale = false
+	if w.timeLoaded {
+		age = "-"
+		if w.commitTime > 0 {
+			d := now.Sub(time.Unix(w.commitTime, 0))
+			age, w.stale = relativeAge(d), d > staleAfter
+		}
+	}
+	w.display = fmt.Sprintf("%-*s  %*s  %s", maxName, w.name(), ageWidth, age, w.path)
+	if w.missing {
+		w.display += " (missing)"
+	}
+	return w
+}
+
+// ageWidth is the width of the age column; relativeAge never exceeds it
+// for ages under a century.
+const ageWidth = 3
+
+// relativeAge formats d compactly: "now", then minutes, hours, days, weeks
+// (under a year), and years, e.g. "5m", "3d", "2w", "1y".
+func relativeAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d < day:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d < 14*day:
+		return fmt.Sprintf("%dd", d/day)
+	case d < 365*day:
+		return fmt.Sprintf("%dw", d/(7*day))
+	default:
+		return fmt.Sprintf("%dy", d/(365*day))
+	}
+}
+
 func createWorktreeCmd(m tuiModel) tea.Cmd {
 	return func() tea.Msg {
 		return createResultMsg{err: m.createWorktree()}
@@ -569,20 +1428,94 @@ func createWorktreeCmd(m tuiModel) tea.Cmd {
 }
 
 func deleteWorktreeCmd(m tuiModel) tea.Cmd {
-	path := m.pendingDelete.path
+	item := m.pendingDelete
 	repoRoot := m.repoRoot
 	return func() tea.Msg {
-		return deleteResultMsg{err: removeWorktree(repoRoot, path)}
+		if err := removeWorktree(repoRoot, item.path); err != nil {
+			return deleteResultMsg{err: err}
+		}
+		recordJournal(repoRoot, journalEntry{Op: journalOpRm, Path: item.path, Branch: item.branch})
+		return deleteResultMsg{}
 	}
 }
 
-func newListModel(title string, items []list.Item) list.Model {
+func renameWorktreeCmd(repoRoot string, item worktreeItem, branch string) tea.Cmd {
+	return func() tea.Msg {
+		newPath, err := renameWorktree(repoRoot, item.path, item.branch, branch)
+		if err == nil {
+			renameJournal(repoRoot, item.path, newPath, item.branch, branch)
+		}
+		return renameResultMsg{branch: branch, err: err}
+	}
+}
+
+// deleteMarkedCmd removes each of items in turn, skipping the ones the
+// single delete would refuse: the main worktree, protected branches and
+// worktrees with uncommitted changes.
+func deleteMarkedCmd(repoRoot, mainWT string, cfg wtConfig, items []worktreeItem) tea.Cmd {
+	return func() tea.Msg {
+		var result deleteMarkedResultMsg
+		paths := make([]string, len(items))
+		for i, item := range items {
+			paths[i] = item.path
+		}
+		clean, errs := worktreeCleanliness(paths)
+		for _, item := range items {
+			if reason := deleteBlocker(mainWT, cfg, item, clean, errs); reason != "" {
+				result.skipped = append(result.skipped, fmt.Sprintf("%s (%s)", itemLabel(item), reason))
+				continue
+			}
+			if err := removeWorktree(repoRoot, item.path); err != nil {
+				result.skipped = append(result.skipped, fmt.Sprintf("%s (%v)", itemLabel(item), err))
+				continue
+			}
+			result.removed++
+		}
+		if result.removed > 0 {
+			forgetJournal(repoRoot)
+		}
+		return result
+	}
+}
+
+// deleteBlocker returns why item must not be deleted, or "" if it may be.
+// clean and errs are the worktreeCleanliness results for the marked items.
+func deleteBlocker(mainWT string, cfg wtConfig, item worktreeItem, clean map[string]bool, errs map[string]error) string {
+	if err := checkNotMainWorktree(mainWT, item.path); err != nil {
+		return "main worktree"
+	}
+	if err := checkProtectedBranch(cfg, item.branch); err != nil {
+		return "protected"
+	}
+	if err := errs[item.path]; err != nil {
+		return err.Error()
+	}
+	if !clean[item.path] {
+		return "uncommitted changes"
+	}
+	return ""
+}
+
+// itemLabel names a worktree item by branch, or by directory when detached.
+func itemLabel(item worktreeItem) string {
+	if item.branch == "" {
+		return filepath.Base(item.path)
+	}
+	return item.branch
+}
+
+// newListDelegate returns the one-line delegate used by every list, showing
+// checkboxes when marked worktrees are listed.
+func newListDelegate(marked int) denseDelegate {
 	delegate := list.NewDefaultDelegate()
 	delegate.SetHeight(1)
 	delegate.SetSpacing(0)
 	delegate.ShowDescription = false
+	return denseDelegate{DefaultDelegate: delegate, marked: marked}
+}
 
-	l := list.New(items, denseDelegate{DefaultDelegate: delegate}, 0, 0)
+func newListModel(title string, items []list.Item) list.Model {
+	l := list.New(items, newListDelegate(0), 0, 0)
 	l.Title = title
 	l.SetShowHelp(false)
 	l.SetShowStatusBar(true)
@@ -594,12 +1527,19 @@ func newListModel(title string, items []list.Item) list.Model {
 	return l
 }
 
-func promptView(prompt string, defaultYes bool, status string, width int) string {
+// promptView renders a yes/no prompt, with any details listed below it.
+func promptView(prompt string, defaultYes bool, status string, width int, details ...string) string {
 	choice := "[y/N]"
 	if defaultYes {
 		choice = "[Y/n]"
 	}
 	content := fmt.Sprintf("%s %s", prompt, choice)
+	if len(details) > 0 {
+		content += "\n"
+		for _, line := range details {
+			content += "\n  " + line
+		}
+	}
 	return renderFramed(content, "enter: accept default  esc: cancel", status, width)
 }
 
@@ -619,25 +1559,49 @@ func withStatus(body, status string) string {
 }
 
 func listFooter(width int) string {
-	full := "enter: go  t: tmux  n: new  d: delete  /: filter  ?: help  q: quit"
+	full := "enter: go  t: tmux  o: open  n: new  d: delete  /: filter  ?: help  q: quit"
 	if width > 0 && width < len(full)+2 {
-		return "↵:go t:tmux n:new d:del /:filter ?:help q:quit"
+		return "↵:go t:tmux o:open n:new d:del /:filter ?:help q:quit"
 	}
 	return full
 }
 
-func branchFooter(width int) string {
-	full := "enter: select  c: create  esc: back  /: filter  ?: help"
+// branchPickerTitle titles the branch picker for the local or remote
+// branch list.
+func branchPickerTitle(remote bool) string {
+	if remote {
+		return "Select remote branch"
+	}
+	return "Select branch"
+}
+
+// branchFooter starts with the branch list being shown and names the one
+// ctrl+r switches to.
+func branchFooter(width int, remote bool) string {
+	mode, other := "local", "remote"
+	if remote {
+		mode, other = "remote", "local"
+	}
+	full := fmt.Sprintf("[%s] enter: select  c: create  ctrl+n: create from filter  ctrl+r: %s  esc: back  /: filter  ?: help", mode, other)
 	if width > 0 && width < len(full)+2 {
-		return "↵:select c:create esc:back /:filter ?:help"
+		return fmt.Sprintf("[%s] ↵:select c:create ^n:new ^r:%s esc:back /:filter ?:help", mode, other)
 	}
 	return full
 }
 
 const listEllipsis = "..."
 
+// Checkboxes shown in front of every worktree while any is marked.
+const (
+	checkboxBlank  = "[ ] "
+	checkboxMarked = "[x] "
+)
+
+// denseDelegate renders list items on one line. marked is the number of
+// marked worktrees, kept by the model so rows needn't scan the list.
 type denseDelegate struct {
 	list.DefaultDelegate
+	marked int
 }
 
 func (d denseDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
@@ -658,8 +1622,24 @@ func (d denseDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 		return
 	}
 
+	marker := ""
+	if wt, ok := item.(worktreeItem); ok && wt.clean == cleanDirty {
+		marker = " " + dirtyStyle.Render("●")
+	}
+	checkbox := ""
+	if wt, ok := item.(worktreeItem); ok && d.marked > 0 {
+		checkbox = checkboxBlank
+		if wt.marked {
+			checkbox = checkboxMarked
+		}
+	}
+
 	textWidth := m.Width() - s.NormalTitle.GetPaddingLeft() - s.NormalTitle.GetPaddingRight()
-	title = ansi.Truncate(title, textWidth, listEllipsis)
+	if marker != "" {
+		textWidth -= 2
+	}
+	textWidth -= len(checkbox)
+	title = checkbox + ansi.Truncate(title, textWidth, listEllipsis)
 	if d.ShowDescription {
 		var lines []string
 		for i, line := range strings.Split(desc, "\n") {
@@ -675,7 +1655,10 @@ func (d denseDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 	isFiltered := m.FilterState() == list.Filtering || m.FilterState() == list.FilterApplied
 
 	if isFiltered && index < len(m.VisibleItems()) {
-		matchedRunes = m.MatchesForItem(index)
+		// Matches index into the title without the checkbox.
+		for _, r := range m.MatchesForItem(index) {
+			matchedRunes = append(matchedRunes, r+len(checkbox))
+		}
 	}
 
 	if isSelected {
@@ -692,15 +1675,19 @@ func (d denseDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 			matched := unmatched.Inherit(s.FilterMatch)
 			title = lipgloss.StyleRunes(title, matchedRunes, matched, unmatched)
 		}
-		title = s.NormalTitle.Render(title)
+		normal := s.NormalTitle
+		if wt, ok := item.(worktreeItem); ok && wt.stale {
+			normal = normal.Foreground(staleColor)
+		}
+		title = normal.Render(title)
 		desc = s.NormalDesc.Render(desc)
 	}
 
 	if d.ShowDescription {
-		fmt.Fprintf(w, "%s\n%s", title, desc) //nolint:errcheck
+		fmt.Fprintf(w, "%s%s\n%s", title, marker, desc) //nolint:errcheck
 		return
 	}
-	fmt.Fprintf(w, "%s", title) //nolint:errcheck
+	fmt.Fprintf(w, "%s%s", title, marker) //nolint:errcheck
 }
 
 func (m tuiModel) isFiltering() bool {
@@ -724,10 +1711,17 @@ func (m tuiModel) updateHelp(msg tea.Msg) (tea.Model, tea.Cmd) {
 func helpContent() string {
 	return titleStyle.Render("Keyboard Shortcuts") + "\n\n" +
 		"  Worktree List\n" +
-		"  enter    Open shell in worktree\n" +
+		"  enter    Open shell in worktree (or tmux, see\n" +
+		"           ui.defaultAction)\n" +
 		"  t        Open tmux session\n" +
+		"  o        Open in file manager\n" +
 		"  n        Create new worktree\n" +
-		"  d        Delete worktree\n" +
+		"  d        Delete worktree (or all marked ones)\n" +
+		"  space    Mark worktree for deleting\n" +
+		"  r        Rename branch and move its worktree\n" +
+		"           to match\n" +
+		"  D        Show only worktrees with uncommitted\n" +
+		"           changes (press again to show all)\n" +
 		"  /        Filter list\n" +
 		"  j/k      Navigate up/down\n" +
 		"  ?        Show this help\n" +
@@ -735,18 +1729,41 @@ func helpContent() string {
 		"  Branch Selection\n" +
 		"  enter    Select branch\n" +
 		"  c        Create new branch\n" +
+		"  ctrl+n   Create new branch named by the filter\n" +
+		"  ctrl+r   Switch between local and remote\n" +
+		"           branches; selecting a remote branch\n" +
+		"           creates a local branch tracking it\n" +
 		"  /        Filter branches\n" +
-		"  esc      Go back"
+		"  esc      Go back\n\n" +
+		"  New Branch Name\n" +
+		"  tab      Expand a Jira key (e.g. PROJ-123) to a\n" +
+		"           branch name from the issue summary"
 }
 
-func loadBranchesCmd(repoRoot string) tea.Cmd {
+// loadBranchesCmd lists the branches for the picker in the order named by
+// sortMode (see uiBranchSort); an empty mode sorts by recent commit. With
+// remote set it lists the remote-tracking branches instead of the local
+// ones.
+func loadBranchesCmd(repoRoot, sortMode string, remote bool) tea.Cmd {
 	return func() tea.Msg {
-		branches, err := gitBranches(repoRoot)
+		recent := sortMode != branchSortNone && sortMode != branchSortAlpha
+		load := gitBranches
+		if remote && recent {
+			load = gitRemoteBranchesByRecent
+		} else if remote {
+			load = gitRemoteBranches
+		}
+		branches, err := load(repoRoot)
 		if err != nil {
-			return branchesResultMsg{err: err}
+			return branchesResultMsg{remote: remote, err: err}
+		}
+		switch {
+		case sortMode == branchSortAlpha:
+			sort.Strings(branches)
+		case recent && !remote:
+			branches = orderByRecentCommit(branches, repoRoot, "branches")
 		}
-		ordered := orderByRecentCommit(branches, repoRoot, "branches")
-		return branchesResultMsg{branches: ordered}
+		return branchesResultMsg{branches: branches, remote: remote}
 	}
 }
 
@@ -3,14 +3,21 @@ package main
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io/fs"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
 )
 
 func TestRunTUISuccess(t *testing.T) {
@@ -150,6 +157,9 @@ func TestNewTUIModelSuccess(t *testing.T) {
 	if len(model.list.Items()) != 1 {
 		t.Fatalf("expected list items")
 	}
+	if model.list.Title != "Worktrees — repo" {
+		t.Fatalf("expected repo name in list title, got %q", model.list.Title)
+	}
 }
 
 func TestNewTUIModelNoWorktrees(t *testing.T) {
@@ -226,16 +236,82 @@ func TestBranchItem(t *testing.T) {
 }
 
 func TestBuildWorktreeItems(t *testing.T) {
+	oldNow := timeNow
+	defer func() { timeNow = oldNow }()
+	now := time.Unix(1_700_000_000, 0)
+	timeNow = func() time.Time { return now }
+
 	items, _ := buildWorktreeItems([]worktree{
 		{Branch: "main", Path: "/repo"},
 		{Path: "/repo-other"},
-	})
-	if len(items) != 2 {
-		t.Fatalf("expected 2 items")
+		{Branch: "old", Path: "/repo-old"},
+		{Branch: "gone", Path: "/repo-gone", Missing: true},
+	}, []int64{now.Add(-3 * 24 * time.Hour).Unix(), 0, now.Add(-60 * 24 * time.Hour).Unix(), 0})
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items")
+	}
+	want := []struct {
+		display string
+		stale   bool
+	}{
+		{"main" + strings.Repeat(" ", 9) + "3d  /repo", false},
+		{"repo-other    -  /repo-other", false},
+		{"old" + strings.Repeat(" ", 10) + "8w  /repo-old", true},
+		{"gone" + strings.Repeat(" ", 10) + "-  /repo-gone (missing)", false},
+	}
+	for i, w := range want {
+		wt := items[i].(worktreeItem)
+		if wt.display != w.display || wt.stale != w.stale {
+			t.Fatalf("item %d: expected %q (stale %v), got %q (stale %v)", i, w.display, w.stale, wt.display, wt.stale)
+		}
+	}
+
+	items, _ = buildWorktreeItems([]worktree{{Branch: "main", Path: "/repo"}}, nil)
+	if wt := items[0].(worktreeItem); wt.timeLoaded || wt.display != "main       /repo" {
+		t.Fatalf("expected the age left to load, got %+v", wt)
+	}
+}
+
+func TestRelativeAge(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Second, "now"},
+		{5 * time.Minute, "5m"},
+		{59 * time.Minute, "59m"},
+		{2 * time.Hour, "2h"},
+		{23 * time.Hour, "23h"},
+		{3 * day, "3d"},
+		{13 * day, "13d"},
+		{14 * day, "2w"},
+		{364 * day, "52w"},
+		{365 * day, "1y"},
+		{3 * 365 * day, "3y"},
+	}
+	for _, tt := range tests {
+		if got := relativeAge(tt.d); got != tt.want || len(got) > ageWidth {
+			t.Errorf("relativeAge(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestDenseDelegateRenderStale(t *testing.T) {
+	delegate := denseDelegate{DefaultDelegate: list.NewDefaultDelegate()}
+	delegate.SetHeight(1)
+	delegate.SetSpacing(0)
+	delegate.ShowDescription = false
+	items := []list.Item{
+		worktreeItem{branch: "main", path: "/repo", display: "main"},
+		worktreeItem{branch: "old", path: "/old", display: "old", stale: true},
 	}
-	wt, ok := items[0].(worktreeItem)
-	if !ok || wt.display == "" {
-		t.Fatalf("expected display string")
+	m := list.New(items, delegate, 40, 10)
+
+	var buf bytes.Buffer
+	delegate.Render(&buf, m, 1, items[1])
+	if !strings.Contains(buf.String(), "old") {
+		t.Fatalf("expected stale title rendered, got %q", buf.String())
 	}
 }
 
@@ -370,6 +446,60 @@ func TestPromptView(t *testing.T) {
 	}
 }
 
+func TestTUICopyPromptPreview(t *testing.T) {
+	src := t.TempDir()
+	mustWriteFile(t, filepath.Join(src, "CLAUDE.md"), "claude")
+	mustWriteFile(t, filepath.Join(src, "node_modules", "pkg.js"), "x")
+
+	model := tuiModel{
+		state:         tuiStateNewBranch,
+		mainWorktree:  src,
+		branches:      newListModel("Select branch", []list.Item{branchItem("feature")}),
+		configPreview: copyPreview{items: []string{"stale"}, done: true},
+	}
+	next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updated := next.(tuiModel)
+	if updated.state != tuiStatePromptConfig || cmd == nil {
+		t.Fatalf("expected config prompt with preview scan, got %v", updated.state)
+	}
+	if !strings.Contains(updated.View(), "scanning...") {
+		t.Fatalf("expected scanning placeholder, got %q", updated.View())
+	}
+
+	for _, libs := range []bool{false, true} {
+		next, _ = updated.Update(copyPreviewCmd(src, wtConfig{}, libs)())
+		updated = next.(tuiModel)
+	}
+	if view := updated.View(); !strings.Contains(view, "  CLAUDE.md") || strings.Contains(view, "stale") {
+		t.Fatalf("expected config preview, got %q", view)
+	}
+
+	next, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	updated = next.(tuiModel)
+	if view := updated.View(); !strings.Contains(view, "node_modules (1 files, 1 B)") {
+		t.Fatalf("expected lib preview, got %q", view)
+	}
+
+	updated.libsPreview = copyPreview{done: true}
+	if view := updated.View(); !strings.Contains(view, "nothing to copy") {
+		t.Fatalf("expected empty preview notice, got %q", view)
+	}
+}
+
+func TestCopyPreviewLines(t *testing.T) {
+	var items []string
+	for i := range 12 {
+		items = append(items, fmt.Sprintf("f%d", i))
+	}
+	lines := copyPreview{items: items, done: true}.lines()
+	if len(lines) != maxPreviewLines || lines[maxPreviewLines-1] != "... and 3 more" {
+		t.Fatalf("expected truncated preview, got %q", lines)
+	}
+	if lines := (copyPreview{items: items[:maxPreviewLines], done: true}).lines(); len(lines) != maxPreviewLines || lines[maxPreviewLines-1] != "f9" {
+		t.Fatalf("expected full preview, got %q", lines)
+	}
+}
+
 func TestWithStatusEmpty(t *testing.T) {
 	out := withStatus("body", "")
 	if out != "body" {
@@ -385,7 +515,7 @@ func TestWithFooter(t *testing.T) {
 }
 
 func TestFooters(t *testing.T) {
-	if listFooter(0) == "" || branchFooter(0) == "" {
+	if listFooter(0) == "" || branchFooter(0, false) == "" {
 		t.Fatalf("expected footers")
 	}
 	// Compact footers for narrow widths
@@ -393,7 +523,7 @@ func TestFooters(t *testing.T) {
 	if !strings.Contains(narrow, "quit") {
 		t.Fatalf("expected compact footer, got %q", narrow)
 	}
-	narrow = branchFooter(30)
+	narrow = branchFooter(30, false)
 	if !strings.Contains(narrow, "help") {
 		t.Fatalf("expected compact footer, got %q", narrow)
 	}
@@ -432,6 +562,20 @@ func TestListContent(t *testing.T) {
 	}
 }
 
+func TestListContentRepoTitle(t *testing.T) {
+	model := tuiModel{
+		repoRoot:     "/src/myrepo",
+		list:         newListModel(worktreesTitle("/src/myrepo"), []list.Item{worktreeItem{branch: "main", path: "/src/myrepo"}}),
+		maxBranchLen: 4,
+	}
+	if out := model.listContent(); !strings.Contains(out, "Worktrees — myrepo") {
+		t.Fatalf("expected repo name in title: %q", out)
+	}
+	if got := worktreesTitle(""); got != "Worktrees" {
+		t.Fatalf("expected plain title without a repo root, got %q", got)
+	}
+}
+
 func TestTUIListEnterGo(t *testing.T) {
 	model := tuiModel{
 		state:    tuiStateList,
@@ -445,6 +589,22 @@ func TestTUIListEnterGo(t *testing.T) {
 	}
 }
 
+func TestTUIListEnterDefaultActionTmux(t *testing.T) {
+	model := tuiModel{
+		state:    tuiStateList,
+		repoRoot: "/repo",
+		list:     newListModel("Worktrees", []list.Item{worktreeItem{branch: "main", path: "/repo"}}),
+	}
+	if err := model.applyConfig(wtConfig{UI: uiConfig{DefaultAction: "tmux"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next, _ := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updated := next.(tuiModel)
+	if updated.action.kind != tuiActionTmux || updated.action.path != "/repo" {
+		t.Fatalf("expected tmux action, got %+v", updated.action)
+	}
+}
+
 func TestTUIListEnterNoSelection(t *testing.T) {
 	model := tuiModel{
 		state:    tuiStateList,
@@ -458,6 +618,54 @@ func TestTUIListEnterNoSelection(t *testing.T) {
 	}
 }
 
+func TestTUIListReveal(t *testing.T) {
+	oldExec := execCommand
+	oldLook := execLookPath
+	oldGOOS := runtimeGOOS
+	defer func() {
+		execCommand = oldExec
+		execLookPath = oldLook
+		runtimeGOOS = oldGOOS
+	}()
+	runtimeGOOS = "darwin"
+	execLookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	opened := ""
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		opened = name + " " + strings.Join(args, " ")
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	model := tuiModel{
+		state:     tuiStateList,
+		repoRoot:  "/repo",
+		list:      newListModel("Worktrees", []list.Item{worktreeItem{branch: "feature", path: "/wt/feature"}}),
+		requested: map[string]bool{"/wt/feature": true},
+	}
+	next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if cmd == nil {
+		t.Fatalf("expected reveal command")
+	}
+	msg := cmd()
+	if opened != "open /wt/feature" {
+		t.Fatalf("expected worktree opened, got %q", opened)
+	}
+	next, _ = next.(tuiModel).Update(msg)
+	updated := next.(tuiModel)
+	if updated.state != tuiStateList || updated.action.kind != tuiActionNone || updated.status != "opened in file manager" {
+		t.Fatalf("expected to stay in the list with a status, got state %v action %+v status %q", updated.state, updated.action, updated.status)
+	}
+
+	next, _ = updated.Update(revealResultMsg{err: errors.New("no file manager opener found")})
+	if got := next.(tuiModel).status; got != "no file manager opener found" {
+		t.Fatalf("expected error status, got %q", got)
+	}
+
+	empty := tuiModel{state: tuiStateList, list: newListModel("Worktrees", []list.Item{branchItem("main")})}
+	if _, cmd := empty.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")}); cmd != nil {
+		t.Fatalf("expected no command without a selected worktree")
+	}
+}
+
 func TestTUIListTmux(t *testing.T) {
 	model := tuiModel{
 		state:    tuiStateList,
@@ -525,6 +733,85 @@ func TestTUIListDeleteNoSelection(t *testing.T) {
 	}
 }
 
+func TestTUIDeleteMainWorktree(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		t.Fatalf("unexpected command %v", args)
+		return nil
+	}
+
+	model := tuiModel{
+		state:        tuiStateList,
+		repoRoot:     "/repo",
+		mainWorktree: "/repo",
+		list:         newListModel("Worktrees", []list.Item{worktreeItem{branch: "main", path: "/repo"}}),
+		requested:    map[string]bool{"/repo": true},
+	}
+	next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	updated := next.(tuiModel)
+	if cmd != nil || updated.state != tuiStateList {
+		t.Fatalf("expected delete to be blocked, got state %v", updated.state)
+	}
+	if updated.status != "cannot remove the main worktree" {
+		t.Fatalf("unexpected status %q", updated.status)
+	}
+}
+
+func TestTUIDeleteProtectedBranch(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		t.Fatalf("unexpected command %v", args)
+		return nil
+	}
+
+	model := tuiModel{
+		state:        tuiStateList,
+		repoRoot:     "/repo",
+		mainWorktree: "/repo",
+		cfg:          wtConfig{Worktree: worktreeConfig{Protected: []string{"release/*"}}},
+		list:         newListModel("Worktrees", []list.Item{worktreeItem{branch: "release/1.0", path: "/wt/release/1.0"}}),
+		requested:    map[string]bool{"/wt/release/1.0": true},
+	}
+	next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	updated := next.(tuiModel)
+	if cmd != nil || updated.state != tuiStateList {
+		t.Fatalf("expected delete to be blocked, got state %v", updated.state)
+	}
+	if updated.status != "branch release/1.0 is protected" {
+		t.Fatalf("unexpected status %q", updated.status)
+	}
+}
+
+func TestTUIDeleteRenameConfigNotLoaded(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		t.Fatalf("unexpected command %v", args)
+		return nil
+	}
+
+	for _, key := range []rune{'d', 'r'} {
+		model := tuiModel{
+			state:        tuiStateList,
+			repoRoot:     "/repo",
+			mainWorktree: "/repo",
+			configErr:    errors.New("invalid config"),
+			list:         newListModel("Worktrees", []list.Item{worktreeItem{branch: "release/1.0", path: "/wt/release/1.0"}}),
+			requested:    map[string]bool{"/wt/release/1.0": true},
+		}
+		next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		updated := next.(tuiModel)
+		if cmd != nil || updated.state != tuiStateList {
+			t.Fatalf("%c: expected action to be blocked, got state %v", key, updated.state)
+		}
+		if !strings.Contains(updated.status, "protected branches are unknown") {
+			t.Fatalf("%c: unexpected status %q", key, updated.status)
+		}
+	}
+}
+
 func TestTUIListUpdateFilterInput(t *testing.T) {
 	model := tuiModel{
 		state:    tuiStateList,
@@ -849,23 +1136,30 @@ func TestCreateWorktreeBranchExistsError(t *testing.T) {
 	oldExec := execCommand
 	defer func() { execCommand = oldExec }()
 
-	execCommand = func(name string, args ...string) *exec.Cmd {
-		if len(args) > 0 && args[0] == "-C" {
-			args = args[2:]
-		}
-		if len(args) >= 2 && args[0] == "show-ref" {
-			return exec.Command("does-not-exist")
+	// The branch is looked up by createWorktree and again by addWorktree.
+	for failAt := 1; failAt <= 2; failAt++ {
+		calls := 0
+		execCommand = func(name string, args ...string) *exec.Cmd {
+			if len(args) > 0 && args[0] == "-C" {
+				args = args[2:]
+			}
+			if len(args) >= 2 && args[0] == "show-ref" {
+				if calls++; calls == failAt {
+					return exec.Command("does-not-exist")
+				}
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			return exec.Command("sh", "-c", "exit 0")
 		}
-		return exec.Command("sh", "-c", "exit 0")
-	}
 
-	model := tuiModel{
-		repoRoot:      repo,
-		mainWorktree:  repo,
-		pendingBranch: "main",
-	}
-	if err := model.createWorktree(); err == nil {
-		t.Fatalf("expected error")
+		model := tuiModel{
+			repoRoot:      repo,
+			mainWorktree:  repo,
+			pendingBranch: "main",
+		}
+		if err := model.createWorktree(); err == nil {
+			t.Fatalf("expected error when lookup %d fails", failAt)
+		}
 	}
 }
 
@@ -931,10 +1225,10 @@ func TestCreateWorktreeCopyConfigError(t *testing.T) {
 	repo := t.TempDir()
 
 	oldExec := execCommand
-	oldStat := osStat
+	oldLstat := osLstat
 	defer func() {
 		execCommand = oldExec
-		osStat = oldStat
+		osLstat = oldLstat
 	}()
 
 	execCommand = func(name string, args ...string) *exec.Cmd {
@@ -947,7 +1241,7 @@ func TestCreateWorktreeCopyConfigError(t *testing.T) {
 		return exec.Command("sh", "-c", "exit 0")
 	}
 
-	osStat = func(name string) (fs.FileInfo, error) {
+	osLstat = func(name string) (fs.FileInfo, error) {
 		return nil, errors.New("stat fail")
 	}
 
@@ -1003,10 +1297,10 @@ func TestCreateWorktreeCopyLibsError(t *testing.T) {
 	repo := t.TempDir()
 
 	oldExec := execCommand
-	oldStat := osStat
+	oldLstat := osLstat
 	defer func() {
 		execCommand = oldExec
-		osStat = oldStat
+		osLstat = oldLstat
 	}()
 
 	execCommand = func(name string, args ...string) *exec.Cmd {
@@ -1019,7 +1313,7 @@ func TestCreateWorktreeCopyLibsError(t *testing.T) {
 		return exec.Command("sh", "-c", "exit 0")
 	}
 
-	osStat = func(name string) (fs.FileInfo, error) {
+	osLstat = func(name string) (fs.FileInfo, error) {
 		return nil, errors.New("stat fail")
 	}
 
@@ -1207,6 +1501,83 @@ func TestTUIBranchCreateFlow(t *testing.T) {
 	}
 }
 
+func TestTUIBranchCreateFromFilter(t *testing.T) {
+	newModel := func(filter string) tuiModel {
+		m := tuiModel{
+			state:    tuiStateNewBranch,
+			repoRoot: "/repo-worktrees/topic",
+			list: newListModel("Worktrees", []list.Item{
+				worktreeItem{path: "/repo", branch: "main"},
+				worktreeItem{path: "/repo-worktrees/topic", branch: "topic"},
+			}),
+			branches: newListModel("Select branch", []list.Item{branchItem("main"), branchItem("topic"), branchItem("feature")}),
+		}
+		if filter != "" {
+			m.branches.SetFilterText(filter)
+			m.branches.SetFilterState(list.Filtering)
+		}
+		return m
+	}
+	ctrlN := tea.KeyMsg{Type: tea.KeyCtrlN}
+
+	// No branch matches: the filter text is the name and the current
+	// worktree's branch the base.
+	next, cmd := newModel("spike-login").Update(ctrlN)
+	updated := next.(tuiModel)
+	if updated.state != tuiStateConfirmNewBranch || updated.pendingBranch != "spike-login" || updated.baseBranch != "topic" {
+		t.Fatalf("expected to confirm spike-login from topic, got state %v, %q from %q", updated.state, updated.pendingBranch, updated.baseBranch)
+	}
+	if cmd == nil {
+		t.Fatal("expected the base commit to be looked up")
+	}
+
+	// A match is highlighted: it becomes the base.
+	next, _ = newModel("feat").Update(ctrlN)
+	updated = next.(tuiModel)
+	if updated.pendingBranch != "feat" || updated.baseBranch != "feature" {
+		t.Fatalf("expected feat from feature, got %q from %q", updated.pendingBranch, updated.baseBranch)
+	}
+
+	// An existing branch can't be created again.
+	next, _ = newModel("topic").Update(ctrlN)
+	updated = next.(tuiModel)
+	if updated.state != tuiStateNewBranch || !strings.Contains(updated.status, "branch topic already exists") {
+		t.Fatalf("expected existing branch to be refused, got state %v, status %q", updated.state, updated.status)
+	}
+
+	// Without a filter there is no name.
+	next, _ = newModel("").Update(ctrlN)
+	updated = next.(tuiModel)
+	if updated.state != tuiStateNewBranch || !strings.Contains(updated.status, "type the new branch name") {
+		t.Fatalf("expected a hint without a filter, got state %v, status %q", updated.state, updated.status)
+	}
+
+	// Detached or unknown current worktree: fall back to HEAD.
+	m := newModel("spike")
+	m.repoRoot = "/elsewhere"
+	m.dirtyOnly = true
+	m.allItems = m.list.Items()
+	next, _ = m.Update(ctrlN)
+	if got := next.(tuiModel).baseBranch; got != "HEAD" {
+		t.Fatalf("expected HEAD base, got %q", got)
+	}
+}
+
+func TestTUIBranchCreateExistingName(t *testing.T) {
+	model := tuiModel{
+		state:      tuiStateInputBranchName,
+		repoRoot:   "/repo",
+		baseBranch: "main",
+		branches:   newListModel("Select branch", []list.Item{branchItem("main"), branchItem("feature")}),
+		input:      newBranchInput("feature"),
+	}
+	next, _ := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updated := next.(tuiModel)
+	if updated.state != tuiStateInputBranchName || !strings.Contains(updated.status, "branch feature already exists") {
+		t.Fatalf("expected existing branch to be refused, got state %v, status %q", updated.state, updated.status)
+	}
+}
+
 func TestTUIBranchCreateEsc(t *testing.T) {
 	model := tuiModel{
 		state:      tuiStateInputBranchName,
@@ -1246,6 +1617,189 @@ func TestTUIBranchCreateInputNonKey(t *testing.T) {
 	}
 }
 
+func TestTUIBranchInputJiraSuggestion(t *testing.T) {
+	oldGetenv := osGetenv
+	oldGet := jiraGet
+	defer func() {
+		osGetenv = oldGetenv
+		jiraGet = oldGet
+	}()
+	osGetenv = func(key string) string { return "x" }
+	jiraGet = func(url, user, token string) ([]byte, error) {
+		return []byte(`{"key":"PROJ-7","fields":{"summary":"Fix login"}}`), nil
+	}
+
+	model := tuiModel{
+		state:      tuiStateInputBranchName,
+		baseBranch: "main",
+		input:      newBranchInput("PROJ-7"),
+	}
+	next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	updated := next.(tuiModel)
+	if cmd == nil {
+		t.Fatalf("expected lookup command")
+	}
+	if updated.status != "looking up PROJ-7…" {
+		t.Fatalf("expected lookup status, got %q", updated.status)
+	}
+
+	next, _ = updated.Update(cmd())
+	updated = next.(tuiModel)
+	if updated.input.Value() != "PROJ-7-fix-login" {
+		t.Fatalf("expected suggested branch, got %q", updated.input.Value())
+	}
+	if updated.status != "" {
+		t.Fatalf("expected status cleared, got %q", updated.status)
+	}
+
+	// The suggestion stays editable.
+	next, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'2'}})
+	updated = next.(tuiModel)
+	if updated.input.Value() != "PROJ-7-fix-login2" {
+		t.Fatalf("expected editable input, got %q", updated.input.Value())
+	}
+}
+
+func TestTUIBranchInputTabWithoutJiraKey(t *testing.T) {
+	model := tuiModel{
+		state:      tuiStateInputBranchName,
+		baseBranch: "main",
+		input:      newBranchInput("feature"),
+	}
+	next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	updated := next.(tuiModel)
+	if cmd != nil || updated.status != "" || updated.input.Value() != "feature" {
+		t.Fatalf("expected tab to be ignored, got status %q value %q", updated.status, updated.input.Value())
+	}
+}
+
+func TestTUIJiraSuggestMsg(t *testing.T) {
+	base := tuiModel{
+		state:      tuiStateInputBranchName,
+		baseBranch: "main",
+		input:      newBranchInput("PROJ-7"),
+	}
+
+	next, _ := base.Update(jiraSuggestMsg{key: "PROJ-7", err: errors.New("jira: issue not found")})
+	if got := next.(tuiModel); got.status != "jira: issue not found" || got.input.Value() != "PROJ-7" {
+		t.Fatalf("expected error status and unchanged input, got %q %q", got.status, got.input.Value())
+	}
+
+	edited := base
+	edited.input = newBranchInput("PROJ-78")
+	next, _ = edited.Update(jiraSuggestMsg{key: "PROJ-7", branch: "PROJ-7-x"})
+	if got := next.(tuiModel); got.input.Value() != "PROJ-78" {
+		t.Fatalf("expected stale suggestion ignored, got %q", got.input.Value())
+	}
+
+	left := base
+	left.state = tuiStateNewBranch
+	next, _ = left.Update(jiraSuggestMsg{key: "PROJ-7", branch: "PROJ-7-x"})
+	if got := next.(tuiModel); got.input.Value() != "PROJ-7" {
+		t.Fatalf("expected suggestion ignored outside input state, got %q", got.input.Value())
+	}
+}
+
+func TestJiraSuggestCmdErrors(t *testing.T) {
+	oldGetenv := osGetenv
+	oldGet := jiraGet
+	defer func() {
+		osGetenv = oldGetenv
+		jiraGet = oldGet
+	}()
+
+	osGetenv = func(key string) string { return "" }
+	if msg := jiraSuggestCmd("PROJ-1")().(jiraSuggestMsg); msg.err == nil {
+		t.Fatalf("expected env error")
+	}
+
+	osGetenv = func(key string) string { return "x" }
+	jiraGet = func(url, user, token string) ([]byte, error) { return nil, errors.New("boom") }
+	if msg := jiraSuggestCmd("PROJ-1")().(jiraSuggestMsg); msg.err == nil || msg.key != "PROJ-1" {
+		t.Fatalf("expected fetch error, got %+v", msg)
+	}
+}
+
+func TestJiraSuggestCmdSiteToken(t *testing.T) {
+	oldRead := osReadFile
+	oldHome := osUserHomeDir
+	oldExec := execCommand
+	oldGet := jiraGet
+	oldIn := stdin
+	oldErr := stderr
+	t.Cleanup(func() {
+		osReadFile = oldRead
+		osUserHomeDir = oldHome
+		execCommand = oldExec
+		jiraGet = oldGet
+		stdin = oldIn
+		stderr = oldErr
+		jiraSiteTokens = map[string]string{}
+	})
+	jiraSiteTokens = map[string]string{}
+	stdin = strings.NewReader("typed\n")
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	osReadFile = func(name string) ([]byte, error) {
+		if name == "/home/test/.config/wt/config.json" {
+			return []byte(`{"jira":{"sites":{"acme":{"url":"https://acme.example.com","user":"me","tokenCommand":"pass acme","projects":["ACME"]}}}}`), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	var mu sync.Mutex
+	var tokenCmds []*exec.Cmd
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if name != "sh" {
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		cmd := exec.Command("sh", "-c", "echo warn >&2; echo acme-token")
+		mu.Lock()
+		tokenCmds = append(tokenCmds, cmd)
+		mu.Unlock()
+		return cmd
+	}
+	jiraGet = func(url, user, token string) ([]byte, error) {
+		if token != "acme-token" {
+			return nil, fmt.Errorf("unexpected token %q", token)
+		}
+		return []byte(`{"key":"ACME-1","fields":{"summary":"Fix it"}}`), nil
+	}
+
+	// Two quick tabs look the issue up at once.
+	var wg sync.WaitGroup
+	msgs := make([]jiraSuggestMsg, 2)
+	for i := range msgs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msgs[i] = jiraSuggestCmd("ACME-1")().(jiraSuggestMsg)
+		}()
+	}
+	wg.Wait()
+	for _, msg := range msgs {
+		if msg.err != nil || msg.branch != "ACME-1-fix-it" {
+			t.Fatalf("expected a suggestion, got %+v", msg)
+		}
+	}
+	if len(tokenCmds) != 1 {
+		t.Fatalf("expected tokenCommand run once, ran %d times", len(tokenCmds))
+	}
+	if tokenCmds[0].Stdin != nil || errBuf.Len() != 0 {
+		t.Fatalf("expected tokenCommand run without the terminal, got stdin %v stderr %q", tokenCmds[0].Stdin, errBuf.String())
+	}
+}
+
+func TestNewBranchInputPrefill(t *testing.T) {
+	if ti := newBranchInput(""); ti.Value() != "" || !ti.Focused() {
+		t.Fatalf("expected empty focused input")
+	}
+	ti := newBranchInput("feature")
+	if ti.Value() != "feature" || ti.Position() != len("feature") {
+		t.Fatalf("expected prefilled input with cursor at end, got %q at %d", ti.Value(), ti.Position())
+	}
+}
+
 func TestTUIConfirmNewBranchCancel(t *testing.T) {
 	model := tuiModel{
 		state:         tuiStateConfirmNewBranch,
@@ -1474,6 +2028,13 @@ func TestDeleteWorktreeCmd(t *testing.T) {
 	if _, ok := msg.(deleteResultMsg); !ok {
 		t.Fatalf("expected deleteResultMsg")
 	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if msg := deleteWorktreeCmd(model)().(deleteResultMsg); msg.err == nil {
+		t.Fatalf("expected remove error")
+	}
 }
 
 func TestCreateWorktreeEmptyBranch(t *testing.T) {
@@ -1772,7 +2333,7 @@ func TestLoadBranchesCmd(t *testing.T) {
 		return exec.Command("sh", "-c", "exit 0")
 	}
 
-	cmd := loadBranchesCmd("/repo")
+	cmd := loadBranchesCmd("/repo", "", false)
 	msg := cmd()
 	result, ok := msg.(branchesResultMsg)
 	if !ok {
@@ -1794,7 +2355,7 @@ func TestLoadBranchesCmdError(t *testing.T) {
 		return exec.Command("sh", "-c", "exit 1")
 	}
 
-	cmd := loadBranchesCmd("/repo")
+	cmd := loadBranchesCmd("/repo", "", false)
 	msg := cmd()
 	result, ok := msg.(branchesResultMsg)
 	if !ok {
@@ -1940,3 +2501,1154 @@ func TestRunTUIInterrupt(t *testing.T) {
 		t.Fatalf("expected no action for interrupt")
 	}
 }
+
+func TestTUIApplyConfig(t *testing.T) {
+	model := tuiModel{}
+	if err := model.applyConfig(wtConfig{UI: uiConfig{RefreshInterval: "15s"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if model.refreshInterval != 15*time.Second {
+		t.Fatalf("expected 15s interval, got %v", model.refreshInterval)
+	}
+	if model.Init() == nil {
+		t.Fatalf("expected refresh tick from Init when interval is set")
+	}
+
+	protected := worktreeConfig{Protected: []string{"main"}}
+	err := model.applyConfig(wtConfig{UI: uiConfig{RefreshInterval: "bad", DefaultAction: "tmux"}, Worktree: protected})
+	if err == nil || !strings.Contains(err.Error(), "ui.refreshInterval") {
+		t.Fatalf("expected error for invalid interval, got %v", err)
+	}
+	if model.enterAction != tuiActionTmux || len(model.cfg.Worktree.Protected) != 1 {
+		t.Fatalf("expected valid settings kept despite the invalid interval, got %q, %v", model.enterAction, model.cfg.Worktree)
+	}
+	if err := model.applyConfig(wtConfig{UI: uiConfig{DefaultAction: "bad"}}); err == nil {
+		t.Fatalf("expected error for invalid default action")
+	}
+	if err := model.applyConfig(wtConfig{UI: uiConfig{BranchSort: "bad"}}); err == nil {
+		t.Fatalf("expected error for invalid branch sort")
+	}
+	if err := model.applyConfig(wtConfig{UI: uiConfig{BranchSort: "alpha"}}); err != nil || model.branchSort != branchSortAlpha {
+		t.Fatalf("expected alpha branch sort, got %q, %v", model.branchSort, err)
+	}
+}
+
+func TestLoadBranchesCmdSortModes(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	var logCalls int
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		switch args[0] {
+		case "branch":
+			return cmdWithOutput("zeta\nalpha\nmid")
+		case "log":
+			logCalls++
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{branchSortNone, []string{"zeta", "alpha", "mid"}},
+		{branchSortAlpha, []string{"alpha", "mid", "zeta"}},
+	}
+	for _, tt := range tests {
+		result := loadBranchesCmd("/repo", tt.mode, false)().(branchesResultMsg)
+		if result.err != nil || !reflect.DeepEqual(result.branches, tt.want) {
+			t.Fatalf("%s: expected %v, got %v, %v", tt.mode, tt.want, result.branches, result.err)
+		}
+	}
+	if logCalls != 0 {
+		t.Fatalf("expected no commit lookups without recent sort, got %d", logCalls)
+	}
+}
+
+func TestLoadBranchesCmdRemoteRecent(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	var gitArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		gitArgs = append(gitArgs, strings.Join(args[2:], " "))
+		if args[2] == "for-each-ref" {
+			return cmdWithOutput("origin/new \norigin/HEAD refs/remotes/origin/main\norigin/old \n")
+		}
+		return exec.Command("sh", "-c", "exit 1")
+	}
+
+	// git sorts by date itself, so there is no git log per ref.
+	result := loadBranchesCmd("/repo", "", true)().(branchesResultMsg)
+	if result.err != nil || !reflect.DeepEqual(result.branches, []string{"origin/new", "origin/old"}) {
+		t.Fatalf("unexpected branches %v, %v", result.branches, result.err)
+	}
+	if len(gitArgs) != 1 || !strings.Contains(gitArgs[0], "--sort=-committerdate") {
+		t.Fatalf("expected a single sorted for-each-ref, got %q", gitArgs)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if result := loadBranchesCmd("/repo", "", true)().(branchesResultMsg); result.err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRunTUIConfigWarning(t *testing.T) {
+	oldProgram := newProgram
+	oldExec := execCommand
+	oldReadFile := osReadFile
+	oldHomeDir := osUserHomeDir
+	oldErr := stderr
+	defer func() {
+		newProgram = oldProgram
+		execCommand = oldExec
+		osReadFile = oldReadFile
+		osUserHomeDir = oldHomeDir
+		stderr = oldErr
+	}()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "rev-parse" {
+			return cmdWithOutput("/repo")
+		}
+		if len(args) >= 2 && args[0] == "worktree" && args[1] == "list" {
+			return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	osReadFile = func(name string) ([]byte, error) {
+		if name == "/repo/.wt.json" {
+			return []byte(`{"ui":{"refreshInterval":"nope"}}`), nil
+		}
+		return nil, fs.ErrNotExist
+	}
+	var got tuiModel
+	newProgram = func(model tea.Model, opts ...tea.ProgramOption) programRunner {
+		got = model.(tuiModel)
+		return stubProgram{model: got}
+	}
+	var buf bytes.Buffer
+	stderr = &buf
+
+	if _, err := runTUI(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "warning: config") {
+		t.Fatalf("expected config warning, got %q", buf.String())
+	}
+	if got.refreshInterval != 0 {
+		t.Fatalf("expected refresh disabled, got %v", got.refreshInterval)
+	}
+	if !strings.Contains(got.status, "ui.refreshInterval") || got.configErr != nil {
+		t.Fatalf("expected the warning in the TUI status, got %q, %v", got.status, got.configErr)
+	}
+}
+
+func TestRunTUIConfigLoadError(t *testing.T) {
+	oldProgram := newProgram
+	oldExec := execCommand
+	oldReadFile := osReadFile
+	oldHomeDir := osUserHomeDir
+	oldErr := stderr
+	defer func() {
+		newProgram = oldProgram
+		execCommand = oldExec
+		osReadFile = oldReadFile
+		osUserHomeDir = oldHomeDir
+		stderr = oldErr
+	}()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		if len(args) >= 2 && args[0] == "rev-parse" {
+			return cmdWithOutput("/repo")
+		}
+		if len(args) >= 2 && args[0] == "worktree" && args[1] == "list" {
+			return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+	osUserHomeDir = func() (string, error) { return "/home/test", nil }
+	osReadFile = func(name string) ([]byte, error) {
+		if name == "/repo/.wt.json" {
+			return []byte(`{`), nil
+		}
+		return nil, fs.ErrNotExist
+	}
+	var got tuiModel
+	newProgram = func(model tea.Model, opts ...tea.ProgramOption) programRunner {
+		got = model.(tuiModel)
+		return stubProgram{model: got}
+	}
+	stderr = &bytes.Buffer{}
+
+	if _, err := runTUI(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.configErr == nil || !strings.HasPrefix(got.status, "config:") {
+		t.Fatalf("expected config error recorded, got %v, %q", got.configErr, got.status)
+	}
+}
+
+func TestTUIRefreshTick(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	wtList := "worktree /repo\nbranch refs/heads/main\n\nworktree /repo-wt/feat\nbranch refs/heads/feat\n\nworktree /repo-wt/fix\nbranch refs/heads/fix\n"
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return cmdWithOutput(wtList)
+	}
+
+	model, err := newTUIModel("/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	model.refreshInterval = time.Second
+	model.list.SetFilterText("f")
+	model.list.Select(1) // fix
+
+	// A teammate adds a worktree that sorts ahead of the selected one.
+	wtList = "worktree /repo\nbranch refs/heads/main\n\nworktree /repo-wt/fab\nbranch refs/heads/fab\n\nworktree /repo-wt/feat\nbranch refs/heads/feat\n\nworktree /repo-wt/fix\nbranch refs/heads/fix\n"
+
+	next, cmd := model.Update(refreshTickMsg{})
+	updated := next.(tuiModel)
+	if cmd == nil {
+		t.Fatalf("expected next refresh tick to be scheduled")
+	}
+	if len(updated.list.Items()) != 4 {
+		t.Fatalf("expected refreshed items, got %d", len(updated.list.Items()))
+	}
+	if updated.list.FilterState() != list.FilterApplied || updated.list.FilterValue() != "f" {
+		t.Fatalf("expected filter preserved, got %v %q", updated.list.FilterState(), updated.list.FilterValue())
+	}
+	if sel := selectedWorktree(updated.list); sel.path != "/repo-wt/fix" {
+		t.Fatalf("expected selection preserved, got %q", sel.path)
+	}
+}
+
+func TestTUILoadVisibleDetails(t *testing.T) {
+	var items []list.Item
+	for i := range 40 {
+		items = append(items, worktreeItem{branch: fmt.Sprintf("b%02d", i), path: fmt.Sprintf("/wt/b%02d", i)})
+	}
+	model := tuiModel{state: tuiStateList, list: newListModel("Worktrees", items)}
+
+	next, cmd := model.Update(tea.WindowSizeMsg{Width: 80, Height: 16})
+	updated := next.(tuiModel)
+	perPage := updated.list.Paginator.PerPage
+	if cmd == nil || perPage >= 20 {
+		t.Fatalf("expected a detail load for a page of %d rows", perPage)
+	}
+	// The first page and the one after it.
+	if len(updated.requested) != 2*perPage || !updated.requested["/wt/b00"] || updated.requested[fmt.Sprintf("/wt/b%02d", 2*perPage)] {
+		t.Fatalf("expected the first two pages requested, got %v", updated.requested)
+	}
+	if msgs, ok := cmd().(tea.BatchMsg); !ok || len(msgs) != 2 {
+		t.Fatalf("expected clean scan and commit time commands, got %T", cmd())
+	}
+
+	// Nothing new is in range, so nothing is requested again.
+	next, cmd = updated.Update(tea.KeyMsg{Type: tea.KeyDown})
+	updated = next.(tuiModel)
+	if cmd != nil {
+		t.Fatalf("expected no new detail load")
+	}
+
+	// Scrolling two pages down brings the next page into range.
+	for range 2 {
+		next, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRight})
+		updated = next.(tuiModel)
+	}
+	if len(updated.requested) != 4*perPage || !updated.requested[fmt.Sprintf("/wt/b%02d", 4*perPage-1)] {
+		t.Fatalf("expected the next page requested after scrolling, got %v", updated.requested)
+	}
+
+	// Other states don't load anything.
+	updated.state = tuiStateHelp
+	updated.requested = nil
+	if next, cmd = updated.Update(tea.WindowSizeMsg{Width: 80, Height: 16}); cmd != nil || next.(tuiModel).requested != nil {
+		t.Fatalf("expected no detail load outside the list")
+	}
+}
+
+func TestTUICommitTimesMsg(t *testing.T) {
+	oldNow := timeNow
+	defer func() { timeNow = oldNow }()
+	now := time.Unix(1_700_000_000, 0)
+	timeNow = func() time.Time { return now }
+
+	items, maxLen := buildWorktreeItems([]worktree{
+		{Branch: "main", Path: "/repo"},
+		{Branch: "old", Path: "/repo-wt/old"},
+		{Branch: "new", Path: "/repo-wt/new"},
+	}, nil)
+	items = append(items, branchItem("other"))
+	model := tuiModel{state: tuiStateList, list: newListModel("Worktrees", items), maxBranchLen: maxLen}
+	if got := items[0].(worktreeItem).display; got != "main       /repo" {
+		t.Fatalf("expected a blank age before loading, got %q", got)
+	}
+
+	next, _ := model.Update(commitTimesMsg{times: map[string]int64{
+		"/repo":        now.Add(-2 * time.Hour).Unix(),
+		"/repo-wt/old": now.Add(-60 * 24 * time.Hour).Unix(),
+	}})
+	got := next.(tuiModel).list.Items()
+	if wt := got[0].(worktreeItem); wt.display != "main   2h  /repo" || wt.stale || !wt.timeLoaded {
+		t.Fatalf("unexpected main item %+v", wt)
+	}
+	if wt := got[1].(worktreeItem); wt.display != "old    8w  /repo-wt/old" || !wt.stale {
+		t.Fatalf("unexpected old item %+v", wt)
+	}
+	if wt := got[2].(worktreeItem); wt.timeLoaded || wt.display != "new        /repo-wt/new" {
+		t.Fatalf("expected new item still loading, got %+v", wt)
+	}
+}
+
+func TestCommitTimesCmd(t *testing.T) {
+	repo := setupTestRepo(t)
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	msg := commitTimesCmd(repo, []string{repo, missing})().(commitTimesMsg)
+	if msg.times[repo] <= 0 {
+		t.Fatalf("expected a commit time for the repo, got %v", msg.times)
+	}
+	if ts, found := msg.times[missing]; !found || ts != 0 {
+		t.Fatalf("expected an unknown time for a missing worktree, got %v", msg.times)
+	}
+}
+
+func TestTUIReloadKeepsLoadedDetails(t *testing.T) {
+	repo := setupTestRepo(t)
+	feature := setupTestWorktree(t, repo, "feature")
+	model, err := newTUIModel(repo)
+	if err != nil {
+		t.Fatalf("newTUIModel: %v", err)
+	}
+	model.requested = map[string]bool{repo: true, feature: true}
+	model.applyCommitTimes(map[string]int64{feature: timeNow().Unix()})
+	model.applyCleanResults(map[string]bool{feature: false})
+
+	if !model.refreshWorktrees() {
+		t.Fatalf("refresh failed: %s", model.status)
+	}
+	if model.requested != nil {
+		t.Fatalf("expected requests reset, got %v", model.requested)
+	}
+	for _, item := range model.list.Items() {
+		wt := item.(worktreeItem)
+		if wt.path == feature && (!wt.timeLoaded || wt.clean != cleanDirty || !strings.Contains(wt.display, "now")) {
+			t.Fatalf("expected feature details kept, got %+v", wt)
+		}
+	}
+}
+
+func TestTUIRefreshTickSkipsWhenBusy(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	calls := 0
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		calls++
+		return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+	}
+
+	for _, state := range []tuiState{tuiStateBusy, tuiStatePromptConfig, tuiStateConfirmDelete, tuiStateInputBranchName} {
+		model := tuiModel{
+			state:           state,
+			repoRoot:        "/repo",
+			list:            newListModel("Worktrees", nil),
+			refreshInterval: time.Second,
+		}
+		next, cmd := model.Update(refreshTickMsg{})
+		if cmd == nil {
+			t.Fatalf("expected tick to be rescheduled in state %v", state)
+		}
+		if next.(tuiModel).state != state {
+			t.Fatalf("expected state %v unchanged", state)
+		}
+	}
+
+	model := tuiModel{
+		state:           tuiStateList,
+		repoRoot:        "/repo",
+		list:            newListModel("Worktrees", nil),
+		refreshInterval: time.Second,
+	}
+	model.list.SetFilterState(list.Filtering)
+	model.Update(refreshTickMsg{})
+
+	if calls != 0 {
+		t.Fatalf("expected no reloads, got %d git calls", calls)
+	}
+}
+
+func TestTUIRefreshTickError(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	model := tuiModel{
+		state:           tuiStateList,
+		repoRoot:        "/repo",
+		list:            newListModel("Worktrees", []list.Item{worktreeItem{branch: "main", path: "/repo"}}),
+		refreshInterval: time.Second,
+	}
+	next, _ := model.Update(refreshTickMsg{})
+	updated := next.(tuiModel)
+	if updated.status == "" {
+		t.Fatalf("expected reload error in status")
+	}
+	if len(updated.list.Items()) != 1 {
+		t.Fatalf("expected items kept on error")
+	}
+}
+
+func TestRefreshTickCmd(t *testing.T) {
+	msg := refreshTickCmd(time.Millisecond)()
+	if _, ok := msg.(refreshTickMsg); !ok {
+		t.Fatalf("expected refreshTickMsg, got %T", msg)
+	}
+}
+
+func TestDenseDelegateRenderDirtyMarker(t *testing.T) {
+	delegate := denseDelegate{DefaultDelegate: list.NewDefaultDelegate()}
+	items := []list.Item{
+		worktreeItem{branch: "main", path: "/repo", clean: cleanClean},
+		worktreeItem{branch: "feat", path: "/repo-wt/feat", clean: cleanDirty},
+		worktreeItem{branch: "fix", path: "/repo-wt/fix"},
+	}
+	model := list.New(items, delegate, 0, 0)
+	model.SetSize(40, 5)
+
+	for i, want := range []bool{false, true, false} {
+		var buf bytes.Buffer
+		delegate.Render(&buf, model, i, items[i])
+		if got := strings.Contains(buf.String(), "●"); got != want {
+			t.Fatalf("item %d: expected marker=%v, got %q", i, want, buf.String())
+		}
+	}
+}
+
+func TestCleanScanCmd(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		switch args[1] {
+		case "/repo-wt/dirty":
+			return cmdWithOutput(" M file.txt\n")
+		case "/repo-wt/broken":
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		return cmdWithOutput("")
+	}
+
+	if cleanScanCmd(nil) != nil {
+		t.Fatalf("expected nil cmd for no paths")
+	}
+
+	msg := cleanScanCmd([]string{"/repo", "/repo-wt/dirty", "/repo-wt/broken"})()
+	res, ok := msg.(cleanResultMsg)
+	if !ok {
+		t.Fatalf("expected cleanResultMsg, got %T", msg)
+	}
+	if clean, found := res.clean["/repo"]; !found || !clean {
+		t.Fatalf("expected /repo clean, got %v", res.clean)
+	}
+	if clean, found := res.clean["/repo-wt/dirty"]; !found || clean {
+		t.Fatalf("expected /repo-wt/dirty dirty, got %v", res.clean)
+	}
+	if _, found := res.clean["/repo-wt/broken"]; found {
+		t.Fatalf("expected errored worktree left unknown, got %v", res.clean)
+	}
+}
+
+func TestTUICleanResultMsg(t *testing.T) {
+	model := tuiModel{
+		state: tuiStateList,
+		list: newListModel("Worktrees", []list.Item{
+			worktreeItem{branch: "main", path: "/repo"},
+			worktreeItem{branch: "feat", path: "/repo-wt/feat"},
+			worktreeItem{branch: "fix", path: "/repo-wt/fix"},
+			branchItem("other"),
+		}),
+	}
+	model.list.SetFilterText("f")
+
+	next, _ := model.Update(cleanResultMsg{clean: map[string]bool{"/repo": true, "/repo-wt/feat": false}})
+	updated := next.(tuiModel)
+
+	items := updated.list.Items()
+	if items[0].(worktreeItem).clean != cleanClean {
+		t.Fatalf("expected main clean")
+	}
+	if items[1].(worktreeItem).clean != cleanDirty {
+		t.Fatalf("expected feat dirty")
+	}
+	if items[2].(worktreeItem).clean != cleanUnknown {
+		t.Fatalf("expected fix unknown")
+	}
+	if n := len(updated.list.VisibleItems()); n != 2 {
+		t.Fatalf("expected filter re-applied to 2 items, got %d", n)
+	}
+	if updated.list.VisibleItems()[0].(worktreeItem).clean != cleanDirty {
+		t.Fatalf("expected visible items to carry new state")
+	}
+}
+
+func TestTUIDirtyOnlyToggle(t *testing.T) {
+	model := tuiModel{
+		state:  tuiStateList,
+		width:  80,
+		height: 30,
+		list: newListModel("Worktrees", []list.Item{
+			worktreeItem{branch: "main", path: "/repo", clean: cleanClean},
+			worktreeItem{branch: "feat", path: "/repo-wt/feat", clean: cleanDirty},
+			worktreeItem{branch: "fix", path: "/repo-wt/fix", clean: cleanDirty},
+		}),
+		requested: map[string]bool{"/repo": true, "/repo-wt/feat": true, "/repo-wt/fix": true},
+	}
+	model.list.Select(2)
+
+	next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'D'}})
+	updated := next.(tuiModel)
+	if cmd != nil {
+		t.Fatalf("expected no scan when every status is known")
+	}
+	if !updated.dirtyOnly || len(updated.list.Items()) != 2 {
+		t.Fatalf("expected only dirty worktrees, got %d", len(updated.list.Items()))
+	}
+	if selectedWorktree(updated.list).path != "/repo-wt/fix" {
+		t.Fatalf("expected selection kept, got %q", selectedWorktree(updated.list).path)
+	}
+	if !strings.Contains(updated.View(), "(dirty only)") {
+		t.Fatalf("expected dirty-only title")
+	}
+
+	// The text filter composes with the dirty filter.
+	updated.list.SetFilterText("fix")
+	if n := len(updated.list.VisibleItems()); n != 1 {
+		t.Fatalf("expected 1 visible item, got %d", n)
+	}
+	updated.list.ResetFilter()
+
+	// A scan that finds main dirty brings it into view.
+	next, _ = updated.Update(cleanResultMsg{clean: map[string]bool{"/repo": false}})
+	updated = next.(tuiModel)
+	if len(updated.list.Items()) != 3 {
+		t.Fatalf("expected main to appear once dirty, got %d", len(updated.list.Items()))
+	}
+
+	next, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'D'}})
+	updated = next.(tuiModel)
+	if updated.dirtyOnly || updated.allItems != nil || len(updated.list.Items()) != 3 {
+		t.Fatalf("expected all worktrees shown again")
+	}
+	if strings.Contains(updated.View(), "(dirty only)") {
+		t.Fatalf("expected plain title")
+	}
+}
+
+func TestTUIDirtyOnlyScansUnknown(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return cmdWithOutput(" M file.txt\n")
+	}
+
+	model := tuiModel{
+		state: tuiStateList,
+		list: newListModel("Worktrees", []list.Item{
+			worktreeItem{branch: "main", path: "/repo"},
+			worktreeItem{branch: "feat", path: "/repo-wt/feat"},
+		}),
+	}
+	next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'D'}})
+	updated := next.(tuiModel)
+	if cmd == nil {
+		t.Fatalf("expected a clean scan for unknown statuses")
+	}
+	if updated.status != dirtyScanStatus || len(updated.list.Items()) != 0 {
+		t.Fatalf("expected scan status and no rows yet, got %q and %d rows", updated.status, len(updated.list.Items()))
+	}
+	if len(updated.requested) != 2 {
+		t.Fatalf("expected hidden worktrees still scanned, got %v", updated.requested)
+	}
+
+	msgs, ok := cmd().(tea.BatchMsg)
+	if !ok || len(msgs) != 2 {
+		t.Fatalf("expected clean scan and commit time commands, got %T", cmd())
+	}
+	next, _ = updated.Update(msgs[0]())
+	updated = next.(tuiModel)
+	if updated.status != "" || len(updated.list.Items()) != 2 {
+		t.Fatalf("expected dirty rows after scan, got %q and %d rows", updated.status, len(updated.list.Items()))
+	}
+
+	// Other status messages survive a later scan.
+	updated.status = "worktree created"
+	next, _ = updated.Update(cleanResultMsg{})
+	if next.(tuiModel).status != "worktree created" {
+		t.Fatalf("expected status kept")
+	}
+}
+
+func TestTUIDirtyOnlyRefreshScansHidden(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 3 && args[3] == "list" {
+			return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n\nworktree /repo-wt/feat\nbranch refs/heads/feat\n\n" +
+				"worktree /repo-wt/new\nbranch refs/heads/new\n")
+		}
+		return cmdWithOutput(" M file.txt\n")
+	}
+
+	model := tuiModel{
+		state:    tuiStateList,
+		repoRoot: "/repo",
+		width:    80,
+		height:   30,
+		list: newListModel("Worktrees", []list.Item{
+			worktreeItem{branch: "main", path: "/repo", clean: cleanClean},
+			worktreeItem{branch: "feat", path: "/repo-wt/feat", clean: cleanDirty},
+		}),
+		requested: map[string]bool{"/repo": true, "/repo-wt/feat": true},
+	}
+	next, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'D'}})
+	updated := next.(tuiModel)
+
+	// main is hidden as clean, and the new worktree as unknown, yet both
+	// are checked again on refresh.
+	next, cmd := updated.Update(refreshTickMsg{})
+	updated = next.(tuiModel)
+	if len(updated.list.Items()) != 1 || cmd == nil {
+		t.Fatalf("expected only feat listed and a scan, got %d rows", len(updated.list.Items()))
+	}
+	for _, path := range []string{"/repo", "/repo-wt/feat", "/repo-wt/new"} {
+		if !updated.requested[path] {
+			t.Fatalf("expected %s rescanned, got %v", path, updated.requested)
+		}
+	}
+
+	next, _ = updated.Update(cleanResultMsg{clean: map[string]bool{"/repo": false, "/repo-wt/feat": false, "/repo-wt/new": false}})
+	if n := len(next.(tuiModel).list.Items()); n != 3 {
+		t.Fatalf("expected every worktree listed once dirty, got %d", n)
+	}
+}
+
+func TestReloadWorktreesKeepsCleanState(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n\nworktree /repo-wt/new\nbranch refs/heads/new\n")
+	}
+	model := tuiModel{
+		repoRoot: "/repo",
+		list:     newListModel("Worktrees", []list.Item{worktreeItem{branch: "main", path: "/repo", clean: cleanDirty}}),
+	}
+	if err := model.reloadWorktrees(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	items := model.list.Items()
+	if items[0].(worktreeItem).clean != cleanDirty {
+		t.Fatalf("expected known state kept")
+	}
+	if items[1].(worktreeItem).clean != cleanUnknown {
+		t.Fatalf("expected new worktree unknown")
+	}
+}
+
+func TestTUIResultMsgsRescan(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return cmdWithOutput("worktree /repo\nbranch refs/heads/main\n")
+	}
+	for _, msg := range []tea.Msg{createResultMsg{}, deleteResultMsg{}, refreshTickMsg{}} {
+		model := tuiModel{
+			state:           tuiStateList,
+			repoRoot:        "/repo",
+			list:            newListModel("Worktrees", nil),
+			refreshInterval: time.Second,
+		}
+		_, cmd := model.Update(msg)
+		if cmd == nil {
+			t.Fatalf("expected rescan command after %T", msg)
+		}
+	}
+}
+
+func TestTUIConfirmNewBranchShowsBaseCommit(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 2 && args[2] == "rev-parse" && args[len(args)-1] == "main^{commit}" {
+			return cmdWithOutput("abc1234\n")
+		}
+		return exec.Command("sh", "-c", "exit 1")
+	}
+
+	model := tuiModel{state: tuiStateInputBranchName, repoRoot: "/repo", baseBranch: "main", input: newBranchInput("feature")}
+	next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updated := next.(tuiModel)
+	if cmd == nil {
+		t.Fatal("expected base commit lookup")
+	}
+	msg := cmd()
+	if msg != (baseCommitMsg{base: "main", sha: "abc1234"}) {
+		t.Fatalf("unexpected message %#v", msg)
+	}
+
+	// A result for a different base is stale and ignored.
+	next, _ = updated.Update(baseCommitMsg{base: "dev", sha: "def5678"})
+	if got := next.(tuiModel).baseCommit; got != "" {
+		t.Fatalf("expected stale result ignored, got %q", got)
+	}
+
+	next, _ = updated.Update(msg)
+	updated = next.(tuiModel)
+	if !strings.Contains(updated.View(), "from main (abc1234)?") {
+		t.Fatalf("expected base commit in view, got %q", updated.View())
+	}
+
+	if msg := baseCommitCmd("/repo", "missing")(); msg != (baseCommitMsg{base: "missing"}) {
+		t.Fatalf("expected empty sha on lookup failure, got %#v", msg)
+	}
+}
+
+func TestTUIRenameBlocked(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		t.Fatalf("unexpected command %v", args)
+		return nil
+	}
+
+	tests := []struct {
+		name string
+		item list.Item
+		want string
+	}{
+		{"no selection", branchItem("main"), ""},
+		{"main worktree", worktreeItem{branch: "main", path: "/repo"}, "cannot rename the main worktree"},
+		{"detached", worktreeItem{path: "/wt/detached"}, "worktree has no branch to rename"},
+		{"protected", worktreeItem{branch: "release/1.0", path: "/wt/release/1.0"}, "branch release/1.0 is protected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := tuiModel{
+				state:        tuiStateList,
+				repoRoot:     "/repo",
+				mainWorktree: "/repo",
+				cfg:          wtConfig{Worktree: worktreeConfig{Protected: []string{"release/*"}}},
+				list:         newListModel("Worktrees", []list.Item{tt.item}),
+				requested:    map[string]bool{"/repo": true, "/wt/detached": true, "/wt/release/1.0": true},
+			}
+			next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+			updated := next.(tuiModel)
+			if cmd != nil || updated.state != tuiStateList {
+				t.Fatalf("expected rename to be blocked, got state %v", updated.state)
+			}
+			if updated.status != tt.want {
+				t.Fatalf("expected status %q, got %q", tt.want, updated.status)
+			}
+		})
+	}
+}
+
+func TestTUIRenameBlockedWhileFiltering(t *testing.T) {
+	model := tuiModel{
+		state:    tuiStateList,
+		repoRoot: "/repo",
+		list:     newListModel("Worktrees", []list.Item{worktreeItem{branch: "feature", path: "/wt/feature"}}),
+	}
+	next, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	updated := next.(tuiModel)
+	if updated.state != tuiStateList || updated.list.FilterValue() != "r" {
+		t.Fatalf("expected r typed into the filter, got state %v filter %q", updated.state, updated.list.FilterValue())
+	}
+}
+
+func TestTUIRenameFlow(t *testing.T) {
+	repo := setupTestRepo(t)
+	wtPath := setupTestWorktree(t, repo, "feature")
+	items := []list.Item{worktreeItem{branch: "main", path: repo}, worktreeItem{branch: "feature", path: wtPath}}
+	model := tuiModel{
+		state:        tuiStateList,
+		repoRoot:     repo,
+		mainWorktree: repo,
+		list:         newListModel("Worktrees", items),
+	}
+	model.list.Select(1)
+
+	next, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	updated := next.(tuiModel)
+	if updated.state != tuiStateInputRename || updated.input.Value() != "feature" {
+		t.Fatalf("expected rename input pre-filled, got state %v value %q", updated.state, updated.input.Value())
+	}
+	if view := updated.View(); !strings.Contains(view, "Rename branch feature to:") {
+		t.Fatalf("expected rename prompt, got %q", view)
+	}
+
+	// q is typed, not quit; an unchanged or empty name does nothing.
+	next, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	if got := next.(tuiModel); got.state != tuiStateInputRename || got.input.Value() != "featureq" {
+		t.Fatalf("expected q typed into the input, got %q", got.input.Value())
+	}
+	next, cmd := updated.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || next.(tuiModel).state != tuiStateInputRename {
+		t.Fatalf("expected unchanged name to be ignored")
+	}
+	next, _ = updated.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	if next.(tuiModel).state != tuiStateInputRename {
+		t.Fatalf("expected non-key message to keep the input")
+	}
+
+	updated.input.SetValue("renamed")
+	next, cmd = updated.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updated = next.(tuiModel)
+	if updated.state != tuiStateBusy || cmd == nil {
+		t.Fatalf("expected busy state, got %v", updated.state)
+	}
+	msg := renameWorktreeCmd(repo, updated.pendingRename, "renamed")()
+	next, _ = updated.Update(msg)
+	updated = next.(tuiModel)
+	if updated.state != tuiStateList || updated.status != "renamed feature to renamed" {
+		t.Fatalf("expected rename success, got state %v status %q", updated.state, updated.status)
+	}
+	if item := updated.list.Items()[1].(worktreeItem); item.branch != "renamed" || item.path != worktreePath(repo, "renamed") {
+		t.Fatalf("expected reloaded list, got %+v", item)
+	}
+}
+
+func TestTUIRenameCancelAndError(t *testing.T) {
+	model := tuiModel{
+		state:         tuiStateInputRename,
+		pendingRename: worktreeItem{branch: "feature", path: "/wt/feature"},
+		input:         newBranchInput("feature"),
+		list:          newListModel("Worktrees", nil),
+	}
+	next, _ := model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	updated := next.(tuiModel)
+	if updated.state != tuiStateList || updated.pendingRename.path != "" {
+		t.Fatalf("expected rename cancelled")
+	}
+
+	model.state = tuiStateBusy
+	next, cmd := model.Update(renameResultMsg{branch: "x", err: errors.New("boom")})
+	updated = next.(tuiModel)
+	if cmd != nil || updated.state != tuiStateList || updated.status != "boom" {
+		t.Fatalf("expected rename error shown, got %q", updated.status)
+	}
+}
+
+func TestDenseDelegateRenderCheckboxes(t *testing.T) {
+	delegate := denseDelegate{DefaultDelegate: list.NewDefaultDelegate(), marked: 1}
+	items := []list.Item{
+		worktreeItem{branch: "main", path: "/repo", display: "main"},
+		worktreeItem{branch: "feat", path: "/repo-wt/feat", display: "feat", marked: true},
+	}
+	model := list.New(items, delegate, 0, 0)
+	model.SetSize(40, 5)
+
+	for i, want := range []string{"[ ] main", "[x] feat"} {
+		var buf bytes.Buffer
+		delegate.Render(&buf, model, i, items[i])
+		if got := ansi.Strip(buf.String()); !strings.Contains(got, want) {
+			t.Fatalf("item %d: expected %q, got %q", i, want, got)
+		}
+	}
+
+	// Filter matches are shifted past the checkbox.
+	model.Filter = exactMatchFilter
+	model.SetFilterText("feat")
+	model.SetFilterState(list.FilterApplied)
+	var buf bytes.Buffer
+	delegate.Render(&buf, model, 0, model.VisibleItems()[0])
+	if got := ansi.Strip(buf.String()); !strings.Contains(got, "[x] feat") {
+		t.Fatalf("expected filtered checkbox, got %q", got)
+	}
+
+	delegate.marked = 0
+	buf.Reset()
+	delegate.Render(&buf, model, 0, items[0])
+	if strings.Contains(buf.String(), "[ ]") {
+		t.Fatalf("expected no checkboxes without marks, got %q", buf.String())
+	}
+}
+
+func TestTUIMarkAndDeleteMarked(t *testing.T) {
+	repo := setupTestRepo(t)
+	clean := setupTestWorktree(t, repo, "clean")
+	dirty := setupTestWorktree(t, repo, "dirty")
+	protected := setupTestWorktree(t, repo, "release/1.0")
+	mustWriteFile(t, filepath.Join(dirty, "wip.txt"), "wip")
+
+	wts, err := gitWorktrees(repo)
+	if err != nil {
+		t.Fatalf("worktrees: %v", err)
+	}
+	items, _ := buildWorktreeItems(wts, nil)
+	model := tuiModel{
+		state:        tuiStateList,
+		repoRoot:     repo,
+		mainWorktree: repo,
+		cfg:          wtConfig{Worktree: worktreeConfig{Protected: []string{"release/*"}}},
+		list:         newListModel("Worktrees", items),
+		width:        100,
+		height:       30,
+	}
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+
+	// The main worktree can't be marked.
+	next, _ := model.Update(space)
+	updated := next.(tuiModel)
+	if updated.status != "cannot remove the main worktree" || len(updated.marked) != 0 {
+		t.Fatalf("expected main worktree refused, got %q %v", updated.status, updated.marked)
+	}
+
+	// Marking moves the cursor down; marking twice unmarks.
+	updated.list.Select(1)
+	for range 3 {
+		next, _ = updated.Update(space)
+		updated = next.(tuiModel)
+	}
+	if updated.list.Index() != 3 || len(updated.marked) != 3 || updated.markedCount != 3 {
+		t.Fatalf("expected three marks and cursor at 3, got %v at %d", updated.marked, updated.list.Index())
+	}
+	updated.list.Select(3)
+	next, _ = updated.Update(space)
+	updated = next.(tuiModel)
+	updated.list.Select(3)
+	next, _ = updated.Update(space)
+	updated = next.(tuiModel)
+	if len(updated.marked) != 3 {
+		t.Fatalf("expected toggling twice to keep the mark, got %v", updated.marked)
+	}
+	if view := updated.View(); !strings.Contains(view, "[x] ") || !strings.Contains(view, "[ ] ") {
+		t.Fatalf("expected checkboxes in view, got %q", view)
+	}
+
+	next, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	updated = next.(tuiModel)
+	if updated.state != tuiStateConfirmDelete || len(updated.pendingMarked) != 3 {
+		t.Fatalf("expected confirm for marked worktrees, got %v %v", updated.state, updated.pendingMarked)
+	}
+	view := updated.View()
+	for _, want := range []string{"Remove 3 marked worktrees?", "clean", "dirty", "release/1.0"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("expected %q in prompt, got %q", want, view)
+		}
+	}
+
+	// Cancelling keeps the marks.
+	next, _ = updated.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	cancelled := next.(tuiModel)
+	if cancelled.state != tuiStateList || cancelled.pendingMarked != nil || len(cancelled.marked) != 3 {
+		t.Fatalf("expected cancel to keep marks")
+	}
+
+	next, cmd := updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	updated = next.(tuiModel)
+	if updated.state != tuiStateBusy || cmd == nil {
+		t.Fatalf("expected busy state")
+	}
+	msg := deleteMarkedCmd(repo, repo, updated.cfg, updated.pendingMarked)()
+	next, _ = updated.Update(msg)
+	updated = next.(tuiModel)
+	want := "removed 1 worktree(s); skipped dirty (uncommitted changes), release/1.0 (protected)"
+	if updated.state != tuiStateList || updated.status != want {
+		t.Fatalf("expected %q, got %q", want, updated.status)
+	}
+	if len(updated.marked) != 0 || updated.markedCount != 0 || len(updated.list.Items()) != 3 {
+		t.Fatalf("expected marks cleared and list reloaded, got %v, %d items", updated.marked, len(updated.list.Items()))
+	}
+	if view := updated.View(); strings.Contains(view, "[ ] ") {
+		t.Fatalf("expected no checkboxes once marks are cleared, got %q", view)
+	}
+	if _, err := os.Stat(clean); !os.IsNotExist(err) {
+		t.Fatalf("expected clean worktree removed, got %v", err)
+	}
+	if _, err := os.Stat(protected); err != nil {
+		t.Fatalf("expected protected worktree kept: %v", err)
+	}
+}
+
+func TestDeleteMarkedCmdReportsFailures(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		dir := ""
+		if len(args) > 0 && args[0] == "-C" {
+			dir, args = args[1], args[2:]
+		}
+		switch args[0] {
+		case "status":
+			if dir == "/wt/broken" {
+				return exec.Command("sh", "-c", "exit 1")
+			}
+			return cmdWithOutput("")
+		case "worktree":
+			return exec.Command("sh", "-c", "echo locked >&2; exit 1")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	items := []worktreeItem{{path: "/repo"}, {path: "/wt/broken"}, {path: "/wt/detached"}}
+	msg := deleteMarkedCmd("/repo", "/repo", wtConfig{}, items)().(deleteMarkedResultMsg)
+	if msg.removed != 0 || len(msg.skipped) != 3 {
+		t.Fatalf("expected all skipped, got %+v", msg)
+	}
+	if msg.skipped[0] != "repo (main worktree)" || !strings.HasPrefix(msg.skipped[1], "broken (") || !strings.HasPrefix(msg.skipped[2], "detached (") {
+		t.Fatalf("unexpected skip reasons %v", msg.skipped)
+	}
+}
+
+func TestTUIMarkNoSelection(t *testing.T) {
+	model := tuiModel{
+		state: tuiStateList,
+		list:  newListModel("Worktrees", []list.Item{branchItem("main")}),
+	}
+	next, _ := model.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if updated := next.(tuiModel); len(updated.marked) != 0 || updated.status != "" {
+		t.Fatalf("expected nothing marked, got %v %q", updated.marked, updated.status)
+	}
+}
+
+func TestTUIBranchRemoteToggle(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+
+	var remoteArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "-C" {
+			args = args[2:]
+		}
+		switch {
+		case args[0] == "branch" && args[1] == "-r":
+			remoteArgs = args
+			return cmdWithOutput("origin/HEAD refs/remotes/origin/main\norigin/main \norigin/feature \n")
+		case args[0] == "branch":
+			return cmdWithOutput("main\n")
+		case args[0] == "show-ref":
+			// Only main exists locally.
+			if args[len(args)-1] == "refs/heads/main" {
+				return exec.Command("sh", "-c", "exit 0")
+			}
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		return exec.Command("sh", "-c", "exit 0")
+	}
+
+	model := tuiModel{
+		state:      tuiStateNewBranch,
+		repoRoot:   "/repo",
+		branchSort: branchSortAlpha,
+		branches:   newListModel("Select branch", []list.Item{branchItem("main")}),
+		width:      200,
+		height:     40,
+	}
+	if view := model.View(); !strings.Contains(view, "[local]") || !strings.Contains(view, "ctrl+r: remote") {
+		t.Fatalf("expected the footer to show local mode, got %q", view)
+	}
+	next, cmd := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	updated := next.(tuiModel)
+	if updated.state != tuiStateBusy || cmd == nil {
+		t.Fatalf("expected busy state loading remote branches, got %v", updated.state)
+	}
+	msg := loadBranchesCmd("/repo", branchSortAlpha, true)().(branchesResultMsg)
+	if !msg.remote || !reflect.DeepEqual(msg.branches, []string{"origin/feature", "origin/main"}) {
+		t.Fatalf("unexpected remote branches %+v (git %v)", msg, remoteArgs)
+	}
+	next, _ = updated.Update(msg)
+	updated = next.(tuiModel)
+	if updated.state != tuiStateNewBranch || !updated.remoteBranches {
+		t.Fatalf("expected remote branch list, got state %v remote %v", updated.state, updated.remoteBranches)
+	}
+	view := updated.View()
+	if !strings.Contains(view, "Select remote branch") || !strings.Contains(view, "[remote]") || !strings.Contains(view, "ctrl+r: local") {
+		t.Fatalf("expected remote mode in the view, got %q", view)
+	}
+
+	// enter creates feature tracking origin/feature.
+	next, _ = updated.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	picked := next.(tuiModel)
+	if picked.state != tuiStatePromptConfig || picked.pendingBranch != "feature" || picked.baseBranch != "origin/feature" || !picked.trackBase {
+		t.Fatalf("expected feature from origin/feature, got %q from %q (track %v)", picked.pendingBranch, picked.baseBranch, picked.trackBase)
+	}
+
+	// c still creates a new branch from the highlighted ref, untracked.
+	next, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	next, _ = next.(tuiModel).confirmNewBranch("other")
+	if created := next.(tuiModel); created.baseBranch != "origin/feature" || created.trackBase {
+		t.Fatalf("expected an untracked branch from origin/feature, got %q (track %v)", created.baseBranch, created.trackBase)
+	}
+
+	// ctrl+r again goes back to the local branches.
+	_, cmd = updated.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if cmd == nil {
+		t.Fatal("expected a command loading local branches")
+	}
+	next, _ = updated.Update(loadBranchesCmd("/repo", branchSortAlpha, false)())
+	if local := next.(tuiModel); local.remoteBranches || local.branches.Title != "Select branch" {
+		t.Fatalf("expected the local branch list, got remote %v title %q", local.remoteBranches, local.branches.Title)
+	}
+}
+
+func TestTUIBranchRemoteErrors(t *testing.T) {
+	oldExec := execCommand
+	defer func() { execCommand = oldExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+
+	model := tuiModel{
+		state:    tuiStateBusy,
+		repoRoot: "/repo",
+		branches: newListModel("Select branch", []list.Item{branchItem("main")}),
+	}
+	next, _ := model.Update(loadBranchesCmd("/repo", "", true)())
+	updated := next.(tuiModel)
+	if updated.state != tuiStateNewBranch || updated.remoteBranches || updated.status == "" {
+		t.Fatalf("expected to stay on the local branches with an error, got state %v status %q", updated.state, updated.status)
+	}
+	next, _ = model.Update(branchesResultMsg{remote: true})
+	updated = next.(tuiModel)
+	if updated.state != tuiStateNewBranch || updated.status != "no remote branches found" {
+		t.Fatalf("expected no remote branches status, got state %v status %q", updated.state, updated.status)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("does-not-exist")
+	}
+	next, _ = model.selectRemoteBranch("origin/feature")
+	if updated := next.(tuiModel); updated.status == "" || updated.pendingBranch != "" {
+		t.Fatalf("expected the branch check error, got status %q", updated.status)
+	}
+}
+
+func TestRemoteBranchName(t *testing.T) {
+	for ref, want := range map[string]string{"origin/main": "main", "origin/feature/x": "feature/x", "main": "main"} {
+		if got := remoteBranchName(ref); got != want {
+			t.Errorf("remoteBranchName(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
@@ -4,6 +4,15 @@ package main
 type worktree struct {
 	Path   string
 	Branch string
+	// Head is the full SHA of the commit checked out, empty on an unborn
+	// branch or in a bare repository's main worktree.
+	Head string
+	// Missing is set when git reports the worktree prunable because its
+	// directory is gone, usually after it was moved by hand.
+	Missing bool
+	// UpstreamGone is set by markUpstreamGone when the branch tracks a
+	// remote branch that no longer exists.
+	UpstreamGone bool
 }
 
 type tuiState int
@@ -18,6 +27,7 @@ const (
 	tuiStateConfirmNewBranch
 	tuiStateBusy
 	tuiStateHelp
+	tuiStateInputRename
 )
 
 const (
@@ -31,10 +41,29 @@ type tuiAction struct {
 	path string
 }
 
+// cleanState records whether a worktree has uncommitted changes. It starts
+// unknown and is filled in by a background status scan.
+type cleanState int
+
+const (
+	cleanUnknown cleanState = iota
+	cleanClean
+	cleanDirty
+)
+
 type worktreeItem struct {
 	branch  string
 	path    string
 	display string
+	clean   cleanState
+	stale   bool
+	missing bool
+	// commitTime is the latest commit's Unix time, 0 if unknown. It is
+	// only meaningful once timeLoaded is set; the TUI loads it lazily.
+	commitTime int64
+	timeLoaded bool
+	// marked is set for worktrees picked for a bulk delete.
+	marked bool
 }
 
 func (w worktreeItem) Title() string {